@@ -0,0 +1,110 @@
+package readline
+
+import (
+	"slices"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/keymap"
+)
+
+// BindContext is a set of binds and commands overlaying those of the main
+// keymap while the line is in a given context, like "editing a SQL statement"
+// or "editing a path", as decided by the host: this way, shortcuts specific to
+// this context are available without having to write and switch keymaps.
+type BindContext struct {
+	// Active returns true if the context applies to the line and cursor.
+	// It is called each time keys are dispatched, and must be fast. If
+	// nil, the context is always active.
+	Active func(line []rune, cursor int) bool
+
+	// Binds maps key sequences, in inputrc syntax (like `\C-x\C-f`), to
+	// command names: either those of builtin commands, or those of the
+	// context commands. They take precedence over the keymap binds.
+	Binds map[string]string
+
+	// Keymaps are the main keymaps (like emacs or vi-insert) in which the
+	// binds are used: if empty, the binds are used in all of them.
+	Keymaps []string
+
+	// Commands are commands that can only be run while the context is
+	// active: they do nothing otherwise, even if bound to keys elsewhere.
+	Commands map[string]func()
+}
+
+// namedBindContext is a binding context with its name.
+type namedBindContext struct {
+	name string
+	BindContext
+}
+
+// AddBindContext declares a binding context with the given name, which
+// replaces the context with the same name, if any. When several contexts
+// are active at once, the binds of the last declared ones take precedence.
+// Binding contexts are not used in search modes, nor when reading forms.
+func (rl *Shell) AddBindContext(name string, context BindContext) {
+	rl.RemoveBindContext(name)
+	rl.bindContexts = append(rl.bindContexts, namedBindContext{name, context})
+
+	commands := make(map[string]func(), len(context.Commands))
+
+	for command, run := range context.Commands {
+		commands[command] = func() {
+			if rl.bindContextActive(name) {
+				run()
+			}
+		}
+	}
+
+	rl.Keymap.Register(commands)
+}
+
+// RemoveBindContext removes the binding context with the given name,
+// if any: its binds are not used anymore, and its commands do nothing.
+func (rl *Shell) RemoveBindContext(name string) {
+	rl.bindContexts = slices.DeleteFunc(rl.bindContexts, func(context namedBindContext) bool {
+		return context.name == name
+	})
+}
+
+// bindContextActive returns true if the named binding
+// context is declared and active for the line and cursor.
+func (rl *Shell) bindContextActive(name string) bool {
+	for _, context := range rl.bindContexts {
+		if context.name == name {
+			return rl.isContextActive(context.BindContext)
+		}
+	}
+
+	return false
+}
+
+// bindContextsOverlay returns the binds of all binding contexts
+// active for the line and cursor, in the given main keymap.
+func (rl *Shell) bindContextsOverlay(main keymap.Mode) map[string]inputrc.Bind {
+	var binds map[string]inputrc.Bind
+
+	for _, context := range rl.bindContexts {
+		if len(context.Keymaps) > 0 && !slices.Contains(context.Keymaps, string(main)) {
+			continue
+		}
+
+		if !rl.isContextActive(context.BindContext) {
+			continue
+		}
+
+		if binds == nil {
+			binds = make(map[string]inputrc.Bind)
+		}
+
+		for seq, command := range context.Binds {
+			binds[inputrc.Unescape(seq)] = inputrc.Bind{Action: command}
+		}
+	}
+
+	return binds
+}
+
+// isContextActive returns true if the context applies to the line and cursor.
+func (rl *Shell) isContextActive(context BindContext) bool {
+	return context.Active == nil || context.Active(*rl.line, rl.cursor.Pos())
+}
@@ -0,0 +1,84 @@
+package readline_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_BindContext(t *testing.T) {
+	sql := readline.BindContext{
+		Active: func(line []rune, _ int) bool {
+			return strings.HasPrefix(strings.ToUpper(string(line)), "SELECT")
+		},
+		Binds: map[string]string{
+			`\C-x\C-u`: "upcase-sql",
+			`\C-a`:     "end-of-line",
+		},
+		Keymaps: []string{"emacs"},
+	}
+
+	tests := []struct {
+		name   string
+		vi     bool
+		bind   bool
+		remove bool
+		line   string
+		keys   []string
+		want   string
+	}{
+		{name: "active", line: "select 1", keys: []string{`\C-x\C-u`}, want: "SELECT 1"},
+		{name: "inactive", line: "ls -l", keys: []string{`\C-x\C-u`}, want: ""},
+		{name: "command", bind: true, line: "ls -l", keys: []string{`\C-xU`}, want: "ls -l"},
+		{name: "bound", bind: true, line: "select 1", keys: []string{`\C-xU`}, want: "SELECT 1"},
+		{name: "overlay", line: "select 1", keys: []string{`\C-a`, "!"}, want: "select 1!"},
+		{name: "keymap", line: "ls", keys: []string{`\C-a`, "!"}, want: "!ls"},
+		{name: "other keymap", vi: true, line: "select 1", keys: []string{`\C-a`, "!"}, want: "!select 1"},
+		{name: "removed", remove: true, line: "select 1", keys: []string{`\C-a`, "!"}, want: "!select 1"},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+
+		if test.vi {
+			if err := rl.SetOption("editing-mode", "vi"); err != nil {
+				t.Fatalf("SetOption() error = %v", err)
+			}
+
+			rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-a`), "beginning-of-line", false)
+		}
+
+		context := sql
+		context.Commands = map[string]func(){
+			"upcase-sql": func() {
+				line := strings.ToUpper(string(*rl.Line()))
+				rl.Line().Set([]rune(line)...)
+			},
+		}
+
+		rl.AddBindContext("sql", context)
+
+		if test.bind {
+			rl.Config.Bind("emacs", inputrc.Unescape(`\C-xU`), "upcase-sql", false)
+		}
+
+		if test.remove {
+			rl.RemoveBindContext("sql")
+		}
+
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(test.keys, `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%s: Run(%q) line = %q, want %q", test.name, test.keys, line, test.want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package readline
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/reeflective/readline/internal/locale"
+)
+
+// maxRecentOutput is the number of output lines kept by AddRecentOutput.
+const maxRecentOutput = 100
+
+// minBufferWord is the length of the shortest words completed with the
+// complete-buffer-words option: shorter ones are faster typed than completed.
+const minBufferWord = 3
+
+// AddRecentOutput records output lines of the application (like the output
+// of the last command run), whose words are then completed along with those
+// of the line when the complete-buffer-words option is enabled. Only the most
+// recent lines are kept. This function must be called either when the shell
+// is not reading input, or from within commands and hooks.
+func (rl *Shell) AddRecentOutput(lines ...string) {
+	rl.recentOutput = append(rl.recentOutput, lines...)
+
+	if len(rl.recentOutput) > maxRecentOutput {
+		rl.recentOutput = slices.Clone(rl.recentOutput[len(rl.recentOutput)-maxRecentOutput:])
+	}
+}
+
+// addBufferWords adds the words of the line (except the one at the cursor)
+// and those of the recent output lines to the completions, in a group of
+// their own displayed after all others, unless they are already candidates.
+// Words are the blank-separated ones, without the quotes and punctuation
+// around them, and the parts of those separated by slashes, colons, equal
+// or at signs, like the components of a path.
+func (rl *Shell) addBufferWords(comps Completions, line []rune, cursor int) Completions {
+	seen := make(map[string]bool)
+	for _, value := range comps.values {
+		seen[value.Value] = true
+	}
+
+	var words []string
+
+	add := func(word string) {
+		if len([]rune(word)) >= minBufferWord && !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+
+	texts := []string{lineWithoutWord(line, cursor)}
+	for i := len(rl.recentOutput) - 1; i >= 0; i-- {
+		texts = append(texts, rl.recentOutput[i])
+	}
+
+	for _, text := range texts {
+		for _, word := range strings.Fields(text) {
+			word = strings.Trim(word, "\"'`,;()[]{}<>")
+			add(word)
+
+			for _, part := range strings.FieldsFunc(word, isWordSeparator) {
+				add(part)
+			}
+		}
+	}
+
+	if len(words) == 0 {
+		return comps
+	}
+
+	buffer := CompleteValues(words...).Tag(rl.locale.Get(locale.BufferWords))
+
+	comps.values = append(comps.values, buffer.values...)
+	comps.merge(buffer)
+
+	return comps
+}
+
+// lineWithoutWord returns the line without the blank-separated
+// word at the cursor, which is the one being completed.
+func lineWithoutWord(line []rune, cursor int) string {
+	start, end := min(cursor, len(line)), min(cursor, len(line))
+
+	for start > 0 && !unicode.IsSpace(line[start-1]) {
+		start--
+	}
+
+	for end < len(line) && !unicode.IsSpace(line[end]) {
+		end++
+	}
+
+	return string(line[:start]) + " " + string(line[end:])
+}
+
+// isWordSeparator returns true if the character separates
+// the parts of a word completed as buffer words.
+func isWordSeparator(char rune) bool {
+	return char == '/' || char == ':' || char == '=' || char == '@'
+}
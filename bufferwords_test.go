@@ -0,0 +1,42 @@
+package readline_test
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_CompleteBufferWords(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{"deploy my-long-service-name && logs my-l", `\t`}, want: "deploy my-long-service-name && logs my-long-service-name"},
+		{keys: []string{"kubectl logs dep", `\t`}, want: "kubectl logs deployment-7f9c"},
+		{keys: []string{"cat /var/log/a", `\t`}, want: "cat /var/log/app.log"},
+		{keys: []string{"echo ab", `\t`}, want: "echo abc"},
+		{keys: []string{"echo comp", `\t`}, want: "echo completed"},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+		rl.Config.Set("complete-buffer-words", true)
+
+		rl.Completer = func(_ []rune, _ int) readline.Completions {
+			return readline.CompleteValues("completed")
+		}
+
+		rl.AddRecentOutput("error: cannot open '/var/log/app.log'", "pod/deployment-7f9c created", "ab abc")
+
+		line, err := term.Run(rl, append(test.keys, `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("keys %q: line = %q, want %q", test.keys, line, test.want)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package readline
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
+	"github.com/reeflective/readline/internal/strutil"
+)
+
+// cheatSheetEntry is a command listed in the cheat sheet, with its label.
+type cheatSheetEntry struct {
+	command string
+	label   string
+}
+
+// cheatSheets are the commands listed in the cheat sheet of each keymap.
+var cheatSheets = map[keymap.Mode][]cheatSheetEntry{
+	keymap.Emacs: {
+		{"complete", locale.CheatComplete},
+		{"reverse-search-history", locale.CheatSearch},
+		{"undo", locale.CheatUndo},
+		{"kill-line", locale.CheatKill},
+		{"yank", locale.CheatYank},
+	},
+	keymap.ViInsert: {
+		{"vi-movement-mode", locale.CheatNormal},
+		{"complete", locale.CheatComplete},
+		{"reverse-search-history", locale.CheatSearch},
+		{"undo", locale.CheatUndo},
+	},
+	keymap.ViCommand: {
+		{"vi-insertion-mode", locale.CheatInsert},
+		{"vi-append-mode", locale.CheatAppend},
+		{"vi-delete-to", locale.CheatDelete},
+		{"vi-change-to", locale.CheatChange},
+		{"vi-yank-to", locale.CheatYank},
+		{"vi-put-after", locale.CheatPut},
+		{"vi-undo", locale.CheatUndo},
+		{"vi-visual-mode", locale.CheatVisual},
+		{"vi-search", locale.CheatSearch},
+	},
+	keymap.Visual: {
+		{"vi-delete-to", locale.CheatDelete},
+		{"vi-change-to", locale.CheatChange},
+		{"vi-yank-to", locale.CheatYank},
+		{"vi-oper-swap-case", locale.CheatCase},
+		{"vi-movement-mode", locale.CheatNormal},
+	},
+	keymap.MenuSelect: {
+		{"menu-complete", locale.CheatNext},
+		{"menu-complete-backward", locale.CheatPrevious},
+		{"accept-and-menu-complete", locale.CheatAccept},
+		{"menu-incremental-search", locale.CheatFilter},
+		{"menu-cancel", locale.CheatCancel},
+	},
+	keymap.Isearch: {
+		{"isearch-next-match", locale.CheatNext},
+		{"isearch-previous-match", locale.CheatPrevious},
+		{"isearch-toggle-fuzzy", locale.CheatFuzzy},
+		{"accept-line", locale.CheatAccept},
+		{"abort", locale.CheatCancel},
+	},
+}
+
+// cheatSheetCommands returns the commands managing the cheat sheet.
+func (rl *Shell) cheatSheetCommands() map[string]func() {
+	return map[string]func(){
+		"toggle-cheat-sheet": rl.toggleCheatSheet,
+	}
+}
+
+// Toggle the cheat-sheet option, which shows the keys of the most
+// common commands of the current keymap below the input line.
+func (rl *Shell) toggleCheatSheet() {
+	rl.History.SkipSave()
+	rl.Config.Set("cheat-sheet", !rl.Config.GetBool("cheat-sheet"))
+}
+
+// updateCheatSheet sets the guide line of the hint to the cheat sheet of
+// the current keymap if the cheat-sheet option is enabled, or clears it.
+func (rl *Shell) updateCheatSheet() {
+	if !rl.Config.GetBool("cheat-sheet") {
+		rl.Hint.Guide("")
+		return
+	}
+
+	rl.Hint.Guide(rl.cheatSheet())
+}
+
+// cheatSheet returns the keys bound to the cheat sheet commands of the
+// current keymap, each followed by its label, and as many as fit on one
+// line after the keymap name. Commands bound to no key are skipped.
+func (rl *Shell) cheatSheet() string {
+	mode := rl.cheatSheetKeymap()
+
+	line := color.Bold + color.FgCyanBright + string(mode) + color.Reset
+	width := strutil.RealLength(line)
+
+	for _, entry := range cheatSheets[mode] {
+		key := rl.cheatSheetKey(mode, entry.command)
+		if key == "" {
+			continue
+		}
+
+		label := rl.locale.Get(entry.label)
+
+		width += strutil.RealLength("  " + key + " " + label)
+		if width >= rl.out.Width() {
+			break
+		}
+
+		line += "  " + color.Bold + key + color.BoldReset + " " + color.Dim + label + color.DimReset
+	}
+
+	return line
+}
+
+// cheatSheetKeymap returns the keymap whose cheat sheet is shown: the local
+// one (visual mode, completion menu or isearch) if any, or the main one.
+func (rl *Shell) cheatSheetKeymap() keymap.Mode {
+	if local := rl.Keymap.Local(); local != "" && local != keymap.ViOpp {
+		return local
+	}
+
+	switch main := rl.Keymap.Main(); main {
+	case keymap.EmacsStandard, keymap.EmacsMeta, keymap.EmacsCtrlX:
+		return keymap.Emacs
+	case keymap.Vi, keymap.ViMove:
+		return keymap.ViCommand
+	default:
+		return main
+	}
+}
+
+// cheatSheetKey returns the shortest key sequence bound to a command
+// in a keymap, or in the main one for local keymaps falling back to it.
+func (rl *Shell) cheatSheetKey(mode keymap.Mode, command string) string {
+	keymaps := []keymap.Mode{mode}
+	if main := rl.Keymap.Main(); mode == keymap.Isearch || mode == keymap.MenuSelect {
+		keymaps = append(keymaps, main)
+	}
+
+	for _, name := range keymaps {
+		var sequences []string
+
+		for seq, bind := range rl.Config.Binds[string(name)] {
+			if bind.Action == command && !bind.Macro {
+				sequences = append(sequences, seq)
+			}
+		}
+
+		if len(sequences) == 0 {
+			continue
+		}
+
+		sortKeySequences(sequences)
+
+		return cheatSheetKeyName(sequences[0])
+	}
+
+	return ""
+}
+
+// sortKeySequences sorts key sequences from the shortest to the longest.
+// Lowercase keys come last in byte order, and are preferred to their
+// uppercase counterparts, like Escape to Ctrl keys.
+func sortKeySequences(sequences []string) {
+	sort.Slice(sequences, func(i, j int) bool {
+		if len(sequences[i]) != len(sequences[j]) {
+			return len(sequences[i]) < len(sequences[j])
+		}
+
+		return sequences[i] > sequences[j]
+	})
+}
+
+// cheatSheetKeyName returns a key sequence in the inputrc notation,
+// but with control keys always written as such: \C-k rather than \v.
+func cheatSheetKeyName(seq string) string {
+	var name strings.Builder
+
+	keys := []rune(seq)
+
+	for i := 0; i < len(keys); i++ {
+		key := keys[i]
+
+		switch {
+		case key == inputrc.Esc && i < len(keys)-1:
+			name.WriteString(`\M-`)
+			continue
+		case key == inputrc.Esc:
+			name.WriteString(`\e`)
+		case key == inputrc.Delete:
+			name.WriteString(`\C-?`)
+		case key < ' ':
+			name.WriteString(`\C-` + string(unicode.ToLower(key+'@')))
+		default:
+			name.WriteString(inputrc.Escape(string(key)))
+		}
+	}
+
+	return name.String()
+}
+
+// menuFooterEntries are the commands listed in the completion menu footer,
+// when bound in its keymap (menu-select, or isearch when filtering).
+var menuFooterEntries = []cheatSheetEntry{
+	{"menu-complete", locale.CheatNext},
+	{"menu-complete-backward", locale.CheatPrevious},
+	{"menu-incremental-search", locale.CheatFilter},
+	{"isearch-toggle-fuzzy", locale.CheatFuzzy},
+	{"menu-cancel", locale.CheatCancel},
+}
+
+// completionFooter returns the footer displayed below the completion menu if
+// the completion-menu-footer option is enabled and the menu is in use: up to
+// two keys bound to each of the main menu commands, followed by its label, and
+// the mode used to match candidates when filtering them. Labels are displayed
+// with the completion-footer-style, and entries not fitting on a line dropped.
+func (rl *Shell) completionFooter() string {
+	local := rl.Keymap.Local()
+	if !rl.Config.GetBool("completion-menu-footer") || (local != keymap.MenuSelect && local != keymap.Isearch) {
+		return ""
+	}
+
+	style := color.UnquoteRC(rl.Config.GetString("completion-footer-style"))
+
+	mode := locale.MatcherRegexp
+	if rl.completer.IsearchFuzzy() {
+		mode = locale.MatcherFuzzy
+	}
+
+	matcher := rl.locale.Sprintf(locale.MenuMatcher, rl.locale.Get(mode))
+	width := strutil.RealLength(matcher)
+
+	var footer string
+
+	for _, entry := range menuFooterEntries {
+		keys := rl.menuFooterKeys(local, entry.command)
+		if keys == "" {
+			continue
+		}
+
+		label := rl.locale.Get(entry.label)
+
+		width += strutil.RealLength(keys + " " + label + "  ")
+		if width >= rl.out.Width() {
+			break
+		}
+
+		footer += color.Bold + keys + color.BoldReset + " " + style + label + color.Reset + "  "
+	}
+
+	return footer + style + matcher + color.Reset
+}
+
+// menuFooterKeys returns the (at most two) shortest key sequences bound
+// to a command in a completion keymap, separated by a slash, with the most
+// common keys named as such (like Tab), and the others like in the cheat sheet.
+func (rl *Shell) menuFooterKeys(mode keymap.Mode, command string) string {
+	var sequences []string
+
+	for seq, bind := range rl.Config.Binds[string(mode)] {
+		if bind.Action == command && !bind.Macro {
+			sequences = append(sequences, seq)
+		}
+	}
+
+	sortKeySequences(sequences)
+
+	names := make([]string, 0, 2)
+
+	for _, seq := range sequences[:min(len(sequences), 2)] {
+		switch seq {
+		case "\t":
+			names = append(names, "Tab")
+		case "\x1b[Z":
+			names = append(names, "S-Tab")
+		case "\x1b":
+			names = append(names, "Esc")
+		case "\r":
+			names = append(names, "Enter")
+		case "\x1b[A":
+			names = append(names, "Up")
+		case "\x1b[B":
+			names = append(names, "Down")
+		case "\x1b[C":
+			names = append(names, "Right")
+		case "\x1b[D":
+			names = append(names, "Left")
+		default:
+			name := strings.NewReplacer(`\C-`, "C-", `\M-`, "M-").Replace(cheatSheetKeyName(seq))
+			names = append(names, name)
+		}
+	}
+
+	return strings.Join(names, "/")
+}
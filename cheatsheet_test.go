@@ -0,0 +1,64 @@
+package readline_test
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_CheatSheet(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-o`), "toggle-cheat-sheet", false)
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("ab", `\C-o`)
+	waitForScreen(t, term, `ab`+"\n"+`emacs  \C-i complete  \C-r search  \C-_ undo  \C-k kill  \C-y yank`)
+
+	term.Type(`\C-r`)
+	waitForScreen(t, term, `ab`+"\n"+"default history (inc-search) (no matches): _\n"+
+		`isearch  \M-n next  \M-p previous  \C-t fuzzy  \C-m accept  \C-c cancel`)
+
+	term.Type(`\C-g`, `\C-o`)
+	waitForScreen(t, term, "ab")
+
+	term.Type(`\r`)
+
+	if line := <-done; line != "ab" {
+		t.Errorf("Readline() line = %q, want %q", line, "ab")
+	}
+
+	// Vi keymaps have their own cheat sheets.
+	term, rl = newViTerminal(t, 80, 10)
+	rl.Config.Set("cheat-sheet", true)
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("ab")
+	waitForScreen(t, term, `ab`+"\n"+`vi-insert  \e normal  \C-i complete  \C-r search  \C-_ undo`)
+
+	term.Type(`\C-o`)
+	waitForScreen(t, term, `ab`+"\n"+`vi-command  i insert  a append  d delete  c change  y yank  p put  u undo`)
+
+	term.Type("v")
+	waitForScreen(t, term, `ab`+"\n"+`vi-visual  x delete  c change  y yank  ~ case  \e normal`)
+
+	term.Type(`\e`, `\r`)
+
+	if line := <-done; line != "ab" {
+		t.Errorf("Readline() line = %q, want %q", line, "ab")
+	}
+}
@@ -0,0 +1,120 @@
+package readline
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// Diagnostic is a span of the input line reported by a checker, like an
+// unknown command or a misspelled path, from its Start position (included)
+// to its End one (excluded), in runes. The span is underlined with the
+// diagnostic-style option (a curly underline by default), and its message,
+// if any, is displayed below the input line.
+type Diagnostic struct {
+	Start   int
+	End     int
+	Message string
+}
+
+// SetChecker registers a checker run asynchronously over the whole input line,
+// for checks too expensive to run while typing (like looking up commands in
+// the PATH, or checking the spelling of words).
+//
+// Once the line has changed, the checker is called in another goroutine if the
+// line stays the same for the debounce duration, and its diagnostics are shown
+// as soon as it returns. Its context is cancelled when the line changes again
+// (usually with the next keystroke) or when the shell stops reading, in which
+// case its diagnostics are discarded. Diagnostics of the previous line are
+// dropped as soon as the line changes. A nil function disables checking.
+func (rl *Shell) SetChecker(debounce time.Duration, checker func(ctx context.Context, line []rune) []Diagnostic) {
+	rl.checker = checker
+	rl.checkDebounce = debounce
+}
+
+// checkLine starts checking the line with the checker, if any, when the line
+// has changed since its last check, and drops the diagnostics of the latter.
+func (rl *Shell) checkLine() {
+	if slices.Equal(*rl.line, rl.checkedLine) {
+		return
+	}
+
+	rl.checkedLine = append(rl.checkedLine[:0], *rl.line...)
+	rl.cancelCheck()
+	rl.showDiagnostics(nil)
+
+	if rl.checker == nil || rl.line.Len() == 0 {
+		return
+	}
+
+	rl.fetchDiagnostics(append([]rune{}, *rl.line...))
+}
+
+// fetchDiagnostics starts checking the line once debounced.
+func (rl *Shell) fetchDiagnostics(line []rune) {
+	ctx, cancel := context.WithCancel(rl.readContext())
+	rl.checkCancel = cancel
+
+	checker, debounce := rl.checker, rl.checkDebounce
+
+	go func() {
+		timer := time.NewTimer(debounce)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		diagnostics := checker(ctx, line)
+
+		// The line is locked while the shell processes keys,
+		// which then cancels this check if the line changes.
+		rl.lineMutex.Lock()
+		defer rl.lineMutex.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		rl.showDiagnostics(diagnostics)
+
+		if rl.isReading() {
+			rl.Display.Refresh()
+		}
+	}()
+}
+
+// cancelCheck cancels the check running asynchronously, if any.
+// It must be called with the line locked.
+func (rl *Shell) cancelCheck() {
+	if rl.checkCancel != nil {
+		rl.checkCancel()
+		rl.checkCancel = nil
+	}
+}
+
+// showDiagnostics underlines the spans of the diagnostics in the line,
+// and displays their messages below it, one per line.
+func (rl *Shell) showDiagnostics(diagnostics []Diagnostic) {
+	var (
+		spans    [][]int
+		messages []string
+	)
+
+	for _, diagnostic := range diagnostics {
+		spans = append(spans, []int{diagnostic.Start, diagnostic.End})
+
+		if diagnostic.Message != "" {
+			messages = append(messages, color.FgRed+diagnostic.Message+color.Reset)
+		}
+	}
+
+	rl.Display.HighlightDiagnostics(spans)
+	rl.Hint.Diagnose(strings.Join(messages, term.NewlineReturn))
+}
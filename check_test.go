@@ -0,0 +1,81 @@
+package readline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_SetChecker(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	checked := make(chan string, 10)
+	cancelled := make(chan string, 10)
+
+	rl.SetChecker(10*time.Millisecond, func(ctx context.Context, line []rune) []readline.Diagnostic {
+		checked <- string(line)
+
+		// Block until cancelled, to check that the next keys cancel checks.
+		if strings.HasSuffix(string(line), "slow") {
+			<-ctx.Done()
+			cancelled <- string(line)
+
+			return []readline.Diagnostic{{Start: 0, End: len(line), Message: "discarded"}}
+		}
+
+		if command, _, _ := strings.Cut(string(line), " "); command != "ls" {
+			return []readline.Diagnostic{{Start: 0, End: len(command), Message: "unknown command: " + command}}
+		}
+
+		return nil
+	})
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("gti status")
+	waitForScreen(t, term, "gti status\nunknown command: gti")
+
+	if got, want := term.Output(), "\x1b[4:3mg"; !strings.Contains(got, want) {
+		t.Errorf("Output() = %q, want the command underlined", got)
+	}
+
+	// Diagnostics are dropped as soon as the line changes.
+	term.Type(" slow")
+	waitForScreen(t, term, "gti status slow")
+
+	select {
+	case line := <-cancelled:
+		t.Errorf("check of %q cancelled before the line changed", line)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	term.Type(`\C-a`, `\C-k`, "ls")
+
+	select {
+	case line := <-cancelled:
+		if line != "gti status slow" {
+			t.Errorf("cancelled check of %q, want %q", line, "gti status slow")
+		}
+	case <-time.After(readlinetest.Timeout):
+		t.Error("check not cancelled when the line changed")
+	}
+
+	waitForScreen(t, term, "ls")
+
+	term.Type(`\r`)
+
+	if line := <-done; line != "ls" {
+		t.Errorf("Readline() line = %q, want %q", line, "ls")
+	}
+}
@@ -0,0 +1,217 @@
+package readline
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/reeflective/readline/internal/core"
+)
+
+// asyncCompleter is a completer run concurrently with the shell completer.
+type asyncCompleter struct {
+	tag       string
+	completer func(ctx context.Context, line []rune, cursor int) Completions
+}
+
+// asyncFetch holds the completions generated so far by the
+// asynchronous completers for a given line and cursor position.
+type asyncFetch struct {
+	line    []rune
+	cursor  int
+	cancel  context.CancelFunc
+	done    []Completions
+	pending []string
+}
+
+// asyncResult is the completions returned by an asynchronous completer.
+type asyncResult struct {
+	tag   string
+	comps Completions
+}
+
+// loadingRefresh is the interval at which completions are redisplayed while
+// some of them are still loading, so that their loading rows are animated.
+const loadingRefresh = 100 * time.Millisecond
+
+// AddAsyncCompleter registers a completer too slow to be waited for, like one
+// querying a remote service. When completing, it runs concurrently with the shell
+// completer, and with the other asynchronous ones: its candidates are displayed
+// along with the others as soon as it returns, and a loading row is displayed
+// under the tag heading in the meantime, so that the first useful candidates
+// appear immediately.
+//
+// Candidates without a tag are given this one. The prefix and the suffix of
+// the completions are those of the shell completer, if any. The context of the
+// completer is cancelled when the line changes before it has returned.
+func (rl *Shell) AddAsyncCompleter(tag string, completer func(ctx context.Context, line []rune, cursor int) Completions) {
+	rl.asyncCompleters = append(rl.asyncCompleters, asyncCompleter{tag: tag, completer: completer})
+}
+
+// addAsyncCompletions adds the completions generated so far by the asynchronous
+// completers for the line to the given ones, and starts generating them if they
+// are not being generated yet. The tags of those not ready yet are returned.
+func (rl *Shell) addAsyncCompletions(comps Completions, line []rune, cursor int) (Completions, []string) {
+	if len(rl.asyncCompleters) == 0 {
+		return comps, nil
+	}
+
+	fetch := rl.asyncFetch
+	if fetch == nil || fetch.cursor != cursor || !slices.Equal(fetch.line, line) {
+		fetch = rl.fetchCompletions(line, cursor)
+	}
+
+	for _, other := range fetch.done {
+		comps.values = append(comps.values, other.values...)
+		comps.merge(other)
+	}
+
+	return comps, slices.Clone(fetch.pending)
+}
+
+// fetchCompletions cancels the completions being generated asynchronously,
+// if any, and starts generating them for the line with all asynchronous
+// completers, each in its own goroutine.
+func (rl *Shell) fetchCompletions(line []rune, cursor int) *asyncFetch {
+	rl.cancelCompletions()
+
+	ctx, cancel := context.WithCancel(rl.readContext())
+
+	fetch := &asyncFetch{line: slices.Clone(line), cursor: cursor, cancel: cancel}
+	rl.asyncFetch = fetch
+
+	results := make(chan asyncResult, len(rl.asyncCompleters))
+
+	for _, async := range rl.asyncCompleters {
+		fetch.pending = append(fetch.pending, async.tag)
+
+		go func(async asyncCompleter) {
+			comps := async.completer(ctx, slices.Clone(line), cursor)
+
+			for i := range comps.values {
+				if comps.values[i].Tag == "" {
+					comps.values[i].Tag = async.tag
+				}
+			}
+
+			results <- asyncResult{tag: async.tag, comps: comps}
+		}(async)
+	}
+
+	go rl.awaitCompletions(ctx, fetch, results)
+
+	return fetch
+}
+
+// awaitCompletions adds the completions of asynchronous completers to those
+// displayed as soon as they are returned, and redisplays the loading rows
+// of the others at regular intervals until all of them have returned.
+func (rl *Shell) awaitCompletions(ctx context.Context, fetch *asyncFetch, results chan asyncResult) {
+	ticker := time.NewTicker(loadingRefresh)
+	defer ticker.Stop()
+
+	for remaining := len(fetch.pending); remaining > 0; {
+		var result *asyncResult
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case done := <-results:
+			result = &done
+			remaining--
+		}
+
+		// The line is locked while the shell processes keys,
+		// which then cancels this fetch if the line changes.
+		rl.lineMutex.Lock()
+
+		if ctx.Err() == nil {
+			rl.updateAsyncCompletions(fetch, result)
+		}
+
+		rl.lineMutex.Unlock()
+	}
+}
+
+// updateAsyncCompletions records the completions returned by an asynchronous
+// completer, if any, and updates the completions displayed if some of them
+// are still loading. It must be called with the line locked.
+func (rl *Shell) updateAsyncCompletions(fetch *asyncFetch, result *asyncResult) {
+	if result != nil {
+		fetch.done = append(fetch.done, result.comps)
+
+		if i := slices.Index(fetch.pending, result.tag); i != -1 {
+			fetch.pending = slices.Delete(fetch.pending, i, i+1)
+		}
+	}
+
+	if !rl.completer.Loading() {
+		return
+	}
+
+	if result != nil {
+		rl.completer.Update()
+	}
+
+	if rl.isReading() {
+		rl.Display.Refresh()
+	}
+}
+
+// completeInterruptible runs the context completer of the shell, and cancels
+// its context as soon as a key is typed before it has returned, so that the
+// key is not delayed: the completions it has produced so far are returned.
+// The key is left in the stack, to be dispatched once done completing.
+func (rl *Shell) completeInterruptible(line []rune, cursor int) Completions {
+	ctx, cancel := context.WithCancel(rl.readContext())
+	defer cancel()
+
+	done := make(chan Completions, 1)
+
+	go func() {
+		done <- rl.CompleterContext(ctx, slices.Clone(line), cursor)
+	}()
+
+	waitCtx, stopWaiting := context.WithCancel(ctx)
+	typed := make(chan error, 1)
+
+	go func() {
+		for {
+			err := core.WaitAvailableKeys(waitCtx, rl.Keys, rl.Config)
+			if !errors.Is(err, core.ErrIdle) {
+				typed <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case comps := <-done:
+		stopWaiting()
+		<-typed
+
+		return comps
+	case err := <-typed:
+		stopWaiting()
+
+		if err == nil {
+			rl.trace("completer", "interrupted", true)
+		}
+
+		cancel()
+
+		return <-done
+	}
+}
+
+// cancelCompletions cancels the completions being generated asynchronously,
+// if any, and forgets those already generated. It must be called with the
+// line locked.
+func (rl *Shell) cancelCompletions() {
+	if rl.asyncFetch != nil {
+		rl.asyncFetch.cancel()
+		rl.asyncFetch = nil
+	}
+}
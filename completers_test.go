@@ -0,0 +1,106 @@
+package readline_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_AsyncCompleters(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteRaw([]readline.Completion{{Value: "local", Tag: "files"}})
+	}
+
+	release := make(chan struct{})
+
+	rl.AddAsyncCompleter("remote", func(ctx context.Context, _ []rune, _ int) readline.Completions {
+		select {
+		case <-release:
+		case <-ctx.Done():
+		}
+
+		return readline.CompleteValues("origin", "upstream")
+	})
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type(`\e?`)
+
+	waitForText(t, term, "loading...")
+
+	if !strings.Contains(term.Screen(), "local") {
+		t.Errorf("synchronous completions not displayed while loading:\n%s", term.Screen())
+	}
+
+	close(release)
+
+	waitForText(t, term, "upstream")
+
+	if strings.Contains(term.Screen(), "loading...") {
+		t.Errorf("loading row still displayed:\n%s", term.Screen())
+	}
+
+	term.Type(`\C-u`)
+	term.Type(`\r`)
+
+	if line := <-done; line != "" {
+		t.Errorf("Readline() line = %q, want %q", line, "")
+	}
+}
+
+func TestShell_CompleterContext(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	started := make(chan struct{})
+
+	rl.CompleterContext = func(ctx context.Context, _ []rune, _ int) readline.Completions {
+		produced := []string{"alpha", "alps"}
+		close(started)
+
+		// Never done before the next key is typed.
+		<-ctx.Done()
+
+		return readline.CompleteValues(produced...)
+	}
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("a", `	`)
+
+	select {
+	case <-started:
+	case <-time.After(readlinetest.Timeout):
+		t.Fatal("completer not called")
+	}
+
+	term.Type("X", `\r`)
+
+	select {
+	case line := <-done:
+		if line != "alphaX" {
+			t.Errorf("Readline() line = %q, want %q", line, "alphaX")
+		}
+	case <-time.After(readlinetest.Timeout):
+		t.Fatalf("key typed while completing not processed:\n%s", term.Screen())
+	}
+}
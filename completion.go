@@ -7,6 +7,7 @@ import (
 	"github.com/reeflective/readline/internal/completion"
 	"github.com/reeflective/readline/internal/history"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
 )
 
 func (rl *Shell) completionCommands() commands {
@@ -20,9 +21,18 @@ func (rl *Shell) completionCommands() commands {
 
 		"menu-complete-next-tag":   rl.menuCompleteNextTag,
 		"menu-complete-prev-tag":   rl.menuCompletePrevTag,
+		"menu-complete-next-alias": rl.menuCompleteNextAlias,
 		"accept-and-menu-complete": rl.acceptAndMenuComplete,
 		"vi-registers-complete":    rl.viRegistersComplete,
 		"menu-incremental-search":  rl.menuIncrementalSearch,
+		"menu-accept":              rl.menuAccept,
+		"menu-cancel":              rl.menuCancel,
+		"menu-toggle-mark":         rl.menuToggleMark,
+		"isearch-toggle-fuzzy":     rl.isearchToggleFuzzy,
+		"isearch-toggle-group":     rl.isearchToggleGroup,
+		"isearch-next-match":       rl.isearchNextMatch,
+		"isearch-previous-match":   rl.isearchPreviousMatch,
+		"isearch-complete-query":   rl.isearchCompleteQuery,
 	}
 }
 
@@ -146,6 +156,19 @@ func (rl *Shell) menuCompletePrevTag() {
 	rl.completer.SelectTag(false)
 }
 
+// In a menu completion, select the next alias of the selected candidate,
+// if it has any (values sharing the same description, like short and long
+// flags), or its first alias if the last one is selected.
+func (rl *Shell) menuCompleteNextAlias() {
+	rl.History.SkipSave()
+
+	if !rl.completer.IsActive() {
+		return
+	}
+
+	rl.completer.SelectAlias()
+}
+
 // In a menu completion, insert the current completion
 // into the buffer, and advance to the next possible completion.
 func (rl *Shell) acceptAndMenuComplete() {
@@ -178,7 +201,8 @@ func (rl *Shell) viRegistersComplete() {
 // (fuzzy search) on the results. Search backward incrementally for a specified string.
 // The search is case-insensitive if the search string does not have uppercase letters
 // and no numeric argument was given. The string may begin with ‘^’ to anchor the search
-// to the beginning of the line. A restricted set of editing functions is available in the
+// to the beginning of the line. Space-separated terms must all match, while terms prefixed
+// with ‘!’ must not. A restricted set of editing functions is available in the
 // mini-buffer. Keys are looked up in the special isearch keymap, On each change in the
 // mini-buffer, any currently selected candidate is dropped from the line and the menu.
 // An interrupt signal, as defined by the stty setting, will stop the search and go back to the original line.
@@ -190,6 +214,108 @@ func (rl *Shell) menuIncrementalSearch() {
 	rl.completer.IsearchStart("completions", false, false)
 }
 
+// In a menu completion, insert the currently selected candidate (if any)
+// into the buffer and exit the completion menu, without accepting the line.
+// If some candidates are marked, they are all inserted instead, separated
+// with spaces. If the candidate asks for it, completion starts again after it.
+func (rl *Shell) menuAccept() {
+	rl.History.SkipSave()
+
+	if !rl.completer.IsActive() {
+		return
+	}
+
+	if rl.completer.AcceptMarked() {
+		rl.completer.Reset()
+		rl.Hint.Reset()
+
+		return
+	}
+
+	selected := rl.completer.Selected()
+
+	rl.completer.Reset()
+	rl.Hint.Reset()
+	rl.completer.Chain(selected)
+}
+
+// In a menu completion, drop any currently inserted candidate from the
+// buffer, restore the line as it was before completing, and exit the menu.
+func (rl *Shell) menuCancel() {
+	rl.History.SkipSave()
+
+	if !rl.completer.IsActive() {
+		return
+	}
+
+	rl.completer.ResetForce()
+	rl.Hint.Reset()
+}
+
+// In a menu completion, mark the currently selected candidate for multiple
+// selection (or unmark it if already marked), and select the next candidate.
+// Marked candidates are underlined, and all inserted in the line, separated
+// with spaces, when accepting the menu or the line.
+func (rl *Shell) menuToggleMark() {
+	rl.History.SkipSave()
+
+	if !rl.completer.IsActive() {
+		return
+	}
+
+	rl.completer.ToggleMark()
+	rl.completer.Select(1, 0)
+}
+
+// In incremental search mode, switch between matching the mini-buffer as a regular
+// expression, or as a fuzzy subsequence of candidates: matches are then ordered from
+// the best to the worst, and their matched characters highlighted. The current mode
+// is shown in the search hint, and kept for subsequent searches.
+func (rl *Shell) isearchToggleFuzzy() {
+	rl.History.SkipSave()
+	rl.completer.IsearchToggleFuzzy()
+}
+
+// In incremental search mode, extend the last term of the search query with the
+// longest continuation common to the words of matching candidates containing it,
+// or list these words in the search hint if they have no common continuation.
+func (rl *Shell) isearchCompleteQuery() {
+	rl.History.SkipSave()
+	rl.completer.IsearchCompleteQuery()
+}
+
+// In incremental search mode, restrict the search to the candidates of the
+// selected group (or of the first group with matches), or search all groups
+// again if it was already restricted. The group searched is shown in the hint.
+func (rl *Shell) isearchToggleGroup() {
+	rl.History.SkipSave()
+	rl.completer.IsearchToggleScope()
+}
+
+// In incremental search mode, select the next match, without leaving the search.
+// The position of the selected match is shown in the search hint.
+func (rl *Shell) isearchNextMatch() {
+	rl.History.SkipSave()
+
+	if rl.Keymap.Local() != keymap.Isearch {
+		return
+	}
+
+	rl.completer.Select(1, 0)
+}
+
+// In incremental search mode, select the previous match, without leaving the search.
+// The position of the selected match is shown in the search hint.
+func (rl *Shell) isearchPreviousMatch() {
+	rl.History.SkipSave()
+
+	if rl.Keymap.Local() != keymap.Isearch {
+		return
+	}
+
+	rl.completer.Select(-1, 0)
+}
+
 //
 // Utilities --------------------------------------------------------------------------
 //
@@ -205,20 +331,44 @@ func (rl *Shell) startMenuComplete(completer completion.Completer) {
 
 // commandCompletion generates the completions for commands/args/flags.
 func (rl *Shell) commandCompletion() completion.Values {
-	if rl.Completer == nil {
+	bufferWords := rl.Config.GetBool("complete-buffer-words") && !rl.standalone
+
+	if rl.Completer == nil && rl.CompleterContext == nil && len(rl.asyncCompleters) == 0 && !bufferWords {
 		return completion.Values{}
 	}
 
 	line, cursor := rl.completer.Line()
-	comps := rl.Completer(*line, cursor.Pos())
 
-	return comps.convert()
+	var comps Completions
+
+	switch {
+	case rl.CompleterContext != nil:
+		traceCompleter := rl.traceDuration("completer", "line", string(*line), "cursor", cursor.Pos())
+		comps = rl.completeInterruptible(*line, cursor.Pos())
+		traceCompleter()
+	case rl.Completer != nil:
+		traceCompleter := rl.traceDuration("completer", "line", string(*line), "cursor", cursor.Pos())
+		comps = rl.Completer(*line, cursor.Pos())
+		traceCompleter()
+	}
+
+	comps, loading := rl.addAsyncCompletions(comps, *line, cursor.Pos())
+
+	if bufferWords {
+		comps = rl.addBufferWords(comps, *line, cursor.Pos())
+	}
+
+	values := comps.convert()
+	values.Loading = loading
+
+	return values
 }
 
 // historyCompletion manages the various completion/isearch modes related
 // to history control. It can start the history completions, stop them, cycle
 // through sources if more than one, and adjust the completion/isearch behavior.
-func (rl *Shell) historyCompletion(forward, filterLine, substring bool) {
+// If byIndex is true, lines are inserted by index (like !42) instead of text.
+func (rl *Shell) historyCompletion(forward, filterLine, substring, byIndex bool) {
 	switch {
 	case rl.Keymap.Local() == keymap.MenuSelect || rl.Keymap.Local() == keymap.Isearch || rl.completer.AutoCompleting():
 		// If we are currently completing the last
@@ -239,14 +389,14 @@ func (rl *Shell) historyCompletion(forward, filterLine, substring bool) {
 	default:
 		// Notify if we don't have history sources at all.
 		if rl.History.Current() == nil {
-			rl.Hint.SetTemporary(fmt.Sprintf("%s%s%s %s", color.Dim, color.FgRed, "No command history source", color.Reset))
+			rl.Hint.SetTemporary(fmt.Sprintf("%s%s%s %s", color.Dim, color.FgRed, rl.locale.Get(locale.NoHistorySource), color.Reset))
 			return
 		}
 
 		// Generate the completions with specified behavior.
 		completer := func() completion.Values {
 			maxLines := rl.Display.AvailableHelperLines()
-			return history.Complete(rl.History, forward, filterLine, maxLines, rl.completer.IsearchRegex)
+			return history.Complete(rl.History, forward, filterLine, byIndex, maxLines, rl.completer.IsearchMatcher)
 		}
 
 		if substring {
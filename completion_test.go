@@ -0,0 +1,689 @@
+package readline_test
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_CompletionPreview(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("alpha", "alphabet")
+	}
+
+	// The selected candidate is styled in the line while cycling.
+	line, err := term.Run(rl, "al", `\t`, `\t`, `\t`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "alpha" {
+		t.Errorf("Run() line = %q, want %q", line, "alpha")
+	}
+
+	if !strings.Contains(term.Output(), "\x1b[4ma\x1b[4ml\x1b[4mp\x1b[4mh\x1b[4ma\x1b[4mb") {
+		t.Errorf("Output() = %q, want the inserted candidate underlined", term.Output())
+	}
+
+	// Cancelling the menu reverts the line at once.
+	line, err = term.Run(rl, "al", `\t`, `\t`, `\C-g`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "al" {
+		t.Errorf("Run() line = %q, want %q", line, "al")
+	}
+}
+
+func TestShell_CompletionMarks(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want string
+	}{
+		{
+			name: "Accept marked",
+			keys: []string{"cmd ", `\t`, `\em`, `\em`, `\e\r`, `\r`},
+			want: "cmd alpha beta",
+		},
+		{
+			name: "Accept line",
+			keys: []string{"cmd ", `\t`, `\em`, `\em`, `\r`},
+			want: "cmd alpha beta",
+		},
+		{
+			name: "Unmark",
+			keys: []string{"cmd ", `\t`, `\em`, `\C-p`, `\em`, `\e\r`, `\r`},
+			want: "cmd beta",
+		},
+		{
+			name: "Type after marking",
+			keys: []string{"cmd ", `\t`, `\em`, " ", `\r`},
+			want: "cmd alpha ",
+		},
+		{
+			name: "Cancel",
+			keys: []string{"cmd ", `\t`, `\em`, `\C-g`, `\r`},
+			want: "cmd ",
+		},
+		{
+			name: "Accept selected",
+			keys: []string{"cmd ", `\t`, `\e\r`, "x", `\r`},
+			want: "cmd alphax",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term := readlinetest.NewTerminal(80, 24)
+			rl := term.Shell()
+
+			rl.Completer = func(_ []rune, _ int) readline.Completions {
+				return readline.CompleteValues("alpha", "beta", "gamma")
+			}
+
+			line, err := term.Run(rl, test.keys...)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if line != test.want {
+				t.Errorf("Run() line = %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestShell_CompletionUniqueSpace(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{"echo foo", `\t`}, want: "echo foobar "},
+		{keys: []string{"echo di", `\t`}, want: "echo dir/"},
+		{keys: []string{"echo foo x", `\C-b`, `\C-b`, `\t`, "y"}, want: "echo foobar yx"},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+		rl.Config.Set("max-redisplay-rate", 0)
+		rl.Config.Set("completion-unique-space", true)
+
+		rl.Completer = func(line []rune, _ int) readline.Completions {
+			if strings.Contains(string(line), "di") {
+				return readline.CompleteValues("dir/").NoSpace('/')
+			}
+
+			return readline.CompleteValues("foobar", "format")
+		}
+
+		line, err := term.Run(rl, append(test.keys, `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("Run() line = %q, want %q", line, test.want)
+		}
+	}
+}
+
+func TestShell_CompletionNoMatch(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("foobar", "format")
+	}
+
+	rl.Config.Set("completion-no-match", "hint")
+
+	if _, err := term.Run(rl, "zz", `\t`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !strings.Contains(term.Output(), "no matching completions") {
+		t.Errorf("Output() = %q, want the no matching completions hint", term.Output())
+	}
+
+	if strings.Contains(term.Output(), "\a") {
+		t.Errorf("Output() = %q, want no bell", term.Output())
+	}
+
+	rl.Config.Set("completion-no-match", "bell")
+
+	if _, err := term.Run(rl, "zz", `\t`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !strings.Contains(term.Output(), "\a") {
+		t.Errorf("Output() = %q, want a bell", term.Output())
+	}
+}
+
+func TestShell_IsearchGroupScope(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteRaw([]readline.Completion{
+			{Value: "file.go", Tag: "files"},
+			{Value: "main.go", Tag: "files"},
+			{Value: "profile", Tag: "commands"},
+			{Value: "ls", Tag: "commands"},
+		})
+	}
+
+	// Restrict the search to the group of the first match,
+	// then search all groups again: the last match is in
+	// the second group.
+	line, err := term.Run(rl, `\t`, `\C-f`, "file", `\en`, `\eg`, `\eg`, `\ep`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "profile" {
+		t.Errorf("Run() line = %q, want %q", line, "profile")
+	}
+
+	if !strings.Contains(term.Output(), " (in files)") {
+		t.Errorf("Output() = %q, want the search scope in the hint", term.Output())
+	}
+
+	// The match selected once the search is restricted is the only one.
+	line, err = term.Run(rl, `\t`, `\C-f`, "file", `\en`, `\eg`, `\ep`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "file.go" {
+		t.Errorf("Run() line = %q, want %q", line, "file.go")
+	}
+}
+
+func TestShell_CompletionAliasPreference(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValuesDescribed(
+			"-a", "show all", "--all", "show all",
+			"-l", "long listing", "--long", "long listing",
+		)
+	}
+
+	// Rows of aliases are cycled on their preferred alias,
+	// and the other aliases of a row can then be selected.
+	for _, test := range []struct {
+		preference string
+		keys       []string
+		want       string
+	}{
+		{"long", []string{`\t`, `\t`}, "ls --long"},
+		{"short", []string{`\t`, `\t`}, "ls -l"},
+		{"short", []string{`\t`, `\e[1;5C`}, "ls --all"},
+	} {
+		rl.Config.Set("completion-alias-preference", test.preference)
+
+		line, err := term.Run(rl, append(append([]string{"ls "}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("Run() line = %q, want %q (%s aliases)", line, test.want, test.preference)
+		}
+	}
+}
+
+func TestShell_CompletionAlignGroups(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("completion-align-groups", true)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteRaw([]readline.Completion{
+			{Value: "x", Description: "short value", Tag: "first"},
+			{Value: "y", Description: "other value", Tag: "first"},
+			{Value: "longer-value", Description: "long value", Tag: "second"},
+			{Value: "z", Description: "last value", Tag: "second"},
+		})
+	}
+
+	if _, err := term.Run(rl, `\e?`, `\C-u`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Replay the output up to the completions being displayed.
+	screen := readlinetest.NewScreen(80, 24)
+
+	for _, frame := range term.Frames() {
+		screen.Write([]byte(frame))
+
+		if strings.Contains(frame, "longer-value") {
+			break
+		}
+	}
+
+	var rows []string
+
+	for _, row := range strings.Split(screen.String(), "\n") {
+		if strings.Contains(row, "--") {
+			rows = append(rows, row)
+		}
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("Screen() = %q, want two rows of completions", screen.String())
+	}
+
+	// Descriptions of both groups start in the same columns.
+	if strings.Index(rows[0], "--") != strings.Index(rows[1], "--") ||
+		strings.LastIndex(rows[0], "--") != strings.LastIndex(rows[1], "--") {
+		t.Errorf("Screen() = %q, want the columns of groups aligned", screen.String())
+	}
+}
+
+func TestShell_CompletionWrapDescriptions(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rows := 2
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValuesDescribed(
+			"first", "a description much too long to fit on the row of its candidate",
+			"second", "short",
+		).DisplayList().WrapDescriptions(rows)
+	}
+
+	// completions replays the output up to the completions being displayed.
+	completions := func() string {
+		screen := readlinetest.NewScreen(40, 24)
+
+		for _, frame := range term.Frames() {
+			screen.Write([]byte(frame))
+
+			if strings.Contains(screen.String(), "second  --") {
+				break
+			}
+		}
+
+		return strings.TrimLeft(screen.String(), "\n")
+	}
+
+	line, err := term.Run(rl, `\e?`, `\t`, `\t`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "second" {
+		t.Errorf("Run() line = %q, want %q", line, "second")
+	}
+
+	// Wrapped rows are cleared along with the completions.
+	if strings.Contains(term.Screen(), "candidate") {
+		t.Errorf("Screen() = %q, want completions cleared", term.Screen())
+	}
+
+	want := "first   -- a description much too long\n" +
+		"           to fit on the row of its\n" +
+		"           candidate\n" +
+		"second  -- short"
+
+	if got := completions(); !strings.HasSuffix(got, want) {
+		t.Errorf("Screen() = %q, want %q", got, want)
+	}
+
+	// Descriptions still too long once wrapped are truncated.
+	rows = 1
+	term.Reset()
+
+	if _, err := term.Run(rl, `\e?`, `\C-u`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want = "first   -- a description much too long\n" +
+		"           to fit on the row of its...\n" +
+		"second  -- short"
+
+	if got := completions(); !strings.HasSuffix(got, want) {
+		t.Errorf("Screen() = %q, want %q", got, want)
+	}
+}
+
+func TestShell_CompletionStyleFunc(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	used := map[string]bool{}
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("--quiet", "--verbose").Style("35").StyleFunc(func(comp readline.Completion) string {
+			if used[comp.Value] {
+				return "2"
+			}
+
+			return ""
+		})
+	}
+
+	// Styles are computed each time candidates are displayed,
+	// and candidates keep their own style if none is returned.
+	for _, test := range []struct {
+		used  string
+		quiet string
+	}{
+		{"--verbose", "\x1b[35m--quiet"},
+		{"--quiet", "\x1b[2m--quiet"},
+	} {
+		used[test.used] = true
+		term.Reset()
+
+		if _, err := term.Run(rl, `\e?`, `\C-u`, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if !strings.Contains(term.Output(), test.quiet) || !strings.Contains(term.Output(), "\x1b[2m--verbose") {
+			t.Errorf("Output() = %q, want %q and --verbose dimmed", term.Output(), test.quiet)
+		}
+	}
+}
+
+func TestShell_CompletionTagUsage(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteRaw([]readline.Completion{
+			{Value: "main", Tag: "branches"},
+			{Value: "v1.0", Tag: "tags"},
+		}).TagUsage("branches", "pick a %s to checkout", "branch")
+	}
+
+	if _, err := term.Run(rl, `\e?`, `\C-u`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	screen := readlinetest.NewScreen(80, 24)
+
+	for _, frame := range term.Frames() {
+		screen.Write([]byte(frame))
+
+		if strings.Contains(screen.String(), "v1.0") {
+			break
+		}
+	}
+
+	want := "branches pick a branch to checkout\nmain\ntags\nv1.0"
+
+	if got := strings.TrimLeft(screen.String(), "\n"); got != want {
+		t.Errorf("Screen() = %q, want %q", got, want)
+	}
+}
+
+func TestShell_CandidateMeta(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	type account struct{ id int }
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteRaw([]readline.Completion{
+			{Value: "alice", Tag: "users", Meta: account{id: 1}},
+			{Value: "bob", Tag: "users", Meta: account{id: 2}},
+		})
+	}
+
+	var selected, accepted []any
+
+	rl.Hooks.OnCandidateSelect(func(candidate readline.Completion) {
+		selected = append(selected, candidate.Meta)
+	})
+	rl.Hooks.OnCandidateAccept(func(candidate readline.Completion) {
+		accepted = append(accepted, candidate.Meta)
+	})
+
+	line, err := term.Run(rl, `\t`, `\t`, " ", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "bob " {
+		t.Errorf("Run() line = %q, want %q", line, "bob ")
+	}
+
+	if want := []any{account{id: 1}, account{id: 2}}; !slices.Equal(selected, want) {
+		t.Errorf("selected candidates meta = %v, want %v", selected, want)
+	}
+
+	if want := []any{account{id: 2}}; !slices.Equal(accepted, want) {
+		t.Errorf("accepted candidates meta = %v, want %v", accepted, want)
+	}
+}
+
+func TestShell_CompletionChain(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(line []rune, _ int) readline.Completions {
+		if strings.HasSuffix(string(line), "--output=") {
+			return readline.CompleteValues("--output=a.txt", "--output=b.txt")
+		}
+
+		return readline.CompleteRaw([]readline.Completion{
+			{Value: "--output=", Chain: true},
+			{Value: "--verbose"},
+		})
+	}
+
+	line, err := term.Run(rl, "cmd --o", `\t`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "cmd --output=a.txt" {
+		t.Errorf("Run() line = %q, want %q", line, "cmd --output=a.txt")
+	}
+}
+
+func TestShell_ClearScreenInMenu(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta")
+	}
+
+	for _, line := range []string{"one", "two"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("x ")
+	term.Type(`\e?`)
+	term.Type(`\t`)
+	term.Type(`\C-l`)
+
+	want := "x alpha\nalpha  beta"
+
+	waitForScreen(t, term, want)
+
+	term.Type(`\C-n`)
+	term.Type(`\r`)
+
+	if line := <-done; line != "x beta" {
+		t.Errorf("Readline() line = %q, want %q", line, "x beta")
+	}
+}
+
+func TestShell_AlternateScreen(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("alternate-screen", true)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		var values []string
+		for i := 0; i < 40; i++ {
+			values = append(values, fmt.Sprintf("value%02d", i), "description")
+		}
+
+		return readline.CompleteValuesDescribed(values...).NoSort()
+	}
+
+	term.Write([]byte("previous output\r\n"))
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	// The menu is too large for the rows below the line:
+	// it is displayed on the alternate screen, below the line.
+	term.Type("v", `\e?`)
+
+	alternate := func() bool {
+		screen := term.Screen()
+		return !strings.Contains(screen, "previous output") && strings.Contains(screen, "value01")
+	}
+
+	if !waitUntil(alternate) {
+		rl.Close()
+		t.Fatalf("screen =\n%s\nwant the menu on the alternate screen", term.Screen())
+	}
+
+	// Once a candidate is accepted, the primary screen is displayed again.
+	term.Type(`\t`, `\r`)
+
+	primary := func() bool {
+		screen := term.Screen()
+		return strings.HasPrefix(screen, "previous output") && strings.Contains(screen, "value00") &&
+			!strings.Contains(screen, "value01")
+	}
+
+	if !waitUntil(primary) {
+		rl.Close()
+		t.Fatalf("screen =\n%s\nwant the completed line on the primary screen", term.Screen())
+	}
+
+	term.Type(`\r`)
+
+	select {
+	case line := <-done:
+		if line != "value00" {
+			t.Errorf("line = %q, want %q", line, "value00")
+		}
+	case <-time.After(readlinetest.Timeout):
+		rl.Close()
+		t.Fatal("the shell did not return")
+	}
+
+	if !strings.Contains(term.Output(), "\x1b[?1049l") || !strings.HasPrefix(term.Screen(), "previous output") {
+		t.Errorf("screen =\n%s\nwant the primary screen", term.Screen())
+	}
+}
+
+func TestShell_CompletionMenuFooter(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("completion-menu-footer", true)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta")
+	}
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("x ", `\e?`, `\t`)
+	waitForScreen(t, term, "x alpha\nalpha  beta\nC-n/Tab next  C-p/S-Tab previous  C-f filter  C-g cancel  matcher: regexp")
+
+	// When filtering, the footer shows the isearch keys and matcher mode.
+	term.Type(`\C-f`, `\C-t`)
+	waitForScreen(t, term, "x alpha\ncompletions (fuzzy-search) (match 2/2): _\nalpha  beta\n"+
+		"C-n/Right next  C-p/S-Tab previous  C-f filter  C-t fuzzy  matcher: fuzzy")
+
+	term.Type(`\C-g`)
+	waitForScreen(t, term, "x")
+
+	term.Type(`\r`)
+	<-done
+
+	// Entries not fitting on the terminal line are dropped,
+	// and the footer is only displayed when enabled.
+	term = readlinetest.NewTerminal(40, 10)
+	rl = term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("completion-menu-footer", true)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta")
+	}
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("x ", `\e?`, `\t`)
+	waitForScreen(t, term, "x alpha\nalpha  beta\nC-n/Tab next  matcher: regexp")
+
+	term.Type(`\C-g`, `\r`)
+	<-done
+
+	term = readlinetest.NewTerminal(40, 10)
+	rl = term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta")
+	}
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("x ", `\e?`, `\t`)
+	waitForScreen(t, term, "x alpha\nalpha  beta")
+
+	term.Type(`\C-g`, `\r`)
+	<-done
+}
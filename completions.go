@@ -23,6 +23,9 @@ type Completions struct {
 	listSep  map[string]string
 	pad      map[string]bool
 	escapes  map[string]bool
+	wrap     map[string]int
+	tagUsage map[string]string
+	styleF   func(Completion) string
 
 	// Initially this will be set to the part of the current word
 	// from the beginning of the word up to the position of the cursor.
@@ -174,6 +177,19 @@ func (c Completions) Usage(usage string, args ...any) Completions {
 	})
 }
 
+// TagUsage sets a usage string displayed next to the heading of the group
+// of candidates with the given tag, so that each group can give its own
+// context, like "pick a branch to checkout" above a group of branches.
+func (c Completions) TagUsage(tag, usage string, args ...any) Completions {
+	if c.tagUsage == nil {
+		c.tagUsage = make(map[string]string)
+	}
+
+	c.tagUsage[tag] = fmt.Sprintf(usage, args...)
+
+	return c
+}
+
 // UsageF sets the usage using a function.
 func (c Completions) UsageF(f func() string) Completions {
 	if usage := f(); usage != "" {
@@ -217,6 +233,17 @@ func (c Completions) StyleF(f func(s string) string) Completions {
 	return c
 }
 
+// StyleFunc sets a function returning the style of candidates each time they are
+// displayed, so that they can be styled depending on their current state (like
+// arguments already used on the line, or files recently modified). An empty style
+// returned by the function leaves the candidate with its own style, if any.
+//
+//	CompleteValues("--verbose", "--quiet").StyleFunc(dimUsedFlags)
+func (c Completions) StyleFunc(f func(comp Completion) string) Completions {
+	c.styleF = f
+	return c
+}
+
 // Tag sets the tag.
 //
 //	CompleteValues("192.168.1.1", "127.0.0.1").Tag("interfaces").
@@ -337,6 +364,27 @@ func (c Completions) JustifyDescriptions(tags ...string) Completions {
 	return c
 }
 
+// WrapDescriptions makes the descriptions too long to fit on their row wrap onto
+// at most rows following ones, indented, instead of being truncated. Wrapping only
+// applies to candidates listed one per row (or to rows of aliases), and descriptions
+// still too long once wrapped are truncated. If no tags are given, wrapping will
+// apply to all tags.
+func (c Completions) WrapDescriptions(rows int, tags ...string) Completions {
+	if c.wrap == nil {
+		c.wrap = make(map[string]int)
+	}
+
+	if len(tags) == 0 {
+		c.wrap["*"] = rows
+	}
+
+	for _, tag := range tags {
+		c.wrap[tag] = rows
+	}
+
+	return c
+}
+
 // PreserveEscapes forces the completion engine to keep all escaped characters in
 // the inserted completion (c.Value of the Completion type). By default, those are
 // stripped out and only kept in the completion.Display. If no arguments are given,
@@ -406,32 +454,72 @@ func (c *Completions) merge(other Completions) {
 		c.usage = other.usage
 	}
 
+	if c.styleF == nil {
+		c.styleF = other.styleF
+	}
+
 	c.noSpace.Merge(other.noSpace)
 	c.messages.Merge(other.messages)
 
+	if c.listLong == nil && len(other.listLong) > 0 {
+		c.listLong = make(map[string]bool)
+	}
+
 	for tag := range other.listLong {
 		if _, found := c.listLong[tag]; !found {
 			c.listLong[tag] = true
 		}
 	}
 
+	if c.noSort == nil && len(other.noSort) > 0 {
+		c.noSort = make(map[string]bool)
+	}
+
 	for tag := range other.noSort {
 		if _, found := c.noSort[tag]; !found {
 			c.noSort[tag] = true
 		}
 	}
 
+	if c.listSep == nil && len(other.listSep) > 0 {
+		c.listSep = make(map[string]string)
+	}
+
 	for tag := range other.listSep {
 		if _, found := c.listSep[tag]; !found {
 			c.listSep[tag] = other.listSep[tag]
 		}
 	}
 
+	if c.pad == nil && len(other.pad) > 0 {
+		c.pad = make(map[string]bool)
+	}
+
 	for tag := range other.pad {
 		if _, found := c.pad[tag]; !found {
 			c.pad[tag] = other.pad[tag]
 		}
 	}
+
+	if c.tagUsage == nil && len(other.tagUsage) > 0 {
+		c.tagUsage = make(map[string]string)
+	}
+
+	for tag := range other.tagUsage {
+		if _, found := c.tagUsage[tag]; !found {
+			c.tagUsage[tag] = other.tagUsage[tag]
+		}
+	}
+
+	if c.wrap == nil && len(other.wrap) > 0 {
+		c.wrap = make(map[string]int)
+	}
+
+	for tag := range other.wrap {
+		if _, found := c.wrap[tag]; !found {
+			c.wrap[tag] = other.wrap[tag]
+		}
+	}
 }
 
 func (c *Completions) convert() completion.Values {
@@ -445,6 +533,9 @@ func (c *Completions) convert() completion.Values {
 	comps.ListSep = c.listSep
 	comps.Pad = c.pad
 	comps.Escapes = c.escapes
+	comps.Wrap = c.wrap
+	comps.TagUsage = c.tagUsage
+	comps.StyleFunc = c.styleF
 
 	comps.PREFIX = c.PREFIX
 	comps.SUFFIX = c.SUFFIX
@@ -0,0 +1,132 @@
+package readline
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// Confirm displays the prompt followed by the choices, like "[Y/n]", reads
+// a single key, and returns the choice typed, matched regardless of its case.
+// The first choice is the default one, returned when Enter is pressed, and
+// the choices are y and n if none are given. Other keys are ignored, and the
+// answer is displayed after the prompt before returning. If Ctrl-C is pressed,
+// ErrInterrupt is returned.
+//
+// This function must not be called while the shell is reading a line: from
+// within commands, use ReadNested instead. If the standard input is not a
+// terminal, a plain line is read, and ErrInvalidChoice is returned if it
+// does not start with one of the choices.
+func (rl *Shell) Confirm(prompt string, choices ...rune) (rune, error) {
+	if len(choices) == 0 {
+		choices = []rune{'y', 'n'}
+	}
+
+	prompt += " " + confirmChoices(choices) + " "
+
+	if !rl.isInteractive() {
+		return rl.confirmPlain(prompt, choices)
+	}
+
+	ctx, err := rl.startReading(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	defer rl.stopReading()
+
+	fmt.Fprint(rl.out, prompt)
+
+	for {
+		key, err := rl.readNestedKey(ctx)
+
+		switch {
+		case errors.Is(err, core.ErrIdle):
+			continue
+		case err != nil:
+			fmt.Fprint(rl.out, term.NewlineReturn)
+			return 0, rl.readError(ctx, err)
+		}
+
+		var answer rune
+
+		switch key {
+		case "\r", "\n":
+			answer = choices[0]
+		case "\x03":
+			fmt.Fprint(rl.out, term.NewlineReturn)
+			return 0, ErrInterrupt
+		default:
+			char, _ := utf8.DecodeRuneInString(key)
+			answer = matchChoice(char, choices)
+		}
+
+		if answer != 0 {
+			fmt.Fprint(rl.out, string(answer)+term.NewlineReturn)
+			return answer, nil
+		}
+	}
+}
+
+// confirmPlain prints the prompt without colors, reads a line from stdin
+// and returns the choice matching its first character, if any.
+func (rl *Shell) confirmPlain(prompt string, choices []rune) (rune, error) {
+	if rl.plainReader == nil {
+		rl.plainReader = bufio.NewReader(os.Stdin)
+	}
+
+	fmt.Fprint(rl.out, color.Strip(prompt))
+
+	input, err := rl.plainReader.ReadString('\n')
+	if err != nil && input == "" {
+		return 0, err
+	}
+
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return choices[0], nil
+	}
+
+	char, _ := utf8.DecodeRuneInString(input)
+	if answer := matchChoice(char, choices); answer != 0 {
+		return answer, nil
+	}
+
+	return 0, fmt.Errorf("%w: %q", ErrInvalidChoice, input)
+}
+
+// confirmChoices returns the choices as displayed
+// after the prompt, with the default one uppercased.
+func confirmChoices(choices []rune) string {
+	names := make([]string, len(choices))
+
+	for i, choice := range choices {
+		if i == 0 {
+			names[i] = string(unicode.ToUpper(choice))
+		} else {
+			names[i] = string(choice)
+		}
+	}
+
+	return "[" + strings.Join(names, "/") + "]"
+}
+
+// matchChoice returns the choice matching the key
+// regardless of its case, or zero if there is none.
+func matchChoice(key rune, choices []rune) rune {
+	for _, choice := range choices {
+		if unicode.ToLower(key) == unicode.ToLower(choice) {
+			return choice
+		}
+	}
+
+	return 0
+}
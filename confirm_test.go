@@ -0,0 +1,47 @@
+package readline_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_Confirm(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	tests := []struct {
+		name    string
+		keys    []string
+		choices []rune
+		want    rune
+		err     error
+	}{
+		{name: "Default", keys: []string{`\r`}, want: 'y'},
+		{name: "Choice", keys: []string{"x", "N"}, want: 'n'},
+		{name: "Custom choices", keys: []string{"b"}, choices: []rune{'a', 'b', 'c'}, want: 'b'},
+		{name: "Interrupt", keys: []string{`\C-c`}, err: readline.ErrInterrupt},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term.Type(test.keys...)
+
+			got, err := rl.Confirm("Overwrite?", test.choices...)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("Confirm() error = %v, want %v", err, test.err)
+			}
+
+			if got != test.want {
+				t.Errorf("Confirm() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	if !strings.Contains(term.Screen(), "Overwrite? [Y/n] n") {
+		t.Errorf("prompt or answer not displayed:\n%s", term.Screen())
+	}
+}
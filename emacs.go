@@ -10,7 +10,10 @@ import (
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/history"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
 	"github.com/reeflective/readline/internal/strutil"
 	"github.com/reeflective/readline/internal/term"
 	"github.com/rivo/uniseg"
@@ -111,6 +114,7 @@ func (rl *Shell) standardCommands() commands {
 		"prefix-meta":               rl.prefixMeta,
 		"undo":                      rl.undoLast,
 		"revert-line":               rl.revertLine,
+		"undo-history":              rl.undoHistory,
 		"set-mark":                  rl.setMark,
 		"exchange-point-and-mark":   rl.exchangePointAndMark,
 		"character-search":          rl.characterSearch,
@@ -123,9 +127,11 @@ func (rl *Shell) standardCommands() commands {
 		"edit-and-execute-command":  rl.editAndExecuteCommand,
 		"edit-command-line":         rl.editCommandLine,
 
-		"redo":                rl.redo,
-		"select-keyword-next": rl.selectKeywordNext,
-		"select-keyword-prev": rl.selectKeywordPrev,
+		"redo":                  rl.redo,
+		"select-keyword-next":   rl.selectKeywordNext,
+		"select-keyword-prev":   rl.selectKeywordPrev,
+		"suspend":               rl.suspend,
+		"toggle-prompt-secrets": rl.togglePromptSecrets,
 	}
 
 	return widgets
@@ -200,7 +206,7 @@ func (rl *Shell) forwardWord() {
 		// of the line, insert the next word from this suggested line.
 		rl.insertAutosuggestPartial(true)
 
-		forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
+		forward := rl.line.ForwardEnd(rl.wordTokenizer(), rl.cursor.Pos())
 		rl.cursor.Move(forward + 1)
 	}
 }
@@ -212,7 +218,7 @@ func (rl *Shell) backwardWord() {
 
 	vii := rl.Iterations.Get()
 	for i := 1; i <= vii; i++ {
-		backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
+		backward := rl.line.Backward(rl.wordTokenizer(), rl.cursor.Pos())
 		rl.cursor.Move(backward)
 	}
 }
@@ -239,7 +245,7 @@ func (rl *Shell) backwardShellWord() {
 	for i := 1; i <= vii; i++ {
 		// First go the beginning of the blank word
 		startPos := rl.cursor.Pos()
-		backward := rl.line.Backward(rl.line.TokenizeSpace, startPos)
+		backward := rl.line.Backward(rl.shellWordTokenizer(), startPos)
 		rl.cursor.Move(backward)
 
 		// Now try to find enclosing quotes from here.
@@ -284,23 +290,26 @@ func (rl *Shell) downLine() {
 }
 
 // Clear the current screen and redisplay the prompt and input line.
-// This does not clear the terminal's output buffer.
+// This does not clear the terminal's output buffer. Any completion
+// menu, search or hint is kept and redisplayed below the line.
 func (rl *Shell) clearScreen() {
 	rl.History.SkipSave()
 
-	fmt.Print(term.CursorTopLeft)
-	fmt.Print(term.ClearScreen)
+	fmt.Fprint(rl.out, term.CursorTopLeft)
+	fmt.Fprint(rl.out, term.ClearScreen)
 
 	rl.Display.PrintPrimaryPrompt()
 }
 
 // Clear the current screen and redisplay the prompt and input line.
-// This does clear the terminal's output buffer.
+// This does clear the terminal's output buffer. Any completion
+// menu, search or hint is kept and redisplayed below the line.
 func (rl *Shell) clearDisplay() {
 	rl.History.SkipSave()
 
-	fmt.Print(term.CursorTopLeft)
-	fmt.Print(term.ClearDisplay)
+	fmt.Fprint(rl.out, term.CursorTopLeft)
+	fmt.Fprint(rl.out, term.ClearScreen)
+	fmt.Fprint(rl.out, term.ClearDisplay)
 
 	rl.Display.PrintPrimaryPrompt()
 }
@@ -313,15 +322,36 @@ func (rl *Shell) clearDisplay() {
 // by “stty”.  If this character is read when there are no
 // characters on the line, and point is at the beginning of
 // the line, readline interprets it as the end of input and
-// returns EOF.
+// returns EOF, unless the shell EndOfFile function returns
+// another error, or none to keep reading. Otherwise, the
+// command named by the end-of-file-command option is run
+// (delete-char by default, or possible-completions, etc).
 func (rl *Shell) endOfFile() {
-	switch rl.line.Len() {
-	case 0:
-		rl.Display.AcceptLine()
-		rl.History.Accept(false, false, io.EOF)
-	default:
-		rl.deleteChar()
+	if rl.line.Len() > 0 {
+		name := rl.Config.GetString("end-of-file-command")
+
+		command, found := rl.Keymap.Commands()[name]
+		if !found || name == "end-of-file" || name == "vi-eof-maybe" {
+			command = rl.deleteChar
+		}
+
+		command()
+
+		return
+	}
+
+	err := io.EOF
+	if rl.EndOfFile != nil {
+		err = rl.EndOfFile(*rl.line)
 	}
+
+	if err == nil {
+		rl.History.SkipSave()
+		return
+	}
+
+	rl.Display.AcceptLine()
+	rl.History.Accept(false, false, err)
 }
 
 // Delete the character under the cursor.
@@ -446,11 +476,6 @@ func (rl *Shell) selfInsert() {
 	rl.cursor.Move(length)
 }
 
-func (rl *Shell) bracketedPasteBegin() {
-	// keys, _ := rl.Keys.PeekAllBytes()
-	// fmt.Println(string(keys))
-}
-
 // Drag the character before point forward over the character
 // at point, moving point forward as well.  If point is at the
 // end of the line, then this transposes the two characters
@@ -584,11 +609,11 @@ func (rl *Shell) downCaseWord() {
 
 	// Save the current word
 	rl.cursor.Inc()
-	backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
+	backward := rl.line.Backward(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(backward)
 
 	rl.selection.Mark(rl.cursor.Pos())
-	forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
+	forward := rl.line.ForwardEnd(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(forward)
 
 	rl.selection.ReplaceWith(unicode.ToLower)
@@ -604,11 +629,11 @@ func (rl *Shell) upCaseWord() {
 
 	// Save the current word
 	rl.cursor.Inc()
-	backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
+	backward := rl.line.Backward(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(backward)
 
 	rl.selection.Mark(rl.cursor.Pos())
-	forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
+	forward := rl.line.ForwardEnd(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(forward)
 
 	rl.selection.ReplaceWith(unicode.ToUpper)
@@ -627,7 +652,7 @@ func (rl *Shell) capitalizeWord() {
 	startPos := rl.cursor.Pos()
 
 	rl.cursor.Inc()
-	backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
+	backward := rl.line.Backward(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(backward)
 
 	letter := rl.cursor.Char()
@@ -724,7 +749,7 @@ func (rl *Shell) deleteWord() {
 	rl.History.Save()
 
 	rl.selection.Mark(rl.cursor.Pos())
-	forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
+	forward := rl.line.ForwardEnd(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(forward)
 
 	rl.selection.Cut()
@@ -905,7 +930,7 @@ func (rl *Shell) killWord() {
 	bpos := rl.cursor.Pos()
 
 	rl.cursor.ToFirstNonSpace(true)
-	forward := rl.line.Forward(rl.line.TokenizeSpace, rl.cursor.Pos())
+	forward := rl.line.Forward(rl.shellWordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(forward - 1)
 	epos := rl.cursor.Pos()
 
@@ -921,7 +946,7 @@ func (rl *Shell) backwardKillWord() {
 	rl.History.SkipSave()
 
 	rl.selection.Mark(rl.cursor.Pos())
-	adjust := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
+	adjust := rl.line.Backward(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(adjust)
 
 	rl.Buffers.Write([]rune(rl.selection.Cut())...)
@@ -957,7 +982,7 @@ func (rl *Shell) copyBackwardWord() {
 	rl.History.Save()
 
 	rl.selection.Mark(rl.cursor.Pos())
-	adjust := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
+	adjust := rl.line.Backward(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(adjust)
 
 	rl.Buffers.Write([]rune(rl.selection.Text())...)
@@ -970,7 +995,7 @@ func (rl *Shell) copyForwardWord() {
 	rl.History.Save()
 
 	rl.selection.Mark(rl.cursor.Pos())
-	adjust := rl.line.Forward(rl.line.Tokenize, rl.cursor.Pos())
+	adjust := rl.line.Forward(rl.wordTokenizer(), rl.cursor.Pos())
 	rl.cursor.Move(adjust + 1)
 
 	rl.Buffers.Write([]rune(rl.selection.Text())...)
@@ -983,19 +1008,36 @@ func (rl *Shell) yank() {
 
 	vii := rl.Iterations.Get()
 
+	rl.yankPos = rl.cursor.Pos()
+	rl.yanked = nil
+
 	for i := 1; i <= vii; i++ {
 		rl.cursor.InsertAt(buf...)
+		rl.yanked = append(rl.yanked, buf...)
 	}
 }
 
-// Rotate the kill ring, and yank the new top.
-// Only works following yank or yank-pop.
+// Rotate the kill ring, and yank the new top in place of the text
+// just yanked. Only works following yank or yank-pop, that is, when
+// the text just yanked is still right before the cursor.
 func (rl *Shell) yankPop() {
+	end := rl.yankPos + len(rl.yanked)
+
+	if len(rl.yanked) == 0 || rl.cursor.Pos() != end || end > rl.line.Len() ||
+		string((*rl.line)[rl.yankPos:end]) != string(rl.yanked) {
+		return
+	}
+
+	rl.line.Cut(rl.yankPos, end)
+	rl.cursor.Set(rl.yankPos)
+	rl.yanked = nil
+
 	vii := rl.Iterations.Get()
 
 	for i := 1; i <= vii; i++ {
 		buf := rl.Buffers.Pop()
 		rl.cursor.InsertAt(buf...)
+		rl.yanked = append(rl.yanked, buf...)
 	}
 }
 
@@ -1135,7 +1177,7 @@ func (rl *Shell) macroToggleRecord() {
 	done := rl.Keymap.PendingCursor()
 	defer done()
 
-	rl.Hint.SetTemporary(color.Dim + "REC (macro arg)")
+	rl.Hint.SetTemporary(color.Dim + rl.locale.Get(locale.MacroArgRecord))
 	rl.Display.Refresh()
 
 	key, isAbort := rl.Keys.ReadKey()
@@ -1147,21 +1189,28 @@ func (rl *Shell) macroToggleRecord() {
 }
 
 // Reads a key from the keyboard, and runs the macro stored for this key identitier.
-// This mimics the Vim-style or running macros. If no macro is recorded for this key,
-// or if the key is invalid, nothing happens.
+// This mimics the Vim-style or running macros: `@` runs the last macro ran again,
+// and the macro is ran as many times as the numeric argument (iterations).
+// If no macro is recorded for this key, or if the key is invalid, nothing happens.
 func (rl *Shell) macroRun() {
+	vii := rl.Iterations.Get()
+
 	done := rl.Keymap.PendingCursor()
 	defer done()
 
-	rl.Hint.SetTemporary(color.Dim + "Run (macro arg)")
-	rl.Display.Refresh()
+	// Replayed macros may run this many times in a row:
+	// don't redisplay the prompt if the key is already there.
+	rl.Hint.SetTemporary(color.Dim + rl.locale.Get(locale.MacroArgRun))
+	if !rl.Display.Throttle(core.PendingKeys(rl.Keys)) {
+		rl.Display.Refresh()
+	}
 
 	key, isAbort := rl.Keys.ReadKey()
 	if isAbort {
 		return
 	}
 
-	rl.Macros.RunMacro(key)
+	rl.Macros.RunMacro(key, vii)
 }
 
 //
@@ -1175,7 +1224,7 @@ func (rl *Shell) reReadInitFile() {
 
 	err := rl.Keymap.ReloadConfig(rl.Opts...)
 	if err != nil {
-		rl.Hint.SetTemporary(color.FgRed + "Inputrc reload error: " + err.Error())
+		rl.Hint.SetTemporary(color.FgRed + rl.locale.Sprintf(locale.InputrcReloadError, err))
 		return
 	}
 
@@ -1196,7 +1245,7 @@ func (rl *Shell) reReadInitFile() {
 	}
 
 	// Notify successfully reloaded
-	rl.Hint.SetTemporary(color.FgGreen + "Inputrc reloaded")
+	rl.Hint.SetTemporary(color.FgGreen + rl.locale.Get(locale.InputrcReloaded))
 }
 
 // Abort the current editing command.
@@ -1233,15 +1282,70 @@ func (rl *Shell) abort() {
 		key := rl.Keys.Caller()
 		if key[0] == rune(inputrc.Unescape(`\C-C`)[0]) {
 			quoted, _ := strutil.Quote(key[0])
-			fmt.Print(string(quoted))
+			fmt.Fprint(rl.out, string(quoted))
 		}
 	}
 
 	// If no line was active,
 	rl.Display.AcceptLine()
+
+	// The user might want to keep reading on a new, empty line.
+	if rl.Interrupt != nil && !rl.Interrupt(*rl.line) {
+		rl.Display.PrintPrimaryPrompt()
+		rl.init()
+
+		return
+	}
+
 	rl.History.Accept(false, false, ErrInterrupt)
 }
 
+// Suspend the shell process, as would the terminal do when receiving
+// a SIGTSTP signal: the terminal is restored to its original state before
+// stopping, and the prompt and line are redisplayed once the process is
+// continued. Does nothing in shells running on a terminal provided by the
+// host, since the process might be shared with other sessions.
+func (rl *Shell) suspend() {
+	rl.History.SkipSave()
+
+	if rl.hosted {
+		return
+	}
+
+	rl.Display.CursorBelowLine()
+	fmt.Fprint(rl.out, term.ClearScreenBelow)
+
+	rl.mutex.Lock()
+	rl.suspended = true
+	guard := rl.rawGuard
+	rl.mutex.Unlock()
+
+	if err := guard.Suspend(); err != nil {
+		rl.mutex.Lock()
+		rl.suspended = false
+		rl.mutex.Unlock()
+
+		rl.Hint.SetTemporary(color.FgRed + rl.locale.Sprintf(locale.SuspendError, err))
+	}
+
+	rl.Display.PrintPrimaryPrompt()
+}
+
+// Reveal the secret segments of the prompts if they are masked, or mask them
+// again if revealed. They are masked again when the next line is read.
+func (rl *Shell) togglePromptSecrets() {
+	rl.History.SkipSave()
+
+	rl.Prompt.RevealSecrets(!rl.Prompt.SecretsRevealed())
+
+	rl.Display.CursorToLineStart()
+	rl.out.MoveCursorBackwards(rl.out.Width())
+	rl.out.MoveCursorUp(rl.Prompt.PrimaryUsed())
+	fmt.Fprint(rl.out, term.ClearScreenBelow)
+
+	rl.Display.PrintPrimaryPrompt()
+}
+
 // If the metafied character x is uppercase, run the command
 // that is bound to the corresponding metafied lowercase character.
 // The behavior is undefined if x is already lowercase.
@@ -1308,6 +1412,23 @@ func (rl *Shell) revertLine() {
 	rl.History.Revert()
 }
 
+// List the undo states of the current line in a completion menu, from the most
+// recent to the oldest, with the time at which they were saved and the text
+// inserted or deleted by each of them: selecting one replaces the line with it.
+// With a numeric argument N, directly restore the line to its Nth undo state,
+// from which undo and redo will then move.
+func (rl *Shell) undoHistory() {
+	if rl.Iterations.IsSet() {
+		rl.History.UndoTo(rl.Iterations.Get())
+		return
+	}
+
+	rl.History.SkipSave()
+	rl.startMenuComplete(func() completion.Values {
+		return history.CompleteUndo(rl.History)
+	})
+}
+
 // Set the mark to the point. If a numeric argument is
 // supplied, the mark is set to that position.
 func (rl *Shell) setMark() {
@@ -1430,7 +1551,7 @@ func (rl *Shell) insertComment() {
 // can be made part of an inputrc file.
 func (rl *Shell) dumpFunctions() {
 	rl.Display.ClearHelpers()
-	fmt.Println()
+	fmt.Fprintln(rl.out)
 
 	defer func() {
 		rl.Prompt.PrimaryPrint()
@@ -1447,13 +1568,19 @@ func (rl *Shell) dumpFunctions() {
 // can be made part of an inputrc file.
 func (rl *Shell) dumpVariables() {
 	rl.Display.ClearHelpers()
-	fmt.Println()
+	fmt.Fprintln(rl.out)
 
 	defer func() {
 		rl.Prompt.PrimaryPrint()
 		rl.Display.Refresh()
 	}()
 
+	// Either print in inputrc format, or wordly one.
+	if rl.Iterations.IsSet() {
+		rl.writeOptions(rl.out)
+		return
+	}
+
 	// Get all variables and their values, alphabetically sorted.
 	var variables []string
 
@@ -1463,17 +1590,9 @@ func (rl *Shell) dumpVariables() {
 
 	sort.Strings(variables)
 
-	// Either print in inputrc format, or wordly one.
-	if rl.Iterations.IsSet() {
-		for _, variable := range variables {
-			value := rl.Config.Vars[variable]
-			fmt.Printf("set %s %v\n", variable, value)
-		}
-	} else {
-		for _, variable := range variables {
-			value := rl.Config.Vars[variable]
-			fmt.Printf("%s is set to `%v'\n", variable, value)
-		}
+	for _, variable := range variables {
+		value := rl.Config.Vars[variable]
+		fmt.Fprintf(rl.out, "%s is set to `%v'\n", variable, value)
 	}
 }
 
@@ -1483,7 +1602,7 @@ func (rl *Shell) dumpVariables() {
 // can be made part of an inputrc file.
 func (rl *Shell) dumpMacros() {
 	rl.Display.ClearHelpers()
-	fmt.Println()
+	fmt.Fprintln(rl.out)
 
 	defer func() {
 		rl.Prompt.PrimaryPrint()
@@ -1509,12 +1628,12 @@ func (rl *Shell) dumpMacros() {
 	if rl.Iterations.IsSet() {
 		for _, key := range macroBinds {
 			action := inputrc.Escape(binds[inputrc.Unescape(key)].Action)
-			fmt.Printf("\"%s\": \"%s\"\n", key, action)
+			fmt.Fprintf(rl.out, "\"%s\": \"%s\"\n", key, action)
 		}
 	} else {
 		for _, key := range macroBinds {
 			action := inputrc.Escape(binds[inputrc.Unescape(key)].Action)
-			fmt.Printf("%s outputs %s\n", key, action)
+			fmt.Fprintf(rl.out, "%s outputs %s\n", key, action)
 		}
 	}
 }
@@ -1525,12 +1644,17 @@ func (rl *Shell) editAndExecuteCommand() {
 	buffer := *rl.line
 
 	// Edit in editor
-	edited, err := rl.Buffers.EditBuffer(buffer, "", "", rl.Keymap.IsEmacs())
+	var edited []rune
+
+	err := rl.RunInTerminal(func() (err error) {
+		edited, err = rl.Buffers.EditBuffer(buffer, "", "", rl.Keymap.IsEmacs())
+		return err
+	})
 	if err != nil || (len(edited) == 0 && len(buffer) != 0) {
 		rl.History.SkipSave()
 
 		errStr := strings.ReplaceAll(err.Error(), "\n", "")
-		changeHint := fmt.Sprintf(color.FgRed+"Editor error: %s", errStr)
+		changeHint := color.FgRed + rl.locale.Sprintf(locale.EditorError, errStr)
 		rl.Hint.SetTemporary(changeHint)
 
 		return
@@ -1547,12 +1671,17 @@ func (rl *Shell) editCommandLine() {
 	keymapCur := rl.Keymap.Main()
 
 	// Edit in editor
-	edited, err := rl.Buffers.EditBuffer(buffer, "", "", rl.Keymap.IsEmacs())
+	var edited []rune
+
+	err := rl.RunInTerminal(func() (err error) {
+		edited, err = rl.Buffers.EditBuffer(buffer, "", "", rl.Keymap.IsEmacs())
+		return err
+	})
 	if err != nil || (len(edited) == 0 && len(buffer) != 0) {
 		rl.History.SkipSave()
 
 		errStr := strings.ReplaceAll(err.Error(), "\n", "")
-		changeHint := fmt.Sprintf(color.FgRed+"Editor error: %s", errStr)
+		changeHint := color.FgRed + rl.locale.Sprintf(locale.EditorError, errStr)
 		rl.Hint.SetTemporary(changeHint)
 
 		return
@@ -0,0 +1,49 @@
+package readline_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_Interrupt(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Prompt.Primary(func() string { return "> " })
+
+	var interrupted []string
+
+	rl.Interrupt = func(line []rune) bool {
+		interrupted = append(interrupted, string(line))
+		return string(line) == "abort"
+	}
+
+	// The interrupted line is dropped, and the shell keeps reading a new one.
+	line, err := term.Run(rl, "sleep", `\C-c`, "ls", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "ls" {
+		t.Errorf("Run() line = %q, want %q", line, "ls")
+	}
+
+	readlinetest.AssertScreen(t, term, "> sleep^C\n> ls")
+
+	line, err = term.Run(rl, "abort", `\C-c`)
+	if !errors.Is(err, readline.ErrInterrupt) {
+		t.Errorf("Run() error = %v, want %v", err, readline.ErrInterrupt)
+	}
+
+	if line != "abort" {
+		t.Errorf("Run() line = %q, want %q", line, "abort")
+	}
+
+	if want := []string{"sleep", "abort"}; !slices.Equal(interrupted, want) {
+		t.Errorf("Interrupt() called with %q, want %q", interrupted, want)
+	}
+}
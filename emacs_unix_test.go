@@ -0,0 +1,39 @@
+//go:build unix
+
+package readline_test
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_SuspendHosted(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+
+	// Catch the signal, so that a regression does not stop the tests.
+	stopped := make(chan os.Signal, 1)
+	signal.Notify(stopped, syscall.SIGTSTP)
+	defer signal.Stop(stopped)
+
+	// The process might be shared with other sessions: it is not suspended,
+	// and the terminal stays in raw mode while the shell keeps reading.
+	line, err := term.Run(rl, "ls", `\C-z`, " -l", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "ls -l" {
+		t.Errorf("Run() line = %q, want %q", line, "ls -l")
+	}
+
+	select {
+	case sig := <-stopped:
+		t.Errorf("suspend sent %v to the process", sig)
+	default:
+	}
+}
@@ -0,0 +1,47 @@
+package readline
+
+import "os"
+
+// Env is the environment of a shell, from which it reads the variables it
+// uses: TERM, INPUTRC and HOME when loading the inputrc configuration, VISUAL
+// and EDITOR when editing the line in an editor, and SHELL when running
+// filter commands. Hosts running shells for remote sessions, like SSH ones,
+// set the environment of each session with SetEnv, so that shells are not
+// tied to the environment of the host process.
+type Env interface {
+	Getenv(key string) string
+}
+
+// EnvMap is an environment made of a map of variables.
+type EnvMap map[string]string
+
+// Getenv implements Env.
+func (e EnvMap) Getenv(key string) string {
+	return e[key]
+}
+
+// SetEnv sets the environment of the shell, in place of the process one,
+// and reloads its inputrc configuration with it. A nil environment restores
+// the process one. This function should not be called while reading input.
+func (rl *Shell) SetEnv(env Env) error {
+	rl.env = env
+
+	var getenv func(key string) string
+	if env != nil {
+		getenv = env.Getenv
+	}
+
+	rl.Keymap.SetEnv(getenv)
+	rl.Buffers.SetEnv(getenv)
+
+	return rl.Keymap.ReloadConfig(rl.Opts...)
+}
+
+// getenv returns the value of a variable in the shell environment.
+func (rl *Shell) getenv(key string) string {
+	if rl.env == nil {
+		return os.Getenv(key)
+	}
+
+	return rl.env.Getenv(key)
+}
@@ -0,0 +1,40 @@
+package readline_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_SetEnv(t *testing.T) {
+	inputrcFile := filepath.Join(t.TempDir(), "inputrc")
+	if err := os.WriteFile(inputrcFile, []byte("set editing-mode vi\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	if err := rl.SetEnv(readline.EnvMap{"INPUTRC": inputrcFile, "HOME": t.TempDir()}); err != nil {
+		t.Fatalf("SetEnv() error = %v", err)
+	}
+
+	if mode := rl.Config.GetString("editing-mode"); mode != "vi" {
+		t.Errorf("editing-mode = %q, want %q", mode, "vi")
+	}
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	line, err := term.Run(rl, "hello", `\C-o`, "0", "x", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "ello" {
+		t.Errorf("Run() line = %q, want %q", line, "ello")
+	}
+}
@@ -0,0 +1,561 @@
+package readline
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
+)
+
+// exCommand is an ex command name, and the length of its shortest abbreviation.
+type exCommand struct {
+	name   string
+	abbrev int
+}
+
+// exCommandNames are the ex commands supported in ex mode.
+var exCommandNames = []exCommand{
+	{"substitute", 1},
+	{"delete", 1},
+	{"normal", 4},
+	{"set", 2},
+}
+
+// exOptions maps the names and abbreviations of vim options
+// accepted by :set to the names of the corresponding options.
+var exOptions = map[string]string{
+	"ignorecase": "vi-ignorecase",
+	"ic":         "vi-ignorecase",
+	"smartcase":  "vi-smartcase",
+	"scs":        "vi-smartcase",
+	"wrapscan":   "vi-wrapscan",
+	"ws":         "vi-wrapscan",
+	"shiftwidth": "vi-shiftwidth",
+	"sw":         "vi-shiftwidth",
+	"iskeyword":  "vi-iskeyword",
+	"isk":        "vi-iskeyword",
+}
+
+// exRange is a range of buffer lines, zero-based and inclusive.
+type exRange struct {
+	start, end int
+	set        bool
+}
+
+// exCommands returns the commands of the vi ex mode.
+func (rl *Shell) exCommands() map[string]func() {
+	return map[string]func(){
+		"vi-ex-command": rl.viExCommand,
+	}
+}
+
+// Read an ex command in a nested prompt below the line, and run it on the
+// buffer. Commands can be preceded by a range of buffer lines: one or two
+// addresses separated by a comma, each of them being a line number, . for
+// the cursor line or $ for the last one, with optional +N/-N offsets, or %
+// for all lines. Without a command, the range moves to its last line.
+//
+// Supported commands are :s/pattern/replacement/[giI] (substitute matches of
+// a regular expression in the cursor line or in the range), :d (delete the
+// cursor line or the range), :norm[al] {keys} (run keys in vi command mode,
+// on each line of the range if any, starting from their first column), and
+// :se[t] {option}... (set, toggle or show options, with their vim names).
+// Errors are shown in the hint.
+func (rl *Shell) viExCommand() {
+	rl.History.SkipSave()
+
+	command, err := rl.ReadNested(":")
+	if err != nil {
+		return
+	}
+
+	if err := rl.runExCommand(command); err != nil {
+		rl.Hint.SetTemporary(color.FgRed + err.Error() + color.Reset)
+	}
+}
+
+// runExCommand parses and runs an ex command line.
+func (rl *Shell) runExCommand(command string) error {
+	lines, command, err := rl.parseExRange(strings.TrimLeft(command, ": \t"))
+	if err != nil {
+		return err
+	}
+
+	command = strings.TrimLeft(command, " \t")
+	name := command[:len(command)-len(strings.TrimLeftFunc(command, unicode.IsLetter))]
+	args := command[len(name):]
+
+	// A range alone moves to its last line.
+	if command == "" {
+		if lines.set {
+			rl.viGotoBufferLine(lines.end)
+		}
+
+		return nil
+	}
+
+	switch exCommandName(name) {
+	case "substitute":
+		return rl.exSubstitute(lines, strings.TrimLeft(args, " \t"))
+	case "delete":
+		return rl.exDelete(lines, strings.TrimSpace(args))
+	case "normal":
+		rl.exNormal(lines, strings.TrimLeft(args, " \t"))
+	case "set":
+		return rl.exSet(strings.Fields(args))
+	default:
+		return rl.exError(locale.ExUnknownCommand, command)
+	}
+
+	return nil
+}
+
+// exCommandName returns the full name of the ex command
+// abbreviated as name, or an empty string if there is none.
+func exCommandName(name string) string {
+	for _, command := range exCommandNames {
+		if len(name) >= command.abbrev && strings.HasPrefix(command.name, name) {
+			return command.name
+		}
+	}
+
+	return ""
+}
+
+// exError returns an ex error with the translated message.
+func (rl *Shell) exError(msg string, args ...any) error {
+	return errors.New(rl.locale.Sprintf(msg, args...))
+}
+
+//
+// Ranges ------------------------------------------------------------------
+//
+
+// parseExRange parses the range of lines at the beginning of an ex command,
+// and returns it with the rest of the command. Without a range, the returned
+// one contains the cursor line only. Backwards ranges are swapped.
+func (rl *Shell) parseExRange(command string) (lines exRange, rest string, err error) {
+	current := strings.Count(string((*rl.line)[:rl.cursor.Pos()]), "\n")
+	last := rl.line.Lines()
+
+	if rest, found := strings.CutPrefix(command, "%"); found {
+		return exRange{start: 0, end: last, set: true}, rest, nil
+	}
+
+	start, rest, found := parseExAddress(command, current, last)
+	if !found {
+		return exRange{start: current, end: current}, command, nil
+	}
+
+	end := start
+
+	if after, comma := strings.CutPrefix(rest, ","); comma {
+		end, rest, found = parseExAddress(after, current, last)
+		if !found {
+			end = current
+		}
+	}
+
+	if start > end {
+		start, end = end, start
+	}
+
+	if start < 0 || end > last {
+		return lines, command, rl.exError(locale.ExInvalidRange)
+	}
+
+	return exRange{start: start, end: end, set: true}, rest, nil
+}
+
+// parseExAddress parses a line address (a line number, . or $ followed by
+// any +N/-N offsets) at the beginning of an ex command, and returns the
+// zero-based line with the rest of the command. If the command does not
+// start with an address, found is false.
+func parseExAddress(command string, current, last int) (line int, rest string, found bool) {
+	line, rest = current, command
+
+	switch {
+	case strings.HasPrefix(rest, "."):
+		rest, found = rest[1:], true
+	case strings.HasPrefix(rest, "$"):
+		line, rest, found = last, rest[1:], true
+	default:
+		if num, after, isNum := parseExNumber(rest); isNum {
+			line, rest, found = num-1, after, true
+		}
+	}
+
+	for len(rest) > 0 && (rest[0] == '+' || rest[0] == '-') {
+		offset, after, isNum := parseExNumber(rest[1:])
+		if !isNum {
+			offset = 1
+		}
+
+		if rest[0] == '-' {
+			offset = -offset
+		}
+
+		line, rest, found = line+offset, after, true
+	}
+
+	return line, rest, found
+}
+
+// parseExNumber parses the decimal number at the beginning of an ex command.
+func parseExNumber(command string) (num int, rest string, found bool) {
+	digits := strings.TrimLeftFunc(command, unicode.IsDigit)
+
+	num, err := strconv.Atoi(command[:len(command)-len(digits)])
+	if err != nil {
+		return 0, command, false
+	}
+
+	return num, digits, true
+}
+
+// bufferLines returns the lines of the buffer.
+func (rl *Shell) bufferLines() []string {
+	return strings.Split(string(*rl.line), "\n")
+}
+
+// setBufferLines replaces the buffer with the lines, and moves the cursor
+// to the first non-blank character of the given (zero-based) line.
+func (rl *Shell) setBufferLines(lines []string, cursorLine int) {
+	rl.line.Set([]rune(strings.Join(lines, "\n"))...)
+	rl.viGotoBufferLine(min(cursorLine, len(lines)-1))
+}
+
+//
+// Commands ----------------------------------------------------------------
+//
+
+// exSubstitute runs :s/pattern/replacement/[flags] on the lines: patterns are
+// regular expressions, whose case is ignored according to the vi-ignorecase
+// and vi-smartcase options, or to the i/I flags. In replacements, & and \0
+// are the whole match, \1 to \9 its groups and \n a newline. Only the first
+// match of each line is replaced, unless the g flag is given.
+func (rl *Shell) exSubstitute(lines exRange, args string) error {
+	delim, size := utf8.DecodeRuneInString(args)
+	if size == 0 || unicode.IsLetter(delim) || unicode.IsDigit(delim) || unicode.IsSpace(delim) || delim == '\\' {
+		return rl.exError(locale.ExInvalidPattern, args)
+	}
+
+	parts := splitExPattern(args[size:], delim)
+	pattern, replacement, flags := parts[0], "", ""
+
+	if len(parts) > 1 {
+		replacement = parts[1]
+	}
+
+	if len(parts) > 2 {
+		flags = strings.Join(parts[2:], string(delim))
+	}
+
+	if pattern == "" {
+		return rl.exError(locale.ExInvalidPattern, args)
+	}
+
+	ignoreCase := rl.Config.GetBool("vi-ignorecase")
+	if ignoreCase && rl.Config.GetBool("vi-smartcase") {
+		ignoreCase = !strings.ContainsFunc(pattern, unicode.IsUpper)
+	}
+
+	var global bool
+
+	for _, flag := range flags {
+		switch flag {
+		case 'g':
+			global = true
+		case 'i':
+			ignoreCase = true
+		case 'I':
+			ignoreCase = false
+		default:
+			return rl.exError(locale.ExTrailingCharacters, flags)
+		}
+	}
+
+	expr := pattern
+	if ignoreCase {
+		expr = "(?i)" + pattern
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return rl.exError(locale.ExInvalidPattern, pattern)
+	}
+
+	template := exReplacement(replacement)
+	buffer := rl.bufferLines()
+	substituted := -1
+
+	var result []string
+
+	result = append(result, buffer[:lines.start]...)
+
+	for i := lines.start; i <= lines.end; i++ {
+		line := buffer[i]
+
+		matches := re.FindAllStringSubmatchIndex(line, -1)
+		if len(matches) == 0 {
+			result = append(result, line)
+			continue
+		}
+
+		if !global {
+			matches = matches[:1]
+		}
+
+		var replaced []byte
+
+		prev := 0
+
+		for _, match := range matches {
+			replaced = append(replaced, line[prev:match[0]]...)
+			replaced = re.ExpandString(replaced, template, line, match)
+			prev = match[1]
+		}
+
+		replaced = append(replaced, line[prev:]...)
+
+		// Replacements might split the line.
+		split := strings.Split(string(replaced), "\n")
+		result = append(result, split...)
+		substituted = len(result) - len(split)
+	}
+
+	if substituted < 0 {
+		return rl.exError(locale.ExPatternNotFound, pattern)
+	}
+
+	rl.History.Save()
+
+	result = append(result, buffer[lines.end+1:]...)
+	rl.setBufferLines(result, substituted)
+
+	return nil
+}
+
+// splitExPattern splits the arguments of a substitution on unescaped
+// delimiters, unescaping them. Other escapes are kept as they are.
+func splitExPattern(args string, delim rune) []string {
+	var (
+		parts   []string
+		current strings.Builder
+		escaped bool
+	)
+
+	for _, char := range args {
+		switch {
+		case escaped && char == delim:
+			current.WriteRune(char)
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(char)
+		case char == '\\':
+			escaped = true
+			continue
+		case char == delim:
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(char)
+		}
+
+		escaped = false
+	}
+
+	if escaped {
+		current.WriteRune('\\')
+	}
+
+	return append(parts, current.String())
+}
+
+// exReplacement converts the replacement of a substitution
+// to a template expanded by regexp.Regexp.ExpandString.
+func exReplacement(replacement string) string {
+	var template strings.Builder
+
+	escaped := false
+
+	for _, char := range replacement {
+		switch {
+		case escaped && char >= '0' && char <= '9':
+			fmt.Fprintf(&template, "${%c}", char)
+		case escaped && char == 'n', escaped && char == 'r':
+			template.WriteRune('\n')
+		case char == '$':
+			template.WriteString("$$")
+		case escaped:
+			template.WriteRune(char)
+		case char == '\\':
+			escaped = true
+			continue
+		case char == '&':
+			template.WriteString("${0}")
+		default:
+			template.WriteRune(char)
+		}
+
+		escaped = false
+	}
+
+	return template.String()
+}
+
+// exDelete runs :d [register] on the lines, writing them to the
+// given register if any, or to the active one (or kill ring) otherwise.
+func (rl *Shell) exDelete(lines exRange, register string) error {
+	if register != "" {
+		name, size := utf8.DecodeRuneInString(register)
+		if size != len(register) || !rl.Buffers.IsValid(name) {
+			return rl.exError(locale.ExTrailingCharacters, register)
+		}
+
+		rl.Buffers.SetActive(name)
+	}
+
+	rl.History.Save()
+
+	buffer := rl.bufferLines()
+
+	rl.Buffers.Write([]rune(strings.Join(buffer[lines.start:lines.end+1], "\n") + "\n")...)
+
+	buffer = slices.Delete(buffer, lines.start, lines.end+1)
+	if len(buffer) == 0 {
+		buffer = []string{""}
+	}
+
+	rl.setBufferLines(buffer, lines.start)
+
+	return nil
+}
+
+// exNormal runs :normal {keys}, by dispatching the keys in vi command mode,
+// at the cursor or on each line of the range, from its first column: lines
+// are processed from the last one to the first, so that deleting or adding
+// lines does not change the numbers of those not processed yet. Keys can
+// contain inputrc escapes (like \e or \C-a), and insert mode is left after
+// running them, while incomplete commands are aborted.
+func (rl *Shell) exNormal(lines exRange, keys string) {
+	if keys == "" {
+		return
+	}
+
+	for line := lines.end; line >= lines.start; line-- {
+		if lines.set {
+			rl.viGotoBufferLine(line)
+			rl.cursor.BeginningOfLine()
+		}
+
+		rl.runKeys([]rune(inputrc.Unescape(keys)))
+
+		if rl.Keymap.Main() == keymap.ViInsert {
+			rl.viCommandMode()
+		}
+	}
+}
+
+// exSet runs :set on each of the arguments: {option} sets a boolean option,
+// or shows the value of another one, no{option} unsets it, inv{option} and
+// {option}! toggle it, {option}? shows it and {option}={value} sets it. Vim
+// names are accepted for the vi-* options, and all other options are accepted
+// with their own name. Without arguments, the vi-* options are shown.
+func (rl *Shell) exSet(args []string) error {
+	var shown []string
+
+	if len(args) == 0 {
+		args = []string{"ignorecase?", "smartcase?", "wrapscan?", "shiftwidth?", "iskeyword?"}
+	}
+
+	for _, arg := range args {
+		name, value, assign := strings.Cut(arg, "=")
+		name, query := strings.CutSuffix(name, "?")
+		name, toggle := strings.CutSuffix(name, "!")
+
+		var unset bool
+
+		option, current, found := rl.exOption(name)
+
+		if after, prefixed := strings.CutPrefix(name, "no"); !found && prefixed {
+			option, current, found = rl.exOption(after)
+			unset = true
+		} else if after, prefixed := strings.CutPrefix(name, "inv"); !found && prefixed {
+			option, current, found = rl.exOption(after)
+			toggle = true
+		}
+
+		if !found {
+			return rl.exError(locale.ExUnknownOption, arg)
+		}
+
+		enabled, isBool := current.(bool)
+
+		switch {
+		case (query || !isBool && !assign) && !toggle && !unset:
+			shown = append(shown, exOptionValue(option, current))
+			continue
+		case assign == isBool, !isBool && (toggle || unset):
+			return rl.exError(locale.ExInvalidArgument, arg)
+		case unset:
+			value = "off"
+		case toggle && enabled:
+			value = "off"
+		case isBool:
+			value = "on"
+		}
+
+		if err := rl.SetOption(option, value); err != nil {
+			return rl.exError(locale.ExInvalidArgument, arg)
+		}
+	}
+
+	if len(shown) > 0 {
+		rl.Hint.SetTemporary(strings.Join(shown, "  "))
+	}
+
+	return nil
+}
+
+// exOption returns the name and the value of the option
+// with the given vim name, or with this name if none.
+func (rl *Shell) exOption(name string) (option string, value any, found bool) {
+	option = name
+	if vimOption, isVim := exOptions[name]; isVim {
+		option = vimOption
+	}
+
+	value, found = rl.GetOption(option)
+
+	return option, value, found
+}
+
+// exOptionValue formats an option value as :set shows it,
+// with the vim name of the option if it has one.
+func exOptionValue(option string, value any) string {
+	name := option
+	if vimName := strings.TrimPrefix(option, "vi-"); exOptions[vimName] == option {
+		name = vimName
+	}
+
+	switch val := value.(type) {
+	case bool:
+		if !val {
+			return "no" + name
+		}
+
+		return name
+	default:
+		return fmt.Sprintf("%s=%v", name, val)
+	}
+}
@@ -0,0 +1,120 @@
+package readline_test
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+func TestShell_ViExCommand(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{":s/o/0/", `\r`}, want: "one\ntwo two\nthree"},
+		{keys: []string{"gg", ":s/o/0/", `\r`}, want: "0ne\ntwo two\nthree"},
+		{keys: []string{":%s/o/0/g", `\r`}, want: "0ne\ntw0 tw0\nthree"},
+		{keys: []string{`:1,2s/\\w+/[&]/`, `\r`}, want: "[one]\n[two] two\nthree"},
+		{keys: []string{`:%s/(\\w)(\\w+)/\\2\\1/g`, `\r`}, want: "neo\nwot wot\nhreet"},
+		{keys: []string{`:2s/ /\\n/`, `\r`}, want: "one\ntwo\ntwo\nthree"},
+		{keys: []string{":.-1,$s#e#E#g", `\r`}, want: "one\ntwo two\nthrEE"},
+		{keys: []string{":%s/O/0/gi", `\r`}, want: "0ne\ntw0 tw0\nthree"},
+		{keys: []string{":%s/O/0/", `\r`}, want: "one\ntwo two\nthree"},
+		{keys: []string{":2d", `\r`}, want: "one\nthree"},
+		{keys: []string{":2,$d", `\r`, "p"}, want: "one\ntwo two\nthree"},
+		{keys: []string{":%d", `\r`}, want: ""},
+		{keys: []string{":1", `\r`, "x"}, want: "ne\ntwo two\nthree"},
+		{keys: []string{":normal Ax", `\r`}, want: "one\ntwo two\nthreex"},
+		{keys: []string{":%norm Ax", `\r`}, want: "onex\ntwo twox\nthreex"},
+		{keys: []string{":1,2norm dd", `\r`}, want: "three"},
+		{keys: []string{":norm d", `\r`, "0"}, want: "one\ntwo two\nthree"},
+		{keys: []string{`:2norm wi\\C-v\\t`, `\r`, "u"}, want: "one\ntwo two\nthree"},
+		{keys: []string{`:2norm wi\\C-v\\t`, `\r`}, want: "one\ntwo \ttwo\nthree"},
+		{keys: []string{":unknown", `\r`}, want: "one\ntwo two\nthree"},
+		{keys: []string{":5d", `\r`}, want: "one\ntwo two\nthree"},
+		{keys: []string{":s/a", `\C-c`}, want: "one\ntwo two\nthree"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune("one\ntwo two\nthree"))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("Run(%q) line = %q, want %q", test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViExSet(t *testing.T) {
+	term, rl := newViTerminal(t, 80, 24)
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	keys := []string{
+		`\C-o`,
+		":set ic nows sw=4 isk=-", `\r`,
+		":se invscs", `\r`,
+		":set ignorecase! smartcase!", `\r`,
+		`\r`,
+	}
+
+	if _, err := term.Run(rl, keys...); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string]any{
+		"vi-ignorecase": false,
+		"vi-smartcase":  false,
+		"vi-wrapscan":   false,
+		"vi-shiftwidth": 4,
+		"vi-iskeyword":  "-",
+	}
+
+	for name, value := range want {
+		if got, _ := rl.GetOption(name); got != value {
+			t.Errorf("GetOption(%q) = %v, want %v", name, got, value)
+		}
+	}
+
+	// Errors and option values are shown in the hint.
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{command: "set sw? ws?", want: "shiftwidth=8  wrapscan"},
+		{command: "set", want: "noignorecase  nosmartcase  wrapscan  shiftwidth=8  iskeyword="},
+		{command: "set foo", want: "Unknown option: foo"},
+		{command: "set sw=x", want: "Invalid argument: sw=x"},
+		{command: "s/x/y/", want: "Pattern not found: x"},
+		{command: "s/(/y/", want: "Invalid pattern: ("},
+		{command: "4,5d", want: "Invalid range"},
+		{command: "foo", want: "Not an editor command: foo"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.command, func(t *testing.T) {
+			term, rl := newViTerminal(t, 80, 24)
+			rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+			done := make(chan struct{})
+
+			go func() {
+				rl.Readline()
+				close(done)
+			}()
+
+			term.Type(`\C-o`, ":"+test.command, `\r`)
+			waitForText(t, term, test.want)
+
+			term.Type(`\r`)
+			<-done
+		})
+	}
+}
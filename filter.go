@@ -0,0 +1,229 @@
+package readline
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/locale"
+)
+
+// filterInterrupted is the exit code of filters interrupted by the user,
+// like fzf when exited with Escape or Ctrl-C: their errors are not reported.
+const filterInterrupted = 130
+
+// Filter runs a command with the system shell (sh -c, or cmd /C on Windows),
+// writes the input to its standard input and returns its standard output,
+// without trailing newlines. The terminal is restored to its original state
+// while the command runs, so that interactive filters like fzf can use it,
+// and the prompt and line are redisplayed once done.
+//
+// This function can be called from commands or widgets run by the shell,
+// for instance to pipe some or all of the input line through a formatter.
+func (rl *Shell) Filter(command, input string) (string, error) {
+	var output bytes.Buffer
+
+	err := rl.RunInTerminal(func() error {
+		cmd := rl.filterCommand(command)
+
+		cmd.Stdin = strings.NewReader(input)
+		cmd.Stdout = &output
+		cmd.Stderr = os.Stderr
+
+		return cmd.Run()
+	})
+
+	return strings.TrimRight(output.String(), "\r\n"), err
+}
+
+// filterCommand returns the command running the filter with the system shell.
+func (rl *Shell) filterCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+
+	shell := rl.getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+
+	return exec.Command(shell, "-c", command)
+}
+
+// filterCommands returns the commands piping the line or history
+// through filters, or inserting the output of commands in the line.
+func (rl *Shell) filterCommands() map[string]func() {
+	return map[string]func(){
+		"filter-line":    rl.filterLine,
+		"filter-history": rl.filterHistory,
+
+		"insert-command-output": rl.insertCommandOutput,
+	}
+}
+
+// Pipe the input line through the command set with the filter-command
+// option, or read from a nested prompt if this option is empty, and replace
+// the line with its output. If the visual selection is active, only the
+// selected text is piped through the command and replaced with its output.
+func (rl *Shell) filterLine() {
+	rl.History.Save()
+
+	command, ok := rl.readFilterCommand("filter-command")
+	if !ok {
+		return
+	}
+
+	bpos, epos := 0, rl.line.Len()
+	if rl.selection.Active() {
+		bpos, epos = rl.selection.Pos()
+	}
+
+	output, ok := rl.runFilter(command, string((*rl.line)[bpos:epos]))
+	if !ok {
+		return
+	}
+
+	filtered := []rune(output)
+
+	rl.selection.Reset()
+	rl.line.InsertBetween(bpos, epos, filtered...)
+	rl.cursor.Set(bpos + len(filtered))
+}
+
+// Pipe the lines of the current history source, most recent first and
+// without duplicates, through the command set with the history-filter-command
+// option, or read from a nested prompt if this option is empty, and replace
+// the input line with the first line of its output, like with fzf.
+func (rl *Shell) filterHistory() {
+	rl.History.Save()
+
+	history := rl.History.Current()
+	if history == nil {
+		return
+	}
+
+	command, ok := rl.readFilterCommand("history-filter-command")
+	if !ok {
+		return
+	}
+
+	var lines []string
+
+	seen := make(map[string]bool)
+
+	for i := history.Len() - 1; i >= 0; i-- {
+		line, err := history.GetLine(i)
+		if err != nil || line == "" || seen[line] {
+			continue
+		}
+
+		seen[line] = true
+		lines = append(lines, line)
+	}
+
+	output, ok := rl.runFilter(command, strings.Join(lines, "\n")+"\n")
+	if !ok || output == "" {
+		return
+	}
+
+	output, _, _ = strings.Cut(output, "\n")
+
+	rl.line.Set([]rune(strings.TrimRight(output, "\r"))...)
+	rl.cursor.Set(rl.line.Len())
+}
+
+// readFilterCommand returns the filter command set with the given
+// option, or the one read from a nested prompt if the option is empty.
+func (rl *Shell) readFilterCommand(option string) (command string, ok bool) {
+	command = strings.Trim(rl.Config.GetString(option), "\"")
+	if command != "" {
+		return command, true
+	}
+
+	command, err := rl.ReadNested(rl.locale.Get(locale.FilterPrompt))
+	if err != nil || strings.TrimSpace(command) == "" {
+		return "", false
+	}
+
+	return command, true
+}
+
+// runFilter runs the filter command on the input, and reports its error.
+func (rl *Shell) runFilter(command, input string) (output string, ok bool) {
+	output, err := rl.Filter(command, input)
+	if err == nil {
+		return output, true
+	}
+
+	rl.reportCommandError(locale.FilterError, err)
+
+	return "", false
+}
+
+// Read a command from a nested prompt, run it with the shell executor (the
+// system shell by default) and insert its output at the cursor, with newlines
+// replaced by spaces: useful to insert dates, identifiers or lists of files.
+// If the visual selection is active, its text is run as the command instead,
+// and the selection is replaced with the output.
+func (rl *Shell) insertCommandOutput() {
+	rl.History.Save()
+
+	bpos, epos := rl.cursor.Pos(), rl.cursor.Pos()
+
+	var command string
+
+	if rl.selection.Active() {
+		bpos, epos = rl.selection.Pos()
+		command = string((*rl.line)[bpos:epos])
+	} else {
+		read, err := rl.ReadNested(rl.locale.Get(locale.CommandPrompt))
+		if err != nil {
+			return
+		}
+
+		command = read
+	}
+
+	if strings.TrimSpace(command) == "" {
+		return
+	}
+
+	output, err := rl.runExecutor(command)
+	if err != nil {
+		rl.reportCommandError(locale.CommandError, err)
+		return
+	}
+
+	output = strings.ReplaceAll(strings.TrimRight(output, "\r\n"), "\r\n", "\n")
+	inserted := []rune(strings.ReplaceAll(output, "\n", " "))
+
+	rl.selection.Reset()
+	rl.line.InsertBetween(bpos, epos, inserted...)
+	rl.cursor.Set(bpos + len(inserted))
+}
+
+// runExecutor runs a command with the shell executor, or with the
+// system shell and without input if there is no executor.
+func (rl *Shell) runExecutor(command string) (string, error) {
+	if rl.Executor != nil {
+		return rl.Executor(command)
+	}
+
+	return rl.Filter(command, "")
+}
+
+// reportCommandError displays the error of an external command in
+// the hint, unless the command was interrupted by the user.
+func (rl *Shell) reportCommandError(msg string, err error) {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == filterInterrupted {
+		return
+	}
+
+	errStr := strings.ReplaceAll(err.Error(), "\n", "")
+	rl.Hint.SetTemporary(color.FgRed + rl.locale.Sprintf(msg, errStr))
+}
@@ -0,0 +1,97 @@
+package readline_test
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_FilterLine(t *testing.T) {
+	tests := []struct {
+		name string
+		vi   bool
+		keys []string
+		want string
+	}{
+		{name: "prompted command", keys: []string{"echo hello", `\C-x|`, "tr a-z A-Z", `\r`, `\r`}, want: "ECHO HELLO"},
+		{name: "visual selection", vi: true, keys: []string{"echo hello", `\C-o`, "vb!", `\r`}, want: "echo olleh"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term := readlinetest.NewTerminal(80, 24)
+			rl := term.Shell()
+			rl.Config.Bind("emacs", inputrc.Unescape(`\C-x|`), "filter-line", false)
+
+			if test.vi {
+				term, rl = newViTerminal(t, 80, 24)
+				rl.Config.Set("filter-command", "rev")
+				rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+				rl.Config.Bind("vi-visual", "!", "filter-line", false)
+			}
+
+			line, err := term.Run(rl, test.keys...)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if line != test.want {
+				t.Errorf("Run() line = %q, want %q", line, test.want)
+			}
+		})
+	}
+}
+
+func TestShell_FilterHistory(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("history-filter-command", "grep two")
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xh`), "filter-history", false)
+
+	for _, line := range []string{"one", "two", "three", "two words"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	line, err := term.Run(rl, `\C-xh`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "two words" {
+		t.Errorf("Run() line = %q, want %q", line, "two words")
+	}
+}
+
+func TestShell_InsertCommandOutput(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-x!`), "insert-command-output", false)
+
+	line, err := term.Run(rl, "touch ", `\C-x!`, "echo a; echo b", `\r`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "touch a b" {
+		t.Errorf("Run() line = %q, want %q", line, "touch a b")
+	}
+
+	var executed string
+
+	rl.Executor = func(command string) (string, error) {
+		executed = command
+		return "2024-01-01\n", nil
+	}
+
+	line, err = term.Run(rl, "mkdir ", `\C-x!`, "date", `\r`, "-backup", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if executed != "date" || line != "mkdir 2024-01-01-backup" {
+		t.Errorf("Run() line = %q (executed %q), want %q", line, executed, "mkdir 2024-01-01-backup")
+	}
+}
@@ -0,0 +1,293 @@
+package readline
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// ErrInvalidChoice is returned by the default validation of
+// select and confirm fields when the answer is not a valid choice.
+var ErrInvalidChoice = errors.New("invalid choice")
+
+// errPreviousField is returned when reading a form field
+// if the user asked to go back to the previous field.
+var errPreviousField = errors.New("previous form field")
+
+// previousFieldKey is the key bound to form-previous-field while reading forms.
+var previousFieldKey = inputrc.Unescape(`\e[Z`)
+
+// FieldKind is the kind of input read by a form field.
+type FieldKind int
+
+const (
+	// TextField reads a line of text.
+	TextField FieldKind = iota
+	// PasswordField reads a line of text, displayed masked.
+	PasswordField
+	// SelectField reads one of the field choices, which are completed.
+	SelectField
+	// ConfirmField reads a yes/no answer, recorded as "yes" or "no".
+	ConfirmField
+)
+
+// Field is a question asked by a form.
+type Field struct {
+	Name     string                    // Name of the answer in the form answers.
+	Prompt   string                    // Question displayed as the prompt.
+	Kind     FieldKind                 // Kind of input to read.
+	Default  string                    // Answer used when the input is left empty.
+	Choices  []string                  // Valid answers of select fields.
+	Validate func(answer string) error // Optional validation of the answer.
+}
+
+// Form is a series of fields read in sequence with RunForm.
+type Form struct {
+	Fields []Field
+
+	// Style is a sequence of color/text effects applied to all prompts.
+	Style string
+
+	// Mask is the character displayed in place of
+	// password characters: an asterisk if zero.
+	Mask rune
+}
+
+// Answers holds the answers to a form, indexed by field names.
+type Answers map[string]string
+
+// Bool returns true if the answer to the named confirm field is "yes".
+func (a Answers) Bool(name string) bool {
+	return a[name] == "yes"
+}
+
+// RunForm reads the answers to all fields of a form in sequence, with the
+// field prompt and the form style in place of the shell prompts. Answers are
+// neither written to, nor navigated from, the shell history, and the shell
+// completer, syntax highlighter, tokenizer, hint provider, suggesters, line
+// checker, binding contexts, hooks, accept, interrupt and end-of-file handlers
+// are disabled meanwhile: they are all restored once done. Keys typed in the
+// fields are not traced either.
+//
+// Invalid answers are reported below the input line, and the field is read
+// again. Shift-Tab goes back to the previous field, with its answer already
+// inserted. If reading a field returns an error, like ErrInterrupt when the
+// user presses Ctrl-C, the answers read so far are returned with this error.
+func (rl *Shell) RunForm(form Form) (Answers, error) {
+	answers := make(Answers)
+
+	restore := rl.startStandalone()
+	defer restore()
+
+	unbind := rl.bindPreviousField()
+	defer unbind()
+
+	for index := 0; index < len(form.Fields); {
+		field := form.Fields[index]
+
+		answer, err := rl.readField(form, field, answers[field.Name])
+
+		switch {
+		case errors.Is(err, errPreviousField):
+			index = max(index-1, 0)
+			continue
+		case err != nil:
+			return answers, err
+		}
+
+		if err := field.validate(answer); err != nil {
+			fmt.Fprint(rl.out, color.FgRed+err.Error()+color.Reset+term.NewlineReturn)
+			answers[field.Name] = answer
+
+			continue
+		}
+
+		answers[field.Name] = answer
+		index++
+	}
+
+	return answers, nil
+}
+
+// startStandalone saves and replaces the shell settings not applying to
+// standalone prompts (forms, selections), and returns a function restoring
+// them. Prompts, completer and syntax highlighter are set by the caller.
+func (rl *Shell) startStandalone() (restore func()) {
+	prompt := rl.Prompt.Snapshot()
+	history := rl.History.Isolate()
+
+	completer, highlighter := rl.Completer, rl.SyntaxHighlighter
+	completerContext, tokenizer := rl.CompleterContext, rl.Tokenizer
+	multiline, interrupt, eof := rl.AcceptMultiline, rl.Interrupt, rl.EndOfFile
+	hints, asyncCompleters, checker := rl.HintProvider, rl.asyncCompleters, rl.checker
+	bindContexts, suggesters := rl.bindContexts, rl.Suggesters
+
+	rl.AcceptMultiline, rl.Interrupt, rl.EndOfFile = nil, nil, nil
+	rl.HintProvider, rl.asyncCompleters, rl.checker = nil, nil, nil
+	rl.bindContexts, rl.Suggesters = nil, nil
+	rl.Completer, rl.SyntaxHighlighter = nil, nil
+	rl.CompleterContext, rl.Tokenizer = nil, nil
+	rl.standalone = true
+	rl.Hooks.mute(true)
+
+	rl.Prompt.Right(nil)
+	rl.Prompt.Secondary(nil)
+	rl.Prompt.Transient(nil)
+	rl.Prompt.Tooltip(func(string) string { return "" })
+
+	return func() {
+		rl.standalone = false
+		rl.Hooks.mute(false)
+		rl.AcceptMultiline, rl.Interrupt, rl.EndOfFile = multiline, interrupt, eof
+		rl.HintProvider, rl.asyncCompleters, rl.checker = hints, asyncCompleters, checker
+		rl.bindContexts, rl.Suggesters = bindContexts, suggesters
+		rl.Completer, rl.SyntaxHighlighter = completer, highlighter
+		rl.CompleterContext, rl.Tokenizer = completerContext, tokenizer
+
+		history()
+		prompt()
+	}
+}
+
+// bindPreviousField binds the previous field key in the main
+// keymaps, and returns a function restoring their previous binds.
+func (rl *Shell) bindPreviousField() (unbind func()) {
+	keymaps := []string{string(rl.Keymap.Main())}
+	if keymaps[0] != "vi-command" {
+		keymaps = append(keymaps, "vi-command")
+	}
+
+	binds := make(map[string]*inputrc.Bind)
+
+	for _, keymap := range keymaps {
+		if bind, bound := rl.Config.Binds[keymap][previousFieldKey]; bound {
+			binds[keymap] = &bind
+		} else {
+			binds[keymap] = nil
+		}
+
+		rl.Config.Bind(keymap, previousFieldKey, "form-previous-field", false)
+	}
+
+	return func() {
+		for keymap, bind := range binds {
+			if bind != nil {
+				rl.Config.Binds[keymap][previousFieldKey] = *bind
+			} else {
+				delete(rl.Config.Binds[keymap], previousFieldKey)
+			}
+		}
+	}
+}
+
+// readField reads the answer to a form field, with the previous answer, if
+// any, inserted in the line. Empty answers are replaced with the default one.
+func (rl *Shell) readField(form Form, field Field, previous string) (string, error) {
+	prompt := form.Style + field.Prompt + color.Reset
+
+	switch {
+	case field.Kind == ConfirmField && field.Default != "":
+		prompt += color.Dim + " (y/n) [" + field.Default + "]" + color.Reset
+	case field.Kind == ConfirmField:
+		prompt += color.Dim + " (y/n)" + color.Reset
+	case field.Default != "" && field.Kind != PasswordField:
+		prompt += color.Dim + " [" + field.Default + "]" + color.Reset
+	}
+
+	rl.Prompt.Primary(func() string { return prompt + " " })
+
+	rl.Completer = nil
+	rl.SyntaxHighlighter = nil
+
+	switch field.Kind {
+	case PasswordField:
+		mask := form.Mask
+		if mask == 0 {
+			mask = '*'
+		}
+
+		rl.SyntaxHighlighter = func(line []rune) string {
+			return strings.Repeat(string(mask), len(line))
+		}
+	case SelectField:
+		rl.Completer = func(_ []rune, _ int) Completions {
+			return CompleteValues(field.Choices...).NoSort()
+		}
+	}
+
+	if previous != "" {
+		rl.History.Prefill([]rune(previous))
+	}
+
+	answer, err := rl.Readline()
+	if err != nil {
+		return answer, err
+	}
+
+	if field.Kind == SelectField || field.Kind == ConfirmField {
+		answer = strings.TrimSpace(answer)
+	}
+
+	if answer == "" {
+		answer = field.Default
+	}
+
+	if field.Kind == ConfirmField {
+		answer = confirmAnswer(answer)
+	}
+
+	return answer, nil
+}
+
+// validate checks the answer is one of the field choices
+// (if any), and runs the field validation function (if any).
+func (f Field) validate(answer string) error {
+	switch {
+	case f.Kind == SelectField && len(f.Choices) > 0 && !slices.Contains(f.Choices, answer):
+		return fmt.Errorf("%w: %q", ErrInvalidChoice, answer)
+	case f.Kind == ConfirmField && answer != "yes" && answer != "no":
+		return fmt.Errorf("%w: %q", ErrInvalidChoice, answer)
+	case f.Validate != nil:
+		return f.Validate(answer)
+	}
+
+	return nil
+}
+
+// confirmAnswer returns "yes" or "no" for the common forms of
+// these answers (like y, Y, yes, n, No), or the answer as is.
+func confirmAnswer(answer string) string {
+	switch strings.ToLower(answer) {
+	case "y", "yes":
+		return "yes"
+	case "n", "no":
+		return "no"
+	}
+
+	return answer
+}
+
+// formCommands returns the commands used when reading forms.
+func (rl *Shell) formCommands() map[string]func() {
+	return map[string]func(){
+		"form-previous-field": rl.formPreviousField,
+	}
+}
+
+// When reading a form with RunForm, go back to the previous
+// field, with its answer inserted. Does nothing otherwise.
+func (rl *Shell) formPreviousField() {
+	rl.History.SkipSave()
+
+	if !rl.standalone {
+		return
+	}
+
+	rl.Display.AcceptLine()
+	rl.History.Accept(false, false, errPreviousField)
+}
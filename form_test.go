@@ -0,0 +1,101 @@
+package readline_test
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_RunForm(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	form := readline.Form{
+		Style: "\x1b[1m",
+		Fields: []readline.Field{
+			{Name: "name", Prompt: "Name?"},
+			{Name: "password", Prompt: "Password?", Kind: readline.PasswordField},
+			{Name: "color", Prompt: "Color?", Kind: readline.SelectField, Choices: []string{"red", "blue"}},
+			{Name: "sure", Prompt: "Sure?", Kind: readline.ConfirmField, Default: "y"},
+		},
+	}
+
+	// Fields are not suggested the shell lines, nor suggesters given the answers.
+	var suggested []string
+
+	rl.Config.Set("history-autosuggest", true)
+	rl.Suggesters = []readline.Suggester{readline.SuggesterFunc(
+		func(_ context.Context, line []rune, _ int) []readline.Suggestion {
+			suggested = append(suggested, string(line))
+			return []readline.Suggestion{{Line: string(line) + "-suggested"}}
+		},
+	)}
+
+	// Neither hooks nor traces are given the answers.
+	var hooked []string
+
+	rl.Hooks.OnPreRead(func() { hooked = append(hooked, "pre-read") })
+	rl.Hooks.OnLineChange(func(line []rune, _ int) { hooked = append(hooked, string(line)) })
+	rl.Hooks.OnPostAccept(func(line string, _ error) { hooked = append(hooked, line) })
+
+	var trace bytes.Buffer
+
+	rl.SetTraceOutput(&trace)
+
+	term.Type("bob", `\r`, "secret", `\r`, "green", `\r`, `\C-u`, "blue", `\r`, `\e[Z`, `\C-u`, "red", `\r`, `\r`)
+
+	answers, err := rl.RunForm(form)
+	if err != nil {
+		t.Fatalf("RunForm() error = %v", err)
+	}
+
+	want := readline.Answers{"name": "bob", "password": "secret", "color": "red", "sure": "yes"}
+	if !maps.Equal(answers, want) {
+		t.Errorf("RunForm() = %v, want %v", answers, want)
+	}
+
+	if !answers.Bool("sure") {
+		t.Error("Answers.Bool() = false, want true")
+	}
+
+	screen := term.Screen()
+	if strings.Contains(screen, "secret") || !strings.Contains(screen, "Password? ******") {
+		t.Errorf("password not masked:\n%s", screen)
+	}
+
+	if strings.Contains(screen, "-suggested") || len(suggested) > 0 {
+		t.Errorf("fields suggested %q:\n%s", suggested, screen)
+	}
+
+	if len(hooked) > 0 {
+		t.Errorf("hooks run with %q", hooked)
+	}
+
+	if !strings.Contains(trace.String(), "msg=dispatch") || strings.Contains(trace.String(), "keys=") {
+		t.Errorf("field keys traced:\n%s", trace.String())
+	}
+
+	rl.SetTraceOutput(nil)
+
+	if len(rl.Suggesters) != 1 {
+		t.Errorf("Suggesters = %v, want them restored", rl.Suggesters)
+	}
+
+	if !strings.Contains(screen, "invalid choice") {
+		t.Errorf("invalid choice not reported:\n%s", screen)
+	}
+
+	line, err := term.Run(rl, `\C-p`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "" {
+		t.Errorf("form answers written to history: %q", line)
+	}
+}
@@ -0,0 +1,50 @@
+package readline
+
+import (
+	"github.com/reeflective/readline/internal/display"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// Frame is the interface of a shell rendered off-screen with Shell.Frame:
+// a grid of cells as tall and wide as the shell terminal.
+type Frame struct {
+	Cells   [][]FrameCell // Rows of the frame, from the top of the screen.
+	CursorX int           // Column of the cursor (0-based).
+	CursorY int           // Row of the cursor (0-based).
+}
+
+// FrameCell is a character of a frame, with its style (colors and effects).
+type FrameCell = term.Cell
+
+// String returns the text of the frame without styles, nor
+// trailing spaces on each row, nor trailing empty rows.
+func (f Frame) String() string {
+	return term.RenderCells(f.Cells, false)
+}
+
+// Styled is like String, but the text keeps its styles as SGR
+// escape sequences, which are reset at the end of each row.
+func (f Frame) Styled() string {
+	return term.RenderCells(f.Cells, true)
+}
+
+// Frame renders the interface of the shell (prompts, input line with its
+// highlighting, hints and completions) as it would be displayed from the
+// top of its terminal, without writing anything to the terminal. This
+// allows hosts to test the rendering of their prompts, highlighters and
+// completers, or to generate screenshots for their documentation.
+//
+// This function can be called whether or not the shell is reading input,
+// but not concurrently with it: only from within commands and callbacks
+// run by the shell, which can safely use Line() and Cursor().
+func (rl *Shell) Frame() Frame {
+	screen := term.NewScreen(rl.out.Width(), rl.out.Height())
+
+	display.Init(rl.Display, rl.highlighter(), rl.suggestLine, rl.pendingCommand)
+	rl.Display.Frame(screen)
+
+	frame := Frame{Cells: screen.Cells()}
+	frame.CursorX, frame.CursorY = screen.Cursor()
+
+	return frame
+}
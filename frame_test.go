@@ -0,0 +1,57 @@
+package readline_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_Frame(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "first\n> " })
+	rl.SyntaxHighlighter = func(line []rune) string {
+		return strings.Replace(string(line), "hello", "\x1b[31mhello\x1b[0m", 1)
+	}
+
+	rl.SetLine([]rune("hello world"))
+	rl.Hint.Set("a hint")
+
+	frame := rl.Frame()
+
+	if want := "first\n> hello world\na hint"; frame.String() != want {
+		t.Errorf("Frame() = %q, want %q", frame.String(), want)
+	}
+
+	if !strings.Contains(frame.Styled(), "\x1b[31mhello\x1b[0m world") {
+		t.Errorf("Frame().Styled() = %q, want the highlighted line", frame.Styled())
+	}
+
+	if frame.CursorX != 13 || frame.CursorY != 1 {
+		t.Errorf("Frame() cursor = %d,%d, want 13,1", frame.CursorX, frame.CursorY)
+	}
+
+	if output := term.Output(); output != "" {
+		t.Errorf("Frame() wrote %q to the terminal", output)
+	}
+
+	// The frame is rendered the same while reading.
+	var reading readline.Frame
+
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xf`), "frame", false)
+	rl.Keymap.Register(map[string]func(){"frame": func() { reading = rl.Frame() }})
+
+	if _, err := term.Run(rl, "hello world", `\C-xf`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if want := "first\n> hello world"; reading.String() != want {
+		t.Errorf("Frame() while reading = %q, want %q", reading.String(), want)
+	}
+
+	readlinetest.AssertScreen(t, term, "first\n> hello world")
+}
@@ -6,7 +6,6 @@ require (
 	github.com/google/go-cmp v0.5.8
 	golang.org/x/exp v0.0.0-20220827204233-334a2380cb91
 	golang.org/x/sys v0.8.0
-	golang.org/x/term v0.8.0
 )
 
 require github.com/rivo/uniseg v0.4.4
@@ -0,0 +1,124 @@
+package readline
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// SetAsyncHintProvider registers a hint provider run asynchronously, for hints
+// too expensive to compute while typing (like the synopsis of a command read
+// from its manual page, or API documentation fetched over the network).
+//
+// Once the line or the cursor position have changed, the provider is called in
+// another goroutine if they stay the same for the debounce duration, and its
+// hint is displayed below the input line as soon as it returns. Its context is
+// cancelled when the line changes again or when the shell stops reading, in
+// which case its hint is discarded. The hint of the previous line is displayed
+// until then. This provider replaces the HintProvider, if any. A nil function
+// disables it.
+func (rl *Shell) SetAsyncHintProvider(debounce time.Duration, provider func(ctx context.Context, line []rune, cursor int) string) {
+	rl.asyncHint = provider
+	rl.hintDebounce = debounce
+}
+
+// provideHint calls the hint provider, if any, when the line or the cursor
+// position have changed since its last call, and displays the hint it returns.
+func (rl *Shell) provideHint() {
+	if diff := rl.historyDiffHint(); diff != "" {
+		rl.cancelHint()
+		rl.hintLine, rl.hintCursor = rl.hintLine[:0], -1
+		rl.Hint.Provide(diff)
+
+		return
+	}
+
+	if rl.HintProvider == nil && rl.asyncHint == nil {
+		rl.Hint.Provide("")
+		return
+	}
+
+	line, cursor := rl.completer.Line()
+	if cursor.Pos() == rl.hintCursor && slices.Equal(*line, rl.hintLine) {
+		return
+	}
+
+	rl.hintLine = append(rl.hintLine[:0], *line...)
+	rl.hintCursor = cursor.Pos()
+
+	if rl.asyncHint != nil {
+		rl.fetchHint(append([]rune{}, *line...), cursor.Pos())
+		return
+	}
+
+	rl.Hint.Provide(providedHint(rl.HintProvider(append([]rune{}, *line...), cursor.Pos())))
+}
+
+// fetchHint cancels the hint being fetched, if any, and starts fetching
+// the hint of the line with the asynchronous hint provider once debounced.
+func (rl *Shell) fetchHint(line []rune, cursor int) {
+	rl.cancelHint()
+
+	ctx, cancel := context.WithCancel(rl.readContext())
+	rl.hintCancel = cancel
+
+	provider, debounce := rl.asyncHint, rl.hintDebounce
+
+	go func() {
+		timer := time.NewTimer(debounce)
+		defer timer.Stop()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		hint := provider(ctx, line, cursor)
+
+		// The line is locked while the shell processes keys,
+		// which then cancels this fetch if the line changes.
+		rl.lineMutex.Lock()
+		defer rl.lineMutex.Unlock()
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		rl.Hint.Provide(providedHint(hint))
+
+		if rl.isReading() {
+			rl.Display.Refresh()
+		}
+	}()
+}
+
+// cancelHint cancels the hint being fetched asynchronously, if any.
+// It must be called with the line locked.
+func (rl *Shell) cancelHint() {
+	if rl.hintCancel != nil {
+		rl.hintCancel()
+		rl.hintCancel = nil
+	}
+}
+
+// historyDiffHint returns the changes made to the input line since it has
+// been recalled from the history, if the history-diff-hint option is on.
+func (rl *Shell) historyDiffHint() string {
+	if !rl.Config.GetBool("history-diff-hint") {
+		return ""
+	}
+
+	return rl.History.RecalledDiff()
+}
+
+// providedHint returns the hint returned by a hint provider, dimmed.
+func providedHint(hint string) string {
+	if hint == "" {
+		return ""
+	}
+
+	return color.Dim + hint
+}
@@ -0,0 +1,88 @@
+package readline_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_HintProvider(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	var calls []string
+
+	rl.HintProvider = func(line []rune, cursor int) string {
+		calls = append(calls, fmt.Sprintf("%s:%d", string(line), cursor))
+
+		if strings.HasPrefix(string(line), "2+2") {
+			return "= 4"
+		}
+
+		return ""
+	}
+
+	line, err := term.Run(rl, "2+2", `\C-e`, `\C-a`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "2+2" {
+		t.Errorf("Run() line = %q, want %q", line, "2+2")
+	}
+
+	if !strings.Contains(term.Output(), "= 4") {
+		t.Error("provided hint not displayed")
+	}
+
+	for i := 1; i < len(calls); i++ {
+		if calls[i] == calls[i-1] {
+			t.Errorf("hint provider called twice with an unchanged line: %v", calls)
+			break
+		}
+	}
+}
+
+func TestShell_AsyncHintProvider(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	called := make(chan string, 10)
+
+	rl.SetAsyncHintProvider(20*time.Millisecond, func(_ context.Context, line []rune, _ int) string {
+		called <- string(line)
+		return "synopsis of " + string(line)
+	})
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("l")
+	term.Type("s")
+
+	select {
+	case line := <-called:
+		if line != "ls" {
+			t.Errorf("hint provider called with %q, want %q", line, "ls")
+		}
+	case <-time.After(readlinetest.Timeout):
+		t.Fatal("hint provider not called")
+	}
+
+	waitForText(t, term, "synopsis of ls")
+
+	term.Type(`\r`)
+
+	if line := <-done; line != "ls" {
+		t.Errorf("Readline() line = %q, want %q", line, "ls")
+	}
+}
@@ -1,10 +1,13 @@
 package readline
 
 import (
-	"strings"
+	"errors"
 
-	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/history"
+	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
 	"github.com/reeflective/readline/internal/strutil"
 )
 
@@ -18,6 +21,24 @@ import (
 // Users who want an easy to use, file-based history should use NewHistoryFromFile().
 type History = history.Source
 
+// HistoryItem is a history line along with its details. History sources giving
+// them, by implementing HistoryItemSource, have them displayed in the history
+// completion menu, with the index and how long ago each line was written, and
+// in the details panel of the selected line if the history-details option is
+// enabled (along with its date, duration and full content). The history-group
+// option groups lines in the menu by the day or the session of their item.
+type HistoryItem = history.Item
+
+// HistoryItemSource is implemented by history sources giving the details of
+// their lines. Both the file-based and the in-memory sources implement it.
+type HistoryItemSource = history.ItemSource
+
+// HistoryDeleteSource is implemented by history sources able to delete their
+// lines, whose lines can then be deleted from the history completion menu, with
+// the delete-history-line command. Both the file-based and the in-memory sources
+// implement it: the file-based one rewrites its file without the deleted line.
+type HistoryDeleteSource = history.DeleteSource
+
 // NewHistoryFromFile creates a new command history source writing to and reading
 // from a file. The caller should bind the history source returned from this call
 // to the readline instance, with shell.History.Add().
@@ -68,6 +89,8 @@ func (rl *Shell) historyCommands() commands {
 		"end-of-line-hist":                   rl.endOfLineHist,
 		"incremental-forward-search-history": rl.incrementalForwardSearchHistory,
 		"incremental-reverse-search-history": rl.incrementalReverseSearchHistory,
+		"history-index-search-forward":       rl.historyIndexSearchForward,
+		"history-index-search-backward":      rl.historyIndexSearchBackward,
 		"save-line":                          rl.saveLine,
 		"history-source-next":                rl.historySourceNext,
 		"history-source-prev":                rl.historySourcePrev,
@@ -76,6 +99,10 @@ func (rl *Shell) historyCommands() commands {
 		"autosuggest-enable":                 rl.autosuggestEnable,
 		"autosuggest-disable":                rl.autosuggestDisable,
 		"autosuggest-toggle":                 rl.autosuggestToggle,
+		"forward-search-match":               rl.forwardSearchMatch,
+		"backward-search-match":              rl.backwardSearchMatch,
+		"revert-history-entry":               rl.revertHistoryEntry,
+		"delete-history-line":                rl.deleteHistoryLine,
 	}
 
 	return widgets
@@ -151,7 +178,7 @@ func (rl *Shell) forwardSearchHistory() {
 	filterLine := false
 	regexp := true
 
-	rl.historyCompletion(forward, filterLine, regexp)
+	rl.historyCompletion(forward, filterLine, regexp, false)
 }
 
 // Search backward starting at the current line and moving `up' through
@@ -164,7 +191,7 @@ func (rl *Shell) reverseSearchHistory() {
 	filterLine := false
 	regexp := true
 
-	rl.historyCompletion(forward, filterLine, regexp)
+	rl.historyCompletion(forward, filterLine, regexp, false)
 }
 
 // Search forward through the history starting at the current line
@@ -263,14 +290,7 @@ func (rl *Shell) yankLastArg() {
 	}
 
 	// Get the last word, and quote it if it contains spaces.
-	lastArg := words[len(words)-1]
-	if strings.ContainsAny(lastArg, " \t") {
-		if strings.Contains(lastArg, "\"") {
-			lastArg = "'" + lastArg + "'"
-		} else {
-			lastArg = "\"" + lastArg + "\""
-		}
-	}
+	lastArg := strutil.QuoteWord(words[len(words)-1])
 
 	// And append it to the end of the line.
 	rl.line.Insert(rl.cursor.Pos(), []rune(lastArg)...)
@@ -305,65 +325,41 @@ func (rl *Shell) yankNthArg() {
 		return
 	}
 
-	lastArg = words[argNth-1]
-
 	// Quote if required.
-	if strings.ContainsAny(lastArg, " \t") {
-		if strings.Contains(lastArg, "\"") {
-			lastArg = "'" + lastArg + "'"
-		} else {
-			lastArg = "\"" + lastArg + "\""
-		}
-	}
+	lastArg = strutil.QuoteWord(words[argNth-1])
 
 	// And append it to the end of the line.
 	rl.line.Insert(rl.line.Len(), []rune(lastArg)...)
 	rl.cursor.Move(len(lastArg))
 }
 
-// Perform history expansion on the current line and insert a space.
-// If the current blank word under cursor starts with an exclamation
-// mark, the word up to the cursor is matched as a prefix against
-// the history lines, and the first match is inserted in place of it.
+// Perform history expansion on the line before the cursor, and insert a space.
+// History designators are replaced with the history lines, or the words of these
+// lines, that they designate: !! is the previous line, !n the nth line, !-n the
+// nth previous one, !string the most recent line starting with string, !?string?
+// the most recent one containing it. They can be followed by a word designator:
+// :n for the nth word (the command being the 0th one), :^ for the first argument,
+// :$ for the last one, :* for all of them, :n-m for a range of words. !$, !^ and
+// !* are shorthands for the words of the previous line. Exclamation marks escaped
+// with a backslash or in single quotes are not expanded, and with a numeric
+// argument, the space is inserted without performing any expansion.
 func (rl *Shell) magicSpace() {
-	cpos := rl.cursor.Pos()
-	lineLen := rl.line.Len()
-
-	// If no line, or the cursor is on a space, we can't perform.
-	if lineLen == 0 || (cpos == lineLen && (*rl.line)[cpos-1] == inputrc.Space) {
-		rl.selfInsert()
-		return
-	}
-
-	// Select the word around cursor.
-	rl.viSelectInBlankWord()
-	word, bpos, _, _ := rl.selection.Pop()
-	rl.cursor.Set(cpos)
-
-	// Fail if empty or not prefixed expandable.
-	if len(strings.TrimSpace(word)) == 0 {
-		rl.selfInsert()
-		return
-	}
-
-	if !strings.HasPrefix(word, "!") || word == "!" {
+	if rl.Iterations.IsSet() {
 		rl.selfInsert()
 		return
 	}
 
-	// Else, perform expansion on the remainder.
-	pattern := (*rl.line)[bpos+1:]
-	suggested := rl.History.Suggest(&pattern)
+	cpos := rl.cursor.Pos()
 
-	if string(suggested) == string(pattern) {
-		rl.selfInsert()
-		return
+	expanded := rl.History.Expand(string((*rl.line)[:cpos]))
+	if expanded != string((*rl.line)[:cpos]) {
+		rl.History.Save()
+		rl.line.Cut(0, cpos)
+		rl.line.Insert(0, []rune(expanded)...)
+		rl.cursor.Set(len([]rune(expanded)))
 	}
 
-	rl.History.Save()
-	rl.line.Cut(bpos, lineLen)
-	rl.line.Insert(bpos, suggested...)
-	rl.cursor.Set(bpos + suggested.Len())
+	rl.selfInsert()
 }
 
 //
@@ -537,7 +533,7 @@ func (rl *Shell) incrementalForwardSearchHistory() {
 	filter := true
 	regexp := false
 
-	rl.historyCompletion(forward, filter, regexp)
+	rl.historyCompletion(forward, filter, regexp, false)
 }
 
 // Start an backward history autocompletion mode, starting at the
@@ -549,7 +545,35 @@ func (rl *Shell) incrementalReverseSearchHistory() {
 	filter := true
 	regexp := false
 
-	rl.historyCompletion(forward, filter, regexp)
+	rl.historyCompletion(forward, filter, regexp, false)
+}
+
+// Start a forward history menu completion, from the most ancient line
+// in the history to the most recent: selecting a line inserts its index
+// (like !42) at the cursor, instead of replacing the line with it.
+func (rl *Shell) historyIndexSearchForward() {
+	rl.History.SkipSave()
+
+	forward := true
+	filter := false
+	regexp := false
+	byIndex := true
+
+	rl.historyCompletion(forward, filter, regexp, byIndex)
+}
+
+// Start a backward history menu completion, from the most recent line
+// in the history to the most ancient: selecting a line inserts its index
+// (like !42) at the cursor, instead of replacing the line with it.
+func (rl *Shell) historyIndexSearchBackward() {
+	rl.History.SkipSave()
+
+	forward := false
+	filter := false
+	regexp := false
+	byIndex := true
+
+	rl.historyCompletion(forward, filter, regexp, byIndex)
 }
 
 // Write the current line to the history if it is not empty
@@ -559,6 +583,77 @@ func (rl *Shell) saveLine() {
 	rl.History.Revert()
 }
 
+// If the line has been recalled from the history and edited since, replace
+// it with the history entry as it was recalled. With the history-diff-hint
+// option, the changes made to such lines are shown in the hint area.
+func (rl *Shell) revertHistoryEntry() {
+	rl.History.RevertRecalled()
+}
+
+// While browsing the history in the completion menu or in an incremental
+// search, delete the selected line from its history source, after asking for
+// confirmation with Enter or `y`: the line is removed from memory and from the
+// backing store of the source (like the history file), if it can delete lines.
+func (rl *Shell) deleteHistoryLine() {
+	rl.History.SkipSave()
+
+	if rl.Keymap.Local() != keymap.MenuSelect && rl.Keymap.Local() != keymap.Isearch {
+		return
+	}
+
+	selected := rl.completer.Selected()
+
+	entry, isHistory := selected.Meta.(history.Entry)
+	if !isHistory || !rl.confirmDeleteHistoryLine(selected.Value) {
+		return
+	}
+
+	if err := rl.History.DeleteLine(entry.Source, entry.Index); err != nil {
+		rl.Hint.SetTemporary(color.FgRed + rl.locale.Sprintf(locale.HistoryError, err) + color.Reset)
+		return
+	}
+
+	rl.completer.Update()
+}
+
+// confirmDeleteHistoryLine returns true if the user confirms the deletion
+// of the line with Enter or `y`, and false if they cancel it with `n`.
+// Other keys cancel it too, and are left in the key stack to be dispatched.
+func (rl *Shell) confirmDeleteHistoryLine(line string) bool {
+	restore := rl.Hint.Snapshot()
+	defer restore()
+
+	rl.Hint.Set(color.FgRed + rl.locale.Sprintf(locale.DeleteHistory, line) + color.Reset + color.Dim + rl.locale.Get(locale.ConfirmAccept) + color.Reset)
+	rl.Display.Refresh()
+
+	ctx := rl.readContext()
+
+	for {
+		err := core.WaitAvailableKeys(ctx, rl.Keys, rl.Config)
+		if errors.Is(err, core.ErrIdle) {
+			continue
+		} else if err != nil {
+			return false
+		}
+
+		key, empty := core.PeekKey(rl.Keys)
+		if empty {
+			continue
+		}
+
+		switch key {
+		case '\r', '\n', 'y', 'Y':
+			core.PopForce(rl.Keys)
+			return true
+		case 'n', 'N':
+			core.PopForce(rl.Keys)
+			return false
+		default:
+			return false
+		}
+	}
+}
+
 // If more than one source of command history is bound to the shell,
 // cycle to the next one and use it for all history search operations,
 // movements across lines, their respective undo histories, etc.
@@ -575,7 +670,7 @@ func (rl *Shell) historySourcePrev() {
 
 // If a line is currently autoggested, make it the buffer.
 func (rl *Shell) autosuggestAccept() {
-	suggested := rl.History.Suggest(rl.line)
+	suggested := rl.suggestLine(rl.line, rl.cursor.Pos())
 
 	if suggested.Len() <= rl.line.Len() {
 		return
@@ -587,7 +682,7 @@ func (rl *Shell) autosuggestAccept() {
 
 // If a line is currently autoggested, make it the buffer and execute it.
 func (rl *Shell) autosuggestExecute() {
-	suggested := rl.History.Suggest(rl.line)
+	suggested := rl.suggestLine(rl.line, rl.cursor.Pos())
 
 	if suggested.Len() <= rl.line.Len() {
 		return
@@ -624,6 +719,48 @@ func (rl *Shell) autosuggestDisable() {
 	rl.Config.Vars["history-autosuggest"] = false
 }
 
+// When the line has been recalled with an incremental history search, move
+// the cursor to the beginning of the next match of the search in the line,
+// going back to the first one after the last one. Matches are highlighted
+// until the line is accepted.
+func (rl *Shell) forwardSearchMatch() {
+	rl.History.SkipSave()
+
+	matches := rl.searchMatches()
+	if len(matches) == 0 {
+		return
+	}
+
+	for _, match := range matches {
+		if match[0] > rl.cursor.Pos() {
+			rl.cursor.Set(match[0])
+			return
+		}
+	}
+
+	rl.cursor.Set(matches[0][0])
+}
+
+// Like forward-search-match, but move the cursor to the previous match,
+// going to the last one before the first one.
+func (rl *Shell) backwardSearchMatch() {
+	rl.History.SkipSave()
+
+	matches := rl.searchMatches()
+	if len(matches) == 0 {
+		return
+	}
+
+	for i := len(matches) - 1; i >= 0; i-- {
+		if matches[i][0] < rl.cursor.Pos() {
+			rl.cursor.Set(matches[i][0])
+			return
+		}
+	}
+
+	rl.cursor.Set(matches[len(matches)-1][0])
+}
+
 //
 // Utils -------------------------------------------------------------------
 //
@@ -642,6 +779,11 @@ func (rl *Shell) acceptLineWith(infer, hold bool) {
 		defer rl.completer.NonIsearchStop()
 
 		line, cursor, _ := rl.completer.GetBuffer()
+
+		if !rl.Keymap.IsEmacs() {
+			defer rl.viSearchOptions(*line)()
+		}
+
 		rl.History.InsertMatch(line, cursor, true, forward, substring)
 
 		return
@@ -652,22 +794,14 @@ func (rl *Shell) acceptLineWith(infer, hold bool) {
 
 	// Without multiline support, we always return the line.
 	if rl.AcceptMultiline == nil {
-		rl.Macros.StopRecord(rl.Keys.Caller()...)
-
-		rl.Display.AcceptLine()
-		rl.History.Accept(hold, infer, nil)
-
+		rl.acceptTransformed(hold, infer)
 		return
 	}
 
 	// Ask the caller if the line should be accepted
 	// as is, save the command line and accept it.
 	if rl.AcceptMultiline(*rl.line) {
-		rl.Macros.StopRecord(rl.Keys.Caller()...)
-
-		rl.Display.AcceptLine()
-		rl.History.Accept(hold, infer, nil)
-
+		rl.acceptTransformed(hold, infer)
 		return
 	}
 
@@ -679,6 +813,80 @@ func (rl *Shell) acceptLineWith(infer, hold bool) {
 	rl.cursor.Inc()
 }
 
+// acceptTransformed runs the line through the accepted line transforms,
+// displays the line as accepted and saves the transformed one to history.
+func (rl *Shell) acceptTransformed(hold, infer bool) {
+	line := string(*rl.line)
+	transformed := line
+	echo := rl.Config.GetBool("echo-transformed-line")
+
+	// Standalone prompts do not read command lines.
+	if !rl.standalone {
+		transformed = rl.Hooks.runTransformAccepted(line)
+
+		if !rl.confirmAccept(transformed) {
+			return
+		}
+	}
+
+	rl.Macros.StopRecord(rl.Keys.Caller()...)
+
+	if transformed != line && echo {
+		rl.line.Set([]rune(transformed)...)
+		rl.cursor.Set(rl.line.Len())
+		rl.Display.Refresh()
+	}
+
+	rl.Display.AcceptLine()
+
+	if transformed != line && !echo {
+		rl.line.Set([]rune(transformed)...)
+		rl.cursor.Set(rl.line.Len())
+	}
+
+	rl.History.Accept(hold, infer, nil)
+}
+
+// confirmAccept returns true if no confirmation hook warns about the line,
+// or if the user confirms it with Enter or `y` after the warning is shown.
+// Other keys are left in the key stack, to be dispatched as usual.
+func (rl *Shell) confirmAccept(line string) bool {
+	warning := rl.Hooks.runConfirmAccept(line)
+	if warning == "" {
+		return true
+	}
+
+	restore := rl.Hint.Snapshot()
+	defer restore()
+
+	rl.Hint.Set(color.FgRed + warning + color.Reset + color.Dim + rl.locale.Get(locale.ConfirmAccept) + color.Reset)
+	rl.Display.Refresh()
+
+	ctx := rl.readContext()
+
+	for {
+		err := core.WaitAvailableKeys(ctx, rl.Keys, rl.Config)
+		if errors.Is(err, core.ErrIdle) {
+			continue
+		} else if err != nil {
+			return false
+		}
+
+		key, empty := core.PeekKey(rl.Keys)
+		if empty {
+			continue
+		}
+
+		switch key {
+		case '\r', '\n', 'y', 'Y':
+			core.PopForce(rl.Keys)
+			return true
+		default:
+			return false
+		}
+	}
+}
+
 func (rl *Shell) insertAutosuggestPartial(emacs bool) {
 	cpos := rl.cursor.Pos()
 	if cpos < rl.line.Len()-1 {
@@ -689,15 +897,15 @@ func (rl *Shell) insertAutosuggestPartial(emacs bool) {
 		return
 	}
 
-	suggested := rl.History.Suggest(rl.line)
+	suggested := rl.suggestLine(rl.line, rl.cursor.Pos())
 
 	if suggested.Len() > rl.line.Len() {
 		var forward int
 
 		if emacs {
-			forward = suggested.ForwardEnd(suggested.Tokenize, cpos)
+			forward = suggested.ForwardEnd(suggested.TokenizeWords(rl.wordClass()), cpos)
 		} else {
-			forward = suggested.Forward(suggested.Tokenize, cpos)
+			forward = suggested.Forward(suggested.TokenizeWords(rl.wordClass()), cpos)
 		}
 
 		if cpos+1+forward > suggested.Len() {
@@ -707,3 +915,28 @@ func (rl *Shell) insertAutosuggestPartial(emacs bool) {
 		rl.line.Insert(cpos+1, suggested[cpos+1:cpos+forward+1]...)
 	}
 }
+
+// updateSearchMatches keeps the matcher of the last incremental search having
+// replaced the line with one of its matches (moving the cursor to the first
+// match in the line), and highlights the matches in the current line.
+func (rl *Shell) updateSearchMatches() {
+	if matcher := rl.completer.KeptMatcher(); matcher != nil && !rl.standalone {
+		rl.searchMatcher = matcher
+
+		if matches := rl.searchMatches(); len(matches) > 0 {
+			rl.cursor.Set(matches[0][0])
+		}
+	}
+
+	rl.Display.HighlightMatches(rl.searchMatches())
+}
+
+// searchMatches returns the positions of the matches of the last
+// incremental search having replaced the line in the line, if any.
+func (rl *Shell) searchMatches() [][]int {
+	if rl.searchMatcher == nil {
+		return nil
+	}
+
+	return rl.searchMatcher.FindAll(string(*rl.line))
+}
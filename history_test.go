@@ -0,0 +1,484 @@
+package readline_test
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_FuzzySearch(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	for _, line := range []string{"go test", "grep foo", "git commit"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	line, err := term.Run(rl, `\C-r`, `\C-t`, "gt", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "go test" {
+		t.Errorf("Run() line = %q, want %q", line, "go test")
+	}
+
+	if !strings.Contains(term.Output(), "(fuzzy-search)") {
+		t.Error("fuzzy search mode not shown in the hint")
+	}
+}
+
+func TestShell_SearchTerms(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	for _, line := range []string{"docker run nginx", "docker compose run", "docker ps"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	line, err := term.Run(rl, `\C-r`, "docker !compose run", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "docker run nginx" {
+		t.Errorf("Run() line = %q, want %q", line, "docker run nginx")
+	}
+}
+
+func TestShell_SearchMatchNavigation(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	for _, line := range []string{"a1", "b", "a2", "a3"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	line, err := term.Run(rl, `\C-r`, "a", `\en`, `\en`, `\ep`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "a2" {
+		t.Errorf("Run() line = %q, want %q", line, "a2")
+	}
+
+	for _, hint := range []string{"(match 1/3)", "(match 2/3)", "(match 3/3)"} {
+		if !strings.Contains(term.Output(), hint) {
+			t.Errorf("match position %q not shown in the hint", hint)
+		}
+	}
+}
+
+func TestShell_SearchCompleteQuery(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	for _, line := range []string{"kubectl get pods", "git status", "kubectl describe pod"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	line, err := term.Run(rl, `\C-r`, "kub", `\t`, " get", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "kubectl get pods" {
+		t.Errorf("Run() line = %q, want %q", line, "kubectl get pods")
+	}
+
+	if !strings.Contains(term.Output(), "\x1b[1mkubectl get\x1b[0m_") {
+		t.Errorf("search query not completed:\n%s", term.Screen())
+	}
+
+	if _, err := term.Run(rl, `\C-r`, "po", `\t`, `\t`, `\C-g`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !strings.Contains(term.Output(), "(words: pod pods)") {
+		t.Errorf("query words not listed in the hint:\n%s", term.Screen())
+	}
+}
+
+func TestShell_HistoryDetails(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("history-details", true)
+
+	history := readline.NewInMemoryHistory()
+	history.Write("echo one\necho two")
+	history.Write("ls")
+	rl.History.Delete()
+	rl.History.Add("test", history)
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type(`\C-r`, "two", `\C-n`)
+
+	waitForText(t, term, "│ echo two")
+
+	screen := term.Screen()
+
+	for _, want := range []string{"│ !0  ", "│ echo one\n│ echo two"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("Screen() = %q, want it to contain %q", screen, want)
+		}
+	}
+
+	term.Type(`\r`)
+
+	if line := <-done; line != "echo one\necho two" {
+		t.Errorf("Readline() line = %q, want %q", line, "echo one\necho two")
+	}
+}
+
+func TestShell_HistoryDiffHint(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("history-diff-hint", true)
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xr`), "revert-history-entry", false)
+
+	if _, err := term.Run(rl, "git commit -m foo", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	line, err := term.Run(rl, `\C-p`, `\C-?`, `\C-?`, `\C-?`, "bar", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "git commit -m bar" {
+		t.Errorf("Run() line = %q, want %q", line, "git commit -m bar")
+	}
+
+	if !strings.Contains(term.Output(), "\x1b[31mfoo\x1b[0m\x1b[32mbar\x1b[0m") {
+		t.Errorf("line changes not shown in the hint:\n%s", term.Screen())
+	}
+
+	line, err = term.Run(rl, `\C-p`, `\C-p`, "s", `\C-xr`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "git commit -m foo" {
+		t.Errorf("Run() line = %q, want %q", line, "git commit -m foo")
+	}
+}
+
+func TestShell_UndoHistory(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xu`), "undo-history", false)
+
+	edits := []string{"one two", `\C-w`, "three", `\C-w`, "four"}
+
+	line, err := term.Run(rl, append(edits, `\e1`, `\C-xu`, `\r`)...)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "one two" {
+		t.Errorf("Run() line = %q, want %q", line, "one two")
+	}
+
+	line, err = term.Run(rl, append(edits, `\C-xu`, `\C-n`, `\r`, `\r`)...)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "one three" {
+		t.Errorf("Run() line = %q, want %q", line, "one three")
+	}
+
+	if !strings.Contains(term.Output(), `-"wo" +"hree"`) {
+		t.Error("undo state diff not shown in the menu")
+	}
+}
+
+func TestShell_UndoHistoryWalk(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	if _, err := term.Run(rl, "foo bar baz", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The edit undone on the history line must not come back when walking to it.
+	line, err := term.Run(rl, `\C-p`, `\C-w`, `\C-w`, `\C-xu`, `\C-xu`, `\C-n`, `\C-p`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "foo bar baz" {
+		t.Errorf("Run() line = %q, want %q", line, "foo bar baz")
+	}
+}
+
+func TestShell_SearchMatches(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	for _, line := range []string{"echo foo bar foo", "ls"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	// The cursor is placed on the first match once the line is recalled,
+	// and moves between matches, wrapping around the line.
+	line, err := term.Run(rl, `\C-r`, "foo", `\C-x]`, `\C-x]`, `\C-x[`, "X", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "echo foo bar Xfoo" {
+		t.Errorf("Run() line = %q, want %q", line, "echo foo bar Xfoo")
+	}
+}
+
+func TestShell_HistoryIndexSearch(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-x!`), "history-index-search-backward", false)
+
+	for _, line := range []string{"go test", "grep foo", "git commit"} {
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	}
+
+	line, err := term.Run(rl, "echo ", `\C-x!`, `\C-n`, `\C-n`, `\r`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "echo !1" {
+		t.Errorf("Run() line = %q, want %q", line, "echo !1")
+	}
+
+	if !strings.Contains(term.Output(), "!2  0s ago") {
+		t.Error("history line index and time not shown in the menu")
+	}
+}
+
+// itemHistory is a history source giving the details of its lines.
+type itemHistory []readline.HistoryItem
+
+func (h *itemHistory) Write(line string) (int, error) {
+	*h = append(*h, readline.HistoryItem{Index: len(*h), Block: line, DateTime: time.Now()})
+	return len(*h), nil
+}
+
+func (h *itemHistory) GetLine(i int) (string, error) { return (*h)[i].Block, nil }
+
+func (h *itemHistory) GetItem(i int) (readline.HistoryItem, error) { return (*h)[i], nil }
+
+func (h *itemHistory) Len() int { return len(*h) }
+
+func (h *itemHistory) Dump() interface{} { return *h }
+
+func TestShell_HistoryGroup(t *testing.T) {
+	now := time.Now()
+	old := now.AddDate(0, 0, -3)
+
+	tests := []struct {
+		group string
+		want  []string
+	}{
+		{group: "day", want: []string{"Today", "Yesterday", old.Format(time.DateOnly)}},
+		{group: "session", want: []string{"Session b", "Session a"}},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+		rl.Config.Set("max-redisplay-rate", 0)
+		rl.Config.Set("history-group", test.group)
+
+		history := &itemHistory{
+			{Block: "echo old", DateTime: old, Session: "a"},
+			{Block: "echo yesterday", DateTime: now.AddDate(0, 0, -1), Session: "a"},
+			{Block: "echo today", DateTime: now, Session: "b"},
+		}
+		rl.History.Delete()
+		rl.History.Add("test", history)
+
+		done := make(chan string, 1)
+
+		go func() {
+			line, _ := rl.Readline()
+			done <- line
+		}()
+
+		term.Type(`\C-r`)
+
+		waitForText(t, term, "echo old")
+
+		screen := term.Screen()
+		last := -1
+
+		for _, want := range test.want {
+			index := strings.Index(screen, want)
+			if index <= last {
+				t.Errorf("group %s: Screen() = %q, want %q after the previous group", test.group, screen, want)
+			}
+
+			last = index
+		}
+
+		term.Type(`\C-g`, `\C-u`, `\r`)
+		<-done
+	}
+}
+
+func TestShell_MagicSpace(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{"sudo !!", " "}, want: `sudo cp "my file" /tmp `},
+		{keys: []string{"ls !$", " "}, want: "ls /tmp "},
+		{keys: []string{"ls !^", " ", "!*", " "}, want: `ls "my file" "my file" /tmp `},
+		{keys: []string{"!0", " "}, want: "echo one two three "},
+		{keys: []string{"!-2:2", " "}, want: "two "},
+		{keys: []string{"!ech:1-2", " "}, want: "one two "},
+		{keys: []string{"!?two?:$", " "}, want: "three "},
+		{keys: []string{"!!:0 ", "x"}, want: "cp x"},
+		{keys: []string{`echo \\!! '!!'`, " "}, want: `echo \!! '!!' `},
+		{keys: []string{"echo !nothing", " "}, want: "echo !nothing "},
+		{keys: []string{"echo !!", `\e2`, " "}, want: "echo !! "},
+		{keys: []string{"echo !!", " ", `\C-_`}, want: "echo !!"},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+
+		history := readline.NewInMemoryHistory()
+		history.Write("echo one two three")
+		history.Write(`cp "my file" /tmp`)
+		rl.History.Delete()
+		rl.History.Add("test", history)
+
+		rl.Config.Bind("emacs", " ", "magic-space", false)
+
+		line, err := term.Run(rl, append(test.keys, `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("keys %q: line = %q, want %q", test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_DeleteHistoryLine(t *testing.T) {
+	newShell := func(history readline.History) (*readlinetest.Terminal, *readline.Shell) {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+		rl.Config.Set("max-redisplay-rate", 0)
+		rl.History.Delete()
+		rl.History.Add("test", history)
+
+		return term, rl
+	}
+
+	lines := func(history readline.History) (all []string) {
+		for i := 0; i < history.Len(); i++ {
+			line, _ := history.GetLine(i)
+			all = append(all, line)
+		}
+
+		return all
+	}
+
+	history := readline.NewInMemoryHistory()
+	for _, line := range []string{"go test", "grep passwd", "git commit"} {
+		history.Write(line)
+	}
+
+	// Other keys than Enter or y cancel the deletion.
+	term, rl := newShell(history)
+
+	if _, err := term.Run(rl, `\C-r`, "grep", `\C-n`, `\e[3;2~`, "n", `\C-g`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got, want := lines(history), []string{"go test", "grep passwd", "git commit"}; !slices.Equal(got, want) {
+		t.Errorf("history lines = %q, want %q", got, want)
+	}
+
+	term, rl = newShell(history)
+
+	if _, err := term.Run(rl, `\C-r`, "grep", `\C-n`, `\e[3;2~`, "y", `\C-g`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if got, want := lines(history), []string{"go test", "git commit"}; !slices.Equal(got, want) {
+		t.Errorf("history lines = %q, want %q", got, want)
+	}
+
+	if !strings.Contains(term.Output(), `delete "grep passwd" from history?`) {
+		t.Error("deletion confirmation not shown in the hint")
+	}
+
+	// History files are rewritten without the deleted line.
+	file := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	fileHistory, err := readline.NewHistoryFromFile(file)
+	if err != nil {
+		t.Fatalf("NewHistoryFromFile() error = %v", err)
+	}
+
+	for _, line := range []string{"go test", "grep passwd", "git commit"} {
+		fileHistory.Write(line)
+	}
+
+	term, rl = newShell(fileHistory)
+
+	if _, err := term.Run(rl, `\C-r`, "grep", `\C-n`, `\e[3;2~`, `\r`, `\C-g`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	reloaded, err := readline.NewHistoryFromFile(file)
+	if err != nil {
+		t.Fatalf("NewHistoryFromFile() error = %v", err)
+	}
+
+	if got, want := lines(reloaded), []string{"go test", "git commit"}; !slices.Equal(got, want) {
+		t.Errorf("history file lines = %q, want %q", got, want)
+	}
+}
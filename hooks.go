@@ -0,0 +1,291 @@
+package readline
+
+import "sync"
+
+// Hooks is a registry of functions called by the shell on various events
+// of its lifecycle, with the relevant payloads. All functions are called
+// synchronously on the goroutine running Readline, in the order in which
+// they have been registered. Hooks can be registered at any time.
+// The only exception are line change hooks, which also run on the
+// goroutine editing the line with Shell methods like SetLine.
+type Hooks struct {
+	preRead    []func()
+	postAccept []func(line string, err error)
+	preRender  []func()
+	modeChange []func(main, local string)
+	preCommand []func(command string)
+	lineChange []func(line []rune, cursor int)
+	transforms []func(line string) string
+	confirms   []func(line string) (warning string)
+	selects    []func(candidate Completion)
+	accepts    []func(candidate Completion)
+	muted      bool // Hooks are not run, like while reading standalone prompts.
+	mutex      sync.RWMutex
+}
+
+// OnPreRead registers a function called each time the
+// shell starts reading a new line, after printing the prompt.
+func (h *Hooks) OnPreRead(hook func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.preRead = append(h.preRead, hook)
+}
+
+// OnPostAccept registers a function called each time the shell returns
+// a line to the caller, with the error returned along with it, if any:
+// this includes reads that are interrupted, cancelled, timed out, closed
+// or failed (like with io.EOF), in which case the line may be empty.
+func (h *Hooks) OnPostAccept(hook func(line string, err error)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.postAccept = append(h.postAccept, hook)
+}
+
+// OnPreRender registers a function called each time the shell is about
+// to redisplay the input line and its helpers (hints, completions, etc).
+func (h *Hooks) OnPreRender(hook func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.preRender = append(h.preRender, hook)
+}
+
+// OnModeChange registers a function called each time the main and/or
+// local keymaps have changed after running a command, with their names.
+// The local keymap is empty when no local keymap is active.
+func (h *Hooks) OnModeChange(hook func(main, local string)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.modeChange = append(h.modeChange, hook)
+}
+
+// OnPreCommand registers a function called each time a command
+// bound to the keys just read is about to run, with its name.
+func (h *Hooks) OnPreCommand(hook func(command string)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.preCommand = append(h.preCommand, hook)
+}
+
+// OnLineChange registers a function called each time the input line or the
+// cursor position have changed, after a command or any Shell method editing
+// the line, with a copy of the line and the cursor position. The line includes
+// any completion candidate currently inserted.
+func (h *Hooks) OnLineChange(hook func(line []rune, cursor int)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lineChange = append(h.lineChange, hook)
+}
+
+// TransformAccepted registers a function transforming the line accepted by
+// the user, before it is saved to history and returned to the caller: for
+// instance to trim whitespace, expand abbreviations or strip comments.
+// Transforms are chained: each of them receives the line returned by the
+// previous one. With the echo-transformed-line option, the final line is
+// redisplayed in place of the one typed.
+func (h *Hooks) TransformAccepted(hook func(line string) string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.transforms = append(h.transforms, hook)
+}
+
+// ConfirmAccept registers a function checking the line accepted by the user
+// (after any transform) before it is returned: when it returns a non-empty
+// warning, like for lines running `rm -rf`, the warning is displayed as a hint,
+// and the line is only returned if the next key is Enter or `y`. Any other key
+// cancels the confirmation, and is then processed as usual.
+func (h *Hooks) ConfirmAccept(hook func(line string) (warning string)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.confirms = append(h.confirms, hook)
+}
+
+// OnCandidateSelect registers a function called each time a completion
+// candidate is selected in the menu (and virtually inserted in the line),
+// with the candidate as generated, including its Meta data.
+func (h *Hooks) OnCandidateSelect(hook func(candidate Completion)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.selects = append(h.selects, hook)
+}
+
+// OnCandidateAccept registers a function called each time a completion
+// candidate is inserted for good in the line, either because it is the
+// only one or because the selected one has been accepted, with the
+// candidate as generated, including its Meta data.
+func (h *Hooks) OnCandidateAccept(hook func(candidate Completion)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.accepts = append(h.accepts, hook)
+}
+
+func (h *Hooks) runPreRead() {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.preRead
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+func (h *Hooks) runPostAccept(line string, err error) {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.postAccept
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(line, err)
+	}
+}
+
+func (h *Hooks) runPreRender() {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.preRender
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook()
+	}
+}
+
+func (h *Hooks) runModeChange(main, local string) {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.modeChange
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(main, local)
+	}
+}
+
+func (h *Hooks) runPreCommand(command string) {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.preCommand
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(command)
+	}
+}
+
+func (h *Hooks) runLineChange(line []rune, cursor int) {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.lineChange
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(append([]rune{}, line...), cursor)
+	}
+}
+
+func (h *Hooks) runTransformAccepted(line string) string {
+	if h.isMuted() {
+		return line
+	}
+
+	h.mutex.RLock()
+	hooks := h.transforms
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		line = hook(line)
+	}
+
+	return line
+}
+
+func (h *Hooks) runConfirmAccept(line string) (warning string) {
+	if h.isMuted() {
+		return ""
+	}
+
+	h.mutex.RLock()
+	hooks := h.confirms
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		if warning = hook(line); warning != "" {
+			return warning
+		}
+	}
+
+	return ""
+}
+
+func (h *Hooks) runCandidateSelect(candidate Completion) {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.selects
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(candidate)
+	}
+}
+
+func (h *Hooks) runCandidateAccept(candidate Completion) {
+	if h.isMuted() {
+		return
+	}
+
+	h.mutex.RLock()
+	hooks := h.accepts
+	h.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(candidate)
+	}
+}
+
+// mute disables all hooks (or enables them again), for instance while reading
+// standalone prompts, so that their answers (like passwords) are not passed to them.
+func (h *Hooks) mute(muted bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.muted = muted
+}
+
+func (h *Hooks) isMuted() bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	return h.muted
+}
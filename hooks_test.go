@@ -0,0 +1,198 @@
+package readline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestHooks_OnLineChange(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	var changes []string
+
+	rl.Hooks.OnLineChange(func(line []rune, cursor int) {
+		changes = append(changes, fmt.Sprintf("%s:%d", string(line), cursor))
+	})
+
+	if _, err := term.Run(rl, "ab", `\C-a`, `\C-a`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := []string{"a:1", "ab:2", "ab:0"}
+	if strings.Join(changes, " ") != strings.Join(want, " ") {
+		t.Errorf("line changes = %v, want %v", changes, want)
+	}
+}
+
+func TestHooks_OnPostAccept(t *testing.T) {
+	tests := []struct {
+		name string
+		read func(term *readlinetest.Terminal, rl *readline.Shell) (string, error)
+		line string
+		err  error
+	}{
+		{
+			name: "Accepted",
+			read: func(term *readlinetest.Terminal, rl *readline.Shell) (string, error) {
+				return term.Run(rl, "ls", `\r`)
+			},
+			line: "ls",
+		},
+		{
+			name: "Interrupted",
+			read: func(term *readlinetest.Terminal, rl *readline.Shell) (string, error) {
+				return term.Run(rl, "ls", `\C-c`)
+			},
+			line: "ls",
+			err:  readline.ErrInterrupt,
+		},
+		{
+			name: "End of file",
+			read: func(term *readlinetest.Terminal, rl *readline.Shell) (string, error) {
+				return term.Run(rl, `\C-d`)
+			},
+			err: io.EOF,
+		},
+		{
+			name: "Cancelled",
+			read: func(term *readlinetest.Terminal, rl *readline.Shell) (string, error) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+
+				return rl.ReadlineCtx(ctx)
+			},
+			err: context.Canceled,
+		},
+		{
+			name: "Timed out",
+			read: func(term *readlinetest.Terminal, rl *readline.Shell) (string, error) {
+				rl.SetTimeout(10 * time.Millisecond)
+				return rl.Readline()
+			},
+			err: readline.ErrTimeout,
+		},
+		{
+			name: "Closed",
+			read: func(term *readlinetest.Terminal, rl *readline.Shell) (string, error) {
+				rl.Close()
+				return rl.Readline()
+			},
+			err: readline.ErrClosed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term := readlinetest.NewTerminal(80, 24)
+			rl := term.Shell()
+
+			var calls []string
+
+			rl.Hooks.OnPostAccept(func(line string, err error) {
+				calls = append(calls, line)
+
+				if !errors.Is(err, test.err) {
+					t.Errorf("hook error = %v, want %v", err, test.err)
+				}
+			})
+
+			line, err := test.read(term, rl)
+			if !errors.Is(err, test.err) || line != test.line {
+				t.Fatalf("read = %q, %v, want %q, %v", line, err, test.line, test.err)
+			}
+
+			if !slices.Equal(calls, []string{test.line}) {
+				t.Errorf("hook calls = %q, want %q", calls, []string{test.line})
+			}
+		})
+	}
+}
+
+func TestHooks_TransformAccepted(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	rl.Hooks.TransformAccepted(strings.TrimSpace)
+	rl.Hooks.TransformAccepted(func(line string) string {
+		return strings.Replace(line, "gs", "git status", 1)
+	})
+
+	line, err := term.Run(rl, "  gs  ", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "git status" {
+		t.Errorf("Run() line = %q, want %q", line, "git status")
+	}
+
+	if strings.Contains(term.Screen(), "git status") {
+		t.Error("transformed line echoed without echo-transformed-line")
+	}
+
+	rl.Config.Set("echo-transformed-line", true)
+
+	line, err = term.Run(rl, `\C-p`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "git status" {
+		t.Errorf("Run() line = %q, want %q from history", line, "git status")
+	}
+
+	term.Reset()
+
+	if _, err = term.Run(rl, " gs", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !strings.Contains(term.Screen(), "git status") || strings.Contains(term.Screen(), " gs") {
+		t.Errorf("transformed line not echoed:\n%s", term.Screen())
+	}
+}
+
+func TestHooks_ConfirmAccept(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	rl.Hooks.ConfirmAccept(func(line string) string {
+		if strings.Contains(line, "rm -rf") {
+			return "Dangerous command"
+		}
+
+		return ""
+	})
+
+	line, err := term.Run(rl, "rm -rf /tmp", `\r`, `\C-a`, "echo ", `\r`, "y")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "echo rm -rf /tmp" {
+		t.Errorf("Run() line = %q, want %q", line, "echo rm -rf /tmp")
+	}
+
+	if !strings.Contains(term.Output(), "Dangerous command") {
+		t.Error("confirmation warning not shown")
+	}
+
+	line, err = term.Run(rl, "ls", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "ls" {
+		t.Errorf("Run() line = %q, want %q", line, "ls")
+	}
+}
@@ -36,19 +36,33 @@ func ParseFile(name string, h Handler, opts ...Option) error {
 	return New(append(opts, WithName(name))...).Parse(f, h)
 }
 
-// UserDefault loads default inputrc settings for the user.
+// UserDefault loads default inputrc settings for the user. The INPUTRC
+// variable is read from the environment set with WithEnv, if any, and so is
+// the home directory of the user if u is nil.
 func UserDefault(u *user.User, cfg *Config, opts ...Option) error {
+	p := New(opts...)
+	getenv := p.getenv
+	if getenv == nil {
+		getenv = os.Getenv
+	}
+	home := ""
+	switch {
+	case u != nil:
+		home = u.HomeDir
+	case p.getenv != nil:
+		home = p.homeDir()
+	}
 	// build possible file list
 	var files []string
-	if name := os.Getenv("INPUTRC"); name != "" {
+	if name := getenv("INPUTRC"); name != "" {
 		files = append(files, name)
 	}
-	if u != nil {
+	if home != "" {
 		name := ".inputrc"
 		if runtime.GOOS == "windows" {
 			name = "_inputrc"
 		}
-		files = append(files, filepath.Join(u.HomeDir, name))
+		files = append(files, filepath.Join(home, name))
 	}
 	if runtime.GOOS != "windows" {
 		files = append(files, "/etc/inputrc")
@@ -68,6 +68,21 @@ func TestUserDefault(t *testing.T) {
 	}
 }
 
+func TestUserDefaultEnv(t *testing.T) {
+	test := readTest(t, path.Join("testdata", "ken.inputrc"))
+	cfg, m := newConfig()
+	env := map[string]string{
+		"HOME":        "/home/ken",
+		"USERPROFILE": "/home/ken",
+		"home":        "/home/ken",
+	}
+	getenv := func(key string) string {
+		return env[key]
+	}
+	opts := append(buildOpts(t, test[0]), WithEnv(getenv))
+	check(t, test[2], cfg, m, UserDefault(nil, cfg, opts...))
+}
+
 func TestEncontrolDecontrol(t *testing.T) {
 	tests := []struct {
 		d, e rune
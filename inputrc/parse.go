@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/user"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 	"unicode"
@@ -23,6 +24,7 @@ type Parser struct {
 	app       string
 	term      string
 	mode      string
+	getenv    func(string) string
 	keymap    string
 	line      int
 	conds     []bool
@@ -175,7 +177,12 @@ func (p *Parser) readSymbols(r []rune, i, end int, tok token) (string, string, t
 	i = findEnd(r, start, end)
 	a := string(r[start:i])
 	start = findNonSpace(r, i, end)
-	i = findEnd(r, start, end)
+	// quoted values (of string variables) may contain spaces
+	if tok == tokenSet && start < end && (r[start] == '"' || r[start] == '\'') {
+		i, _ = findStringEnd(r, start, end)
+	} else {
+		i = findEnd(r, start, end)
+	}
 	return a, string(r[start:i]), tok, nil
 }
 
@@ -297,7 +304,7 @@ func (p *Parser) do(h Handler, a, b string) error {
 		if !p.conds[len(p.conds)-1] {
 			return nil
 		}
-		path := expandIncludePath(b)
+		path := p.expandIncludePath(b)
 		buf, err := h.ReadFile(path)
 		switch {
 		case err != nil && errors.Is(err, os.ErrNotExist):
@@ -305,7 +312,7 @@ func (p *Parser) do(h Handler, a, b string) error {
 		case err != nil:
 			return err
 		}
-		return Parse(bytes.NewReader(buf), h, WithName(b), WithApp(p.app), WithTerm(p.term), WithMode(p.mode))
+		return Parse(bytes.NewReader(buf), h, WithName(b), WithApp(p.app), WithTerm(p.term), WithMode(p.mode), WithEnv(p.getenv))
 	}
 	if !p.conds[len(p.conds)-1] {
 		return nil
@@ -367,6 +374,14 @@ func WithMode(mode string) Option {
 	}
 }
 
+// WithEnv is a parser option to set the function used to read environment
+// variables, like INPUTRC and HOME, instead of the process environment.
+func WithEnv(getenv func(key string) string) Option {
+	return func(p *Parser) {
+		p.getenv = getenv
+	}
+}
+
 // ParseError is a parse error.
 type ParseError struct {
 	Name string
@@ -425,11 +440,11 @@ func findNonSpace(r []rune, i, end int) int {
 	return i
 }
 
-// findEnd finds end of the current symbol (position of next #, space, or line
-// end), returning end if not found.
+// findEnd finds end of the current symbol (position of next space, or line
+// end), returning end if not found. A # only starts a comment at the start of
+// a line or after a space, so it is part of the symbol (like in comment-begin).
 func findEnd(r []rune, i, end int) int {
-	for c := grab(r, i+1, end); i < end && c != '#' && !unicode.IsSpace(c) && !unicode.IsControl(c); i++ {
-		c = grab(r, i+1, end)
+	for ; i < end && !unicode.IsSpace(r[i]) && !unicode.IsControl(r[i]); i++ {
 	}
 	return i
 }
@@ -645,15 +660,37 @@ func hexVal(c rune) rune {
 }
 
 // expandIncludePath handles tilde home directory expansion in $include path directives.
-func expandIncludePath(file string) string {
+func (p *Parser) expandIncludePath(file string) string {
 	if !strings.HasPrefix(file, "~/") {
 		return file
 	}
 
-	u, err := user.Current()
-	if err != nil || u == nil || u.HomeDir == "" {
+	home := p.homeDir()
+	if home == "" {
 		return file
 	}
 
-	return filepath.Join(u.HomeDir, file[2:])
+	return filepath.Join(home, file[2:])
+}
+
+// homeDir returns the home directory of the user, read from the parser
+// environment if set, or the home directory of the current user otherwise.
+func (p *Parser) homeDir() string {
+	if p.getenv != nil {
+		switch runtime.GOOS {
+		case "windows":
+			return p.getenv("USERPROFILE")
+		case "plan9":
+			return p.getenv("home")
+		default:
+			return p.getenv("HOME")
+		}
+	}
+
+	u, err := user.Current()
+	if err != nil || u == nil {
+		return ""
+	}
+
+	return u.HomeDir
 }
@@ -12,6 +12,9 @@ type Candidate struct {
 	Description string // A description to display next to the completion candidate.
 	Style       string // An arbitrary string of color/text effects to use when displaying the completion.
 	Tag         string // All completions with the same tag are grouped together and displayed under the tag heading.
+	Chain       bool   // Completion starts again once the candidate is inserted, like for the value of a --output= flag.
+	Meta        any    // Opaque data about the candidate (like the object it represents), kept as is up to candidate hooks.
+	Details     string // Text displayed in a panel below the completions while the candidate is selected.
 
 	// A list of runes that are automatically trimmed when a space or a non-nil character is
 	// inserted immediately after the completion. This is used for slash-autoremoval in path
@@ -33,6 +36,16 @@ type Values struct {
 	ListSep  map[string]string
 	Pad      map[string]bool
 	Escapes  map[string]bool
+	Wrap     map[string]int
+	TagUsage map[string]string
+
+	// Loading are the tags of the groups of completions still being
+	// generated: they are displayed with a loading row in the meantime.
+	Loading []string
+
+	// StyleFunc, if not nil, returns the style of candidates
+	// when displayed, overriding their own style if not empty.
+	StyleFunc func(Candidate) string
 
 	// Initially this will be set to the part of the current word
 	// from the beginning of the word up to the position of the cursor.
@@ -44,6 +57,10 @@ type Values struct {
 	// It may be altered so that inserted completions don't overwrite
 	// entirely any suffix when completing in the middle of a word.
 	SUFFIX string
+
+	// When true, the completions are not filtered with the PREFIX,
+	// which is then only used to know what part of the line they replace.
+	NoFilter bool
 }
 
 // AddRaw adds completion values in bulk.
@@ -5,25 +5,35 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
+	"github.com/reeflective/readline/internal/strutil"
 	"github.com/reeflective/readline/internal/term"
 )
 
+// spinnerFrames are displayed in turn in the rows of groups still loading,
+// changing every spinnerInterval milliseconds when completions are redisplayed.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 100
+
 // Display prints the current completion list to the screen,
 // respecting the current display and completion settings.
 func Display(eng *Engine, maxRows int) {
 	eng.usedY = 0
 
-	defer fmt.Print(term.ClearScreenBelow)
+	defer fmt.Fprint(eng.out, term.ClearScreenBelow)
 
 	// The completion engine might be inactive but still having
 	// a non-empty list of completions. This is on purpose, as
 	// sometimes it's better to keep completions printed for a
 	// little more time. The engine itself is responsible for
 	// deleting those lists when it deems them useless.
-	if eng.Matches() == 0 || eng.skipDisplay {
-		fmt.Print(term.ClearLineAfter)
+	if (eng.Matches() == 0 && len(eng.loading) == 0) || eng.skipDisplay {
+		fmt.Fprint(eng.out, term.ClearLineAfter)
 		return
 	}
 
@@ -34,14 +44,55 @@ func Display(eng *Engine, maxRows int) {
 		completions += eng.renderCompletions(group)
 	}
 
+	completions += eng.renderLoading()
+
+	// The details of the selected candidate, if any, are always
+	// displayed below the completions, which are cropped to leave
+	// them room, unless they would not leave room for completions.
+	details := eng.renderDetails()
+
+	rows := strings.Count(details, term.NewlineReturn) + 1
+	if details == "" || maxRows-rows < 2 {
+		details, rows = "", 0
+	}
+
+	// The footer comes last, and is dropped first when short of room.
+	footer := eng.renderFooter()
+	if footer == "" || maxRows-rows-1 < 2 {
+		footer = ""
+	} else {
+		rows++
+	}
+
 	// Crop the completions so that it fits within our terminal
-	completions, eng.usedY = eng.cropCompletions(completions, maxRows)
+	completions, eng.usedY = eng.cropCompletions(completions, maxRows-rows)
+	eng.usedY += rows
+
+	if details != "" {
+		completions += term.NewlineReturn + details
+	}
+
+	if footer != "" {
+		completions += term.NewlineReturn + footer
+	}
 
 	if completions != "" {
-		fmt.Print(completions)
+		fmt.Fprint(eng.out, completions)
 	}
 }
 
+// Rows returns the number of terminal rows needed to
+// display all completions, without cropping any of them.
+func Rows(e *Engine) int {
+	if (e.Matches() == 0 && len(e.loading) == 0) || e.skipDisplay {
+		return 0
+	}
+
+	_, used := e.completionCount()
+
+	return used
+}
+
 // Coordinates returns the number of terminal rows used
 // when displaying the completions with Display().
 func Coordinates(e *Engine) int {
@@ -57,8 +108,13 @@ func (e *Engine) renderCompletions(grp *group) string {
 		return ""
 	}
 
-	if grp.tag != "" {
+	if grp.hasHeader() {
 		tag := fmt.Sprintf("%s%s%s %s", color.Bold, color.FgYellow, grp.tag, color.Reset)
+
+		if grp.usage != "" {
+			tag += color.Dim + grp.usage + color.Reset
+		}
+
 		builder.WriteString(tag + term.ClearLineAfter + term.NewlineReturn)
 	}
 
@@ -97,24 +153,91 @@ func (e *Engine) renderCompletions(grp *group) string {
 
 		// We're done for this line.
 		builder.WriteString(term.ClearLineAfter + term.NewlineReturn)
+
+		// Add the rest of its description if wrapped, aligned with it.
+		for _, line := range grp.wrappedLines(rowIndex) {
+			indent := padSpace(grp.termWidth - grp.maxDescAllowed)
+			descStyle := color.UnquoteRC(e.config.GetString("completion-description-style"))
+
+			builder.WriteString(indent + descStyle + line + color.Reset + term.ClearLineAfter + term.NewlineReturn)
+		}
 	}
 
 	return builder.String()
 }
 
+// renderLoading renders the groups of completions still being generated,
+// with their tag heading and a spinner row, animated along redisplays.
+func (e *Engine) renderLoading() string {
+	var builder strings.Builder
+
+	spinner := spinnerFrames[int(time.Now().UnixMilli()/spinnerInterval)%len(spinnerFrames)]
+
+	for _, tag := range e.loading {
+		heading := fmt.Sprintf("%s%s%s %s", color.Bold, color.FgYellow, tag, color.Reset)
+		loading := color.Dim + spinner + " " + e.locale.Get(locale.LoadingCompletions) + color.Reset
+
+		builder.WriteString(heading + term.ClearLineAfter + term.NewlineReturn)
+		builder.WriteString(loading + term.ClearLineAfter + term.NewlineReturn)
+	}
+
+	return builder.String()
+}
+
+// renderDetails renders the details of the selected candidate, if any, as a
+// panel whose lines are indented with a border and cropped to the terminal.
+func (e *Engine) renderDetails() string {
+	if e.selected.Details == "" {
+		return ""
+	}
+
+	border := color.Dim + "│ " + color.Reset
+	width := e.out.Width() - len("│ ") - 1
+
+	var details []string
+
+	for _, line := range strings.Split(strings.TrimRight(e.selected.Details, "\n"), "\n") {
+		line = sanitizer.Replace(strutil.FormatTabs(line))
+
+		if strutil.RealLength(line) > width {
+			line = color.Trim(line, width-trailingValueLen) + "..."
+		}
+
+		details = append(details, border+line+color.Reset+term.ClearLineAfter)
+	}
+
+	return strings.Join(details, term.NewlineReturn)
+}
+
+// renderFooter renders the footer line, if any, cropped to the terminal.
+func (e *Engine) renderFooter() string {
+	if e.footer == nil {
+		return ""
+	}
+
+	footer := e.footer()
+	if footer == "" {
+		return ""
+	}
+
+	if width := e.out.Width() - 1; strutil.RealLength(footer) > width {
+		footer = color.Trim(footer, width)
+	}
+
+	return footer + color.Reset + term.ClearLineAfter
+}
+
 func (e *Engine) highlightDisplay(grp *group, val Candidate, pad, col int, selected bool) (candidate string) {
 	// An empty display value means padding.
 	if val.Display == "" {
 		return padSpace(pad)
 	}
 
-	reset := color.Fmt(val.Style)
+	reset := color.Fmt(grp.style(val))
 	candidate, padded := grp.trimDisplay(val, pad, col)
 
-	if e.IsearchRegex != nil && e.isearchBuf.Len() > 0 && !selected {
-		match := e.IsearchRegex.FindString(candidate)
-		match = color.Fmt(color.Bg+"244") + match + color.Reset + reset
-		candidate = e.IsearchRegex.ReplaceAllLiteralString(candidate, match)
+	if e.IsearchMatcher != nil && e.isearchBuf.Len() > 0 && !selected {
+		candidate = e.IsearchMatcher.Highlight(candidate, color.Fmt(color.Bg+"244"), reset)
 	}
 
 	if selected {
@@ -135,6 +258,11 @@ func (e *Engine) highlightDisplay(grp *group, val Candidate, pad, col int, selec
 			}
 		}
 
+		// Candidates marked for multiple selection are underlined.
+		if e.isMarked(val) {
+			reset += color.Underscore
+		}
+
 		candidate = reset + candidate + color.Reset
 	}
 
@@ -151,10 +279,8 @@ func (e *Engine) highlightDesc(grp *group, val Candidate, pad, row, col int, sel
 	// If the next row has the same completions, replace the description with our hint.
 	if len(grp.rows) > row+1 && grp.rows[row+1][0].Description == val.Description {
 		desc = "|"
-	} else if e.IsearchRegex != nil && e.isearchBuf.Len() > 0 && !selected {
-		match := e.IsearchRegex.FindString(desc)
-		match = color.Fmt(color.Bg+"244") + match + color.Reset + color.Dim
-		desc = e.IsearchRegex.ReplaceAllLiteralString(desc, match)
+	} else if e.IsearchMatcher != nil && e.isearchBuf.Len() > 0 && !selected {
+		desc = e.IsearchMatcher.Highlight(desc, color.Fmt(color.Bg+"244"), color.Dim)
 	}
 
 	// If the comp is currently selected, overwrite any highlighting already applied.
@@ -174,91 +300,63 @@ func (e *Engine) highlightDesc(grp *group, val Candidate, pad, row, col int, sel
 // cropCompletions - When the user cycles through a completion list longer
 // than the console MaxTabCompleterRows value, we crop the completions string
 // so that "global" cycling (across all groups) is printed correctly.
+// In incremental search mode, the selected candidate is kept centered.
 func (e *Engine) cropCompletions(comps string, maxRows int) (cropped string, usedY int) {
 	// Get the current absolute candidate position
 	absPos := e.getAbsPos()
+	_, used := e.completionCount()
 
 	// Scan the completions for cutting them at newlines
 	scanner := bufio.NewScanner(strings.NewReader(comps))
 
-	// If absPos < MaxTabCompleterRows, cut below MaxTabCompleterRows and return
-	if absPos < maxRows-1 {
-		return e.cutCompletionsBelow(scanner, maxRows)
-	}
-
-	// If absolute > MaxTabCompleterRows, cut above and below and return
-	//      -> This includes de facto when we tabCompletionReverse
-	if absPos >= maxRows-1 {
-		return e.cutCompletionsAboveBelow(scanner, maxRows, absPos)
-	}
-
-	return
-}
-
-func (e *Engine) cutCompletionsBelow(scanner *bufio.Scanner, maxRows int) (string, int) {
-	var count int
-	var cropped string
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if count < maxRows-1 {
-			cropped += line + term.NewlineReturn
-			count++
-		} else {
-			break
-		}
+	// The first line to display: either the first one, or the one
+	// keeping the selected candidate at the bottom of the list.
+	// When searching, keep it in the middle of the list instead.
+	var start int
+
+	switch {
+	case e.keymap.Local() == keymap.Isearch && len(e.selected.Value) > 0:
+		start = absPos - (maxRows-1)/2
+		start = min(start, used-(maxRows-1))
+		start = max(start, 0)
+	case absPos >= maxRows-1:
+		start = absPos - maxRows + 2
 	}
 
-	cropped = strings.TrimSuffix(cropped, term.NewlineReturn)
-
-	// Add hint for remaining completions, if any.
-	_, used := e.completionCount()
-	remain := used - count
-
-	if remain <= 0 {
-		return cropped, count - 1
-	}
-
-	cropped += fmt.Sprintf(term.NewlineReturn+color.Dim+color.FgYellow+" %d more completion rows... (scroll down to show)"+color.Reset, remain)
-
-	return cropped, count
+	return e.cutCompletions(scanner, start, maxRows, used)
 }
 
-func (e *Engine) cutCompletionsAboveBelow(scanner *bufio.Scanner, maxRows, absPos int) (string, int) {
-	cutAbove := absPos - maxRows + 1
-
+// cutCompletions keeps at most maxRows-1 completion lines, starting at
+// the given line, and adds a hint if some completions are not shown.
+func (e *Engine) cutCompletions(scanner *bufio.Scanner, start, maxRows, used int) (string, int) {
 	var cropped string
-	var count int
+	var line, count int
 
 	for scanner.Scan() {
-		line := scanner.Text()
-
-		if count <= cutAbove {
-			count++
+		if line < start {
+			line++
 
 			continue
 		}
 
-		if count > cutAbove && count <= absPos {
-			cropped += line + term.NewlineReturn
-			count++
-		} else {
+		if count >= maxRows-1 {
 			break
 		}
+
+		cropped += scanner.Text() + term.NewlineReturn
+		count++
 	}
 
 	cropped = strings.TrimSuffix(cropped, term.NewlineReturn)
-	count -= cutAbove + 1
 
 	// Add hint for remaining completions, if any.
-	_, used := e.completionCount()
-	remain := used - (maxRows + cutAbove)
+	remain := used - (start + count)
 
 	if remain <= 0 {
 		return cropped, count - 1
 	}
 
-	cropped += fmt.Sprintf(term.NewlineReturn+color.Dim+color.FgYellow+" %d more completion rows... (scroll down to show)"+color.Reset, remain)
+	cropped += term.NewlineReturn + color.Dim + color.FgYellow + e.locale.Sprintf(locale.MoreCompletionRows, remain) + color.Reset
 
 	return cropped, count
 }
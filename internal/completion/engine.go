@@ -1,21 +1,25 @@
 package completion
 
 import (
-	"regexp"
-
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
+	"github.com/reeflective/readline/internal/term"
 	"github.com/reeflective/readline/internal/ui"
 )
 
 // Engine is responsible for all completion tasks: generating, computing,
 // displaying and updating completion values and inserted candidates.
 type Engine struct {
+	out           *term.Output    // Where completions are displayed.
 	config        *inputrc.Config // The inputrc contains options relative to completion.
 	cached        Completer       // A cached completer function to use when updating.
 	autoCompleter Completer       // Completer used by things like autocomplete
 	hint          *ui.Hint        // The completions can feed hint/usage messages
+	onSelect      func(Candidate) // Called when a candidate is selected (virtually inserted).
+	onAccept      func(Candidate) // Called when a candidate is inserted in the real line.
+	footer        func() string   // Returns the line displayed below the completions, if any.
 
 	// Line parameters
 	keys       *core.Keys      // The input keys reader
@@ -25,11 +29,15 @@ type Engine struct {
 	compLine   *core.Line      // A line that might include a virtually inserted candidate.
 	compCursor *core.Cursor    // The adjusted cursor.
 	keymap     *keymap.Engine  // The main/local keymaps of the shell
+	locale     *locale.Catalog // Translates the messages displayed by the engine.
 
 	// Completion parameters
 	groups      []*group      // All of our suggestions tree is in here
+	loading     []string      // Tags of the groups of completions still being generated.
 	sm          SuffixMatcher // The suffix matcher is kept for removal after actually inserting the candidate.
 	selected    Candidate     // The currently selected item, not yet a real part of the input line.
+	marked      []Candidate   // Candidates marked for multiple selection, in marking order.
+	accepted    []Candidate   // Marked candidates last inserted in the line.
 	prefix      string        // The current tab completion prefix against which to build candidates
 	suffix      string        // The current word suffix
 	inserted    []rune        // The selected candidate (inserted in line) without prefix or suffix.
@@ -37,28 +45,34 @@ type Engine struct {
 	auto        bool          // Is the engine autocompleting ?
 	autoForce   bool          // Special autocompletion mode (isearch-style)
 	skipDisplay bool          // Don't display completions if there are some.
+	chaining    bool          // Completions are being generated for what follows a chained candidate.
 
 	// Incremental search
-	IsearchRegex       *regexp.Regexp // Holds the current search regex match
-	isearchBuf         *core.Line     // The isearch minibuffer
-	isearchCur         *core.Cursor   // Cursor position in the minibuffer.
-	isearchName        string         // What is being incrementally searched for.
-	isearchInsert      bool           // Whether to insert the first match in the line
-	isearchForward     bool           // Match results in forward order, or backward.
-	isearchSubstring   bool           // Match results as a substring (regex), or as a prefix.
-	isearchReplaceLine bool           // Replace the current line with the search result
-	isearchStartBuf    string         // The buffer before starting isearch
-	isearchStartCursor int            // The cursor position before starting isearch
-	isearchLast        string         // The last non-incremental buffer.
-	isearchModeExit    keymap.Mode    // The main keymap to restore after exiting isearch
+	IsearchMatcher     *Matcher     // Holds the current search query matcher
+	isearchBuf         *core.Line   // The isearch minibuffer
+	isearchCur         *core.Cursor // Cursor position in the minibuffer.
+	isearchName        string       // What is being incrementally searched for.
+	isearchInsert      bool         // Whether to insert the first match in the line
+	isearchForward     bool         // Match results in forward order, or backward.
+	isearchSubstring   bool         // Match results as a substring (regex), or as a prefix.
+	isearchFuzzy       bool         // Match results as a fuzzy subsequence, sorted by score.
+	isearchTag         string       // Only match results in the group with this tag, if not empty.
+	isearchReplaceLine bool         // Replace the current line with the search result
+	isearchStartBuf    string       // The buffer before starting isearch
+	isearchStartCursor int          // The cursor position before starting isearch
+	isearchLast        string       // The last non-incremental buffer.
+	isearchModeExit    keymap.Mode  // The main keymap to restore after exiting isearch
+	isearchKept        *Matcher     // The matcher of the last search whose match replaced the line.
 }
 
 // NewEngine initializes a new completion engine with the shell operating parameters.
-func NewEngine(h *ui.Hint, km *keymap.Engine, o *inputrc.Config) *Engine {
+func NewEngine(out *term.Output, h *ui.Hint, km *keymap.Engine, o *inputrc.Config, l *locale.Catalog) *Engine {
 	return &Engine{
+		out:    out,
 		config: o,
 		hint:   h,
 		keymap: km,
+		locale: l,
 	}
 }
 
@@ -79,19 +93,35 @@ func Init(eng *Engine, k *core.Keys, l *core.Line, cur *core.Cursor, s *core.Sel
 func (e *Engine) Generate(completions Values) {
 	e.prepare(completions)
 
-	if e.noCompletions() {
+	if e.noCompletions() && len(e.loading) == 0 {
+		e.notifyNoMatch()
 		e.ClearMenu(true)
 	}
 
 	// Incremental search is a special case, because the user may
 	// want to keep searching for another match, so we don't drop
 	// the completion list and exit the incremental search mode.
-	if e.hasUniqueCandidate() && e.keymap.Local() != keymap.Isearch {
+	if e.hasUniqueCandidate() && e.keymap.Local() != keymap.Isearch && len(e.loading) == 0 {
 		e.acceptCandidate()
+		accepted := e.selected
 		e.ClearMenu(true)
+		e.Chain(accepted)
 	}
 }
 
+// SetHooks sets the functions called with a candidate each time it is selected
+// (virtually inserted in the line) and each time it is inserted in the real line.
+func (e *Engine) SetHooks(selected, accepted func(Candidate)) {
+	e.onSelect, e.onAccept = selected, accepted
+}
+
+// SetFooter sets the function returning a line displayed below the completions
+// (and the details of the selected candidate), like a legend of the menu keys.
+// Nothing is displayed when it returns an empty string.
+func (e *Engine) SetFooter(footer func() string) {
+	e.footer = footer
+}
+
 // GenerateWith generates completions with a completer function, itself cached
 // so that the next time it must update its results, it can reuse this completer.
 func (e *Engine) GenerateWith(completer Completer) {
@@ -105,6 +135,71 @@ func (e *Engine) GenerateWith(completer Completer) {
 	e.Generate(e.cached())
 }
 
+// Update generates the completions again with the cached completer, if any,
+// for instance when some groups of completions were still loading and are now
+// ready. The selected candidate, if any, stays selected if still generated.
+func (e *Engine) Update() {
+	if e.cached == nil {
+		return
+	}
+
+	if e.keymap.Local() == keymap.Isearch {
+		e.updateIncrementalSearch()
+		return
+	}
+
+	// Generate completions for the line without the selected candidate.
+	selected, skip := e.selected, e.skipDisplay
+	e.selected = Candidate{}
+
+	e.prepare(e.cached())
+	e.skipDisplay = skip
+
+	if selected.Value == "" {
+		return
+	}
+
+	for _, grp := range e.groups {
+		grp.isCurrent = false
+
+		if grp.tag != selected.Tag {
+			continue
+		}
+
+		for y, row := range grp.rows {
+			for x, candidate := range row {
+				if candidate.Value == selected.Value {
+					grp.isCurrent = true
+					grp.posX, grp.posY = x, y
+					e.selected = selected
+				}
+			}
+		}
+	}
+}
+
+// Chain starts completing again with the shell completer if the candidate
+// just inserted in the line asks for it, so that what follows it is completed
+// right away (like the value of a --output= flag). Candidates inserted while
+// chaining do not chain again, so that completers cannot loop forever.
+func (e *Engine) Chain(inserted Candidate) {
+	if !inserted.Chain || e.chaining || e.autoCompleter == nil {
+		return
+	}
+
+	e.chaining = true
+	defer func() { e.chaining = false }()
+
+	e.keymap.SetLocal(keymap.MenuSelect)
+	e.GenerateWith(e.autoCompleter)
+}
+
+// Loading returns true if some groups of the completions
+// generated are still loading, and displayed as such.
+func (e *Engine) Loading() bool {
+	return len(e.loading) > 0
+}
+
 // SkipDisplay avoids printing completions below the
 // input line, but still enables cycling through them.
 func (e *Engine) SkipDisplay() {
@@ -122,6 +217,7 @@ func (e *Engine) Select(row, column int) {
 
 	// Ensure the completion keymaps are set.
 	e.adjustSelectKeymap()
+	defer e.updateIsearchHint()
 
 	// Some keys used to move around completions
 	// will influence the coordinates' offsets.
@@ -154,11 +250,30 @@ func (e *Engine) Select(row, column int) {
 	}
 }
 
+// SelectAlias selects the next alias of the selected candidate, in
+// groups of aliases (values sharing the same description), so that
+// users can choose between short and long forms of flags, and such.
+func (e *Engine) SelectAlias() {
+	grp := e.currentGroup()
+
+	if grp == nil || !grp.aliased || len(e.selected.Value) == 0 {
+		return
+	}
+
+	defer e.updateIsearchHint()
+
+	e.cancelCompletedLine()
+	defer e.refreshLine()
+
+	grp.nextAlias()
+}
+
 // SelectTag allows to select the first value of the next tag (next=true),
 // or the last value of the previous tag (next=false).
 func (e *Engine) SelectTag(next bool) {
 	// Ensure the completion keymaps are set.
 	e.adjustSelectKeymap()
+	defer e.updateIsearchHint()
 
 	if len(e.groups) <= 1 {
 		return
@@ -211,6 +326,7 @@ func (e *Engine) Cancel(inserted, cached bool) {
 	} else {
 		e.line.Set(*e.compLine...)
 		e.cursor.Set(e.compCursor.Pos())
+		e.notify(e.onAccept)
 	}
 }
 
@@ -274,6 +390,11 @@ func (e *Engine) IsInserting() bool {
 	return e.selected.Value != ""
 }
 
+// Selected returns the currently selected candidate, if any.
+func (e *Engine) Selected() Candidate {
+	return e.selected
+}
+
 // Matches returns the number of completion candidates
 // matching the current line/settings requirements.
 func (e *Engine) Matches() int {
@@ -281,6 +402,20 @@ func (e *Engine) Matches() int {
 	return comps
 }
 
+// Inserted returns the begin (included) and end (excluded) positions, in the
+// completed line returned by Line(), of the candidate virtually inserted, or
+// -1 if no candidate is selected.
+func (e *Engine) Inserted() (bpos, epos int) {
+	if len(e.selected.Value) == 0 || e.compCursor == nil {
+		return -1, -1
+	}
+
+	epos = e.compCursor.Pos()
+	bpos = max(epos-len(e.inserted), 0)
+
+	return bpos, epos
+}
+
 // Line returns the relevant input line at the time this function is called:
 // if a candidate is currently selected, the line returned is the one containing
 // the candidate. If no candidate is selected, the normal input line is returned.
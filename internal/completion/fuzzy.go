@@ -0,0 +1,162 @@
+package completion
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// Fuzzy matching scores: each matched character is worth a fixed amount,
+// with bonuses when following the previous match or starting a word, and
+// a penalty for each character skipped between the first and last match.
+const (
+	fuzzyMatchScore       = 16
+	fuzzyConsecutiveBonus = 8
+	fuzzyWordStartBonus   = 8
+	fuzzyGapPenalty       = 1
+)
+
+// fuzzyRegexp returns a regular expression matching
+// any string containing the query runes as a subsequence.
+func fuzzyRegexp(query []rune, ignoreCase bool) (*regexp.Regexp, error) {
+	var pattern strings.Builder
+
+	if ignoreCase {
+		pattern.WriteString("(?i)")
+	}
+
+	for i, char := range query {
+		if i > 0 {
+			pattern.WriteString(".*?")
+		}
+
+		pattern.WriteString(regexp.QuoteMeta(string(char)))
+	}
+
+	return regexp.Compile(pattern.String())
+}
+
+// fuzzyScore returns the best score of the query runes matched as a subsequence
+// of the text, or -1 if they are not. Escape sequences in the text are ignored.
+func fuzzyScore(query []rune, text string, ignoreCase bool) int {
+	runes := []rune(color.Strip(text))
+	best := -1
+
+	if len(query) == 0 {
+		return 0
+	}
+
+	// Try all starting positions of the first query rune, and
+	// match greedily from there, keeping the best alignment.
+	for start := range runes {
+		if !fuzzyEqual(runes[start], query[0], ignoreCase) {
+			continue
+		}
+
+		score, last, qpos := 0, -1, 0
+
+		for pos := start; pos < len(runes) && qpos < len(query); pos++ {
+			if !fuzzyEqual(runes[pos], query[qpos], ignoreCase) {
+				continue
+			}
+
+			score += fuzzyMatchScore
+
+			switch {
+			case last >= 0 && pos == last+1:
+				score += fuzzyConsecutiveBonus
+			case last >= 0:
+				score -= (pos - last - 1) * fuzzyGapPenalty
+			}
+
+			if pos == 0 || !isWordRune(runes[pos-1]) {
+				score += fuzzyWordStartBonus
+			}
+
+			last = pos
+			qpos++
+		}
+
+		if qpos == len(query) && score > best {
+			best = score
+		}
+	}
+
+	return best
+}
+
+// fuzzySort sorts the candidates by decreasing score against the matcher,
+// keeping the original order of candidates with the same score.
+func fuzzySort(vals []Candidate, matcher *Matcher) {
+	scores := make(map[int]int, len(vals))
+
+	for i, val := range vals {
+		scores[i] = matcher.Score(val.Value, val.Description)
+	}
+
+	indexes := make([]int, len(vals))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return scores[indexes[i]] > scores[indexes[j]]
+	})
+
+	sorted := make([]Candidate, len(vals))
+	for i, index := range indexes {
+		sorted[i] = vals[index]
+	}
+
+	copy(vals, sorted)
+}
+
+// fuzzyHighlight highlights each rune of the text matched by a rune of the query,
+// leftmost first, and resets the style after it. Escape sequences are kept as is.
+func fuzzyHighlight(query []rune, text string, ignoreCase bool, highlight, reset string) string {
+	var builder strings.Builder
+
+	qpos := 0
+	runes := []rune(text)
+
+	for pos := 0; pos < len(runes); pos++ {
+		// Copy escape sequences untouched.
+		if runes[pos] == '\x1b' {
+			end := pos + 1
+			for end < len(runes) && !unicode.IsLetter(runes[end]) {
+				end++
+			}
+
+			builder.WriteString(string(runes[pos:min(end+1, len(runes))]))
+			pos = end
+
+			continue
+		}
+
+		if qpos < len(query) && fuzzyEqual(runes[pos], query[qpos], ignoreCase) {
+			builder.WriteString(highlight + string(runes[pos]) + color.Reset + reset)
+			qpos++
+
+			continue
+		}
+
+		builder.WriteRune(runes[pos])
+	}
+
+	return builder.String()
+}
+
+func fuzzyEqual(char, query rune, ignoreCase bool) bool {
+	if ignoreCase {
+		return unicode.ToLower(char) == unicode.ToLower(query)
+	}
+
+	return char == query
+}
+
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char)
+}
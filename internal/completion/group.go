@@ -9,13 +9,13 @@ import (
 	"golang.org/x/exp/slices"
 
 	"github.com/reeflective/readline/internal/color"
-	"github.com/reeflective/readline/internal/term"
 )
 
 // group is used to structure different types of completions with different
 // display types, autosuffix removal matchers, under their tag heading.
 type group struct {
 	tag               string        // Printed on top of the group's completions
+	usage             string        // Printed next to the tag, on top of the group's completions
 	rows              [][]Candidate // Values are grouped by aliases/rows, with computed paddings.
 	noSpace           SuffixMatcher // Suffixes to remove if a space or non-nil character is entered after the completion.
 	columnsWidth      []int         // Computed width for each column of completions, when aliases
@@ -24,13 +24,18 @@ type group struct {
 	list              bool          // Force completions to be listed instead of grided
 	noSort            bool          // Don't sort completions
 	aliased           bool          // Are their aliased completions
+	aliasPreference   string        // Alias selected in each row when cycling aliased completions: short, long, or all of them.
 	preserveEscapes   bool          // Preserve escape sequences in the completion inserted values.
+	wrapRows          int           // Maximum number of rows onto which descriptions can be wrapped.
 	isCurrent         bool          // Currently cycling through this group, for highlighting choice
 	longestValue      int           // Used when display is map/list, for determining message width
 	longestDesc       int           // Used to know how much descriptions can use when there are aliases.
 	maxDescAllowed    int           // Maximum ALLOWED description width.
 	termWidth         int           // Term size queried at beginning of computes by the engine.
 
+	// Style of candidates computed when displayed, if any.
+	styleFunc func(Candidate) string
+
 	// Selectors (position/bounds) management
 	posX int
 	posY int
@@ -46,7 +51,7 @@ func (e *Engine) newCompletionGroup(comps Values, tag string, vals RawValues, de
 		posX:         -1,
 		posY:         -1,
 		columnsWidth: []int{0},
-		termWidth:    term.GetWidth(),
+		termWidth:    e.out.Width(),
 		longestDesc:  longest(descriptions, true),
 	}
 
@@ -111,6 +116,21 @@ func (g *group) initOptions(eng *Engine, comps *Values, tag string, vals RawValu
 		g.listSeparator = listSep
 	}
 
+	// Usage displayed next to the group tag
+	g.usage = comps.TagUsage[tag]
+
+	// Styles computed when displaying candidates
+	g.styleFunc = comps.StyleFunc
+
+	// Descriptions wrapped onto following rows
+	g.wrapRows = comps.Wrap[tag]
+	if wrapRows, all := comps.Wrap["*"]; all && g.wrapRows == 0 {
+		g.wrapRows = wrapRows
+	}
+
+	// Aliases to select when cycling through rows of aliases.
+	g.aliasPreference = strings.Trim(eng.config.GetString("completion-alias-preference"), "\"")
+
 	// Override sorting or sort if needed
 	g.noSort = comps.NoSort[tag]
 	if noSort, all := comps.NoSort["*"]; noSort && all && len(comps.NoSort) == 1 {
@@ -347,6 +367,24 @@ func (g *group) longestValueDescribed(vals []Candidate) int {
 	return longestVal + longestDesc + 1
 }
 
+// hasHeader returns true if the group has a tag or a usage
+// to display on the line above its completions.
+func (g *group) hasHeader() bool {
+	return g.tag != "" || g.usage != ""
+}
+
+// style returns the style of a candidate when displayed: the one returned
+// by the group style function, if any and not empty, or its own style.
+func (g *group) style(comp Candidate) string {
+	if g.styleFunc != nil {
+		if style := g.styleFunc(comp); style != "" {
+			return style
+		}
+	}
+
+	return comp.Style
+}
+
 func (g *group) trimDisplay(comp Candidate, pad, col int) (candidate, padded string) {
 	val := comp.Display
 
@@ -391,6 +429,11 @@ func (g *group) trimDesc(val Candidate, pad int) (desc, padded string) {
 
 	desc = sanitizer.Replace(desc)
 
+	// Wrapped descriptions only show their first line on the row.
+	if lines := g.wrapDesc(val); len(lines) > 1 {
+		return g.listSep() + lines[0], ""
+	}
+
 	// Trim the description accounting for escapes.
 	if val.descLen > g.maxDescAllowed && g.maxDescAllowed > 0 {
 		desc = color.Trim(desc, g.maxDescAllowed-trailingDescLen)
@@ -406,6 +449,73 @@ func (g *group) trimDesc(val Candidate, pad int) (desc, padded string) {
 	return g.listSep() + desc, padSpace(pad)
 }
 
+// wrapDesc returns the lines of a description wrapped onto following rows, if the
+// group wraps descriptions and the description does not fit on its row, or nil.
+// The last line is truncated if the description is still too long.
+func (g *group) wrapDesc(val Candidate) (lines []string) {
+	wrappable := len(g.columnsWidth) == 1 || g.aliased
+
+	if g.wrapRows <= 0 || !wrappable || len(g.descriptionsWidth) < len(g.columnsWidth) {
+		return nil
+	}
+
+	width := g.setMaximumSizes(len(g.columnsWidth)-1) - 1
+	if val.descLen <= width || width <= trailingDescLen {
+		return nil
+	}
+
+	text := []rune(strings.Join(strings.Fields(color.Strip(sanitizer.Replace(val.Description))), " "))
+
+	for len(text) > width && len(lines) < g.wrapRows {
+		// Cut at the last space fitting on the line, if any.
+		cut := width
+		for i := width; i > 0; i-- {
+			if text[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+
+		lines = append(lines, string(text[:cut]))
+		text = []rune(strings.TrimLeft(string(text[cut:]), " "))
+	}
+
+	if len(text) > width {
+		text = []rune(strings.TrimRight(string(text[:width-trailingDescLen]), " ") + "...")
+	}
+
+	return append(lines, string(text))
+}
+
+// wrappedLines returns the lines of the description of a row
+// displayed on the following rows, if it is wrapped, or nil.
+func (g *group) wrappedLines(row int) []string {
+	if g.wrapRows <= 0 || len(g.rows[row]) == 0 {
+		return nil
+	}
+
+	// Descriptions shared with the next row are not displayed.
+	if len(g.rows) > row+1 && g.rows[row+1][0].Description == g.rows[row][0].Description {
+		return nil
+	}
+
+	if lines := g.wrapDesc(g.rows[row][0]); len(lines) > 1 {
+		return lines[1:]
+	}
+
+	return nil
+}
+
+// wrappedRows returns the number of rows onto which the descriptions
+// of the rows of the group before the given one are wrapped.
+func (g *group) wrappedRows(before int) (rows int) {
+	for row := 0; row < before && row < len(g.rows); row++ {
+		rows += len(g.wrappedLines(row))
+	}
+
+	return rows
+}
+
 func (g *group) getPad(value Candidate, columnIndex int, desc bool) int {
 	columns := g.columnsWidth
 	valLen := value.displayLen - 1
@@ -444,7 +554,7 @@ func (g *group) listSep() string {
 // we ask each of them to filter its own items and return the results to the shell for aggregating them.
 // The rx parameter is passed, as the shell already checked that the search pattern is valid.
 func (g *group) updateIsearch(eng *Engine) {
-	if eng.IsearchRegex == nil {
+	if eng.IsearchMatcher == nil {
 		return
 	}
 
@@ -454,14 +564,17 @@ func (g *group) updateIsearch(eng *Engine) {
 		row := g.rows[i]
 
 		for _, val := range row {
-			if eng.IsearchRegex.MatchString(val.Value) {
-				suggs = append(suggs, val)
-			} else if val.Description != "" && eng.IsearchRegex.MatchString(val.Description) {
+			if eng.IsearchMatcher.Match(val.Value, val.Description) {
 				suggs = append(suggs, val)
 			}
 		}
 	}
 
+	// Fuzzy matches are ordered from the best to the worst.
+	if eng.isearchFuzzy {
+		fuzzySort(suggs, eng.IsearchMatcher)
+	}
+
 	// Reset the group parameters
 	g.rows = make([][]Candidate, 0)
 	g.posX = -1
@@ -496,6 +609,11 @@ func (g *group) selected() (comp Candidate) {
 }
 
 func (g *group) moveSelector(x, y int) (done, next bool) {
+	// Aliased rows might be cycled one at a time, on their preferred alias.
+	if g.cyclesRows() && x == 0 {
+		return g.moveRow(y)
+	}
+
 	// When the group has not yet been used, adjust
 	if g.posX == -1 && g.posY == -1 {
 		if x != 0 {
@@ -602,16 +720,79 @@ func (g *group) findFirstCandidate(x, y int) (done, next bool) {
 	return
 }
 
+// cyclesRows returns true if the group is aliased, and only
+// the preferred alias of each row is selected when cycling.
+func (g *group) cyclesRows() bool {
+	return g.aliased && (g.aliasPreference == "short" || g.aliasPreference == "long")
+}
+
+// moveRow moves the selector to the preferred alias of the next
+// (or previous) row, or notifies that the group is done with.
+func (g *group) moveRow(y int) (done, next bool) {
+	if g.posX == -1 && g.posY == -1 && y > 0 {
+		y--
+		g.posY = 0
+	}
+
+	g.posY += y
+
+	switch {
+	case g.posY < 0:
+		g.posY = 0
+		return true, false
+	case g.posY > len(g.rows)-1:
+		g.posY = len(g.rows) - 1
+		return true, true
+	}
+
+	g.posX = g.preferredAlias(g.posY)
+
+	return false, false
+}
+
+// preferredAlias returns the column of the shortest or longest
+// alias in a row, depending on the alias preference, or 0.
+func (g *group) preferredAlias(row int) (column int) {
+	for i, alias := range g.rows[row] {
+		current := len(g.rows[row][column].Value)
+
+		switch {
+		case g.aliasPreference == "short" && len(alias.Value) < current:
+			column = i
+		case g.aliasPreference == "long" && len(alias.Value) > current:
+			column = i
+		}
+	}
+
+	return column
+}
+
+// nextAlias selects the next alias in the selected row, if any,
+// or the first one of the row if the last one is selected.
+func (g *group) nextAlias() {
+	if !g.aliased || g.posY < 0 || g.posY > len(g.rows)-1 {
+		return
+	}
+
+	g.posX = (g.posX + 1) % len(g.rows[g.posY])
+}
+
 func (g *group) firstCell() {
 	g.posX = 0
 	g.posY = 0
+
+	if g.cyclesRows() {
+		g.posX = g.preferredAlias(g.posY)
+	}
 }
 
 func (g *group) lastCell() {
 	g.posY = len(g.rows) - 1
 	g.posX = len(g.columnsWidth) - 1
 
-	if g.aliased {
+	if g.cyclesRows() {
+		g.posX = g.preferredAlias(g.posY)
+	} else if g.aliased {
 		g.findFirstCandidate(0, -1)
 	} else {
 		g.posX = len(g.rows[g.posY]) - 1
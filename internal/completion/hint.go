@@ -4,7 +4,9 @@ import (
 	"strings"
 
 	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/term"
+	"github.com/reeflective/readline/internal/ui"
 )
 
 func (e *Engine) hintCompletions(comps Values) {
@@ -42,6 +44,25 @@ func (e *Engine) hintCompletions(comps Values) {
 	e.hint.Set(hint + color.Reset)
 }
 
+// notifyNoMatch notifies the user that no candidate matches, with the no
+// matching completions hint or the bell, depending on the value
+// of the completion-no-match option. The hint is not set if completions
+// already have messages or usage hints, nor when autocompleting.
+func (e *Engine) notifyNoMatch() {
+	if e.auto || e.keymap.Local() == keymap.Isearch {
+		return
+	}
+
+	switch strings.Trim(e.config.GetString("completion-no-match"), "\"") {
+	case "hint":
+		if e.hint.Len() == 0 {
+			e.hint.Set(e.hintNoMatches() + color.Reset)
+		}
+	case "bell":
+		ui.Bell(e.out, e.config)
+	}
+}
+
 func (e *Engine) hintNoMatches() string {
 	noMatches := color.Dim + "no matching"
 
@@ -24,6 +24,12 @@ func UpdateInserted(eng *Engine) {
 		defer eng.ClearMenu(choices)
 	}
 
+	// Marked candidates are kept in the line in place of the selected one,
+	// unless the isearch buffer is updated, in which case they stay marked.
+	if !eng.mustRemoveInserted() {
+		eng.AcceptMarked()
+	}
+
 	// If autocomplete is on, we also drop the list of generated
 	// completions, because it will be recomputed shortly after.
 	// Do the same when using incremental search, except if the
@@ -95,7 +101,9 @@ func (e *Engine) refreshLine() {
 	// the completion list and exit the incremental search mode.
 	if e.hasUniqueCandidate() && e.keymap.Local() != keymap.Isearch {
 		e.acceptCandidate()
+		accepted := e.selected
 		e.ResetForce()
+		e.Chain(accepted)
 	} else {
 		e.insertCandidate()
 	}
@@ -119,11 +127,14 @@ func (e *Engine) acceptCandidate() {
 	e.cursor.Move(-1 * len(e.prefix))
 	e.line.Cut(e.cursor.Pos(), e.cursor.Pos()+len(e.prefix))
 	e.cursor.InsertAt(e.inserted...)
+	e.insertUniqueSpace()
 
 	// And forget about this inserted completion.
 	e.inserted = make([]rune, 0)
 	e.prefix = ""
 	e.suffix = ""
+
+	e.notify(e.onAccept)
 }
 
 // insertCandidate inserts a completion candidate into the virtual (completed) line.
@@ -155,6 +166,15 @@ func (e *Engine) insertCandidate() {
 	e.compCursor.Move(-1 * len(e.prefix))
 	e.compLine.Cut(e.compCursor.Pos(), e.compCursor.Pos()+len(e.prefix))
 	e.compCursor.InsertAt(e.inserted...)
+
+	e.notify(e.onSelect)
+}
+
+// notify calls a candidate hook, if any, with the selected candidate.
+func (e *Engine) notify(hook func(Candidate)) {
+	if hook != nil && e.selected.Value != "" {
+		hook(e.selected)
+	}
 }
 
 // prepareSuffix caches any suffix matcher associated with the completion candidate
@@ -183,6 +203,28 @@ func (e *Engine) prepareSuffix() (comp string) {
 	return comp
 }
 
+// insertUniqueSpace inserts a space after the unique candidate just inserted,
+// if the completion-unique-space option is enabled, and unless the candidate
+// already ends with a space, asks for completions to be chained, or ends with
+// one of the suffixes of its no-space suffix matcher (like a path slash).
+func (e *Engine) insertUniqueSpace() {
+	if !e.config.GetBool("completion-unique-space") || len(e.inserted) == 0 || e.selected.Chain {
+		return
+	}
+
+	last := e.inserted[len(e.inserted)-1]
+	if unicode.IsSpace(last) || e.currentGroup().noSpace.Matches(string(last)) {
+		return
+	}
+
+	if e.cursor.Pos() < e.line.Len() && unicode.IsSpace((*e.line)[e.cursor.Pos()]) {
+		e.cursor.Inc()
+		return
+	}
+
+	e.cursor.InsertAt(' ')
+}
+
 func (e *Engine) cancelCompletedLine() {
 	// The completed line includes any currently selected
 	// candidate, just overwrite it with the normal line.
@@ -1,11 +1,16 @@
 package completion
 
 import (
-	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
+	"github.com/reeflective/readline/internal/ui"
 )
 
 // IsearchStart starts incremental search (fuzzy-finding)
@@ -20,6 +25,7 @@ func (e *Engine) IsearchStart(name string, autoinsert, replaceLine bool) {
 
 	e.isearchBuf = new(core.Line)
 	e.isearchCur = core.NewCursor(e.isearchBuf)
+	e.isearchTag = ""
 
 	// Prepare all keymaps and modes.
 	e.auto = true
@@ -28,16 +34,21 @@ func (e *Engine) IsearchStart(name string, autoinsert, replaceLine bool) {
 
 	// Hints
 	e.isearchName = name
-	e.hint.Set(color.Bold + color.FgCyan + e.isearchName + " (isearch): " + color.Reset + string(*e.isearchBuf))
+	e.hint.Set(color.Bold + color.FgCyan + e.locale.Sprintf(locale.Isearch, e.isearchName) + color.Reset + string(*e.isearchBuf))
 }
 
 // IsearchStop exists the incremental search mode,
 // and drops the currently used regexp matcher.
 // If revertLine is true, the original line is restored.
 func (e *Engine) IsearchStop(revertLine bool) {
+	// Keep the matcher of searches replacing the line with a match.
+	if e.isearchReplaceLine && !revertLine && e.IsearchMatcher != nil && e.isearchBuf != nil && e.isearchBuf.Len() > 0 {
+		e.isearchKept = e.IsearchMatcher
+	}
+
 	// Reset all buffers and cursors.
 	e.isearchBuf = nil
-	e.IsearchRegex = nil
+	e.IsearchMatcher = nil
 	e.isearchCur = nil
 
 	// Reset the original line when needed.
@@ -49,6 +60,7 @@ func (e *Engine) IsearchStop(revertLine bool) {
 	e.isearchStartBuf = ""
 	e.isearchStartCursor = 0
 	e.isearchReplaceLine = false
+	e.isearchTag = ""
 
 	// And clear all related completion keymaps/modes.
 	e.auto = false
@@ -57,6 +69,16 @@ func (e *Engine) IsearchStop(revertLine bool) {
 	e.resetIsearchInsertMode()
 }
 
+// KeptMatcher returns the matcher of the last incremental search which
+// replaced the input line with one of its matches (like history searches),
+// if any, and forgets it, so that it is only returned once.
+func (e *Engine) KeptMatcher() *Matcher {
+	matcher := e.isearchKept
+	e.isearchKept = nil
+
+	return matcher
+}
+
 // GetBuffer returns the correct input line buffer (and its cursor/
 // selection) depending on the context and active components:
 // - If in non/incremental-search mode, the minibuffer.
@@ -131,7 +153,7 @@ func (e *Engine) NonIsearchStart(name string, repeat, forward, substring bool) {
 func (e *Engine) NonIsearchStop() {
 	e.isearchLast = string(*e.isearchBuf)
 	e.isearchBuf = nil
-	e.IsearchRegex = nil
+	e.IsearchMatcher = nil
 	e.isearchCur = nil
 	e.isearchForward = false
 	e.isearchSubstring = false
@@ -152,39 +174,160 @@ func (e *Engine) NonIncrementallySearching() (searching, forward, substring bool
 	return
 }
 
-func (e *Engine) updateIncrementalSearch() {
-	var regexStr string
-	if hasUpper(*e.isearchBuf) {
-		regexStr = string(*e.isearchBuf)
-	} else {
-		regexStr = "(?i)" + string(*e.isearchBuf)
+// IsearchToggleFuzzy switches the incremental search between regexp and fuzzy
+// matching modes, and immediately updates the matches. The mode is kept for all
+// subsequent searches until toggled again.
+func (e *Engine) IsearchToggleFuzzy() {
+	e.isearchFuzzy = !e.isearchFuzzy
+
+	if e.keymap.Local() != keymap.Isearch {
+		return
+	}
+
+	e.updateIncrementalSearch()
+}
+
+// IsearchFuzzy returns true if incremental searches match
+// candidates as fuzzy subsequences, or false as regexps.
+func (e *Engine) IsearchFuzzy() bool {
+	return e.isearchFuzzy
+}
+
+// IsearchToggleScope restricts the incremental search to the candidates of the
+// currently selected group, or to the first one with matches if none is selected,
+// or searches all groups again if the search was already restricted.
+func (e *Engine) IsearchToggleScope() {
+	if e.keymap.Local() != keymap.Isearch {
+		return
+	}
+
+	if e.isearchTag != "" {
+		e.isearchTag = ""
+	} else if grp := e.currentGroup(); grp != nil {
+		e.isearchTag = grp.tag
+	}
+
+	e.updateIncrementalSearch()
+}
+
+// IsearchCompleteQuery extends the last term of the incremental search query with
+// the longest continuation shared by all words of the matching candidates which
+// contain this term. If there is no such continuation, these words are listed
+// in the search hint instead, so that the user can type the one they want.
+func (e *Engine) IsearchCompleteQuery() {
+	if e.keymap.Local() != keymap.Isearch || e.isearchBuf == nil {
+		return
+	}
+
+	query := string(*e.isearchBuf)
+	term := strings.TrimPrefix(query[strings.LastIndex(query, " ")+1:], "^")
+
+	if term == "" || strings.HasPrefix(term, "!") {
+		return
+	}
+
+	words := e.queryWords(term)
+	if len(words) == 0 {
+		return
+	}
+
+	continuation := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, continuation) {
+			continuation = continuation[:len(continuation)-1]
+		}
+	}
+
+	for !utf8.ValidString(continuation) {
+		continuation = continuation[:len(continuation)-1]
+	}
+
+	continuation = strings.TrimPrefix(continuation, term)
+
+	if continuation == "" {
+		e.updateIsearchHint()
+
+		if len(words) > 1 {
+			words = words[:min(len(words), maxQueryWords)]
+			e.hint.Set(e.hint.Text() + color.Dim + e.locale.Sprintf(locale.QueryWords, strings.Join(words, " ")) + color.Reset)
+		}
+
+		return
 	}
 
+	// Drop any match inserted, as when typing in the minibuffer.
+	e.Cancel(true, false)
+
+	e.isearchBuf.Set([]rune(query + continuation)...)
+	e.isearchCur.Set(e.isearchBuf.Len())
+
+	e.updateIncrementalSearch()
+}
+
+// maxQueryWords is the maximum number of words listed
+// when the search query cannot be extended with them.
+const maxQueryWords = 10
+
+// queryWords returns the distinct words of the values of matching candidates
+// which contain the term, from its start on, sorted. Like terms matching
+// candidates, the term is case-insensitive if it does not have uppercase
+// letters: words are then returned in lowercase.
+func (e *Engine) queryWords(term string) (words []string) {
+	insensitive := !strings.ContainsFunc(term, unicode.IsUpper)
+
+	for _, grp := range e.groups {
+		for _, row := range grp.rows {
+			for _, candidate := range row {
+				for _, word := range strings.Fields(color.Strip(candidate.Value)) {
+					if insensitive {
+						word = strings.ToLower(word)
+					}
+
+					if start := strings.Index(word, term); start != -1 {
+						words = append(words, word[start:])
+					}
+				}
+			}
+		}
+	}
+
+	slices.Sort(words)
+
+	return slices.Compact(words)
+}
+
+func (e *Engine) updateIncrementalSearch() {
 	var err error
-	e.IsearchRegex, err = regexp.Compile(regexStr)
+	e.IsearchMatcher, err = NewMatcher(*e.isearchBuf, e.isearchFuzzy)
 
 	if err != nil {
-		e.hint.Set(color.FgRed + "Failed to compile i-search regexp")
+		e.hint.Set(color.FgRed + e.locale.Get(locale.IsearchRegexpError))
 	}
 
 	// Refresh completions with the current minibuffer as a filter.
 	e.GenerateWith(e.cached)
 
+	// Drop the groups out of the search scope, if restricted.
+	if e.isearchTag != "" {
+		e.groups = slices.DeleteFunc(e.groups, func(g *group) bool {
+			return g.tag != e.isearchTag
+		})
+	}
+
 	// And filter out the completions.
 	for _, g := range e.groups {
 		g.updateIsearch(e)
 	}
 
-	// Update the hint section.
-	isearchHint := color.Bold + color.FgCyan + e.isearchName + " (inc-search)"
+	e.alignGroups()
 
-	if e.Matches() == 0 {
-		isearchHint += color.Reset + color.Bold + color.FgRed + " (no matches)"
+	// Ring the bell when the search fails.
+	if e.isearchBuf.Len() > 0 && e.Matches() == 0 && len(e.loading) == 0 {
+		ui.Bell(e.out, e.config)
 	}
 
-	isearchHint += ": " + color.Reset + color.Bold + string(*e.isearchBuf) + color.Reset + "_"
-
-	e.hint.Set(isearchHint)
+	// Update the hint section.
+	e.updateIsearchHint()
 
 	// And update the inserted candidate if autoinsert is enabled.
 	if e.isearchInsert && e.Matches() > 0 && e.isearchBuf.Len() > 0 {
@@ -203,9 +346,41 @@ func (e *Engine) updateIncrementalSearch() {
 	}
 }
 
+// updateIsearchHint displays the search mode, the number of matches and
+// the position of the selected one, if any, and the minibuffer.
+func (e *Engine) updateIsearchHint() {
+	if e.keymap.Local() != keymap.Isearch || e.isearchBuf == nil {
+		return
+	}
+
+	mode := locale.IncSearch
+	if e.isearchFuzzy {
+		mode = locale.FuzzySearch
+	}
+
+	isearchHint := color.Bold + color.FgCyan + e.locale.Sprintf(mode, e.isearchName)
+
+	if e.isearchTag != "" {
+		isearchHint += e.locale.Sprintf(locale.SearchScope, e.isearchTag)
+	}
+
+	switch matches := e.Matches(); {
+	case matches == 0:
+		isearchHint += color.Reset + color.Bold + color.FgRed + e.locale.Get(locale.NoMatches)
+	case len(e.selected.Value) > 0:
+		isearchHint += color.Reset + color.Dim + e.locale.Sprintf(locale.MatchPosition, e.selectedIndex(), matches)
+	case e.isearchBuf.Len() > 0:
+		isearchHint += color.Reset + color.Dim + e.locale.Sprintf(locale.MatchCount, matches)
+	}
+
+	isearchHint += color.Reset + color.Bold + color.FgCyan + ": " + color.Reset + color.Bold + string(*e.isearchBuf) + color.Reset + "_"
+
+	e.hint.Set(isearchHint)
+}
+
 func (e *Engine) updateNonIncrementalSearch() {
-	isearchHint := color.Bold + color.FgCyan + e.isearchName +
-		" (non-inc-search): " + color.Reset + color.Bold + string(*e.isearchBuf) + color.Reset + "_"
+	isearchHint := color.Bold + color.FgCyan + e.locale.Sprintf(locale.NonIncSearch, e.isearchName) +
+		color.Reset + color.Bold + string(*e.isearchBuf) + color.Reset + "_"
 	e.hint.Set(isearchHint)
 }
 
@@ -0,0 +1,80 @@
+package completion
+
+import (
+	"slices"
+	"strings"
+)
+
+// ToggleMark marks the selected candidate for multiple selection, or unmarks it if
+// already marked. Marked candidates are inserted together when exiting the menu.
+func (e *Engine) ToggleMark() {
+	if len(e.selected.Value) == 0 {
+		return
+	}
+
+	if index := e.markIndex(e.selected); index >= 0 {
+		e.marked = slices.Delete(e.marked, index, index+1)
+	} else {
+		e.marked = append(e.marked, e.selected)
+	}
+}
+
+// Marked returns the candidates currently marked, in the order they were marked.
+func (e *Engine) Marked() []Candidate {
+	return slices.Clone(e.marked)
+}
+
+// AcceptMarked inserts all marked candidates in the real input line, in place
+// of the word being completed, in the order they were marked and separated with
+// spaces. Any virtually inserted candidate is dropped beforehand. The candidates
+// are kept until retrieved with TakeAccepted. Returns false if none is marked.
+func (e *Engine) AcceptMarked() bool {
+	if len(e.marked) == 0 {
+		return false
+	}
+
+	if len(e.selected.Value) > 0 {
+		e.cancelCompletedLine()
+	}
+
+	values := make([]string, 0, len(e.marked))
+	for _, candidate := range e.marked {
+		values = append(values, candidate.Value)
+	}
+
+	e.cursor.Move(-1 * len(e.prefix))
+	e.line.Cut(e.cursor.Pos(), e.cursor.Pos()+len(e.prefix))
+	e.cursor.InsertAt([]rune(strings.Join(values, " "))...)
+	e.prefix = ""
+
+	for _, candidate := range e.marked {
+		e.selected = candidate
+		e.notify(e.onAccept)
+	}
+
+	e.selected = Candidate{}
+	e.accepted = e.marked
+	e.marked = nil
+
+	return true
+}
+
+// TakeAccepted returns the candidates last inserted with AcceptMarked, if
+// any, and forgets them, so that they are only returned once.
+func (e *Engine) TakeAccepted() []Candidate {
+	accepted := e.accepted
+	e.accepted = nil
+
+	return accepted
+}
+
+// isMarked returns true if the candidate is marked for multiple selection.
+func (e *Engine) isMarked(candidate Candidate) bool {
+	return len(e.marked) > 0 && e.markIndex(candidate) >= 0
+}
+
+func (e *Engine) markIndex(candidate Candidate) int {
+	return slices.IndexFunc(e.marked, func(marked Candidate) bool {
+		return marked.Display == candidate.Display && marked.Tag == candidate.Tag
+	})
+}
@@ -0,0 +1,181 @@
+package completion
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// Matcher matches candidates against an incremental search query. Queries are made
+// of space-separated terms which must all match, except those prefixed with an `!`,
+// which must not match. Terms are either regular expressions or fuzzy subsequences,
+// depending on the search mode.
+type Matcher struct {
+	include    []*regexp.Regexp // Terms that must match.
+	exclude    []*regexp.Regexp // Terms that must not match.
+	terms      [][]rune         // Terms that must match, as typed.
+	fuzzy      bool             // Terms are fuzzy subsequences.
+	ignoreCase bool             // No term has uppercase letters.
+}
+
+// NewMatcher parses and compiles an incremental search query. Unless the query has
+// uppercase letters, terms are case-insensitive. An error is returned if any of the
+// terms is not a valid regular expression.
+func NewMatcher(query []rune, fuzzy bool) (*Matcher, error) {
+	matcher := &Matcher{
+		fuzzy:      fuzzy,
+		ignoreCase: !hasUpper(query),
+	}
+
+	for _, term := range strings.Fields(string(query)) {
+		negated := strings.HasPrefix(term, "!")
+		if negated {
+			term = strings.TrimPrefix(term, "!")
+		}
+
+		if term == "" {
+			continue
+		}
+
+		regex, err := matcher.compile([]rune(term))
+		if err != nil {
+			return nil, err
+		}
+
+		if negated {
+			matcher.exclude = append(matcher.exclude, regex)
+		} else {
+			matcher.include = append(matcher.include, regex)
+			matcher.terms = append(matcher.terms, []rune(term))
+		}
+	}
+
+	return matcher, nil
+}
+
+// MatchString returns true if the text matches the query.
+func (m *Matcher) MatchString(text string) bool {
+	return m.Match(text)
+}
+
+// Match returns true if each term that must match does so in at least
+// one of the texts, and if no term that must not match does in any of them.
+func (m *Matcher) Match(texts ...string) bool {
+	for _, regex := range m.exclude {
+		for _, text := range texts {
+			if text != "" && regex.MatchString(text) {
+				return false
+			}
+		}
+	}
+
+	for _, regex := range m.include {
+		matched := false
+
+		for _, text := range texts {
+			if text != "" && regex.MatchString(text) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Highlight highlights the parts of the text matched by the terms that must match:
+// the first match of each regular expression, or each rune of fuzzy subsequences.
+func (m *Matcher) Highlight(text, highlight, reset string) string {
+	for i, regex := range m.include {
+		if m.fuzzy {
+			text = fuzzyHighlight(m.terms[i], text, m.ignoreCase, highlight, reset)
+			continue
+		}
+
+		match := regex.FindString(text)
+		if match == "" {
+			continue
+		}
+
+		text = regex.ReplaceAllLiteralString(text, highlight+match+color.Reset+reset)
+	}
+
+	return text
+}
+
+// FindAll returns the rune positions (begin included, end excluded) of all
+// the matches of the terms that must match in the text, sorted and without
+// overlaps: matches of fuzzy terms span from their first to their last rune.
+func (m *Matcher) FindAll(text string) [][]int {
+	var matches [][]int
+
+	for _, regex := range m.include {
+		for _, match := range regex.FindAllStringIndex(text, -1) {
+			if match[0] == match[1] {
+				continue
+			}
+
+			bpos := utf8.RuneCountInString(text[:match[0]])
+			epos := bpos + utf8.RuneCountInString(text[match[0]:match[1]])
+			matches = append(matches, []int{bpos, epos})
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i][0] < matches[j][0]
+	})
+
+	// Drop matches overlapping a previous one.
+	kept := matches[:0]
+
+	for _, match := range matches {
+		if len(kept) > 0 && match[0] < kept[len(kept)-1][1] {
+			continue
+		}
+
+		kept = append(kept, match)
+	}
+
+	return kept
+}
+
+// Score returns how well a value (or its description) matches the fuzzy
+// terms of the query: the higher, the better. Without fuzzy terms, all
+// values have the same score.
+func (m *Matcher) Score(value, description string) int {
+	if !m.fuzzy {
+		return 0
+	}
+
+	var score int
+
+	for _, term := range m.terms {
+		termScore := fuzzyScore(term, value, m.ignoreCase)
+		if termScore < 0 {
+			termScore = fuzzyScore(term, description, m.ignoreCase) / 2
+		}
+
+		score += termScore
+	}
+
+	return score
+}
+
+func (m *Matcher) compile(term []rune) (*regexp.Regexp, error) {
+	if m.fuzzy {
+		return fuzzyRegexp(term, m.ignoreCase)
+	}
+
+	if m.ignoreCase {
+		return regexp.Compile("(?i)" + string(term))
+	}
+
+	return regexp.Compile(string(term))
+}
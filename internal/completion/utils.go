@@ -26,6 +26,7 @@ var sanitizer = strings.NewReplacer(
 func (e *Engine) prepare(completions Values) {
 	e.prefix = ""
 	e.groups = make([]*group, 0)
+	e.loading = completions.Loading
 
 	e.setPrefix(completions)
 	e.setSuffix(completions)
@@ -46,12 +47,15 @@ func (e *Engine) generate(completions Values) {
 
 	// Apply the prefix to the completions, and filter out any
 	// completions that don't match, optionally ignoring case.
-	matchCase := e.config.GetBool("completion-ignore-case")
-	completions.values = completions.values.FilterPrefix(e.prefix, !matchCase)
+	if !completions.NoFilter {
+		matchCase := e.config.GetBool("completion-ignore-case")
+		completions.values = completions.values.FilterPrefix(e.prefix, !matchCase)
+	}
 
 	// Classify, group together and initialize completions.
 	completions.values.EachTag(e.generateGroup(completions))
 	e.justifyGroups(completions)
+	e.alignGroups()
 }
 
 func (e *Engine) setPrefix(completions Values) {
@@ -63,7 +67,7 @@ func (e *Engine) setPrefix(completions Values) {
 			cpos = 0
 		}
 
-		bpos, _ := e.line.SelectBlankWord(cpos)
+		bpos, _ := e.selectWord(cpos)
 
 		// Safety checks and adjustments.
 		if bpos > cpos {
@@ -88,7 +92,7 @@ func (e *Engine) setSuffix(completions Values) {
 	switch completions.SUFFIX {
 	case "":
 		cpos := e.cursor.Pos()
-		_, epos := e.line.SelectBlankWord(cpos)
+		_, epos := e.selectWord(cpos)
 
 		// Safety checks and adjustments.
 		if epos < e.line.Len() {
@@ -113,6 +117,18 @@ func (e *Engine) setSuffix(completions Values) {
 	}
 }
 
+// selectWord returns the begin and end positions of the word completed around
+// a position: the token of the host tokenizer if any, or the blank word.
+func (e *Engine) selectWord(pos int) (bpos, epos int) {
+	if e.selection.Tokens != nil {
+		if spans := e.selection.Tokens(*e.line); spans != nil {
+			return e.line.SelectSpan(pos, spans)
+		}
+	}
+
+	return e.line.SelectBlankWord(pos)
+}
+
 // Returns a function to run on each completio group tag.
 func (e *Engine) generateGroup(comps Values) func(tag string, values RawValues) {
 	return func(tag string, values RawValues) {
@@ -282,6 +298,49 @@ func (e *Engine) justifyGroups(values Values) {
 	}
 }
 
+// alignGroups widens the columns of candidates and descriptions of all groups
+// to the widest ones at the same position in any group, if the completion-align-groups
+// option is enabled, so that all groups are displayed like a single table.
+// Groups which would not fit in the terminal once aligned are left as is.
+func (e *Engine) alignGroups() {
+	if !e.config.GetBool("completion-align-groups") || len(e.groups) < 2 {
+		return
+	}
+
+	var values, descriptions []int
+
+	for _, grp := range e.groups {
+		for len(values) < len(grp.columnsWidth) {
+			values = append(values, 0)
+		}
+
+		for len(descriptions) < len(grp.descriptionsWidth) {
+			descriptions = append(descriptions, 0)
+		}
+
+		for i, width := range grp.columnsWidth {
+			values[i] = max(values[i], width)
+		}
+
+		for i, width := range grp.descriptionsWidth {
+			descriptions[i] = max(descriptions[i], width)
+		}
+	}
+
+	for _, grp := range e.groups {
+		columns := len(grp.columnsWidth)
+		aligned := sum(values[:columns]) + sum(descriptions[:len(grp.descriptionsWidth)]) + columns*2
+
+		if len(grp.rows) == 0 || aligned > grp.termWidth {
+			continue
+		}
+
+		copy(grp.columnsWidth, values)
+		copy(grp.descriptionsWidth, descriptions)
+		grp.longestValue = max(grp.longestValue, values[0])
+	}
+}
+
 func (e *Engine) adjustCycleKeys(row, column int) (int, int) {
 	cur := e.currentGroup()
 
@@ -323,7 +382,7 @@ func (e *Engine) completionCount() (comps int, used int) {
 		}
 
 		// One line for the group name
-		if group.tag != "" {
+		if group.hasHeader() {
 			used++
 		}
 
@@ -332,11 +391,37 @@ func (e *Engine) completionCount() (comps int, used int) {
 		} else {
 			used += len(group.rows)
 		}
+
+		// And the rows onto which descriptions are wrapped.
+		used += group.wrappedRows(len(group.rows))
 	}
 
+	// Groups still loading have a heading and a loading row.
+	used += len(e.loading) * 2
+
 	return comps, used
 }
 
+// selectedIndex returns the position (starting at 1) of the
+// selected candidate among all candidates, in display order.
+func (e *Engine) selectedIndex() (index int) {
+	for _, grp := range e.groups {
+		if grp.isCurrent && grp.posY >= 0 && grp.posX >= 0 {
+			for _, row := range grp.rows[:grp.posY] {
+				index += len(row)
+			}
+
+			return index + grp.posX + 1
+		}
+
+		for _, row := range grp.rows {
+			index += len(row)
+		}
+	}
+
+	return index
+}
+
 func (e *Engine) hasUniqueCandidate() bool {
 	switch len(e.groups) {
 	case 0:
@@ -391,6 +476,8 @@ func (e *Engine) resetValues(comps, cached bool) {
 	if comps {
 		e.usedY = 0
 		e.groups = make([]*group, 0)
+		e.loading = nil
+		e.marked = nil
 	}
 
 	// Drop the completion generation function.
@@ -446,18 +533,18 @@ func (e *Engine) getAbsPos() int {
 			continue
 		}
 
-		if grp.tag != "" {
+		if grp.hasHeader() {
 			prev++
 		}
 
 		if grp.isCurrent {
-			prev += grp.posY
+			prev += grp.posY + grp.wrappedRows(grp.posY)
 			foundCurrent = true
 
 			break
 		}
 
-		prev += grp.maxY
+		prev += grp.maxY + grp.wrappedRows(len(grp.rows))
 	}
 
 	// If there was no current group, it means
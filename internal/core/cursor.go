@@ -358,7 +358,8 @@ func (c *Cursor) CheckCommand() {
 // CoordinatesCursor returns the number of real terminal lines above the cursor position
 // (y value), and the number of columns since the beginning of the current line (x value).
 // @indent -    Used to align all lines (except the first) together on a single column.
-func CoordinatesCursor(cur *Cursor, indent int) (x, y int) {
+// @width -     The width of the terminal on which the line is displayed.
+func CoordinatesCursor(cur *Cursor, indent, width int) (x, y int) {
 	cur.CheckAppend()
 
 	newlines := cur.line.newlines()
@@ -372,13 +373,13 @@ func CoordinatesCursor(cur *Cursor, indent int) (x, y int) {
 			// simply care about the line count.
 			line := (*cur.line)[bpos:newline[0]]
 			bpos = newline[0] + 1
-			_, y := strutil.LineSpan(line, pos, indent)
+			_, y := strutil.LineSpan(line, pos, indent, width)
 			usedY += y
 
 		default:
 			// On the cursor line, use both line and column count.
 			line := (*cur.line)[bpos:cur.pos]
-			usedX, y := strutil.LineSpan(line, pos, indent)
+			usedX, y := strutil.LineSpan(line, pos, indent, width)
 			usedY += y
 
 			return usedX, usedY
@@ -1005,7 +1005,7 @@ func TestCursor_Coordinates(t *testing.T) {
 				mark: test.fields.mark,
 				line: test.fields.line,
 			}
-			gotX, gotY := CoordinatesCursor(c, indent)
+			gotX, gotY := CoordinatesCursor(c, indent, getTermWidth())
 			if gotX != test.wantX {
 				t.Errorf("Cursor.Coordinates() gotX = %v, want %v", gotX, test.wantX)
 			}
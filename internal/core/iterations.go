@@ -66,6 +66,15 @@ func (i *Iterations) Get() int {
 	return times
 }
 
+// Typed returns the iterations typed so far, if they are being set.
+func (i *Iterations) Typed() string {
+	if !i.active {
+		return ""
+	}
+
+	return i.times
+}
+
 // IsSet returns true if an iteration/numeric argument is active.
 func (i *Iterations) IsSet() bool {
 	return i.active
@@ -1,11 +1,14 @@
 package core
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"io"
-	"os"
 	"regexp"
 	"sync"
+	"time"
+	"unicode/utf8"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/strutil"
@@ -13,48 +16,87 @@ import (
 
 const (
 	keyScanBufSize = 1024
+	keyPollDelay   = 50 * time.Millisecond
+
+	// cursorPosTimeout is the maximum time to wait for the terminal
+	// to answer a cursor position query, before considering that it
+	// does not support them.
+	cursorPosTimeout = time.Second
 )
 
-// Stdin is used by the Keys struct to read and write keys.
-// It can be overwritten to use other file descriptors or
-// custom io.Readers, such as the one used on Windows.
-var Stdin io.ReadCloser = os.Stdin
+// ErrIdle is returned when no input keys have been
+// read before the configured idle timeout has expired.
+var ErrIdle = errors.New("no input keys read before idle timeout")
 
 var rxRcvCursorPos = regexp.MustCompile(`\x1b\[([0-9]+);([0-9]+)R`)
 
+// pasteEnd is the sequence ending a bracketed paste.
+var pasteEnd = []byte("\x1b[201~")
+
 // Keys is used read, manage and use keys input by the shell user.
 type Keys struct {
-	buf       []byte      // Keys read and waiting to be used.
-	matched   []rune      // Keys that have been successfully matched against a bind.
-	macroKeys []rune      // Keys that have been fed by a macro.
-	mustWait  bool        // Keys are in the stack, but we must still read stdin.
-	waiting   bool        // Currently waiting for keys on stdin.
-	reading   bool        // Currently reading keys out of the main loop.
-	keysOnce  chan []byte // Passing keys from the main routine.
-	cursor    chan []byte // Cursor coordinates has been read on stdin.
-	resize    chan bool   // Resize events on Windows are sent on stdin.
+	buf       []byte        // Keys read and waiting to be used.
+	matched   []rune        // Keys that have been successfully matched against a bind.
+	macroKeys []rune        // Keys that have been fed by a macro.
+	paste     []byte        // Keys read at once and detected as a paste.
+	mustWait  bool          // Keys are in the stack, but we must still read stdin.
+	waiting   bool          // Currently waiting for keys on stdin.
+	reading   bool          // Currently reading keys out of the main loop.
+	keysOnce  chan []byte   // Passing keys from the main routine.
+	cursor    chan []byte   // Cursor coordinates has been read on stdin.
+	resize    chan bool     // Resize events on Windows are sent on stdin.
+	idle      time.Duration // Maximum time to wait for keys before notifying idleness.
+	inflight  chan keysRead // Keys being read in the background, if any.
+	injected  chan []byte   // Keys injected as if they were typed on stdin.
+	injecting int           // Number of injections waiting for their keys to be read.
+	record    func([]byte)  // Called with all keys read, if recording.
+	typed     int           // Number of times keys have been read on stdin.
+	hosted    bool          // Keys are read from a reader provided by the host.
+
+	noCursorPos bool // The terminal does not answer cursor position queries.
+
+	stdin  io.Reader // Where keys are read from.
+	stdout io.Writer // Where terminal queries are written.
 
 	cfg   *inputrc.Config // Configuration file used for meta key settings
 	mutex sync.RWMutex    // Concurrency safety
 }
 
+// NewKeys returns a key stack reading its input from stdin, and writing its
+// terminal queries (like cursor position requests) to stdout. If stdin is nil,
+// the process standard input is used, with platform-specific key translation.
+func NewKeys(stdin io.Reader, stdout io.Writer) *Keys {
+	hosted := stdin != nil
+	if !hosted {
+		stdin = defaultStdin()
+	}
+
+	return &Keys{stdin: stdin, stdout: stdout, hosted: hosted, injected: make(chan []byte)}
+}
+
 // WaitAvailableKeys waits until an input key is either read from standard input,
 // or directly returns if the key stack still/already has available keys.
-func WaitAvailableKeys(keys *Keys, cfg *inputrc.Config) {
+// If an idle timeout is set and no key has been read before it expires, the
+// function returns ErrIdle, without having read anything from standard input.
+// If the context is done before any key is read, its error is returned, and
+// if the reader provided by the host is closed (like an SSH channel), io.EOF is.
+func WaitAvailableKeys(ctx context.Context, keys *Keys, cfg *inputrc.Config) error {
 	keys.cfg = cfg
 
 	if len(keys.buf) > 0 && !keys.mustWait {
-		return
+		return nil
 	}
 
 	// The macro engine might have fed some keys
 	if len(keys.macroKeys) > 0 {
-		return
+		return nil
 	}
 
 	keys.mutex.Lock()
 	keys.waiting = true
-	keys.cursor = make(chan []byte)
+	if keys.cursor == nil {
+		keys.cursor = make(chan []byte, 1)
+	}
 	keys.mutex.Unlock()
 
 	defer func() {
@@ -64,12 +106,30 @@ func WaitAvailableKeys(keys *Keys, cfg *inputrc.Config) {
 	}()
 
 	for {
-		// Start reading from os.Stdin in the background.
+		// Don't block on reading if we must notify
+		// idleness or might have to cancel the read.
+		if err := keys.waitInput(ctx); err != nil {
+			return err
+		}
+
+		// Start reading from stdin in the background.
 		// We will either read keyBuf from user, or an EOF
 		// send by ourselves, because we pause reading.
-		keyBuf, err := keys.readInputFiltered()
-		if err != nil && errors.Is(err, io.EOF) {
-			return
+		keyBuf, err := keys.readInputIdle(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if errors.Is(err, ErrIdle) {
+			return err
+		}
+
+		if errors.Is(err, io.EOF) && len(keyBuf) == 0 {
+			if keys.hosted {
+				return io.EOF
+			}
+
+			return nil
 		}
 
 		if len(keyBuf) == 0 {
@@ -88,15 +148,35 @@ func WaitAvailableKeys(keys *Keys, cfg *inputrc.Config) {
 				keyBuf = []byte(strutil.ConvertMeta([]rune(string(keyBuf))))
 			}
 
-			keys.mutex.RLock()
+			// Pastes to be confirmed are not dispatched as keys.
+			if keys.isPaste(keyBuf) {
+				keys.mutex.Lock()
+				keys.paste = keyBuf
+				keys.typed++
+				keys.mutex.Unlock()
+
+				return nil
+			}
+
+			keys.mutex.Lock()
 			keys.buf = append(keys.buf, keyBuf...)
-			keys.mutex.RUnlock()
+			keys.typed++
+			keys.mutex.Unlock()
 		}
 
-		return
+		return nil
 	}
 }
 
+// SetIdleTimeout sets the maximum duration to wait for input keys before
+// WaitAvailableKeys returns with an idle notification. A zero or negative
+// duration disables the timeout: the function will block until keys are read.
+func (k *Keys) SetIdleTimeout(timeout time.Duration) {
+	k.mutex.Lock()
+	k.idle = timeout
+	k.mutex.Unlock()
+}
+
 // PopKey is used to pop a key off the key stack without
 // yet marking this key as having matched a bind command.
 func PopKey(keys *Keys) (key byte, empty bool) {
@@ -194,16 +274,61 @@ func MacroKeys(keys *Keys) []rune {
 	return keys.matched
 }
 
-// FlushUsed drops the keys that have matched a given command.
+// PendingKeys returns true if some keys can be dispatched without waiting, either
+// because they have already been read (like when a large paste is read at once)
+// or fed by a macro, or because more input is immediately available on stdin.
+func PendingKeys(keys *Keys) bool {
+	keys.mutex.RLock()
+	buffered := (len(keys.buf) > 0 && !keys.mustWait) || len(keys.macroKeys) > 0
+	keys.mutex.RUnlock()
+
+	return buffered || keys.inputPending()
+}
+
+// Typed returns the number of times keys have been read from stdin and added
+// to the stack, so that callers can know if the user has typed some keys since
+// a previous call (for instance, to tell user input from keys fed by macros).
+func Typed(keys *Keys) int {
+	keys.mutex.RLock()
+	defer keys.mutex.RUnlock()
+
+	return keys.typed
+}
+
+// TakePaste returns the keys read at once and detected as a paste, if any,
+// which must be confirmed before being inserted: see the paste-confirm option.
+func TakePaste(keys *Keys) []byte {
+	keys.mutex.Lock()
+	defer keys.mutex.Unlock()
+
+	paste := keys.paste
+	keys.paste = nil
+
+	return paste
+}
+
+// FlushUsed drops the keys that have matched a given command. The keys fed by
+// this command (like a macro being ran) are then placed before the keys typed
+// ahead but not dispatched yet, so that they are dispatched first.
 func FlushUsed(keys *Keys) {
 	keys.mutex.Lock()
-	keys.matched = nil
 	defer keys.mutex.Unlock()
+
+	keys.matched = nil
+
+	if len(keys.macroKeys) == 0 || keys.mustWait {
+		return
+	}
+
+	keys.buf = append([]byte(string(keys.macroKeys)), keys.buf...)
+	keys.macroKeys = nil
 }
 
 // ReadKey reads keys from stdin like Read(), but immediately
 // returns them instead of storing them in the stack, along with
 // an indication on whether this key is an escape/abort one.
+// Keys already read but not dispatched yet (typed ahead, like
+// when typing `fx` at once) are returned first.
 func (k *Keys) ReadKey() (key rune, isAbort bool) {
 	k.mutex.RLock()
 	k.keysOnce = make(chan []byte)
@@ -221,11 +346,20 @@ func (k *Keys) ReadKey() (key rune, isAbort bool) {
 		key = k.macroKeys[0]
 		k.macroKeys = k.macroKeys[1:]
 
+	case len(k.buf) > 0 && !k.mustWait:
+		var size int
+		key, size = utf8.DecodeRune(k.buf)
+		k.buf = k.buf[size:]
+
 	case k.waiting:
 		buf := <-k.keysOnce
 		key = []rune(string(buf))[0]
 	default:
-		buf, _ := k.readInputFiltered()
+		buf, _ := k.readInputContext(context.Background())
+		if len(buf) == 0 {
+			return inputrc.Esc, true
+		}
+
 		key = []rune(string(buf))[0]
 	}
 
@@ -287,6 +421,227 @@ func (k *Keys) Feed(begin bool, keys ...rune) {
 	}
 }
 
+// Isolate removes all keys from the stack, typed ahead or fed by macros,
+// and returns a function putting them back: keys fed in the meantime are
+// thus dispatched alone, and the ones not dispatched when restoring (like
+// those of an incomplete sequence) are dropped.
+func Isolate(keys *Keys) (restore func()) {
+	keys.mutex.Lock()
+	buf, macroKeys, matched, mustWait := keys.buf, keys.macroKeys, keys.matched, keys.mustWait
+	keys.buf, keys.macroKeys, keys.matched, keys.mustWait = nil, nil, nil, false
+	keys.mutex.Unlock()
+
+	return func() {
+		keys.mutex.Lock()
+		defer keys.mutex.Unlock()
+
+		keys.buf, keys.macroKeys, keys.matched, keys.mustWait = buf, macroKeys, matched, mustWait
+	}
+}
+
+// Record sets a function called with each chunk of keys read as input,
+// without the terminal answers to cursor position queries. A nil function
+// stops recording.
+func (k *Keys) Record(record func(keys []byte)) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.record = record
+}
+
+// ReadPaste returns the keys following a bracketed paste start sequence, up
+// to the end sequence, which is dropped. The keys typed ahead are used first,
+// and more are read until the end sequence is, or until stdin is closed.
+func (k *Keys) ReadPaste() []byte {
+	var paste []byte
+
+	for {
+		k.mutex.Lock()
+		paste = append(paste, k.buf...)
+		k.buf = nil
+
+		if end := bytes.Index(paste, pasteEnd); end >= 0 {
+			k.buf = append(k.buf, paste[end+len(pasteEnd):]...)
+			k.mutex.Unlock()
+
+			return paste[:end]
+		}
+		k.mutex.Unlock()
+
+		keys, err := k.readInputContext(context.Background())
+		if err != nil {
+			return paste
+		}
+
+		k.mutex.Lock()
+		k.buf = append(k.buf, keys...)
+		k.mutex.Unlock()
+	}
+}
+
+// isPaste returns true if keys read at once look like a paste to be
+// confirmed (when the paste-confirm option is enabled): several keys
+// which are not an escape sequence, and contain a newline or are at
+// least as long as the paste-confirm-size option.
+func (k *Keys) isPaste(keys []byte) bool {
+	if k.cfg == nil || !k.cfg.GetBool("paste-confirm") {
+		return false
+	}
+
+	if len(keys) < 2 || rune(keys[0]) == inputrc.Esc {
+		return false
+	}
+
+	size := k.cfg.GetInt("paste-confirm-size")
+
+	return bytes.ContainsAny(keys, "\r\n") || (size > 0 && len(keys) >= size)
+}
+
+// Inject sends keys to be read as if they were typed on stdin, along with
+// the keys actually typed, and blocks until they are read or the context is
+// done, in which case the context error is returned.
+func (k *Keys) Inject(ctx context.Context, keys []byte) error {
+	k.mutex.Lock()
+	k.injecting++
+	k.mutex.Unlock()
+
+	defer func() {
+		k.mutex.Lock()
+		k.injecting--
+		k.mutex.Unlock()
+	}()
+
+	select {
+	case k.injected <- keys:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// keysRead is the result of reading keys in the background.
+type keysRead struct {
+	keys []byte
+	err  error
+}
+
+// readInputContext reads keys from stdin like readInputFiltered, but returns
+// when the context is done, even if stdin cannot be polled and no key is read.
+// Keys are then still read in the background, and returned by the next call,
+// so that none of them is lost.
+func (k *Keys) readInputContext(ctx context.Context) ([]byte, error) {
+	k.mutex.Lock()
+	if k.inflight == nil {
+		k.inflight = make(chan keysRead, 1)
+
+		go func(read chan keysRead) {
+			keys, err := k.readInputFiltered()
+			read <- keysRead{keys: keys, err: err}
+		}(k.inflight)
+	}
+	inflight := k.inflight
+	k.mutex.Unlock()
+
+	select {
+	case read := <-inflight:
+		k.mutex.Lock()
+		k.inflight = nil
+		k.mutex.Unlock()
+
+		k.recordKeys(read.keys)
+
+		return read.keys, read.err
+	case keys := <-k.injected:
+		k.recordKeys(keys)
+
+		return keys, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// readInputIdle reads keys like readInputContext, but returns ErrIdle if none
+// has been read before the idle timeout expires. This is needed when stdin
+// cannot be polled (like custom readers), since it is then always considered
+// readable by waitInput. Keys read afterwards are returned by the next call.
+func (k *Keys) readInputIdle(ctx context.Context) ([]byte, error) {
+	k.mutex.RLock()
+	idle := k.idle
+	k.mutex.RUnlock()
+
+	if idle <= 0 {
+		return k.readInputContext(ctx)
+	}
+
+	idleCtx, cancel := context.WithTimeoutCause(ctx, idle, ErrIdle)
+	defer cancel()
+
+	keys, err := k.readInputContext(idleCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(context.Cause(idleCtx), ErrIdle) {
+		return nil, ErrIdle
+	}
+
+	return keys, err
+}
+
+// recordKeys passes the keys read to the recording function, if any.
+func (k *Keys) recordKeys(keys []byte) {
+	k.mutex.RLock()
+	record := k.record
+	k.mutex.RUnlock()
+
+	if record != nil && len(keys) > 0 {
+		record(keys)
+	}
+}
+
+// waitInput blocks until some input is available on stdin, or returns
+// an error if the idle timeout has expired or if the context is done.
+func (k *Keys) waitInput(ctx context.Context) error {
+	cancellable := ctx.Done() != nil
+
+	k.mutex.RLock()
+	idle := k.idle
+	k.mutex.RUnlock()
+
+	if idle <= 0 && !cancellable {
+		return nil
+	}
+
+	start := time.Now()
+
+	for {
+		timeout := keyPollDelay
+
+		if idle > 0 {
+			remaining := idle - time.Since(start)
+			if remaining <= 0 {
+				return ErrIdle
+			}
+
+			if !cancellable || remaining < timeout {
+				timeout = remaining
+			}
+		}
+
+		if k.keysInjected() || k.inputAvailable(timeout) {
+			return nil
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// keysInjected returns true if some keys are waiting to be injected.
+func (k *Keys) keysInjected() bool {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	return k.injecting > 0
+}
+
 func (k *Keys) extractCursorPos(keys []byte) (cursor, remain []byte) {
 	if !rxRcvCursorPos.Match(keys) {
 		return cursor, keys
@@ -0,0 +1,16 @@
+//go:build !unix && !windows
+
+package core
+
+import "time"
+
+// inputAvailable always returns true on platforms where stdin cannot
+// be polled: reading it will block until some input is available.
+func (k *Keys) inputAvailable(timeout time.Duration) bool {
+	return true
+}
+
+// inputPending always returns false on platforms where stdin cannot be polled.
+func (k *Keys) inputPending() bool {
+	return false
+}
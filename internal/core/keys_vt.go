@@ -0,0 +1,164 @@
+//go:build !windows
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// defaultStdin returns the process standard input.
+func defaultStdin() io.Reader {
+	return os.Stdin
+}
+
+// GetCursorPos returns the current cursor position in the terminal.
+// It is safe to call this function even if the shell is reading input.
+func (k *Keys) GetCursorPos() (x, y int) {
+	if k.noCursorPos {
+		return -1, -1
+	}
+
+	// Terminals not answering the query are not queried again. Nothing
+	// is printed, since the output might not even be the process one.
+	disable := func() (int, int) {
+		k.noCursorPos = true
+
+		return -1, -1
+	}
+
+	var cursor []byte
+	var match [][]string
+
+	// Echo the query and wait for the main key
+	// reading routine to send us the response back.
+	fmt.Fprint(k.stdout, "\x1b[6n")
+
+	// In order not to get stuck with an input that might be user-one
+	// (like when the user typed before the shell is fully started, and yet not having
+	// queried cursor yet), we keep reading from stdin until we find the cursor response.
+	// Everything else is passed back as user input.
+	for {
+		k.mutex.RLock()
+		waiting := k.waiting || k.reading
+		inflight := k.inflight
+		k.mutex.RUnlock()
+
+		switch {
+		case waiting:
+			select {
+			case cursor = <-k.cursor:
+			case <-time.After(cursorPosTimeout):
+				return disable()
+			}
+
+		// The last read returned with injected keys, and stdin is
+		// still read in the background: the answer will be read there.
+		case inflight != nil:
+			select {
+			case read := <-inflight:
+				k.recordKeys(read.keys)
+
+				k.mutex.Lock()
+				k.inflight = nil
+				k.buf = append(k.buf, read.keys...)
+
+				// Keys read here must not be waited for once more,
+				// like when they follow a prefix being dispatched.
+				k.mustWait = k.mustWait && len(read.keys) == 0
+				k.mutex.Unlock()
+			case <-time.After(cursorPosTimeout):
+				return disable()
+			}
+
+			select {
+			case cursor = <-k.cursor:
+			default:
+				continue
+			}
+		default:
+			if !k.inputAvailable(cursorPosTimeout) {
+				return disable()
+			}
+
+			buf := make([]byte, keyScanBufSize)
+
+			read, err := k.stdin.Read(buf)
+			if err != nil {
+				return disable()
+			}
+
+			cursor = buf[:read]
+		}
+
+		// We have read (or have been passed) something.
+		if len(cursor) == 0 {
+			return disable()
+		}
+
+		// Attempt to locate cursor response in it.
+		match = rxRcvCursorPos.FindAllStringSubmatch(string(cursor), 1)
+
+		// If there is something but not cursor answer, its user input.
+		if len(match) == 0 && len(cursor) > 0 {
+			k.recordKeys(cursor)
+
+			k.mutex.Lock()
+			k.buf = append(k.buf, cursor...)
+			k.mustWait = false
+			k.mutex.Unlock()
+
+			continue
+		}
+
+		// And if empty, then we should abort.
+		if len(match) == 0 {
+			return disable()
+		}
+
+		break
+	}
+
+	// We know that we have a cursor answer, process it.
+	y, err := strconv.Atoi(match[0][1])
+	if err != nil {
+		return disable()
+	}
+
+	x, err = strconv.Atoi(match[0][2])
+	if err != nil {
+		return disable()
+	}
+
+	return x, y
+}
+
+func (k *Keys) readInputFiltered() (keys []byte, err error) {
+	// Start reading from stdin in the background.
+	// We will either read keys from user, or an EOF
+	// send by ourselves, because we pause reading.
+	buf := make([]byte, keyScanBufSize)
+
+	read, err := k.stdin.Read(buf)
+	if read == 0 && errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	// Always attempt to extract cursor position info.
+	// If found, strip it and keep the remaining keys.
+	cursor, keys := k.extractCursorPos(buf[:read])
+
+	// The cursor query might have timed out already.
+	if len(cursor) > 0 {
+		select {
+		case k.cursor <- cursor:
+		default:
+		}
+	}
+
+	return keys, nil
+}
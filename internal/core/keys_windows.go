@@ -6,6 +6,8 @@ package core
 import (
 	"errors"
 	"io"
+	"syscall"
+	"time"
 	"unsafe"
 
 	"github.com/reeflective/readline/inputrc"
@@ -50,8 +52,10 @@ const (
 	charBackspace = 127
 )
 
-func init() {
-	Stdin = newRawReader()
+// defaultStdin returns a reader of the console input, translating
+// Windows key events into their virtual terminal sequences.
+func defaultStdin() io.Reader {
+	return newRawReader()
 }
 
 // GetTerminalResize sends booleans over a channel to notify resize events on Windows.
@@ -66,13 +70,13 @@ func GetTerminalResize(keys *Keys) <-chan bool {
 // readInputFiltered on Windows needs to check for terminal resize events.
 func (k *Keys) readInputFiltered() (keys []byte, err error) {
 	for {
-		// Start reading from os.Stdin in the background.
+		// Start reading from stdin in the background.
 		// We will either read keys from user, or an EOF
 		// send by ourselves, because we pause reading.
 		buf := make([]byte, keyScanBufSize)
 
-		read, err := Stdin.Read(buf)
-		if err != nil && errors.Is(err, io.EOF) {
+		read, err := k.stdin.Read(buf)
+		if read == 0 && errors.Is(err, io.EOF) {
 			return keys, err
 		}
 
@@ -97,6 +101,23 @@ func (k *Keys) readInputFiltered() (keys []byte, err error) {
 	}
 }
 
+// inputAvailable returns true if some console input events are
+// available on stdin before the timeout expires.
+func (k *Keys) inputAvailable(timeout time.Duration) bool {
+	event, err := syscall.WaitForSingleObject(syscall.Handle(stdin), uint32(timeout.Milliseconds()))
+	if err != nil {
+		return true
+	}
+
+	return event != syscall.WAIT_TIMEOUT
+}
+
+// inputPending always returns false on Windows, since console input events
+// (focus, mouse, etc) do not necessarily translate into keys to dispatch.
+func (k *Keys) inputPending() bool {
+	return false
+}
+
 // rawReader translates Windows input to ANSI sequences,
 // to provide the same behavior as Unix terminals.
 type rawReader struct {
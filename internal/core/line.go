@@ -5,7 +5,6 @@ import (
 	"regexp"
 	"strings"
 	"unicode"
-	"unicode/utf8"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
@@ -13,10 +12,86 @@ import (
 	"github.com/reeflective/readline/internal/term"
 )
 
+// Regular expressions used to select words and to find newlines.
+var (
+	rxWord      = regexp.MustCompile("[0-9a-zA-Z_]")
+	rxBlankWord = regexp.MustCompile(`[^\s]`)
+	rxSpace     = regexp.MustCompile(`\s`)
+	rxNewline   = regexp.MustCompile(string(inputrc.Newline))
+)
+
 // Tokenizer is a method used by a (line) type to split itself according to
 // different rules (split between spaces, punctuation, brackets, quotes, etc.).
 type Tokenizer func(cursorPos int) (split []string, index int, newPos int)
 
+// WordClass returns the class of a character: consecutive characters of
+// the same class form a word, and blank characters (of class BlankClass)
+// separate words. Newlines are always blanks.
+type WordClass func(char rune) int
+
+// Spans returns the spans of the tokens of a line, as their begin (included)
+// and end (excluded) positions: each token is a word, and characters out of
+// them are blanks. A nil result means that the line has no such tokenizer.
+type Spans func(line []rune) [][]int
+
+// Character classes used by word styles.
+const (
+	BlankClass = iota
+	PunctClass
+	WordCharClass
+)
+
+// WordStyle returns the word class of a word style, with the word characters
+// being part of words in addition to those of the style. With the "vim" style,
+// words are either letters, digits and underscores (like the default vim
+// iskeyword option), or sequences of other non-blank characters. With "bash",
+// words are letters and digits, all other characters separating them. With
+// "space", words are sequences of non-blank characters. Otherwise, words are
+// split on blanks and punctuation, successive identical punctuation characters
+// forming a word: this is the default "readline" style.
+func WordStyle(style, wordChars string) WordClass {
+	return func(char rune) int {
+		switch {
+		case unicode.IsSpace(char):
+			return BlankClass
+		case strings.ContainsRune(wordChars, char):
+			return WordCharClass
+		}
+
+		switch style {
+		case "vim":
+			if char == '_' || unicode.IsLetter(char) || unicode.IsDigit(char) {
+				return WordCharClass
+			}
+
+			return PunctClass
+		case "bash":
+			if unicode.IsLetter(char) || unicode.IsDigit(char) {
+				return WordCharClass
+			}
+
+			return BlankClass
+		case "space":
+			return WordCharClass
+		default:
+			return readlineWordClass(char)
+		}
+	}
+}
+
+// readlineWordClass is the default word class, where spaces and tabs are
+// blanks, and where each punctuation character is a class of its own.
+func readlineWordClass(char rune) int {
+	switch {
+	case char == ' ' || char == '\t':
+		return BlankClass
+	case unicode.IsPunct(char):
+		return WordCharClass + 1 + int(char)
+	default:
+		return WordCharClass
+	}
+}
+
 // Line is an input line buffer.
 // Contains methods to search and modify its contents,
 // split itself with tokenizers, and displaying itself.
@@ -52,10 +127,7 @@ func (l *Line) Insert(pos int, chars ...rune) {
 	case l.Len() == 0:
 		*l = chars
 	case pos < l.Len():
-		forward := string((*l)[pos:])
-		cut := string(append((*l)[:pos], chars...))
-		cut += forward
-		*l = []rune(cut)
+		*l = l.splice(pos, pos, chars)
 	case pos == l.Len():
 		*l = append(*l, chars...)
 	}
@@ -74,17 +146,21 @@ func (l *Line) InsertBetween(bpos, epos int, chars ...rune) {
 	switch {
 	case epos == -1:
 		l.Insert(bpos, chars...)
-	case epos == l.Len():
-		cut := string((*l)[:bpos]) + string(chars)
-		*l = []rune(cut)
 	default:
-		forward := string((*l)[epos:])
-		cut := string(append((*l)[:bpos], chars...))
-		cut += forward
-		*l = []rune(cut)
+		*l = l.splice(bpos, epos, chars)
 	}
 }
 
+// splice returns a new line made of the line up to bpos, the
+// given runes, and the remaining of the line starting at epos.
+func (l *Line) splice(bpos, epos int, chars []rune) []rune {
+	line := make([]rune, 0, bpos+len(chars)+l.Len()-epos)
+	line = append(line, (*l)[:bpos]...)
+	line = append(line, chars...)
+
+	return append(line, (*l)[epos:]...)
+}
+
 // Cut deletes a slice of runes between a beginning and end position on the line.
 // If the begin/end pos is negative/greater than the line, all runes located on
 // valid indexes in the given range are removed.
@@ -130,7 +206,7 @@ func (l *Line) CutRune(pos int) {
 // This should NOT confused with the length of the line in terms of
 // how many terminal columns its printed representation will take.
 func (l *Line) Len() int {
-	return utf8.RuneCountInString(string(*l))
+	return len(*l)
 }
 
 // SelectWord returns the begin and end index positions of a word
@@ -145,11 +221,11 @@ func (l *Line) SelectWord(pos int) (bpos, epos int) {
 		pos--
 	}
 
-	wordRgx := regexp.MustCompile("[0-9a-zA-Z_]")
+	wordRgx := rxWord
 	bpos, epos = pos, pos
 
 	if match := wordRgx.MatchString(string((*l)[pos])); !match {
-		wordRgx = regexp.MustCompile(`\s`)
+		wordRgx = rxSpace
 	}
 
 	// To first space found backward
@@ -176,6 +252,52 @@ func (l *Line) SelectWord(pos int) (bpos, epos int) {
 	return bpos, epos
 }
 
+// SelectWordClass is like SelectWord, but with words made of
+// consecutive characters of the same class. If the class is nil,
+// words are selected with SelectWord.
+func (l *Line) SelectWordClass(pos int, class WordClass) (bpos, epos int) {
+	if class == nil {
+		return l.SelectWord(pos)
+	}
+
+	return l.selectClass(pos, func(i int) int {
+		return wordClass(class, (*l)[i])
+	})
+}
+
+// SelectSpan is like SelectWordClass, but with words being the token spans
+// (begin positions included, end ones excluded) of a host tokenizer, and all
+// characters out of these tokens being blanks.
+func (l *Line) SelectSpan(pos int, spans [][]int) (bpos, epos int) {
+	return l.selectClass(pos, l.spanClass(spans))
+}
+
+// selectClass returns the begin and end positions of the consecutive
+// characters around a position having the same class than the latter.
+func (l *Line) selectClass(pos int, classAt func(i int) int) (bpos, epos int) {
+	if l.Len() == 0 {
+		return
+	}
+
+	pos = l.checkPosRange(pos)
+	if pos == l.Len() {
+		pos--
+	}
+
+	word := classAt(pos)
+	bpos, epos = pos, pos
+
+	for bpos > 0 && classAt(bpos-1) == word {
+		bpos--
+	}
+
+	for epos < l.Len()-1 && classAt(epos+1) == word {
+		epos++
+	}
+
+	return bpos, epos
+}
+
 // SelectBlankWord returns the begin and end index positions
 // of a full bigword (blank word) around the specified position.
 func (l *Line) SelectBlankWord(pos int) (bpos, epos int) {
@@ -188,12 +310,12 @@ func (l *Line) SelectBlankWord(pos int) (bpos, epos int) {
 		pos--
 	}
 
-	blankWordRgx := regexp.MustCompile(`[^\s]`)
+	blankWordRgx := rxBlankWord
 
 	bpos, epos = pos, pos
 
 	if match := blankWordRgx.MatchString(string((*l)[pos])); !match {
-		blankWordRgx = regexp.MustCompile(`\s`)
+		blankWordRgx = rxSpace
 	}
 
 	// To first space found backward
@@ -314,11 +436,107 @@ func (l *Line) SurroundQuotes(single bool, pos int) (bpos, epos int) {
 	return bpos, epos
 }
 
-// DisplayLine prints the line to stdout, starting at the current terminal
+// FindSurroundPair returns the positions of the brackets (bchar and echar) enclosing
+// the given position, skipping the pairs nested in them: the count-th enclosing pair
+// is returned, the innermost one being the first. A bracket under the position is
+// part of the innermost pair. bpos and epos are -1 if there is no such pair.
+func (l *Line) FindSurroundPair(bchar, echar rune, pos, count int) (bpos, epos int) {
+	if pos < 0 || pos >= l.Len() {
+		return -1, -1
+	}
+
+	switch (*l)[pos] {
+	case bchar:
+		bpos = pos
+	default:
+		bpos = l.findUnmatched(bchar, echar, pos, false)
+	}
+
+	for ; count > 1 && bpos != -1; count-- {
+		bpos = l.findUnmatched(bchar, echar, bpos, false)
+	}
+
+	if bpos == -1 {
+		return -1, -1
+	}
+
+	epos = l.findUnmatched(bchar, echar, bpos, true)
+	if epos == -1 {
+		return -1, -1
+	}
+
+	return bpos, epos
+}
+
+// FindQuotes returns the positions of the quotes enclosing the given position,
+// or of the next quoted string if there is none, on the line (as separated by
+// newlines) of this position. Quotes are paired from the beginning of the line,
+// and those escaped with a backslash are ignored. bpos and epos are -1 if no
+// pair of quotes can be found.
+func (l *Line) FindQuotes(quote rune, pos int) (bpos, epos int) {
+	if pos < 0 || pos >= l.Len() {
+		return -1, -1
+	}
+
+	start, end := pos, pos
+
+	for start > 0 && (*l)[start-1] != inputrc.Newline {
+		start--
+	}
+
+	for end < l.Len() && (*l)[end] != inputrc.Newline {
+		end++
+	}
+
+	var quotes []int
+
+	for i := start; i < end; i++ {
+		if (*l)[i] == quote && (i == 0 || (*l)[i-1] != '\\') {
+			quotes = append(quotes, i)
+		}
+	}
+
+	for i := 0; i+1 < len(quotes); i += 2 {
+		if quotes[i+1] >= pos {
+			return quotes[i], quotes[i+1]
+		}
+	}
+
+	return -1, -1
+}
+
+// findUnmatched returns the position of the first bracket, in the given direction
+// and excluding pos, not matched by another one between pos and itself: the closing
+// bracket (echar) when going forward, or the opening one (bchar) when backward.
+func (l *Line) findUnmatched(bchar, echar rune, pos int, forward bool) int {
+	opening, closing, step := bchar, echar, 1
+	if !forward {
+		opening, closing, step = echar, bchar, -1
+	}
+
+	depth := 0
+
+	for i := pos + step; i >= 0 && i < l.Len(); i += step {
+		switch (*l)[i] {
+		case opening:
+			depth++
+		case closing:
+			if depth == 0 {
+				return i
+			}
+
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// DisplayLine prints the line to the output, starting at the current terminal
 // cursor position, assuming it is at the end of the shell prompt string.
 // Params:
 // @indent -    Used to align all lines (except the first) together on a single column.
-func DisplayLine(l *Line, indent int) {
+func DisplayLine(out *term.Output, l *Line, indent int) {
 	lines := strings.Split(string(*l), "\n")
 
 	if strings.HasSuffix(string(*l), "\n") {
@@ -331,19 +549,19 @@ func DisplayLine(l *Line, indent int) {
 
 		// Clear everything before each line, except the first.
 		if num > 0 {
-			term.MoveCursorForwards(indent)
+			out.MoveCursorForwards(indent)
 			line = term.ClearLineBefore + line
 		}
 
 		// Clear everything after each line, except the last.
 		if num < len(lines)-1 {
-			if len(line)+indent < term.GetWidth() {
+			if len(line)+indent < out.Width() {
 				line += term.ClearLineAfter
 			}
 			line += term.NewlineReturn
 		}
 
-		fmt.Print(line)
+		fmt.Fprint(out, line)
 	}
 }
 
@@ -352,16 +570,17 @@ func DisplayLine(l *Line, indent int) {
 // take into account an eventual suggestion added to the line before printing.
 // Params:
 // @indent - Coordinates to align all lines (except the first) together on a single column.
+// @width -  The width of the terminal on which the line is displayed.
 // Returns:
 // @x - The number of columns, starting from the terminal left, to the end of the last line.
 // @y - The number of actual lines on which the line spans, accounting for line wrap.
-func CoordinatesLine(l *Line, indent int) (x, y int) {
+func CoordinatesLine(l *Line, indent, width int) (x, y int) {
 	line := string(*l)
 	lines := strings.Split(line, "\n")
 	usedY, usedX := 0, 0
 
 	for i, line := range lines {
-		x, y := strutil.LineSpan([]rune(line), i, indent)
+		x, y := strutil.LineSpan([]rune(line), i, indent, width)
 		usedY += y
 		usedX = x
 	}
@@ -373,11 +592,15 @@ func CoordinatesLine(l *Line, indent int) (x, y int) {
 // If there are no newlines, the result is 0, otherwise it's
 // the number of lines - 1.
 func (l *Line) Lines() int {
-	line := string(*l)
-	nl := regexp.MustCompile(string(inputrc.Newline))
-	lines := nl.FindAllStringIndex(line, -1)
+	lines := 0
+
+	for _, char := range *l {
+		if char == inputrc.Newline {
+			lines++
+		}
+	}
 
-	return len(lines)
+	return lines
 }
 
 // Forward returns the offset to the beginning of the next
@@ -440,70 +663,111 @@ func (l *Line) Backward(tokenizer Tokenizer, pos int) (adjust int) {
 	return adjust * -1
 }
 
+// BackwardEnd returns the offset to the end position of the previous
+// (backward) token determined by the tokenizer function. Empty lines are
+// tokens of their own, and without any previous token, the offset is the
+// one to the beginning of the line.
+func (l *Line) BackwardEnd(tokenizer Tokenizer, pos int) (adjust int) {
+	split, index, pos := tokenizer(pos)
+
+	switch {
+	case len(split) == 0:
+		return
+	case pos > tokenEnd(split[index]):
+		adjust = tokenEnd(split[index]) - pos
+	case index == 0:
+		adjust = -pos
+	default:
+		adjust = tokenEnd(split[index-1]) - len(split[index-1]) - pos
+	}
+
+	return adjust
+}
+
 // Tokenize splits the line on each word, that is, split on every punctuation or space.
 func (l *Line) Tokenize(cpos int) ([]string, int, int) {
-	line := *l
+	return l.TokenizeWords(readlineWordClass)(cpos)
+}
 
-	if line.Len() == 0 {
-		return nil, 0, 0
+// TokenizeWords returns a tokenizer splitting the line on each word of the
+// word class, or on each word of the default one if nil. Blanks are part of
+// the word before them, and are returned as spaces whatever their class.
+func (l *Line) TokenizeWords(class WordClass) Tokenizer {
+	if class == nil {
+		class = readlineWordClass
 	}
 
-	cpos = l.checkPosRange(cpos)
+	return l.tokenizeClass(func(i int) int {
+		return wordClass(class, (*l)[i])
+	})
+}
 
-	var index, pos int
-	var punc bool
+// TokenizeSpans returns a tokenizer splitting the line on each token span
+// (begin positions included, end ones excluded) of a host tokenizer, the
+// characters out of these tokens being blanks, like with TokenizeWords.
+func (l *Line) TokenizeSpans(spans [][]int) Tokenizer {
+	return l.tokenizeClass(l.spanClass(spans))
+}
 
-	split := make([]string, 1)
+// tokenizeClass returns a tokenizer splitting the line
+// on each series of characters having the same class.
+func (l *Line) tokenizeClass(classAt func(i int) int) Tokenizer {
+	return func(cpos int) ([]string, int, int) {
+		line := *l
 
-	for i, char := range line {
-		switch {
-		case unicode.IsPunct(char):
-			if i > 0 && line[i-1] != char {
-				split = append(split, "")
-			}
+		if line.Len() == 0 {
+			return nil, 0, 0
+		}
 
-			split[len(split)-1] += string(char)
-			punc = true
+		cpos = l.checkPosRange(cpos)
 
-		case char == ' ' || char == '\t':
-			split[len(split)-1] += string(char)
-			punc = true
+		var index, pos int
+		var blank bool
 
-		case char == '\n':
-			// Newlines are a word of their own only
-			// when the last rune of the previous word
-			// is one as well.
-			if i > 0 && line[i-1] == char {
-				split = append(split, "")
-			}
+		split := make([]string, 1)
 
-			split[len(split)-1] += string(char)
-			punc = true
+		for i, char := range line {
+			switch {
+			case char == '\n':
+				// Newlines are a word of their own only
+				// when the last rune of the previous word
+				// is one as well.
+				if i > 0 && line[i-1] == char {
+					split = append(split, "")
+				}
 
-		default:
-			if punc {
-				split = append(split, "")
+				split[len(split)-1] += string(char)
+				blank = true
+
+			case classAt(i) == BlankClass:
+				split[len(split)-1] += " "
+				blank = true
+
+			default:
+				if i > 0 && (blank || classAt(i-1) != classAt(i)) {
+					split = append(split, "")
+				}
+
+				split[len(split)-1] += string(char)
+				blank = false
 			}
 
-			split[len(split)-1] += string(char)
-			punc = false
+			// Not caught when we are appending to the end
+			// of the line, where rl.pos = linePos + 1, so...
+			if i == cpos {
+				index = len(split) - 1
+				pos = len(split[index]) - 1
+			}
 		}
 
-		// Not caught when we are appending to the end
-		// of the line, where rl.pos = linePos + 1, so...
-		if i == cpos {
+		// ... so we ajust here for this case.
+		if cpos == len(line) {
 			index = len(split) - 1
-			pos = len(split[index]) - 1
+			pos = len(split[index])
 		}
-	}
 
-	// ... so we ajust here for this case.
-	if cpos == len(line) {
-		index = len(split) - 1
-		pos = len(split[index])
+		return split, index, pos
 	}
-
-	return split, index, pos
 }
 
 // TokenizeSpace splits the line on each WORD (blank word), that is, split on every space.
@@ -677,13 +941,50 @@ func closeToken(idx, count, cpos, match int, pos map[int]int, line []rune, split
 	return count, split
 }
 
+// spanClass returns the class of the characters of the line given token
+// spans: all characters of a token are of the same class, distinct from the
+// class of other tokens, and characters out of them (or newlines) are blanks.
+func (l *Line) spanClass(spans [][]int) func(i int) int {
+	classes := make([]int, l.Len())
+
+	for token, span := range spans {
+		if len(span) < 2 {
+			continue
+		}
+
+		for i := max(span[0], 0); i < min(span[1], len(classes)); i++ {
+			if (*l)[i] != '\n' {
+				classes[i] = BlankClass + 1 + token
+			}
+		}
+	}
+
+	return func(i int) int {
+		return classes[i]
+	}
+}
+
+// tokenEnd returns the position of the last non-blank character of a
+// token split by a tokenizer, or zero if the token is only made of blanks.
+func tokenEnd(token string) int {
+	return max(len(strings.TrimRightFunc(token, unicode.IsSpace))-1, 0)
+}
+
+// wordClass returns the class of a character, newlines being always blanks.
+func wordClass(class WordClass, char rune) int {
+	if char == '\n' {
+		return BlankClass
+	}
+
+	return class(char)
+}
+
 // newlines gives the indexes of all newline characters in the line.
 func (l *Line) newlines() [][]int {
 	line := string(*l)
 	line += string(inputrc.Newline)
-	nl := regexp.MustCompile(string(inputrc.Newline))
 
-	return nl.FindAllStringIndex(line, -1)
+	return rxNewline.FindAllStringIndex(line, -1)
 }
 
 // returns bpos, epos ordered and true if either is valid.
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"io"
 	"reflect"
 	"testing"
 
@@ -9,7 +10,7 @@ import (
 
 // getTermWidth is used as a variable so that we can
 // use specific terminal widths in our tests.
-var getTermWidth = term.GetWidth
+var getTermWidth = term.NewOutput(io.Discard, nil).Width
 
 func TestLine_Insert(t *testing.T) {
 	line := Line("multiple-ambiguous 10.203.23.45")
@@ -535,6 +536,68 @@ func TestLine_FindSurround(t *testing.T) {
 	}
 }
 
+func TestLine_FindSurroundPair(t *testing.T) {
+	line := Line("call(one, f(two), [three]) (four")
+
+	tests := []struct {
+		name     string
+		bchar    rune
+		echar    rune
+		pos      int
+		count    int
+		wantBpos int
+		wantEpos int
+	}{
+		{name: "Innermost pair", bchar: '(', echar: ')', pos: 5, count: 1, wantBpos: 4, wantEpos: 25},
+		{name: "Nested pair", bchar: '(', echar: ')', pos: 13, count: 1, wantBpos: 11, wantEpos: 15},
+		{name: "Outer pair (count)", bchar: '(', echar: ')', pos: 13, count: 2, wantBpos: 4, wantEpos: 25},
+		{name: "On the opening bracket", bchar: '(', echar: ')', pos: 11, count: 1, wantBpos: 11, wantEpos: 15},
+		{name: "On the closing bracket", bchar: '(', echar: ')', pos: 15, count: 1, wantBpos: 11, wantEpos: 15},
+		{name: "Other brackets", bchar: '[', echar: ']', pos: 20, count: 1, wantBpos: 18, wantEpos: 24},
+		{name: "Outside of pairs", bchar: '(', echar: ')', pos: 0, count: 1, wantBpos: -1, wantEpos: -1},
+		{name: "Unclosed pair", bchar: '(', echar: ')', pos: 30, count: 1, wantBpos: -1, wantEpos: -1},
+		{name: "Too many levels", bchar: '(', echar: ')', pos: 13, count: 3, wantBpos: -1, wantEpos: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotBpos, gotEpos := line.FindSurroundPair(test.bchar, test.echar, test.pos, test.count)
+			if gotBpos != test.wantBpos || gotEpos != test.wantEpos {
+				t.Errorf("Line.FindSurroundPair() = %v, %v, want %v, %v", gotBpos, gotEpos, test.wantBpos, test.wantEpos)
+			}
+		})
+	}
+}
+
+func TestLine_FindQuotes(t *testing.T) {
+	line := Line(`echo "one" "t\"wo" x` + "\n" + `"three"`)
+
+	tests := []struct {
+		name     string
+		pos      int
+		wantBpos int
+		wantEpos int
+	}{
+		{name: "Inside quotes", pos: 7, wantBpos: 5, wantEpos: 9},
+		{name: "On the opening quote", pos: 5, wantBpos: 5, wantEpos: 9},
+		{name: "On the closing quote", pos: 9, wantBpos: 5, wantEpos: 9},
+		{name: "Before quotes", pos: 0, wantBpos: 5, wantEpos: 9},
+		{name: "Between quotes", pos: 10, wantBpos: 11, wantEpos: 17},
+		{name: "Escaped quote", pos: 13, wantBpos: 11, wantEpos: 17},
+		{name: "After quotes", pos: 19, wantBpos: -1, wantEpos: -1},
+		{name: "Other line", pos: 23, wantBpos: 21, wantEpos: 27},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			gotBpos, gotEpos := line.FindQuotes('"', test.pos)
+			if gotBpos != test.wantBpos || gotEpos != test.wantEpos {
+				t.Errorf("Line.FindQuotes() = %v, %v, want %v, %v", gotBpos, gotEpos, test.wantBpos, test.wantEpos)
+			}
+		})
+	}
+}
+
 func TestLine_SurroundQuotes(t *testing.T) {
 	line := Line("basic -f \"commands.go,line.go\" -cp=/usr \"another\" --option 'value1 value2'")
 
@@ -794,6 +857,67 @@ func TestLine_Backward(t *testing.T) {
 	}
 }
 
+func TestLine_BackwardEnd(t *testing.T) {
+	line := Line("basic -f \"commands.go,line.go\"  -cp=/usr\n\nvalue")
+	emptyLine := new(Line)
+
+	type args struct {
+		split Tokenizer
+		pos   int
+	}
+	tests := []struct {
+		name       string
+		l          *Line
+		args       args
+		wantAdjust int
+	}{
+		{
+			name:       "Backward word end (empty line)",
+			l:          emptyLine,
+			args:       args{split: emptyLine.Tokenize, pos: 0},
+			wantAdjust: 0,
+		},
+		{
+			name:       "Backward word end (first word)",
+			l:          &line,
+			args:       args{split: line.Tokenize, pos: 3},
+			wantAdjust: -3,
+		},
+		{
+			name:       "Backward word end (beginning of word)",
+			l:          &line,
+			args:       args{split: line.Tokenize, pos: 7},
+			wantAdjust: -1,
+		},
+		{
+			name:       "Backward word end (in blanks)",
+			l:          &line,
+			args:       args{split: line.Tokenize, pos: 31},
+			wantAdjust: -2,
+		},
+		{
+			name:       "Backward word end (empty line)",
+			l:          &line,
+			args:       args{split: line.Tokenize, pos: 42},
+			wantAdjust: -1,
+		},
+		{
+			name:       "Backward blank word end",
+			l:          &line,
+			args:       args{split: line.TokenizeSpace, pos: 36},
+			wantAdjust: -7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if gotAdjust := tt.l.BackwardEnd(tt.args.split, tt.args.pos); gotAdjust != tt.wantAdjust {
+				t.Errorf("Line.BackwardEnd() = %v, want %v", gotAdjust, tt.wantAdjust)
+			}
+		})
+	}
+}
+
 func TestLine_Tokenize(t *testing.T) {
 	line := Line("basic -f \"commands.go \nanother testing\" --alternate \"another\nquote\" -c")
 	emptyLines := Line("basic -f \"commands.go \n\nanother testing\" --alternate \"another\nquote\" -c")
@@ -851,6 +975,80 @@ func TestLine_Tokenize(t *testing.T) {
 	}
 }
 
+func TestLine_TokenizeWords(t *testing.T) {
+	line := Line("git foo->bar_baz --all")
+
+	tests := []struct {
+		name  string
+		class WordClass
+		want  []string
+		want1 int
+		want2 int
+	}{
+		{
+			name:  "Tokenize vim words",
+			class: WordStyle("vim", ""),
+			want:  []string{"git ", "foo", "->", "bar_baz ", "--", "all"},
+			want1: 3,
+			want2: 2,
+		},
+		{
+			name:  "Tokenize bash words",
+			class: WordStyle("bash", ""),
+			want:  []string{"git ", "foo  ", "bar ", "baz   ", "all"},
+			want1: 2,
+			want2: 2,
+		},
+		{
+			name:  "Tokenize blank words",
+			class: WordStyle("space", ""),
+			want:  []string{"git ", "foo->bar_baz ", "--all"},
+			want1: 1,
+			want2: 7,
+		},
+		{
+			name:  "Tokenize words with word characters",
+			class: WordStyle("vim", "-"),
+			want:  []string{"git ", "foo-", ">", "bar_baz ", "--all"},
+			want1: 3,
+			want2: 2,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, got1, got2 := line.TokenizeWords(test.class)(11)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Line.TokenizeWords() got = %q, want %q", got, test.want)
+			}
+			if got1 != test.want1 {
+				t.Errorf("Line.TokenizeWords() got1 = %v, want %v", got1, test.want1)
+			}
+			if got2 != test.want2 {
+				t.Errorf("Line.TokenizeWords() got2 = %v, want %v", got2, test.want2)
+			}
+		})
+	}
+}
+
+func TestLine_TokenizeSpans(t *testing.T) {
+	line := Line(`(concat "a b" x)`)
+	spans := [][]int{{0, 1}, {1, 7}, {8, 13}, {14, 15}, {15, 16}}
+
+	got, got1, got2 := line.TokenizeSpans(spans)(10)
+	if want := []string{"(", "concat ", `"a b" `, "x", ")"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Line.TokenizeSpans() got = %q, want %q", got, want)
+	}
+
+	if got1 != 2 || got2 != 2 {
+		t.Errorf("Line.TokenizeSpans() got1, got2 = %d, %d, want 2, 2", got1, got2)
+	}
+
+	if bpos, epos := line.SelectSpan(10, spans); bpos != 8 || epos != 12 {
+		t.Errorf("Line.SelectSpan() = %d, %d, want 8, 12", bpos, epos)
+	}
+}
+
 func TestLine_TokenizeSpace(t *testing.T) {
 	line := Line("basic -f \"commands.go \nanother testing\" --alternate \"another\nquote\" -c")
 	emptyLine := new(Line)
@@ -1038,7 +1236,7 @@ func TestDisplayLine(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			DisplayLine(tt.l, tt.args.indent)
+			DisplayLine(term.NewOutput(io.Discard, nil), tt.l, tt.args.indent)
 		})
 	}
 }
@@ -1080,7 +1278,7 @@ func TestCoordinatesLine(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			gotX, gotY := CoordinatesLine(test.l, test.args.indent)
+			gotX, gotY := CoordinatesLine(test.l, test.args.indent, getTermWidth())
 			if gotX != test.wantX {
 				t.Errorf("CoordinatesLine() gotX = %v, want %v", gotX, test.wantX)
 			}
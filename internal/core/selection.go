@@ -2,6 +2,7 @@ package core
 
 import (
 	"regexp"
+	"strings"
 	"unicode"
 
 	"github.com/reeflective/readline/inputrc"
@@ -14,14 +15,17 @@ import (
 // with the default cursor mark and position, and contains a list of additional surround
 // selections used to change/select multiple parts of the line at once.
 type Selection struct {
-	Type       string // Can be a normal one, surrounding (pairs), (cursor) matchers, etc.
-	active     bool   // The selection is running.
-	visual     bool   // The selection is highlighted.
-	visualLine bool   // The selection should span entire lines.
-	bpos       int    // Beginning index position
-	epos       int    // End index position (can be +1 in visual mode, to encompass cursor pos)
-	kpos       int    // Keyword regexp matchers cycling counter.
-	kmpos      int    // Keyword regexp matcher subgroups counter.
+	Type        string    // Can be a normal one, surrounding (pairs), (cursor) matchers, etc.
+	Words       WordClass // Class of characters selected as words, or the default one if nil.
+	Tokens      Spans     // Token spans of a host tokenizer, selected as words and shell words if not nil.
+	active      bool      // The selection is running.
+	visual      bool      // The selection is highlighted.
+	visualLine  bool      // The selection should span entire lines.
+	visualBlock bool      // The selection spans the same columns on several lines.
+	bpos        int       // Beginning index position
+	epos        int       // End index position (can be +1 in visual mode, to encompass cursor pos)
+	kpos        int       // Keyword regexp matchers cycling counter.
+	kmpos       int       // Keyword regexp matcher subgroups counter.
 
 	// Display
 	fg        string      // Foreground color of the highlighted selection.
@@ -108,6 +112,7 @@ func (s *Selection) Active() bool {
 func (s *Selection) Visual(line bool) {
 	s.visual = true
 	s.visualLine = line
+	s.visualBlock = false
 }
 
 // IsVisual indicates whether the selection should be highlighted.
@@ -115,6 +120,73 @@ func (s *Selection) IsVisual() bool {
 	return s.visual
 }
 
+// VisualBlock sets the selection as a blockwise visual one, which spans
+// the same columns on all lines between its mark and the cursor.
+func (s *Selection) VisualBlock() {
+	s.visual = true
+	s.visualLine = false
+	s.visualBlock = true
+}
+
+// IsVisualBlock indicates whether the selection is a blockwise visual one.
+func (s *Selection) IsVisualBlock() bool {
+	return s.active && s.visual && s.visualBlock
+}
+
+// Block returns the begin and end positions of the region selected on each
+// line of a blockwise selection, from the first line to the last one, along
+// with the begin and end (excluded) columns of the block. The regions of lines
+// shorter than the block are empty or truncated at the end of these lines.
+func (s *Selection) Block() (regions [][]int, bcol, ecol int) {
+	if !s.IsVisualBlock() || s.line.Len() == 0 || s.bpos < 0 {
+		return nil, 0, 0
+	}
+
+	mark := min(s.bpos, s.line.Len())
+	cpos := s.cursor.Pos()
+
+	mstart, _ := s.lineBounds(mark)
+	cstart, _ := s.lineBounds(cpos)
+
+	bcol = min(mark-mstart, cpos-cstart)
+	ecol = max(mark-mstart, cpos-cstart) + 1
+
+	for start := min(mstart, cstart); start <= max(mark, cpos); {
+		_, end := s.lineBounds(start)
+		regions = append(regions, []int{min(start+bcol, end), min(start+ecol, end)})
+		start = end + 1
+	}
+
+	return regions, bcol, ecol
+}
+
+// BlockRegions returns the non-empty regions of a blockwise
+// selection as highlighted selections, one for each line.
+func (s *Selection) BlockRegions() []Selection {
+	regions, _, _ := s.Block()
+	selections := make([]Selection, 0, len(regions))
+
+	for _, region := range regions {
+		if region[0] == region[1] {
+			continue
+		}
+
+		selections = append(selections, Selection{
+			Type:   "visual",
+			active: true,
+			visual: true,
+			bpos:   region[0],
+			epos:   region[1] - 1,
+			fg:     s.fg,
+			bg:     s.bg,
+			line:   s.line,
+			cursor: s.cursor,
+		})
+	}
+
+	return selections
+}
+
 // Pos returns the begin and end positions of the selection.
 // If any of these is not set, it is set to the cursor position.
 // This is generally the case with "pending" visual selections.
@@ -154,6 +226,10 @@ func (s *Selection) Pos() (bpos, epos int) {
 // Cursor returns what should be the cursor position if the active
 // selection is to be deleted, but also works for yank operations.
 func (s *Selection) Cursor() int {
+	if regions, _, _ := s.Block(); len(regions) > 0 {
+		return regions[0][0]
+	}
+
 	bpos, epos := s.Pos()
 	if bpos == -1 && epos == -1 {
 		return s.cursor.Pos()
@@ -235,6 +311,15 @@ func (s *Selection) Text() string {
 		return ""
 	}
 
+	if regions, _, _ := s.Block(); len(regions) > 0 {
+		lines := make([]string, 0, len(regions))
+		for _, region := range regions {
+			lines = append(lines, string((*s.line)[region[0]:region[1]]))
+		}
+
+		return strings.Join(lines, string(inputrc.Newline))
+	}
+
 	bpos, epos := s.Pos()
 	if bpos == -1 || epos == -1 {
 		return ""
@@ -253,6 +338,10 @@ func (s *Selection) Pop() (buf string, bpos, epos, cpos int) {
 
 	defer s.Reset()
 
+	if regions, _, _ := s.Block(); len(regions) > 0 {
+		return s.Text(), regions[0][0], regions[len(regions)-1][1], s.Cursor()
+	}
+
 	bpos, epos = s.Pos()
 	if bpos == -1 || epos == -1 {
 		return "", -1, -1, 0
@@ -330,7 +419,12 @@ func (s *Selection) SelectAWord() (bpos, epos int) {
 
 	spaceBefore, spaceUnder := s.spacesAroundWord(bpos)
 
-	bpos, epos = s.line.SelectWord(cpos)
+	if spans := s.tokens(); spans != nil {
+		bpos, epos = s.line.SelectSpan(cpos, spans)
+	} else {
+		bpos, epos = s.line.SelectWordClass(cpos, s.Words)
+	}
+
 	s.cursor.Set(epos)
 	cpos = s.cursor.Pos()
 
@@ -393,6 +487,18 @@ func (s *Selection) SelectAShellWord() (bpos, epos int) {
 	s.cursor.CheckCommand()
 	s.cursor.ToFirstNonSpace(true)
 
+	// Host tokens already include their quotes, if any.
+	if spans := s.tokens(); spans != nil {
+		bpos, epos = s.line.SelectSpan(s.cursor.Pos(), spans)
+		s.cursor.Set(epos)
+
+		if !s.Active() || bpos < epos {
+			s.Mark(bpos)
+		}
+
+		return bpos, epos
+	}
+
 	sBpos, sEpos := s.line.SurroundQuotes(true, s.cursor.Pos())
 	dBpos, dEpos := s.line.SurroundQuotes(false, s.cursor.Pos())
 
@@ -445,6 +551,16 @@ func (s *Selection) SelectAShellWord() (bpos, epos int) {
 	return bpos, cpos
 }
 
+// tokens returns the token spans of the host tokenizer on
+// the line, or nil if there is no such tokenizer.
+func (s *Selection) tokens() [][]int {
+	if s.Tokens == nil {
+		return nil
+	}
+
+	return s.Tokens(*s.line)
+}
+
 // SelectKeyword attempts to find a pattern in the current blank word
 // around the current cursor position, using various regular expressions.
 // Repeatedly calling this function will cycle through all regex matches,
@@ -523,6 +639,14 @@ func (s *Selection) Cut() (buf string) {
 			offset++
 		}
 
+	case s.IsVisualBlock():
+		regions, _, _ := s.Block()
+		buf = s.Text()
+
+		for i := len(regions) - 1; i >= 0; i-- {
+			s.line.Cut(regions[i][0], regions[i][1])
+		}
+
 	default:
 		bpos, epos := s.Pos()
 		if bpos == -1 || epos == -1 {
@@ -585,13 +709,76 @@ func HighlightMatchers(sel *Selection) {
 	}
 }
 
-// ResetMatchers is used by the display engine
-// to reset matching parens highlighting regions.
+// HighlightMatches adds highlighting to the given ranges of the line,
+// with begin positions included and end positions excluded.
+func HighlightMatches(sel *Selection, matches [][]int) {
+	for _, match := range matches {
+		if match[0] >= match[1] || match[1] > sel.line.Len() {
+			continue
+		}
+
+		sel.surrounds = append(sel.surrounds, Selection{
+			Type:   "matcher",
+			active: true,
+			visual: true,
+			bpos:   match[0],
+			epos:   match[1] - 1,
+			bg:     color.Fmt(color.Bg + "244"),
+			line:   sel.line,
+			cursor: sel.cursor,
+		})
+	}
+}
+
+// HighlightInserted adds the style to the range of the completed line in which
+// a completion candidate is virtually inserted, with the begin position included
+// and the end position excluded.
+func HighlightInserted(sel *Selection, completed *Line, bpos, epos int, style string) {
+	if style == "" || bpos < 0 || bpos >= epos || epos > completed.Len() {
+		return
+	}
+
+	sel.surrounds = append(sel.surrounds, Selection{
+		Type:   "preview",
+		active: true,
+		visual: true,
+		bpos:   bpos,
+		epos:   epos - 1,
+		fg:     style,
+		line:   completed,
+		cursor: sel.cursor,
+	})
+}
+
+// HighlightDiagnostics adds the style to the ranges of the line reported by
+// a checker, with their begin positions included and end positions excluded.
+func HighlightDiagnostics(sel *Selection, spans [][]int, style string) {
+	for _, span := range spans {
+		if style == "" || span[0] < 0 || span[0] >= span[1] || span[1] > sel.line.Len() {
+			continue
+		}
+
+		sel.surrounds = append(sel.surrounds, Selection{
+			Type:   "diagnostic",
+			active: true,
+			visual: true,
+			bpos:   span[0],
+			epos:   span[1] - 1,
+			fg:     style,
+			line:   sel.line,
+			cursor: sel.cursor,
+		})
+	}
+}
+
+// ResetMatchers is used by the display engine to reset matching parens,
+// search matches, inserted candidate and diagnostics highlighting regions.
 func ResetMatchers(sel *Selection) {
 	var surrounds []Selection
 
 	for _, surround := range sel.surrounds {
-		if surround.Type == "matcher" {
+		switch surround.Type {
+		case "matcher", "preview", "diagnostic":
 			continue
 		}
 
@@ -607,6 +794,7 @@ func (s *Selection) Reset() {
 	s.active = false
 	s.visual = false
 	s.visualLine = false
+	s.visualBlock = false
 	s.bpos = -1
 	s.epos = -1
 	s.kpos = 0
@@ -627,6 +815,20 @@ func (s *Selection) Reset() {
 	s.surrounds = surrounds
 }
 
+// lineBounds returns the positions of the first character of the line
+// containing pos, and of the newline ending it (or the end of the buffer).
+func (s *Selection) lineBounds(pos int) (start, end int) {
+	start = s.line.Find(inputrc.Newline, pos, false) + 1
+
+	for end = pos; end < s.line.Len(); end++ {
+		if (*s.line)[end] == inputrc.Newline {
+			break
+		}
+	}
+
+	return start, end
+}
+
 func (s *Selection) checkRange(bpos, epos int) (int, int, bool) {
 	// Return on some on unfixable cases.
 	switch {
@@ -1316,6 +1316,71 @@ func TestSelection_ReplaceWith(t *testing.T) {
 	}
 }
 
+func TestSelection_Block(t *testing.T) {
+	tests := []struct {
+		name        string
+		line        string
+		mark        int
+		cursor      int
+		wantRegions [][]int
+		wantText    string
+		wantBuf     string
+	}{
+		{
+			name:        "Single line",
+			line:        "one two",
+			mark:        1,
+			cursor:      3,
+			wantRegions: [][]int{{1, 4}},
+			wantText:    "ne ",
+			wantBuf:     "otwo",
+		},
+		{
+			name:        "Cursor above and left of the mark",
+			line:        "abcd\nefgh\nijkl",
+			mark:        12,
+			cursor:      1,
+			wantRegions: [][]int{{1, 3}, {6, 8}, {11, 13}},
+			wantText:    "bc\nfg\njk",
+			wantBuf:     "ad\neh\nil",
+		},
+		{
+			name:        "Short and empty lines",
+			line:        "abcd\ne\n\nijkl",
+			mark:        1,
+			cursor:      11,
+			wantRegions: [][]int{{1, 4}, {6, 6}, {7, 7}, {9, 12}},
+			wantText:    "bcd\n\n\njkl",
+			wantBuf:     "a\ne\n\ni",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			line := Line([]rune(test.line))
+			cur := NewCursor(&line)
+			sel := NewSelection(&line, cur)
+
+			cur.Set(test.mark)
+			sel.Mark(test.mark)
+			sel.VisualBlock()
+			cur.Set(test.cursor)
+
+			if regions, _, _ := sel.Block(); !reflect.DeepEqual(regions, test.wantRegions) {
+				t.Errorf("Selection.Block() = %v, want %v", regions, test.wantRegions)
+			}
+
+			if text := sel.Text(); text != test.wantText {
+				t.Errorf("Selection.Text() = %q, want %q", text, test.wantText)
+			}
+
+			if sel.Cut(); string(line) != test.wantBuf {
+				t.Errorf("Selection.Cut() line = %q, want %q", string(line), test.wantBuf)
+			}
+		})
+	}
+}
+
 func TestSelection_Cut(t *testing.T) {
 	emptyline, emptycur := newLine("")
 	line, cur := newLine("multiple-ambiguous 10.203.23.45 127.0.0.1")
@@ -0,0 +1,21 @@
+//go:build !unix && !windows
+
+package display
+
+import "github.com/reeflective/readline/internal/term"
+
+// WatchResume does nothing on platforms where processes cannot be stopped and continued.
+func WatchResume(eng *Engine, resume func() (redisplay bool)) chan<- bool {
+	return make(chan<- bool)
+}
+
+// WatchResize redisplays the interface each time the terminal notifies
+// a resize event, if it can do so: there are no resize signals on those platforms.
+func WatchResize(eng *Engine) chan<- bool {
+	resizer, isResizer := eng.out.Terminal.(term.Resizer)
+	if !isResizer {
+		return make(chan bool, 1)
+	}
+
+	return watchResizer(eng, resizer)
+}
@@ -7,16 +7,33 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+
+	"github.com/reeflective/readline/internal/term"
 )
 
-// WatchResize redisplays the interface on terminal resize events.
+// WatchResize redisplays the interface on terminal resize events: those
+// notified by the terminal itself if it can, or SIGWINCH signals if the
+// terminal is connected to the process files. Other terminals provided
+// by the host are not watched, since those signals are not about them.
 func WatchResize(eng *Engine) chan<- bool {
+	if resizer, isResizer := eng.out.Terminal.(term.Resizer); isResizer {
+		return watchResizer(eng, resizer)
+	}
+
 	done := make(chan bool, 1)
 
-	resizeChannel := make(chan os.Signal)
+	if !term.IsFileTerminal(eng.out.Terminal) {
+		return done
+	}
+
+	// Signals are not delivered if the channel is not ready to receive
+	// them, so it must be buffered not to miss the ones sent while busy.
+	resizeChannel := make(chan os.Signal, 1)
 	signal.Notify(resizeChannel, syscall.SIGWINCH)
 
 	go func() {
+		defer signal.Stop(resizeChannel)
+
 		for {
 			select {
 			case <-resizeChannel:
@@ -29,3 +46,36 @@ func WatchResize(eng *Engine) chan<- bool {
 
 	return done
 }
+
+// WatchResume calls the resume function each time the process is continued
+// after having been stopped, and redisplays the prompt and the interface if
+// the function returns true. Only terminals connected to the process files
+// are watched: those provided by the host are not stopped with the process.
+func WatchResume(eng *Engine, resume func() (redisplay bool)) chan<- bool {
+	done := make(chan bool, 1)
+
+	if !term.IsFileTerminal(eng.out.Terminal) {
+		return done
+	}
+
+	resumeChannel := make(chan os.Signal, 1)
+	signal.Notify(resumeChannel, syscall.SIGCONT)
+
+	go func() {
+		defer signal.Stop(resumeChannel)
+
+		for {
+			select {
+			case <-resumeChannel:
+				if resume() {
+					eng.PrintPrimaryPrompt()
+					eng.Refresh()
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return done
+}
@@ -3,6 +3,11 @@
 
 package display
 
+// WatchResume does nothing on Windows, where processes cannot be stopped and continued.
+func WatchResume(eng *Engine, resume func() (redisplay bool)) chan<- bool {
+	return make(chan<- bool)
+}
+
 // WatchResize redisplays the interface on terminal resize events on Windows.
 // Currently not implemented, see related issue in repo: too buggy right now.
 func WatchResize(eng *Engine) chan<- bool {
@@ -30,9 +35,9 @@ func WatchResize(eng *Engine) chan<- bool {
 	// 				compRows++
 	// 			}
 	//
-	// 			term.MoveCursorBackwards(term.GetWidth())
-	// 			term.MoveCursorUp(compRows)
-	// 			term.MoveCursorUp(ui.CoordinatesHint(eng.hint))
+	// 			eng.out.MoveCursorBackwards(eng.out.Width())
+	// 			eng.out.MoveCursorUp(compRows)
+	// 			eng.out.MoveCursorUp(ui.CoordinatesHint(eng.hint, eng.out.Width()))
 	// 			eng.cursorHintToLineStart()
 	// 			eng.lineStartToCursorPos()
 	// 			fmt.Println(term.ShowCursor)
@@ -2,6 +2,10 @@ package display
 
 import (
 	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
@@ -23,6 +27,7 @@ var (
 type Engine struct {
 	// Operating parameters
 	highlighter    func(line []rune) string
+	status         func() string
 	startCols      int
 	startRows      int
 	lineCol        int
@@ -31,12 +36,32 @@ type Engine struct {
 	cursorCol      int
 	hintRows       int
 	compRows       int
+	viewTop        int
+	viewLines      int
 	primaryPrinted bool
+	altScreen      bool // Completions are displayed on the alternate screen.
+	primaryRow     int  // Cursor row on the primary screen, while on the alternate one.
+	primaryCol     int  // Cursor column on the primary screen, while on the alternate one.
+	lastRefresh    time.Time
+	outdated       bool
+	commentBegin   string
+	accepted       string // The prompt and line last accepted, as displayed, in plain text.
+	comments       *regexp.Regexp
+
+	// Screen reader announcements
+	announces     []string
+	announcedHint string
+	announcedComp string
 
 	// UI components
+	out       *term.Output
+	frame     *term.Screen // The screen on which a frame is rendered, if any.
 	keys      *core.Keys
 	line      *core.Line
 	suggested core.Line
+	suggest   func(line *core.Line, cursor int) core.Line
+	matches   [][]int
+	diagnosed [][]int
 	cursor    *core.Cursor
 	selection *core.Selection
 	histories *history.Sources
@@ -47,8 +72,9 @@ type Engine struct {
 }
 
 // NewEngine is a required constructor for the display engine.
-func NewEngine(k *core.Keys, s *core.Selection, h *history.Sources, p *ui.Prompt, i *ui.Hint, c *completion.Engine, opts *inputrc.Config) *Engine {
+func NewEngine(out *term.Output, k *core.Keys, s *core.Selection, h *history.Sources, p *ui.Prompt, i *ui.Hint, c *completion.Engine, opts *inputrc.Config) *Engine {
 	return &Engine{
+		out:       out,
 		keys:      k,
 		selection: s,
 		histories: h,
@@ -60,23 +86,52 @@ func NewEngine(k *core.Keys, s *core.Selection, h *history.Sources, p *ui.Prompt
 }
 
 // Init computes some base coordinates needed before displaying the line and helpers.
-// The shell syntax highlighter is also provided here, since any consumer library will
-// have bound it after instantiating a new shell instance.
-func Init(e *Engine, highlighter func([]rune) string) {
+// The shell syntax highlighter and line suggester are also provided here, since any
+// consumer library will have bound them after instantiating a new shell instance.
+// If the suggester is nil, lines are suggested from the history. The status returns
+// the pending command displayed in place of the right prompt, if any.
+func Init(e *Engine, highlighter func([]rune) string, suggest func(line *core.Line, cursor int) core.Line, status func() string) {
 	e.highlighter = highlighter
+	e.suggest = suggest
+	e.status = status
+}
+
+// HighlightMatches sets the ranges of the input line (begin positions
+// included, end ones excluded) highlighted as search matches, if any.
+func (e *Engine) HighlightMatches(matches [][]int) {
+	e.matches = matches
+}
+
+// HighlightDiagnostics sets the ranges of the input line (begin positions
+// included, end ones excluded) styled with the diagnostic-style option, as
+// reported by a checker, if any.
+func (e *Engine) HighlightDiagnostics(spans [][]int) {
+	e.diagnosed = spans
 }
 
 // Refresh recomputes and redisplays the entire readline interface, except
 // the first lines of the primary prompt when the latter is a multiline one.
 func (e *Engine) Refresh() {
-	fmt.Print(term.HideCursor)
+	// Display the interface on the alternate screen
+	// if completions are too large for the primary one.
+	e.switchScreen()
+
+	// Announce changes in the helpers as plain text lines below
+	// the input line, if using a screen reader, which also follows
+	// the cursor: it is not hidden while redisplaying.
+	if e.screenReader() {
+		e.announceChanges()
+		e.printAnnounces()
+	} else {
+		fmt.Fprint(e.out, term.HideCursor)
+	}
 
 	// Go back to the first column, and if the primary prompt
 	// was not printed yet, back up to the line's beginning row.
-	term.MoveCursorBackwards(term.GetWidth())
+	e.out.MoveCursorBackwards(e.out.Width())
 
 	if !e.primaryPrinted {
-		term.MoveCursorUp(e.cursorRow)
+		e.out.MoveCursorUp(e.cursorRow)
 	}
 
 	// Print either all or the last line of the prompt.
@@ -88,13 +143,54 @@ func (e *Engine) Refresh() {
 
 	// Print the line, right prompt, hints and completions.
 	e.displayLine()
-	e.prompt.RightPrint(e.lineCol, true)
+	if !e.screenReader() {
+		e.displayRightPrompt()
+	}
 	e.displayHelpers()
 
 	// Go back to the start of the line, then to cursor.
-	e.cursorHintToLineStart()
-	e.lineStartToCursorPos()
-	fmt.Print(term.ShowCursor)
+	if e.screenReader() {
+		e.lineBelowToCursorPos()
+	} else {
+		e.cursorHintToLineStart()
+		e.lineStartToCursorPos()
+		fmt.Fprint(e.out, term.ShowCursor)
+	}
+
+	e.lastRefresh = time.Now()
+	e.outdated = false
+}
+
+// Throttle returns true if the refresh should be skipped because more keys are
+// pending, and because the interface has been refreshed too recently for the
+// maximum redisplay rate (max-redisplay-rate option, in frames per second).
+// The display is then outdated until the next refresh, which is done at the
+// latest when the line is accepted.
+func (e *Engine) Throttle(pending bool) bool {
+	rate := e.opts.GetInt("max-redisplay-rate")
+	if !pending || rate <= 0 {
+		return false
+	}
+
+	e.outdated = time.Since(e.lastRefresh) < time.Second/time.Duration(rate)
+
+	return e.outdated
+}
+
+// StartFreshLine queries the terminal for the cursor position, and if the cursor
+// is not at the beginning of a line (like when the program has printed something
+// without a trailing newline), prints the partial-line-marker option in reverse
+// video and goes to the next line, so that the prompt is not printed after it.
+func (e *Engine) StartFreshLine() {
+	if col, _ := e.keys.GetCursorPos(); col <= 1 {
+		return
+	}
+
+	if marker := strings.Trim(e.opts.GetString("partial-line-marker"), "\""); marker != "" {
+		fmt.Fprint(e.out, color.Reverse+marker+color.ReverseReset)
+	}
+
+	fmt.Fprint(e.out, term.NewlineReturn)
 }
 
 // PrintPrimaryPrompt redraws the primary prompt.
@@ -107,13 +203,16 @@ func (e *Engine) PrintPrimaryPrompt() {
 
 // ClearHelpers clears the hint and completion sections below the line.
 func (e *Engine) ClearHelpers() {
+	// Anything printed below the line is printed on the primary screen.
+	e.exitAlternateScreen()
+
 	e.CursorBelowLine()
-	fmt.Print(term.ClearScreenBelow)
+	fmt.Fprint(e.out, term.ClearScreenBelow)
 
-	term.MoveCursorUp(1)
-	term.MoveCursorUp(e.lineRows)
-	term.MoveCursorDown(e.cursorRow)
-	term.MoveCursorForwards(e.cursorCol)
+	e.out.MoveCursorUp(1)
+	e.out.MoveCursorUp(e.lineRows)
+	e.out.MoveCursorDown(e.cursorRow)
+	e.out.MoveCursorForwards(e.cursorCol)
 }
 
 // ResetHelpers cancels all active hints and completions.
@@ -127,48 +226,93 @@ func (e *Engine) ResetHelpers() {
 // hints, completions and some right prompts, the shell will put the
 // display at the start of the line immediately following the line.
 func (e *Engine) AcceptLine() {
+	// Go back to the primary screen if completions were displayed on the
+	// alternate one: the line displayed there is redisplayed below.
+	primary := e.exitAlternateScreen()
+
+	// Display the final line if some frames have been skipped.
+	if e.outdated && !primary {
+		e.Refresh()
+	}
+
 	e.CursorToLineStart()
 
+	scrolled := e.viewLines > 0 || primary
+
 	e.computeCoordinates(false)
+	e.accepted = e.plainText(e.prompt.PrimaryText())
+
+	// Display all lines of a line scrolled in the viewport, which leaves
+	// the cursor at its end, or go back to the end of the non-suggested line.
+	if scrolled {
+		e.suggested = *e.line
+		e.displayLine()
+	} else {
+		e.out.MoveCursorBackwards(e.out.Width())
+		e.out.MoveCursorDown(e.lineRows)
+		e.out.MoveCursorForwards(e.lineCol)
+	}
 
-	// Go back to the end of the non-suggested line.
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorDown(e.lineRows)
-	term.MoveCursorForwards(e.lineCol)
-	fmt.Print(term.ClearScreenBelow)
+	fmt.Fprint(e.out, term.ClearScreenBelow)
 
 	// Reprint the right-side prompt if it's not a tooltip one.
 	e.prompt.RightPrint(e.lineCol, false)
 
 	// Go below this non-suggested line and clear everything.
-	term.MoveCursorBackwards(term.GetWidth())
-	fmt.Print(term.NewlineReturn)
+	e.out.MoveCursorBackwards(e.out.Width())
+	fmt.Fprint(e.out, term.NewlineReturn)
 }
 
 // RefreshTransient goes back to the first line of the input buffer
 // and displays the transient prompt, then redisplays the input line.
 func (e *Engine) RefreshTransient() {
-	if !e.opts.GetBool("prompt-transient") {
+	if !e.opts.GetBool("transient-prompt") {
 		return
 	}
 
+	if transient, ok := e.prompt.TransientText(); ok {
+		e.accepted = e.plainText(transient)
+	}
+
 	// Go to the beginning of the primary prompt.
 	e.CursorToLineStart()
-	term.MoveCursorUp(e.prompt.PrimaryUsed())
+	e.out.MoveCursorUp(e.prompt.PrimaryUsed())
 
 	// And redisplay the transient/primary/line.
 	e.prompt.TransientPrint()
 	e.displayLine()
-	fmt.Print(term.NewlineReturn)
+	fmt.Fprint(e.out, term.NewlineReturn)
+}
+
+// AcceptedText returns the prompt and the input line last accepted, as they
+// were left displayed (that is, with the transient prompt if any), stripped
+// of any escape sequence. Lines following the first one are indented like
+// on screen, and the right prompt is not included.
+func (e *Engine) AcceptedText() string {
+	return e.accepted
+}
+
+// plainText returns the prompt followed by the input line, as
+// displayed after this prompt, but without escape sequences.
+func (e *Engine) plainText(prompt string) string {
+	line := string(*e.line)
+	if e.highlighter != nil && !e.screenReader() {
+		line = color.Strip(e.highlighter(*e.line))
+	}
+
+	line = strutil.FormatTabs(line)
+	line = strings.ReplaceAll(line, "\n", "\n"+strings.Repeat(" ", e.startCols))
+
+	return prompt + line
 }
 
 // CursorToLineStart moves the cursor just after the primary prompt.
 // This function should only be called when the cursor is on its
 // "cursor" position on the input line.
 func (e *Engine) CursorToLineStart() {
-	term.MoveCursorBackwards(e.cursorCol)
-	term.MoveCursorUp(e.cursorRow)
-	term.MoveCursorForwards(e.startCols)
+	e.out.MoveCursorBackwards(e.cursorCol)
+	e.out.MoveCursorUp(e.cursorRow)
+	e.out.MoveCursorForwards(e.startCols)
 }
 
 // CursorBelowLine moves the cursor to the leftmost
@@ -176,39 +320,53 @@ func (e *Engine) CursorToLineStart() {
 // This function should only be called when the cursor
 // is on its "cursor" position on the input line.
 func (e *Engine) CursorBelowLine() {
-	term.MoveCursorUp(e.cursorRow)
-	term.MoveCursorDown(e.lineRows)
-	fmt.Print(term.NewlineReturn)
+	e.out.MoveCursorUp(e.cursorRow)
+	e.out.MoveCursorDown(e.lineRows)
+	fmt.Fprint(e.out, term.NewlineReturn)
 }
 
 // lineStartToCursorPos can be used if the cursor is currently
 // at the very start of the input line, that is just after the
 // last character of the prompt.
 func (e *Engine) lineStartToCursorPos() {
-	term.MoveCursorDown(e.cursorRow)
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorForwards(e.cursorCol)
+	e.out.MoveCursorDown(e.cursorRow)
+	e.out.MoveCursorBackwards(e.out.Width())
+	e.out.MoveCursorForwards(e.cursorCol)
+}
+
+// lineBelowToCursorPos moves the cursor straight to its position on the
+// input line, from the first column of the row below the last line of input,
+// so that screen readers have fewer cursor jumps to follow.
+func (e *Engine) lineBelowToCursorPos() {
+	e.out.MoveCursorUp(1 + e.lineRows - e.cursorRow)
+	e.out.MoveCursorForwards(e.cursorCol)
 }
 
 // cursor is on the line below the last line of input.
 func (e *Engine) cursorHintToLineStart() {
-	term.MoveCursorUp(1)
-	term.MoveCursorUp(e.lineRows - e.cursorRow)
+	e.out.MoveCursorUp(1)
+	e.out.MoveCursorUp(e.lineRows - e.cursorRow)
 	e.CursorToLineStart()
 }
 
-func (e *Engine) computeCoordinates(suggested bool) {
+// computeCoordinates computes the coordinates of the line and cursor to display,
+// with the suggested line if refreshing. Otherwise, the line is being accepted,
+// and the coordinates are those of the whole line, even if it is scrolled.
+func (e *Engine) computeCoordinates(refresh bool) {
 	// Get the new input line and auto-suggested one.
 	e.line, e.cursor = e.completer.Line()
-	if e.completer.IsInserting() {
+	switch {
+	case e.completer.IsInserting():
 		e.suggested = *e.line
-	} else {
+	case e.suggest != nil:
+		e.suggested = e.suggest(e.line, e.cursor.Pos())
+	default:
 		e.suggested = e.histories.Suggest(e.line)
 	}
 
 	// Get the position of the line's beginning by querying
 	// the terminal for the cursor position.
-	e.startCols, e.startRows = e.keys.GetCursorPos()
+	e.startCols, e.startRows = e.cursorPos()
 
 	if e.startCols > 0 {
 		e.startCols--
@@ -219,13 +377,23 @@ func (e *Engine) computeCoordinates(suggested bool) {
 		e.startCols = e.prompt.LastUsed()
 	}
 
-	e.cursorCol, e.cursorRow = core.CoordinatesCursor(e.cursor, e.startCols)
+	// Only use the lines displayed in the viewport, if the line is scrolled.
+	if refresh {
+		e.updateViewport()
+	} else {
+		e.viewTop, e.viewLines = 0, 0
+	}
+
+	line, cursor := e.viewLine(*e.line), e.viewCursor()
+	suggested := e.viewLine(e.suggested)
+
+	e.cursorCol, e.cursorRow = core.CoordinatesCursor(cursor, e.startCols, e.out.Width())
 
 	// Get the number of rows used by the line, and the end line X pos.
-	if e.opts.GetBool("history-autosuggest") && suggested {
-		e.lineCol, e.lineRows = core.CoordinatesLine(&e.suggested, e.startCols)
+	if e.opts.GetBool("history-autosuggest") && refresh && !e.screenReader() {
+		e.lineCol, e.lineRows = core.CoordinatesLine(&suggested, e.startCols, e.out.Width())
 	} else {
-		e.lineCol, e.lineRows = core.CoordinatesLine(e.line, e.startCols)
+		e.lineCol, e.lineRows = core.CoordinatesLine(&line, e.startCols, e.out.Width())
 	}
 
 	e.primaryPrinted = false
@@ -235,7 +403,7 @@ func (e *Engine) displayLine() {
 	var line string
 
 	// Apply user-defined highlighter to the input line.
-	if e.highlighter != nil {
+	if e.highlighter != nil && !e.screenReader() {
 		line = e.highlighter(*e.line)
 	} else {
 		line = string(*e.line)
@@ -247,53 +415,138 @@ func (e *Engine) displayLine() {
 		defer core.ResetMatchers(e.selection)
 	}
 
+	// Highlight search matches in the line, if any.
+	if len(e.matches) > 0 && !e.completer.IsInserting() {
+		core.HighlightMatches(e.selection, e.matches)
+		defer core.ResetMatchers(e.selection)
+	}
+
+	// Underline the spans reported by the line checker, if any.
+	if len(e.diagnosed) > 0 && !e.completer.IsInserting() {
+		style := color.UnquoteRC(e.opts.GetString("diagnostic-style"))
+		core.HighlightDiagnostics(e.selection, e.diagnosed, style)
+		defer core.ResetMatchers(e.selection)
+	}
+
+	// Highlight the candidate virtually inserted, if any.
+	if bpos, epos := e.completer.Inserted(); bpos != -1 {
+		style := color.UnquoteRC(e.opts.GetString("completion-preview-style"))
+		core.HighlightInserted(e.selection, e.line, bpos, epos, style)
+		defer core.ResetMatchers(e.selection)
+	}
+
 	// Apply visual selections highlighting if any
 	line = e.highlightLine([]rune(line), *e.selection)
 
 	// Get the subset of the suggested line to print.
-	if len(e.suggested) > e.line.Len() && e.opts.GetBool("history-autosuggest") {
+	if len(e.suggested) > e.line.Len() && e.opts.GetBool("history-autosuggest") && !e.screenReader() {
 		line += color.Dim + color.Fmt(color.Fg+"242") + string(e.suggested[e.line.Len():]) + color.Reset
 	}
 
+	// Only display the lines in the viewport, if the line is scrolled.
+	line = e.viewText(line)
+
 	// Format tabs as spaces, for consistent display
 	line = strutil.FormatTabs(line) + term.ClearLineAfter
 
 	// And display the line.
 	e.suggested.Set([]rune(line)...)
-	core.DisplayLine(&e.suggested, e.startCols)
+	core.DisplayLine(e.out, &e.suggested, e.startCols)
 
 	// Adjust the cursor if the line fits exactly in the terminal width.
 	if e.lineCol == 0 {
-		fmt.Print(term.NewlineReturn)
-		fmt.Print(term.ClearLineAfter)
+		fmt.Fprint(e.out, term.NewlineReturn)
+		fmt.Fprint(e.out, term.ClearLineAfter)
 	}
 }
 
+// displayRightPrompt prints the pending command, if any and if the
+// show-pending-command option is enabled, or the right prompt otherwise.
+func (e *Engine) displayRightPrompt() {
+	if e.status != nil && e.opts.GetBool("show-pending-command") {
+		if status := e.status(); status != "" {
+			e.prompt.StatusPrint(status, e.lineCol)
+			return
+		}
+	}
+
+	e.prompt.RightPrint(e.lineCol, true)
+}
+
 // displayHelpers renders the hint and completion sections.
 // It assumes that the cursor is on the last line of input,
 // and goes back to this same line after displaying this.
 func (e *Engine) displayHelpers() {
-	fmt.Print(term.NewlineReturn)
+	fmt.Fprint(e.out, term.NewlineReturn)
 
 	// Recompute completions and hints if autocompletion is on.
 	e.completer.Autocomplete()
 
-	// Display hint and completions.
-	ui.DisplayHint(e.hint)
-	e.hintRows = ui.CoordinatesHint(e.hint)
-	completion.Display(e.completer, e.AvailableHelperLines())
+	// Screen readers are notified of changes in the helpers
+	// with plain text lines: don't display them below the line.
+	if e.screenReader() {
+		e.hint.Expire()
+		e.hintRows, e.compRows = 0, 0
+
+		fmt.Fprint(e.out, term.ClearScreenBelow)
+
+		return
+	}
+
+	// Display hint and completions, within the rows budget if any.
+	hintRows, compRows := e.helperRows()
+
+	e.hint.SetMaxRows(hintRows)
+	ui.DisplayHint(e.out, e.hint)
+	e.hintRows = ui.CoordinatesHint(e.hint, e.out.Width())
+	completion.Display(e.completer, min(compRows, e.AvailableHelperLines()))
 	e.compRows = completion.Coordinates(e.completer)
 
 	// Go back to the first line below the input line.
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorUp(e.compRows)
-	term.MoveCursorUp(ui.CoordinatesHint(e.hint))
+	e.out.MoveCursorBackwards(e.out.Width())
+	e.out.MoveCursorUp(e.compRows)
+	e.out.MoveCursorUp(ui.CoordinatesHint(e.hint, e.out.Width()))
+}
+
+// helperRows returns the maximum number of rows used by the hint and the
+// completions when the max-helper-rows option is set: the section with the
+// priority set by the helper-priority option keeps all the rows it needs,
+// while leaving at least one row to the hint, or two to the completions.
+// Without a budget, the hint is not limited, and completions use all rows.
+func (e *Engine) helperRows() (hint, comps int) {
+	e.hint.SetMaxRows(0)
+
+	budget := e.opts.GetInt("max-helper-rows")
+	if budget <= 0 {
+		return 0, math.MaxInt
+	}
+
+	hintRows := ui.CoordinatesHint(e.hint, e.out.Width())
+	compRows := completion.Rows(e.completer)
+
+	// A hint always keeps one row, even if the budget is too small.
+	switch {
+	case hintRows == 0:
+	case strings.Trim(e.opts.GetString("helper-priority"), "\"") == "completions":
+		hintRows = max(min(hintRows, budget-compRows), 1)
+	default:
+		hintRows = max(min(hintRows, budget-min(compRows, 2)), 1)
+	}
+
+	// Completions are cropped with a row left for the number of
+	// those not displayed: this row is not needed if all fit.
+	comps = max(budget-hintRows, 1)
+	if compRows == comps {
+		comps++
+	}
+
+	return hintRows, comps
 }
 
 // AvailableHelperLines returns the number of lines available below the hint section.
 // It returns half the terminal space if we currently have less than 1/3rd of it below.
 func (e *Engine) AvailableHelperLines() int {
-	termHeight := term.GetLength()
+	termHeight := e.out.Height()
 	compLines := termHeight - e.startRows - e.lineRows - e.hintRows
 
 	if compLines < (termHeight / oneThirdTerminalHeight) {
@@ -302,3 +555,156 @@ func (e *Engine) AvailableHelperLines() int {
 
 	return compLines
 }
+
+// switchScreen switches to the alternate screen when the alternate-screen option
+// is enabled and the completions (including history and undo menus) need more rows
+// than available below the line, and back to the primary screen once they are
+// cleared: large menus thus never end up in the terminal scrollback.
+func (e *Engine) switchScreen() {
+	rows := completion.Rows(e.completer)
+
+	switch {
+	case e.frame != nil:
+	case e.altScreen && (rows == 0 || !e.opts.GetBool("alternate-screen")):
+		e.exitAlternateScreen()
+	case e.altScreen, e.screenReader(), !e.opts.GetBool("alternate-screen"):
+	case rows > e.AvailableHelperLines():
+		e.enterAlternateScreen()
+	}
+}
+
+// enterAlternateScreen switches to the alternate screen, saving the cursor
+// position on the primary one, and prints all lines of the primary prompt
+// at its top, so that the next refresh displays the interface below them.
+func (e *Engine) enterAlternateScreen() {
+	e.altScreen = true
+	e.primaryRow, e.primaryCol = e.cursorRow, e.cursorCol
+
+	fmt.Fprint(e.out, term.AlternateScreen+term.CursorTopLeft)
+	e.PrintPrimaryPrompt()
+}
+
+// exitAlternateScreen switches back to the primary screen if the alternate one
+// is displayed, and returns true if so. The terminal restores the cursor where
+// it was on the input line, for which the previous coordinates are restored.
+func (e *Engine) exitAlternateScreen() bool {
+	if !e.altScreen {
+		return false
+	}
+
+	e.altScreen = false
+	e.cursorRow, e.cursorCol = e.primaryRow, e.primaryCol
+	e.primaryPrinted = false
+
+	fmt.Fprint(e.out, term.PrimaryScreen)
+
+	return true
+}
+
+// Announce prints a message as a plain text line below the input line at the
+// next refresh (the prompt and line being printed again below it), when the
+// screen-reader option is enabled, so that screen readers can notify users
+// of changes that are otherwise only displayed.
+func (e *Engine) Announce(message string) {
+	if !e.screenReader() || message == "" {
+		return
+	}
+
+	e.announces = append(e.announces, color.Strip(message))
+}
+
+// announceChanges announces the hint and the selected completion
+// candidate (with its description) if they changed since last time.
+func (e *Engine) announceChanges() {
+	hint := strings.TrimSpace(color.Strip(e.hint.Text()))
+	if hint != e.announcedHint {
+		e.Announce(hint)
+		e.announcedHint = hint
+	}
+
+	var comp string
+
+	if selected := e.completer.Selected(); selected.Value != "" {
+		comp = selected.Value
+		if selected.Description != "" {
+			comp += ": " + selected.Description
+		}
+	}
+
+	if comp != e.announcedComp {
+		e.Announce(comp)
+		e.announcedComp = comp
+	}
+}
+
+// printAnnounces prints all pending announcements below the input
+// line, and reprints the primary prompt below them.
+func (e *Engine) printAnnounces() {
+	if len(e.announces) == 0 {
+		return
+	}
+
+	e.CursorBelowLine()
+	fmt.Fprint(e.out, term.ClearScreenBelow)
+
+	for _, message := range e.announces {
+		fmt.Fprint(e.out, message+term.NewlineReturn)
+	}
+
+	e.announces = nil
+	e.PrintPrimaryPrompt()
+}
+
+func (e *Engine) screenReader() bool {
+	return e.opts.GetBool("screen-reader")
+}
+
+// Frame renders the entire interface (all lines of the primary prompt, the
+// input line and the helpers) off-screen, on the given screen, starting from
+// its current cursor position. Nothing is written to the terminal, and the
+// display state used by the next refresh is left unchanged.
+func (e *Engine) Frame(screen *term.Screen) {
+	state, output := *e, *e.out
+	restoreHint := e.hint.Snapshot()
+
+	defer func() {
+		*e, *e.out = state, output
+		restoreHint()
+	}()
+
+	e.out.Writer = screen
+	e.frame = screen
+
+	e.PrintPrimaryPrompt()
+	e.Refresh()
+}
+
+// cursorPos returns the current cursor position (1-based), queried from
+// the terminal, or from the screen on which a frame is being rendered.
+func (e *Engine) cursorPos() (x, y int) {
+	if e.frame != nil {
+		x, y = e.frame.Cursor()
+		return x + 1, y + 1
+	}
+
+	return e.keys.GetCursorPos()
+}
+
+// watchResizer redisplays the interface each time the terminal
+// notifies a resize event, until the returned channel is closed.
+func watchResizer(eng *Engine, resizer term.Resizer) chan<- bool {
+	done := make(chan bool, 1)
+
+	go func() {
+		for {
+			select {
+			case <-resizer.Resized():
+				eng.Refresh()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return done
+}
@@ -11,6 +11,19 @@ import (
 	"github.com/reeflective/readline/internal/core"
 )
 
+var (
+	rxColor        = regexp.MustCompile(`\x1b\[[0-9;]+m`)
+	commentReplace = color.SGRStart + color.Fg + "244" + color.SGREnd + "${0}" + color.Reset
+
+	// previewReset resets the effects and colors commonly
+	// used to style the completion candidate being inserted.
+	previewReset = color.DimReset + color.UnderscoreReset + color.ReverseReset + color.FgDefault + color.BgDefault
+
+	// diagnosticReset also resets the underline color, commonly
+	// set along with a curly underline to style diagnostics.
+	diagnosticReset = previewReset + "\x1b[59m"
+)
+
 // highlightLine applies visual/selection highlighting to a line.
 // The provided line might already have been highlighted by a user-provided
 // highlighter: this function accounts for any embedded color sequences.
@@ -19,29 +32,42 @@ func (e *Engine) highlightLine(line []rune, selection core.Selection) string {
 	sorted := sortHighlights(selection)
 	colors := e.getHighlights(line, sorted)
 
-	var highlighted string
+	var highlighted strings.Builder
+
+	highlighted.Grow(len(line) + len(color.Reset))
 
 	// And apply highlighting before each rune.
 	for i, r := range line {
 		if highlight, found := colors[i]; found {
-			highlighted += string(highlight)
+			highlighted.WriteString(string(highlight))
 		}
 
-		highlighted += string(r)
+		highlighted.WriteRune(r)
 	}
 
 	// Finally, highlight comments using a regex.
+	hlLine := highlighted.String()
+
+	if commentsMatch := e.commentRegexp(); commentsMatch != nil {
+		hlLine = commentsMatch.ReplaceAllString(hlLine, commentReplace)
+	}
+
+	return hlLine + color.Reset
+}
+
+// commentRegexp returns the regular expression matching comments in the line,
+// which is only compiled again when the comment-begin option has changed.
+func (e *Engine) commentRegexp() *regexp.Regexp {
 	comment := strings.Trim(e.opts.GetString("comment-begin"), "\"")
-	commentPattern := fmt.Sprintf(`(^|\s)%s.*`, comment)
 
-	if commentsMatch, err := regexp.Compile(commentPattern); err == nil {
-		commentColor := color.SGRStart + color.Fg + "244" + color.SGREnd
-		highlighted = commentsMatch.ReplaceAllString(highlighted, fmt.Sprintf("%s${0}%s", commentColor, color.Reset))
+	if e.comments != nil && comment == e.commentBegin {
+		return e.comments
 	}
 
-	highlighted += color.Reset
+	e.commentBegin = comment
+	e.comments, _ = regexp.Compile(fmt.Sprintf(`(^|\s)%s.*`, comment))
 
-	return highlighted
+	return e.comments
 }
 
 func sortHighlights(vhl core.Selection) []core.Selection {
@@ -55,7 +81,14 @@ func sortHighlights(vhl core.Selection) []core.Selection {
 		bpos = append(bpos, rbpos)
 	}
 
-	if vhl.Active() && vhl.IsVisual() {
+	switch {
+	case vhl.IsVisualBlock():
+		for _, reg := range vhl.BlockRegions() {
+			all = append(all, reg)
+			rbpos, _ := reg.Pos()
+			bpos = append(bpos, rbpos)
+		}
+	case vhl.Active() && vhl.IsVisual():
 		all = append(all, vhl)
 		vbpos, _ := vhl.Pos()
 		bpos = append(bpos, vbpos)
@@ -98,12 +131,17 @@ func sortHighlights(vhl core.Selection) []core.Selection {
 func (e *Engine) getHighlights(line []rune, sorted []core.Selection) map[int][]rune {
 	highlights := make(map[int][]rune)
 
+	// Without regions to highlight, we don't even need to
+	// look for colors already applied to the line.
+	if len(sorted) == 0 {
+		return highlights
+	}
+
 	// Find any highlighting already applied on the line,
 	// and keep the indexes so that we can skip those.
 	var colors [][]int
 
-	colorMatch := regexp.MustCompile(`\x1b\[[0-9;]+m`)
-	colors = colorMatch.FindAllStringIndex(string(line), -1)
+	colors = rxColor.FindAllStringIndex(string(line), -1)
 
 	// marks that started highlighting, but not done yet.
 	regions := make([]core.Selection, 0)
@@ -203,6 +241,16 @@ func (e *Engine) hlReset(regions []core.Selection, line []rune, pos int) ([]core
 			regions = regions[:i]
 		}
 
+		// Inserted candidates and diagnostics can be styled with any effect.
+		switch reg.Type {
+		case "preview":
+			line = append(line, []rune(previewReset)...)
+			continue
+		case "diagnostic":
+			line = append(line, []rune(diagnosticReset)...)
+			continue
+		}
+
 		if foreground != "" {
 			line = append(line, []rune(color.FgDefault)...)
 		}
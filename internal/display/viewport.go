@@ -0,0 +1,118 @@
+package display
+
+import (
+	"strings"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/core"
+)
+
+// Viewport returns the index of the first line of the input buffer displayed,
+// and the number of lines displayed. Unless the buffer has more lines than the
+// max-display-lines option, all of them are displayed.
+func (e *Engine) Viewport() (top, lines int) {
+	if e.viewLines > 0 {
+		return e.viewTop, e.viewLines
+	}
+
+	line, _ := e.completer.Line()
+
+	return 0, line.Lines() + 1
+}
+
+// Scroll sets the first line of the input buffer displayed at the next refresh,
+// if the buffer has more lines than the max-display-lines option. The viewport
+// is still scrolled so that the cursor line is displayed, if it would not be.
+func (e *Engine) Scroll(top int) {
+	e.viewTop = top
+}
+
+// updateViewport scrolls the viewport to display the cursor line, if the
+// buffer has more lines than the max-display-lines option. The viewport
+// is only scrolled by the number of lines needed for it.
+func (e *Engine) updateViewport() {
+	limit := e.opts.GetInt("max-display-lines")
+	lines := e.line.Lines() + 1
+
+	if limit <= 0 || lines <= limit {
+		e.viewTop, e.viewLines = 0, 0
+		return
+	}
+
+	cursorLine := e.cursor.LinePos()
+
+	switch {
+	case cursorLine < e.viewTop:
+		e.viewTop = cursorLine
+	case cursorLine >= e.viewTop+limit:
+		e.viewTop = cursorLine - limit + 1
+	}
+
+	e.viewTop = max(min(e.viewTop, lines-limit), 0)
+	e.viewLines = limit
+}
+
+// viewLine returns the lines of a buffer displayed in the viewport.
+func (e *Engine) viewLine(line core.Line) core.Line {
+	if e.viewLines == 0 {
+		return line
+	}
+
+	bpos, epos := viewRange(line, e.viewTop, e.viewLines)
+
+	return line[bpos:epos]
+}
+
+// viewCursor returns a cursor on the lines displayed in the
+// viewport, at the same position as the input line cursor.
+func (e *Engine) viewCursor() *core.Cursor {
+	if e.viewLines == 0 {
+		return e.cursor
+	}
+
+	bpos, epos := viewRange(*e.line, e.viewTop, e.viewLines)
+	view := (*e.line)[bpos:epos]
+
+	cursor := core.NewCursor(&view)
+	cursor.Set(e.cursor.Pos() - bpos)
+
+	return cursor
+}
+
+// viewText returns the lines of the highlighted line displayed in the viewport.
+func (e *Engine) viewText(line string) string {
+	if e.viewLines == 0 {
+		return line
+	}
+
+	lines := strings.Split(line, string(inputrc.Newline))
+	top := min(e.viewTop, len(lines))
+
+	return strings.Join(lines[top:min(top+e.viewLines, len(lines))], string(inputrc.Newline))
+}
+
+// viewRange returns the begin and end positions of a
+// number of lines of a buffer, starting at the top one.
+func viewRange(line []rune, top, count int) (bpos, epos int) {
+	epos = len(line)
+	lines := 0
+
+	for pos, char := range line {
+		if char != inputrc.Newline {
+			continue
+		}
+
+		lines++
+
+		if lines == top {
+			bpos = pos + 1
+		}
+
+		if lines == top+count {
+			epos = pos
+			break
+		}
+	}
+
+	return bpos, epos
+}
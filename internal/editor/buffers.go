@@ -10,6 +10,7 @@ import (
 
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/locale"
 )
 
 var (
@@ -20,54 +21,87 @@ var (
 	alphaRegisters = 52
 )
 
+// Registers with special meanings: the unnamed register (the kill
+// buffer), the clipboard ones, and the black hole discarding writes.
+const (
+	unnamedRegister   = '"'
+	clipboardRegister = '+'
+	selectionRegister = '*'
+	blackHoleRegister = '_'
+)
+
 // Buffers is a list of registers in which to put yanked/cut contents.
 // These buffers technically are Vim registers with full functionality.
 type Buffers struct {
-	num      map[int][]rune  // numbered registers (0-9)
-	alpha    map[rune][]rune // lettered registers ( a-z )
-	ro       map[rune][]rune // read-only registers ( . % : )
-	waiting  bool            // The user wants to use a still unidentified register
-	selected bool            // We have identified the register, and acting on it.
-	active   rune            // Any of the read/write registers ("/num/alpha)
-	mutex    *sync.Mutex
+	num       map[int][]rune      // numbered registers (0-9)
+	alpha     map[rune][]rune     // lettered registers ( a-z )
+	ro        map[rune][]rune     // read-only registers ( . % : )
+	clip      []rune              // clipboard registers ( + * )
+	clipboard func(text string)   // Copies the clipboard registers to the system clipboard.
+	waiting   bool                // The user wants to use a still unidentified register
+	selected  bool                // We have identified the register, and acting on it.
+	active    rune                // Any of the read/write registers ("/num/alpha)
+	locale    *locale.Catalog     // Translates the registers completion hint.
+	getenv    func(string) string // Reads VISUAL and EDITOR, if not from the process environment.
+	mutex     *sync.Mutex
 }
 
 // NewBuffers is a required constructor to set up all the buffers/registers
 // for the shell, because it contains maps that must be correctly initialized.
-func NewBuffers() *Buffers {
+func NewBuffers(l *locale.Catalog) *Buffers {
 	return &Buffers{
-		num:   make(map[int][]rune, numRegisters),
-		alpha: make(map[rune][]rune, alphaRegisters),
-		ro:    map[rune][]rune{},
-		mutex: &sync.Mutex{},
+		num:    make(map[int][]rune, numRegisters),
+		alpha:  make(map[rune][]rune, alphaRegisters),
+		ro:     map[rune][]rune{},
+		locale: l,
+		mutex:  &sync.Mutex{},
 	}
 }
 
+// SetEnv sets the function reading the environment variables selecting
+// the system editor, instead of the process environment. A nil function
+// restores the latter.
+func (reg *Buffers) SetEnv(getenv func(key string) string) {
+	reg.getenv = getenv
+}
+
+// SetClipboard sets the function copying the contents written to the
+// clipboard registers (+ and *) to the system clipboard, if any.
+func (reg *Buffers) SetClipboard(write func(text string)) {
+	reg.clipboard = write
+}
+
 // SetActive sets the currently active register/buffer.
-// Valid values are letters (lower/upper), digits (1-9),
-// or read-only buffers ( . % : ).
+// Valid values are letters (lower/upper), digits (0-9), the unnamed
+// register ("), the clipboard ones (+ *), the black hole (_), or
+// read-only buffers ( . % : ). Invalid names select no register.
 func (reg *Buffers) SetActive(register rune) {
-	defer func() {
-		// We now have an active, identified register
-		reg.waiting = false
-		reg.selected = true
-	}()
-
-	// Numbered
-	num, err := strconv.Atoi(string(register))
-	if err == nil && num < 10 {
-		reg.active = register
+	if !reg.IsValid(register) {
+		reg.Reset()
 		return
 	}
-	// Read-only
-	if _, found := reg.ro[register]; found {
-		reg.active = register
 
-		return
+	reg.active = register
+
+	// We now have an active, identified register
+	reg.waiting = false
+	reg.selected = true
+}
+
+// IsValid returns true if the rune is the name of a register.
+func (reg *Buffers) IsValid(register rune) bool {
+	switch register {
+	case unnamedRegister, clipboardRegister, selectionRegister, blackHoleRegister:
+		return true
 	}
 
-	// Else, lettered
-	reg.active = register
+	if _, found := reg.ro[register]; found {
+		return true
+	}
+
+	return (register >= '0' && register <= '9') ||
+		(register >= 'a' && register <= 'z') ||
+		(register >= 'A' && register <= 'Z')
 }
 
 // Get returns the contents of a given register.
@@ -75,8 +109,13 @@ func (reg *Buffers) SetActive(register rune) {
 // If the rune is an alphanumeric comprised in the valid register IDs, their content is returned.
 // If the register name is invalid, the function returns an empty rune slice.
 func (reg *Buffers) Get(register rune) []rune {
-	if register == 0 {
+	switch register {
+	case 0, unnamedRegister:
 		return reg.GetKill()
+	case clipboardRegister, selectionRegister:
+		return reg.clip
+	case blackHoleRegister:
+		return nil
 	}
 
 	num, err := strconv.Atoi(string(register))
@@ -107,23 +146,29 @@ func (reg *Buffers) Active() []rune {
 	return reg.Get(reg.active)
 }
 
-// Pop rotates the kill ring and returns the new top.
+// Pop rotates the kill ring and returns the new top:
+// the former top goes to the bottom of the ring.
 func (reg *Buffers) Pop() []rune {
 	if len(reg.num) == 0 {
 		return nil
 	}
 
-	// Reassign the kill buffer and
-	// pop the first numbered register.
-	buf := string(reg.num[0])
-	delete(reg.num, 0)
+	ring := make([]int, 0, len(reg.num))
+	for num := range reg.num {
+		ring = append(ring, num)
+	}
+
+	sort.Ints(ring)
 
-	for i := 0; i < len(reg.num); i++ {
-		reg.num[i] = []rune(string(reg.num[i+1]))
-		delete(reg.num, i+1)
+	top := reg.num[ring[0]]
+
+	for i := 0; i < len(ring)-1; i++ {
+		reg.num[ring[i]] = reg.num[ring[i+1]]
 	}
 
-	return []rune(buf)
+	reg.num[ring[len(ring)-1]] = top
+
+	return reg.num[ring[0]]
 }
 
 // GetKill returns the contents of the kill buffer.
@@ -163,14 +208,20 @@ func (reg *Buffers) WriteTo(register rune, content ...rune) {
 		return
 	}
 
-	if register == 0 {
+	switch register {
+	case 0, unnamedRegister:
 		reg.writeNum(0, []rune(buf))
 		return
+	case clipboardRegister, selectionRegister:
+		reg.writeClipboard([]rune(buf))
+		return
+	case blackHoleRegister:
+		return
 	}
 
 	// If number register.
 	num, err := strconv.Atoi(string(register))
-	if num > 0 && num < 10 && err != nil {
+	if num >= 0 && num < 10 && err == nil {
 		reg.writeNum(num, []rune(buf))
 		return
 	}
@@ -188,6 +239,46 @@ func (reg *Buffers) IsSelected() (name string, selected bool) {
 	return string(reg.active), reg.selected
 }
 
+// Filled returns the names of all registers with some contents,
+// in the order in which they are listed by Complete: numbered,
+// lettered, clipboard, then read-only registers.
+func (reg *Buffers) Filled() []rune {
+	var names []rune
+
+	for num := 0; num < numRegisters; num++ {
+		if len(reg.num[num]) > 0 {
+			names = append(names, rune('0'+num))
+		}
+	}
+
+	var letters []rune
+
+	for letter, buf := range reg.alpha {
+		if len(buf) > 0 {
+			letters = append(letters, letter)
+		}
+	}
+
+	sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+	names = append(names, letters...)
+
+	if len(reg.clip) > 0 {
+		names = append(names, clipboardRegister)
+	}
+
+	var readOnly []rune
+
+	for name, buf := range reg.ro {
+		if len(buf) > 0 {
+			readOnly = append(readOnly, name)
+		}
+	}
+
+	sort.Slice(readOnly, func(i, j int) bool { return readOnly[i] < readOnly[j] })
+
+	return append(names, readOnly...)
+}
+
 // Reset forgets any active/pending buffer/register, but does not delete its contents.
 func (reg *Buffers) Reset() {
 	reg.active = 0
@@ -202,6 +293,7 @@ func (reg *Buffers) Complete() completion.Values {
 	// Alpha and numbered registers
 	vals = append(vals, reg.completeNumRegs()...)
 	vals = append(vals, reg.completeAlphaRegs()...)
+	vals = append(vals, reg.completeClipboard()...)
 
 	// Disable sorting, force list long and add hint.
 	comps := completion.AddRaw(vals)
@@ -218,10 +310,10 @@ func (reg *Buffers) Complete() completion.Values {
 	comps.ListLong["*"] = true
 
 	// Registers Hint
-	hint := color.Bold + color.FgBlue + "(registers)"
+	hint := color.Bold + color.FgBlue + reg.locale.Get(locale.Registers)
 
 	if len(vals) == 0 {
-		hint += " - empty -"
+		hint += reg.locale.Get(locale.RegistersEmpty)
 	}
 
 	comps.Messages.Add(hint)
@@ -229,6 +321,40 @@ func (reg *Buffers) Complete() completion.Values {
 	return comps
 }
 
+// Export returns the contents of all numbered and lettered registers,
+// indexed by their names, for instance to save them to a file.
+func (reg *Buffers) Export() map[string]string {
+	registers := make(map[string]string, len(reg.num)+len(reg.alpha))
+
+	for num, buf := range reg.num {
+		registers[strconv.Itoa(num)] = string(buf)
+	}
+
+	for letter, buf := range reg.alpha {
+		registers[string(letter)] = string(buf)
+	}
+
+	return registers
+}
+
+// Import sets the contents of numbered and lettered registers, as returned
+// by Export. Registers with invalid names are ignored.
+func (reg *Buffers) Import(registers map[string]string) {
+	for name, buf := range registers {
+		register := []rune(name)
+		if len(register) != 1 {
+			continue
+		}
+
+		switch {
+		case register[0] >= '0' && register[0] <= '9':
+			reg.num[int(register[0]-'0')] = []rune(buf)
+		case unicode.IsLetter(register[0]):
+			reg.alpha[register[0]] = []rune(buf)
+		}
+	}
+}
+
 func (reg *Buffers) writeNum(register int, buf []rune) {
 	// No numbered register above 10
 	if register > numRegisters-1 {
@@ -254,13 +380,21 @@ func (reg *Buffers) writeNum(register int, buf []rune) {
 	reg.num[0] = append([]rune{}, buf...)
 }
 
+func (reg *Buffers) writeClipboard(buf []rune) {
+	reg.clip = buf
+
+	if reg.clipboard != nil {
+		reg.clipboard(string(buf))
+	}
+}
+
 func (reg *Buffers) writeAlpha(register rune, buf []rune) {
 	appendRegs := "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
 	appended := false
 
 	for _, char := range appendRegs {
 		if char == register {
-			register = unicode.ToLower(reg.active)
+			register = unicode.ToLower(register)
 			_, exists := reg.alpha[register]
 
 			if exists {
@@ -331,3 +465,18 @@ func (reg *Buffers) completeAlphaRegs() []completion.Candidate {
 
 	return regs
 }
+
+func (reg *Buffers) completeClipboard() []completion.Candidate {
+	if len(reg.clip) == 0 {
+		return nil
+	}
+
+	tag := color.Dim + "clipboard (+, *)" + color.Reset
+	display := strings.ReplaceAll(string(reg.clip), "\n", ` `)
+
+	return []completion.Candidate{{
+		Tag:     tag,
+		Value:   string(reg.clip),
+		Display: fmt.Sprintf("%s\"%s%s %s", color.Dim, string(clipboardRegister), color.DimReset, display),
+	}}
+}
@@ -107,15 +107,19 @@ func readTempFile(name string) ([]byte, error) {
 	return buf, nil
 }
 
-func getSystemEditor(emacsDefault bool) (editor string) {
-	editor = os.Getenv("VISUAL")
-	if editor == "" {
-		return
+// getSystemEditor returns the editor set with the VISUAL or the EDITOR
+// environment variables, in that order, or emacs/vi if none is set.
+func getSystemEditor(getenv func(string) string, emacsDefault bool) (editor string) {
+	if getenv == nil {
+		getenv = os.Getenv
 	}
 
-	editor = os.Getenv("EDITOR")
-	if editor == "" {
-		return
+	if editor = getenv("VISUAL"); editor != "" {
+		return editor
+	}
+
+	if editor = getenv("EDITOR"); editor != "" {
+		return editor
 	}
 
 	if emacsDefault {
@@ -23,7 +23,7 @@ func (reg *Buffers) EditBuffer(buf []rune, filename, filetype string, emacs bool
 		return buf, err
 	}
 
-	editor := getSystemEditor(emacs)
+	editor := getSystemEditor(reg.getenv, emacs)
 
 	args := []string{}
 	if filetype != "" {
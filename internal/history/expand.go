@@ -0,0 +1,244 @@
+package history
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/reeflective/readline/internal/strutil"
+)
+
+// Expand performs history expansion on a line, replacing the history designators
+// it contains (like !!, !$ or !?string?:2) with the lines of the active source, or
+// the words of those lines, that they designate. Exclamation marks escaped with a
+// backslash or in single quotes are not expanded, and designators whose line or
+// words cannot be found are left as is.
+func (h *Sources) Expand(line string) string {
+	history := h.Current()
+	if history == nil || history.Len() == 0 {
+		return line
+	}
+
+	var (
+		expanded                strings.Builder
+		single, double, escaped bool
+	)
+
+	runes := []rune(line)
+
+	for pos := 0; pos < len(runes); pos++ {
+		char := runes[pos]
+
+		switch {
+		case escaped:
+			escaped = false
+		case char == '\\' && !single:
+			escaped = true
+		case char == '\'' && !double:
+			single = !single
+		case char == '"' && !single:
+			double = !double
+		case char == '!' && !single:
+			if text, end, found := expandDesignator(history, runes, pos); found {
+				expanded.WriteString(text)
+				pos = end - 1
+
+				continue
+			}
+		}
+
+		expanded.WriteRune(char)
+	}
+
+	return expanded.String()
+}
+
+// expandDesignator returns the text designated by the history designator starting
+// at pos in the line, and the position following the designator, or false if there
+// is no valid designator at this position, or if its line or words are not found.
+func expandDesignator(history Source, line []rune, pos int) (text string, end int, found bool) {
+	end = pos + 1
+	if end == len(line) {
+		return "", 0, false
+	}
+
+	var (
+		event string
+		words string
+	)
+
+	// Event designators
+	switch char := line[end]; {
+	case char == '!':
+		event, found = lineAt(history, history.Len()-1)
+		end++
+
+	case char == '$' || char == '^' || char == '*':
+		event, found = lineAt(history, history.Len()-1)
+		words = string(char)
+		end++
+
+	case unicode.IsDigit(char) || (char == '-' && end+1 < len(line) && unicode.IsDigit(line[end+1])):
+		start := end
+		end++
+
+		for end < len(line) && unicode.IsDigit(line[end]) {
+			end++
+		}
+
+		index, _ := strconv.Atoi(string(line[start:end]))
+		if index < 0 {
+			index += history.Len()
+		}
+
+		event, found = lineAt(history, index)
+
+	case char == '?':
+		end++
+		start := end
+
+		for end < len(line) && line[end] != '?' {
+			end++
+		}
+
+		event, found = searchLine(history, string(line[start:end]), strings.Contains)
+
+		if end < len(line) {
+			end++
+		}
+
+	case unicode.IsSpace(char) || char == '=' || char == '(':
+		return "", 0, false
+
+	default:
+		start := end
+
+		for end < len(line) && !unicode.IsSpace(line[end]) && line[end] != ':' {
+			end++
+		}
+
+		event, found = searchLine(history, string(line[start:end]), strings.HasPrefix)
+	}
+
+	if !found {
+		return "", 0, false
+	}
+
+	// Word designators
+	if words == "" && end+1 < len(line) && line[end] == ':' && strings.ContainsRune("0123456789^$*", line[end+1]) {
+		words, end = wordDesignator(line, end+1)
+	}
+
+	if words == "" {
+		return event, end, true
+	}
+
+	text, found = selectWords(event, words)
+
+	return text, end, found
+}
+
+// wordDesignator returns the word designator starting at pos in
+// the line (like 2, ^, $, * or 1-3), and the position following it.
+func wordDesignator(line []rune, pos int) (words string, end int) {
+	end = pos
+
+	word := func() {
+		switch {
+		case end == len(line):
+		case line[end] == '^' || line[end] == '$':
+			end++
+		default:
+			for end < len(line) && unicode.IsDigit(line[end]) {
+				end++
+			}
+		}
+	}
+
+	if line[end] == '*' {
+		return "*", end + 1
+	}
+
+	word()
+
+	if end+1 < len(line) && line[end] == '-' && (unicode.IsDigit(line[end+1]) || line[end+1] == '$') {
+		end++
+		word()
+	}
+
+	return string(line[pos:end]), end
+}
+
+// selectWords returns the words of a history line selected by a word
+// designator, or false if the line does not have the designated words.
+func selectWords(event, designator string) (string, bool) {
+	words, err := strutil.Split(event)
+	if err != nil {
+		words = strings.Fields(event)
+	}
+
+	if designator == "*" {
+		designator = "1-$"
+
+		if len(words) < 2 {
+			return "", true
+		}
+	}
+
+	index := func(word string) int {
+		switch word {
+		case "^":
+			return 1
+		case "$":
+			return len(words) - 1
+		}
+
+		index, _ := strconv.Atoi(word)
+
+		return index
+	}
+
+	first, last, isRange := strings.Cut(designator, "-")
+
+	bpos, epos := index(first), index(first)
+	if isRange {
+		epos = index(last)
+	}
+
+	if bpos < 0 || epos >= len(words) || bpos > epos {
+		return "", false
+	}
+
+	selected := make([]string, 0, epos-bpos+1)
+	for _, word := range words[bpos : epos+1] {
+		selected = append(selected, strutil.QuoteWord(word))
+	}
+
+	return strings.Join(selected, " "), true
+}
+
+// lineAt returns the line of the history source at the given index, if any.
+func lineAt(history Source, index int) (string, bool) {
+	if index < 0 || index >= history.Len() {
+		return "", false
+	}
+
+	line, err := history.GetLine(index)
+
+	return line, err == nil
+}
+
+// searchLine returns the most recent line of the history source matching a string.
+func searchLine(history Source, search string, match func(line, search string) bool) (string, bool) {
+	if search == "" {
+		return "", false
+	}
+
+	for index := history.Len() - 1; index >= 0; index-- {
+		if line, err := history.GetLine(index); err == nil && match(line, search) {
+			return line, true
+		}
+	}
+
+	return "", false
+}
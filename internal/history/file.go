@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 	"time"
 )
@@ -14,6 +15,7 @@ var (
 	errOpenHistoryFile = errors.New("failed to open history file")
 	errNegativeIndex   = errors.New("cannot use a negative index when requesting historic commands")
 	errOutOfRangeIndex = errors.New("index requested greater than number of items in history")
+	errCannotDelete    = errors.New("history source cannot delete lines")
 )
 
 // fileHistory provides a history source based on a file.
@@ -27,6 +29,18 @@ type Item struct {
 	Index    int
 	DateTime time.Time
 	Block    string
+	Context  string        // Optional tag, like the directory in which the line was run.
+	Duration time.Duration // Optional time taken to run the line, if known.
+	Session  string        // Optional identifier of the shell session in which the line was run.
+}
+
+// fileItem is a line of the history file.
+type fileItem struct {
+	DateTime time.Time     `json:"datetime"`
+	Block    string        `json:"block"`
+	Context  string        `json:"context,omitempty"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Session  string        `json:"session,omitempty"`
 }
 
 // NewSourceFromFile returns a new history source writing to and reading from a file.
@@ -81,15 +95,7 @@ func (h *fileHistory) Write(s string) (int, error) {
 		h.lines = append(h.lines, item)
 	}
 
-	line := struct {
-		DateTime time.Time `json:"datetime"`
-		Block    string    `json:"block"`
-	}{
-		Block:    block,
-		DateTime: item.DateTime,
-	}
-
-	data, err := json.Marshal(line)
+	data, err := json.Marshal(fileItem{Block: block, DateTime: item.DateTime})
 	if err != nil {
 		return h.Len(), err
 	}
@@ -118,6 +124,60 @@ func (h *fileHistory) GetLine(pos int) (string, error) {
 	return "", errOutOfRangeIndex
 }
 
+// GetItem returns a specific item from the history file.
+func (h *fileHistory) GetItem(pos int) (Item, error) {
+	if pos < 0 {
+		return Item{}, errNegativeIndex
+	}
+
+	if pos < len(h.lines) {
+		return h.lines[pos], nil
+	}
+
+	return Item{}, errOutOfRangeIndex
+}
+
+// Delete removes a line from the history, and rewrites
+// the history file with all lines but this one.
+func (h *fileHistory) Delete(pos int) error {
+	if pos < 0 {
+		return errNegativeIndex
+	}
+
+	if pos >= len(h.lines) {
+		return errOutOfRangeIndex
+	}
+
+	lines := slices.Delete(slices.Clone(h.lines), pos, pos+1)
+
+	var data []byte
+
+	for index := range lines {
+		lines[index].Index = index
+
+		line, err := json.Marshal(fileItem{
+			DateTime: lines[index].DateTime,
+			Block:    lines[index].Block,
+			Context:  lines[index].Context,
+			Duration: lines[index].Duration,
+			Session:  lines[index].Session,
+		})
+		if err != nil {
+			return err
+		}
+
+		data = append(append(data, line...), '\n')
+	}
+
+	if err := os.WriteFile(h.file, data, 0o600); err != nil {
+		return fmt.Errorf("%w: %s", errOpenHistoryFile, err.Error())
+	}
+
+	h.lines = lines
+
+	return nil
+}
+
 // Len returns the number of items in the history file.
 func (h *fileHistory) Len() int {
 	return len(h.lines)
@@ -1,5 +1,10 @@
 package history
 
+import (
+	"slices"
+	"time"
+)
+
 var defaultSourceName = "default history"
 
 // Source is an interface to allow you to write your own history logging tools.
@@ -24,10 +29,34 @@ type Source interface {
 	Dump() interface{}
 }
 
+// ItemSource is implemented by history sources giving the details of their
+// lines, like the time at which they were written: those details are then
+// displayed along with the lines in the history completion menu.
+type ItemSource interface {
+	// GetItem takes the historic line number and returns its item or an error.
+	GetItem(int) (Item, error)
+}
+
+// DeleteSource is implemented by history sources able to delete their lines,
+// from memory and from their backing store, if any: those lines can then be
+// deleted from the history completion menu.
+type DeleteSource interface {
+	// Delete removes the historic line number: the numbers of the following
+	// lines are decremented, like if the line had never been written.
+	Delete(int) error
+}
+
+// Entry identifies the history line of a candidate in the history completion menu.
+type Entry struct {
+	Source string // Name of the history source.
+	Index  int    // Number of the line in the source.
+}
+
 // memory is an in memory history.
 // One such history is bound to the readline shell by default.
 type memory struct {
 	items []string
+	times []time.Time
 }
 
 // NewInMemoryHistory creates a new in-memory command history source.
@@ -38,6 +67,8 @@ func NewInMemoryHistory() Source {
 // Write to history.
 func (h *memory) Write(s string) (int, error) {
 	h.items = append(h.items, s)
+	h.times = append(h.times, time.Now())
+
 	return len(h.items), nil
 }
 
@@ -50,6 +81,31 @@ func (h *memory) GetLine(i int) (string, error) {
 	return h.items[i], nil
 }
 
+// GetItem returns a line from history, with the time it was written at.
+func (h *memory) GetItem(i int) (Item, error) {
+	if i < 0 || i >= len(h.items) {
+		return Item{}, nil
+	}
+
+	return Item{Index: i, DateTime: h.times[i], Block: h.items[i]}, nil
+}
+
+// Delete removes a line from history.
+func (h *memory) Delete(i int) error {
+	if i < 0 {
+		return errNegativeIndex
+	}
+
+	if i >= len(h.items) {
+		return errOutOfRangeIndex
+	}
+
+	h.items = slices.Delete(h.items, i, i+1)
+	h.times = slices.Delete(h.times, i, i+1)
+
+	return nil
+}
+
 // Len returns the number of lines in history.
 func (h *memory) Len() int {
 	return len(h.items)
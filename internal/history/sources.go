@@ -5,11 +5,13 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/completion"
 	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/locale"
 	"github.com/reeflective/readline/internal/ui"
 )
 
@@ -20,6 +22,7 @@ type Sources struct {
 	cursor *core.Cursor
 	hint   *ui.Hint
 	config *inputrc.Config
+	locale *locale.Catalog
 
 	// History sources
 	list       map[string]Source // Sources of history lines
@@ -28,12 +31,13 @@ type Sources struct {
 	sourcePos  int               // The index of the currently used history
 	hpos       int               // Index used for navigating the history lines with arrows/j/k
 	cpos       int               // A temporary cursor position used when searching/moving around.
+	searchFold bool              // Substring searches ignore case.
+	searchWrap bool              // Searches wrap around the ends of the history.
 
 	// Line changes history
-	skip    bool                            // Skip saving the current line state.
-	undoing bool                            // The last command executed was an undo.
-	last    inputrc.Bind                    // The last command being ran.
-	lines   map[string]map[int]*lineHistory // Each line in each history source has its own buffer history.
+	skip  bool                            // Skip saving the current line state.
+	last  inputrc.Bind                    // The last command being ran.
+	lines map[string]map[int]*lineHistory // Each line in each history source has its own buffer history.
 
 	// Lines accepted
 	infer      bool      // If the last command ran needs to infer the history line.
@@ -44,7 +48,7 @@ type Sources struct {
 }
 
 // NewSources is a required constructor for the history sources manager type.
-func NewSources(line *core.Line, cur *core.Cursor, hint *ui.Hint, opts *inputrc.Config) *Sources {
+func NewSources(line *core.Line, cur *core.Cursor, hint *ui.Hint, opts *inputrc.Config, l *locale.Catalog) *Sources {
 	sources := &Sources{
 		// History sourcces
 		list: make(map[string]Source),
@@ -57,22 +61,47 @@ func NewSources(line *core.Line, cur *core.Cursor, hint *ui.Hint, opts *inputrc.
 		hpos:   -1,
 		hint:   hint,
 		config: opts,
+		locale: l,
 	}
 
 	sources.names = append(sources.names, defaultSourceName)
 	sources.list[defaultSourceName] = new(memory)
 
 	// Inputrc settings.
-	sources.maxEntries = opts.GetInt("history-size")
-	sizeSet := opts.GetString("history-size") != ""
+	sources.UpdateConfig()
 
-	if sources.maxEntries == 0 && !sizeSet {
-		sources.maxEntries = -1
-	} else if sources.maxEntries == 0 && sizeSet {
-		sources.maxEntries = 500
+	return sources
+}
+
+// UpdateConfig updates the history settings read from the
+// configuration, such as the maximum number of entries.
+func (h *Sources) UpdateConfig() {
+	h.maxEntries = h.config.GetInt("history-size")
+	sizeSet := h.config.GetString("history-size") != ""
+
+	if h.maxEntries == 0 && !sizeSet {
+		h.maxEntries = -1
+	} else if h.maxEntries == 0 && sizeSet {
+		h.maxEntries = 500
 	}
+}
 
-	return sources
+// Isolate replaces all history sources with a single, empty in-memory one,
+// until the returned function is called: lines accepted meanwhile are neither
+// written to, nor navigated from, the sources in use before.
+func (h *Sources) Isolate() (restore func()) {
+	list, names, lines := h.list, h.names, h.lines
+	sourcePos, hpos := h.sourcePos, h.hpos
+
+	h.list = map[string]Source{defaultSourceName: new(memory)}
+	h.names = []string{defaultSourceName}
+	h.lines = make(map[string]map[int]*lineHistory)
+	h.sourcePos, h.hpos = 0, -1
+
+	return func() {
+		h.list, h.names, h.lines = list, names, lines
+		h.sourcePos, h.hpos = sourcePos, hpos
+	}
 }
 
 // Init initializes the history sources positions and buffers
@@ -163,6 +192,48 @@ func (h *Sources) Delete(sources ...string) {
 	}
 }
 
+// DeleteLine deletes a line from the named history source, if the source
+// implements DeleteSource. The changes made to the other lines of the source
+// while walking the history are kept, and the line walked to stays the same,
+// unless it is the deleted one, in which case the next older one is walked to.
+func (h *Sources) DeleteLine(source string, pos int) error {
+	history, found := h.list[source]
+	if !found {
+		return nil
+	}
+
+	deleter, ok := history.(DeleteSource)
+	if !ok {
+		return errCannotDelete
+	}
+
+	// Lines changes are indexed from the end of the history.
+	deleted := history.Len() - pos
+
+	if err := deleter.Delete(pos); err != nil {
+		return err
+	}
+
+	changes := make(map[int]*lineHistory)
+
+	for index, changed := range h.lines[source] {
+		switch {
+		case index < deleted:
+			changes[index] = changed
+		case index > deleted:
+			changes[index-1] = changed
+		}
+	}
+
+	h.lines[source] = changes
+
+	if h.names[h.sourcePos] == source && h.hpos > deleted {
+		h.hpos--
+	}
+
+	return nil
+}
+
 // Walk goes to the next or previous history line in the active source.
 // If at the beginning of the history, the first history line is kept.
 // If at the end of it, the main input buffer and cursor position is restored.
@@ -203,11 +274,11 @@ func (h *Sources) Walk(pos int) {
 	var err error
 
 	// When there is an available change history for
-	// this line, use it instead of the fetched line.
+	// this line, use its current state instead of the fetched line.
 	if hist := h.getLineHistory(); hist != nil && len(hist.items) > 0 {
-		line = hist.items[len(hist.items)-1].line
+		line = hist.items[hist.current].line
 	} else if line, err = history.GetLine(history.Len() - h.hpos); err != nil {
-		h.hint.Set(color.FgRed + "history error: " + err.Error())
+		h.hint.Set(color.FgRed + h.locale.Sprintf(locale.HistoryError, err))
 		return
 	}
 
@@ -230,13 +301,54 @@ func (h *Sources) Fetch(pos int) {
 
 	line, err := history.GetLine(pos)
 	if err != nil {
-		h.hint.Set(color.FgRed + "history error: " + err.Error())
+		h.hint.Set(color.FgRed + h.locale.Sprintf(locale.HistoryError, err))
 		return
 	}
 
 	h.setLineCursorMatch(line)
 }
 
+// Recalled returns the history entry of the active source from which the
+// input line has been recalled, if the line was walked to or matched in it.
+func (h *Sources) Recalled() (entry string, recalled bool) {
+	history := h.Current()
+
+	if history == nil || h.hpos <= 0 || h.hpos > history.Len() {
+		return "", false
+	}
+
+	entry, err := history.GetLine(history.Len() - h.hpos)
+	if err != nil {
+		return "", false
+	}
+
+	return entry, true
+}
+
+// RecalledDiff returns the history entry from which the input line has been
+// recalled with the changes made to it since, colored, or an empty string
+// if the line has not been recalled, or if it has not been changed.
+func (h *Sources) RecalledDiff() string {
+	entry, recalled := h.Recalled()
+	if !recalled || entry == string(*h.line) {
+		return ""
+	}
+
+	return lineDiff(entry, string(*h.line))
+}
+
+// RevertRecalled replaces the input line with the history entry from which
+// it has been recalled, if any, as an undoable change.
+func (h *Sources) RevertRecalled() {
+	entry, recalled := h.Recalled()
+	if !recalled || entry == string(*h.line) {
+		return
+	}
+
+	h.line.Set([]rune(entry)...)
+	h.cursor.Set(h.line.Len())
+}
+
 // GetLast returns the last saved history line in the active history source.
 func (h *Sources) GetLast() string {
 	history := h.Current()
@@ -348,6 +460,14 @@ func (h *Sources) Accept(hold, infer bool, err error) {
 	}
 }
 
+// Prefill sets the line inserted in the buffer, with the cursor at its end,
+// when the shell starts reading the next line. This is how the line accepted
+// by accept-and-hold is kept for the next loop.
+func (h *Sources) Prefill(line []rune) {
+	h.acceptHold = true
+	h.acceptLine = append(core.Line{}, line...)
+}
+
 // LineAccepted returns true if the user has accepted the line, signaling
 // that the shell must return from its loop. The error can be nil, but may
 // indicate a CtrlC/CtrlD style error.
@@ -427,6 +547,17 @@ func (h *Sources) InsertMatch(line *core.Line, cur *core.Cursor, usePos, fwd, re
 	}
 }
 
+// SearchOptions sets whether the next substring searches ignore case, and
+// whether searches from the current history position wrap around the ends
+// of the history, and returns a function restoring the default searches.
+func (h *Sources) SearchOptions(ignoreCase, wrap bool) (restore func()) {
+	h.searchFold, h.searchWrap = ignoreCase, wrap
+
+	return func() {
+		h.searchFold, h.searchWrap = false, false
+	}
+}
+
 // InferNext finds a line matching the current line in the history,
 // then finds the line event following it and, if any, inserts it.
 func (h *Sources) InferNext() {
@@ -483,7 +614,14 @@ func (h *Sources) Suggest(line *core.Line) core.Line {
 // If forward is true, the completions are proposed from the most ancient
 // line in the history source to the most recent. If filter is true,
 // only lines that match the current input line as a prefix are given.
-func Complete(h *Sources, forward, filter bool, maxLines int, regex *regexp.Regexp) completion.Values {
+// Lines are described with their index, how long ago they were written and
+// their context, if known. If byIndex is true, selecting a line inserts its
+// index (like !42) at the cursor, instead of replacing the input line with it.
+// If the history-details option is enabled, the full line and all its details
+// are displayed in a panel below the completions while the line is selected.
+// If the history-group option is "day" or "session", lines are grouped under
+// the day on which they were written, or the session in which they were run.
+func Complete(h *Sources, forward, filter, byIndex bool, maxLines int, matcher *completion.Matcher) completion.Values {
 	if len(h.list) == 0 {
 		return completion.Values{}
 	}
@@ -495,6 +633,9 @@ func Complete(h *Sources, forward, filter bool, maxLines int, regex *regexp.Rege
 
 	h.hint.Set(color.Bold + color.FgCyanBright + h.names[h.sourcePos] + color.Reset)
 
+	group := strings.Trim(h.config.GetString("history-group"), "\"")
+	now := time.Now()
+
 	compLines := make([]completion.Candidate, 0)
 
 	// Set up iteration clauses
@@ -529,20 +670,27 @@ func Complete(h *Sources, forward, filter bool, maxLines int, regex *regexp.Rege
 
 		if filter && !strings.HasPrefix(line, string(*h.line)) {
 			continue
-		} else if regex != nil && !regex.MatchString(line) {
+		} else if matcher != nil && !matcher.MatchString(line) {
 			continue
 		}
 
-		display := strings.ReplaceAll(line, "\n", ` `)
+		value := completion.Candidate{
+			Display:     strings.ReplaceAll(line, "\n", ` `),
+			Value:       line,
+			Description: describeItem(history, histPos),
+			Meta:        Entry{Source: h.names[h.sourcePos], Index: histPos},
+		}
+
+		if byIndex {
+			value.Value = "!" + strconv.Itoa(histPos)
+		}
 
-		// Proper pad for indexes
-		indexStr := strconv.Itoa(histPos)
-		pad := strings.Repeat(" ", len(strconv.Itoa(history.Len()))-len(indexStr))
-		display = fmt.Sprintf("%s%s %s%s", color.Dim, indexStr+pad, color.DimReset, display)
+		if h.config.GetBool("history-details") {
+			value.Details = detailItem(history, histPos, line)
+		}
 
-		value := completion.Candidate{
-			Display: display,
-			Value:   line,
+		if group != "" {
+			value.Tag = h.groupItem(history, histPos, group, now)
 		}
 
 		compLines = append(compLines, value)
@@ -555,9 +703,123 @@ func Complete(h *Sources, forward, filter bool, maxLines int, regex *regexp.Rege
 	comps.ListLong["*"] = true
 	comps.PREFIX = string(*h.line)
 
+	if byIndex {
+		comps.PREFIX = ""
+		comps.NoFilter = true
+	}
+
 	return comps
 }
 
+// describeItem returns the index of a history line, how long ago
+// it was written and its context, if the source gives them.
+func describeItem(history Source, pos int) string {
+	description := "!" + strconv.Itoa(pos)
+
+	source, ok := history.(ItemSource)
+	if !ok {
+		return description
+	}
+
+	item, err := source.GetItem(pos)
+	if err != nil {
+		return description
+	}
+
+	if !item.DateTime.IsZero() {
+		description += "  " + timeAgo(time.Since(item.DateTime))
+	}
+
+	if item.Context != "" {
+		description += "  " + item.Context
+	}
+
+	return description
+}
+
+// groupItem returns the group of a history line in the completions: the day
+// on which it was written (today, yesterday or its date), or the session in
+// which it was run, if the source gives them. Other lines are not grouped.
+func (h *Sources) groupItem(history Source, pos int, group string, now time.Time) string {
+	source, ok := history.(ItemSource)
+	if !ok {
+		return ""
+	}
+
+	item, err := source.GetItem(pos)
+	if err != nil {
+		return ""
+	}
+
+	switch group {
+	case "day":
+		if item.DateTime.IsZero() {
+			return ""
+		}
+
+		day := item.DateTime.Local().Format(time.DateOnly)
+
+		switch day {
+		case now.Format(time.DateOnly):
+			return h.locale.Get(locale.Today)
+		case now.AddDate(0, 0, -1).Format(time.DateOnly):
+			return h.locale.Get(locale.Yesterday)
+		default:
+			return day
+		}
+
+	case "session":
+		if item.Session == "" {
+			return ""
+		}
+
+		return h.locale.Sprintf(locale.Session, item.Session)
+	}
+
+	return ""
+}
+
+// detailItem returns the details of a history line displayed in the details
+// panel: its index, the date and time at which it was written, how long it
+// took to run and its context, if the source gives them, and the full line.
+func detailItem(history Source, pos int, line string) string {
+	details := "!" + strconv.Itoa(pos)
+
+	if source, ok := history.(ItemSource); ok {
+		if item, err := source.GetItem(pos); err == nil {
+			if !item.DateTime.IsZero() {
+				details += "  " + item.DateTime.Format(time.DateTime)
+				details += " (" + timeAgo(time.Since(item.DateTime)) + ")"
+			}
+
+			if item.Duration > 0 {
+				details += "  " + item.Duration.Round(time.Millisecond).String()
+			}
+
+			if item.Context != "" {
+				details += "  " + item.Context
+			}
+		}
+	}
+
+	return color.Dim + details + color.Reset + "\n" + line
+}
+
+// timeAgo returns a short description of how long ago
+// something happened, like 5m ago, in its largest unit.
+func timeAgo(elapsed time.Duration) string {
+	switch {
+	case elapsed < time.Minute:
+		return fmt.Sprintf("%ds ago", int(elapsed.Seconds()))
+	case elapsed < time.Hour:
+		return fmt.Sprintf("%dm ago", int(elapsed.Minutes()))
+	case elapsed < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(elapsed.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(elapsed.Hours()/24))
+	}
+}
+
 // Name returns the name of the currently active history source.
 func (h *Sources) Name() string {
 	return h.names[h.sourcePos]
@@ -580,7 +842,7 @@ func (h *Sources) match(match *core.Line, cur *core.Cursor, usePos, fwd, regex b
 
 	if fwd {
 		histPos = -1
-		done = func(i int) bool { return i < history.Len() }
+		done = func(i int) bool { return i < history.Len()-1 }
 		move = func(pos int) int { return pos + 1 }
 	} else {
 		histPos = history.Len()
@@ -609,7 +871,12 @@ func (h *Sources) match(match *core.Line, cur *core.Cursor, usePos, fwd, regex b
 		// Matching: either as substring (regex) or since beginning.
 		switch {
 		case regex:
-			regexLine, err := regexp.Compile(regexp.QuoteMeta(cline))
+			pattern := regexp.QuoteMeta(cline)
+			if h.searchFold {
+				pattern = "(?i)" + pattern
+			}
+
+			regexLine, err := regexp.Compile(pattern)
 			if err != nil {
 				continue
 			}
@@ -630,6 +897,15 @@ func (h *Sources) match(match *core.Line, cur *core.Cursor, usePos, fwd, regex b
 		return histline, histPos, true
 	}
 
+	// Without match up to the end of the history, start
+	// again from its other end, if searches wrap around.
+	if h.searchWrap && usePos && h.hpos > -1 {
+		h.searchWrap = false
+		defer func() { h.searchWrap = true }()
+
+		return h.match(match, cur, false, fwd, regex)
+	}
+
 	// We should have returned a match from the loop.
 	return "", 0, false
 }
@@ -657,7 +933,7 @@ func (h *Sources) getLine(line *core.Line, cur *core.Cursor) (*core.Line, *core.
 			return line, cur
 		}
 
-		undo := lh.items[len(lh.items)-1]
+		undo := lh.items[lh.current]
 		line.Set([]rune(undo.line)...)
 		cur.Set(undo.pos)
 	}
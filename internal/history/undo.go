@@ -1,25 +1,45 @@
 package history
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/completion"
 	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/locale"
 )
 
-// lineHistory contains all state changes for a given input line,
-// whether it is the current input line or one of the history ones.
+// undoDiffLength is the maximum length of inserted/deleted
+// text shown in the descriptions of undo history states.
+const undoDiffLength = 20
+
+// lineHistory contains all state changes for a given input line, whether it
+// is the current input line or one of the history ones. States form a tree:
+// a state saved after undoing starts a new branch from the state undone to,
+// the states undone being kept in their own branch instead of being dropped.
 type lineHistory struct {
-	pos   int
-	items []undoItem
+	current int        // Index of the current state, if any.
+	moves   int        // Number of undo and redo moves made in the states.
+	items   []undoItem // All states, in the order they were saved.
 }
 
 type undoItem struct {
-	line string
-	pos  int
+	line   string
+	pos    int
+	time   time.Time
+	parent int // Index of the state this one was saved from, or -1.
+	next   int // Index of the state redone from this one, or -1.
 }
 
 // Save saves the current line and cursor position as an undo state item.
 // If this was called while the shell was in the middle of its undo history
-// (eg. the caller has undone one or more times), all undone steps are dropped.
+// (eg. the caller has undone one or more times), the new state starts a new
+// branch from the current one, and the undone states are kept in theirs.
 func (h *Sources) Save() {
 	defer h.Reset()
 
@@ -33,31 +53,34 @@ func (h *Sources) Save() {
 		return
 	}
 
-	// When the line is identical to the previous undo, we just update
+	// When the line is identical to the current undo, we just update
 	// the cursor position if it's a different one.
-	if len(line.items) > 0 && line.items[len(line.items)-1].line == string(*h.line) {
-		line.items[len(line.items)-1].pos = h.cursor.Pos()
+	if len(line.items) > 0 && line.items[line.current].line == string(*h.line) {
+		line.items[line.current].pos = h.cursor.Pos()
 		return
 	}
 
-	// When we add an item to the undo history, the history
-	// is cut from the current undo hist position onwards.
-	if line.pos > len(line.items) {
-		line.pos = len(line.items)
-	}
-
-	line.items = line.items[:len(line.items)-line.pos]
-
 	// Make a copy of the cursor and ensure its position.
 	cur := core.NewCursor(h.line)
 	cur.Set(h.cursor.Pos())
 	cur.CheckCommand()
 
-	// And save the item.
-	line.items = append(line.items, undoItem{
-		line: string(*h.line),
-		pos:  cur.Pos(),
-	})
+	// And save the item as the next state of the current one.
+	item := undoItem{
+		line:   string(*h.line),
+		pos:    cur.Pos(),
+		time:   time.Now(),
+		parent: -1,
+		next:   -1,
+	}
+
+	if len(line.items) > 0 {
+		item.parent = line.current
+		line.items[line.current].next = len(line.items)
+	}
+
+	line.items = append(line.items, item)
+	line.current = len(line.items) - 1
 }
 
 // SkipSave will not save the current line when the target command is done
@@ -76,10 +99,11 @@ func (h *Sources) SaveWithCommand(bind inputrc.Bind) {
 	h.Save()
 }
 
-// Undo restores the line and cursor position to their last saved state.
+// Undo restores the line and cursor position to their previous state,
+// going up the branch of the current state in the undo tree: the state
+// undone is the one restored by a subsequent redo.
 func (h *Sources) Undo() {
 	h.skip = true
-	h.undoing = true
 
 	// Get the undo states for the current line.
 	line := h.getLineHistory()
@@ -87,29 +111,22 @@ func (h *Sources) Undo() {
 		return
 	}
 
-	var undo undoItem
+	index := line.current
 
-	// When undoing, we loop through preceding undo items
+	// When undoing, we go through preceding undo items
 	// as long as they are identical to the current line.
-	for {
-		line.pos++
-
-		// Exit if we reached the end.
-		if line.pos > len(line.items) {
-			line.pos = len(line.items)
+	for line.items[index].line == string(*h.line) {
+		parent := line.items[index].parent
+		if parent == -1 {
 			return
 		}
 
-		// Break as soon as we find a non-matching line.
-		undo = line.items[len(line.items)-line.pos]
-		if undo.line != string(*h.line) {
-			break
-		}
+		line.items[parent].next = index
+		index = parent
 	}
 
 	// Use the undo we found
-	h.line.Set([]rune(undo.line)...)
-	h.cursor.Set(undo.pos)
+	line.restore(h, index)
 }
 
 // Revert goes back to the initial state of the line, which is what it was
@@ -129,31 +146,208 @@ func (h *Sources) Revert() {
 
 	// And reset everything
 	line.items = make([]undoItem, 0)
+	line.current = 0
 
 	h.Reset()
 }
 
-// Redo cancels an undo action if any has been made, or if
-// at the begin of the undo history, restores the original
-// line's contents as their were before starting undoing.
+// Redo cancels an undo action if any has been made: it restores the state
+// last undone from the current one, or the state last saved from it if none
+// was undone. If the current state has no next state, nothing is done.
 func (h *Sources) Redo() {
 	h.skip = true
-	h.undoing = true
 
 	line := h.getLineHistory()
 	if line == nil || len(line.items) == 0 {
 		return
 	}
 
-	line.pos--
+	next := line.items[line.current].next
+	if next == -1 {
+		return
+	}
+
+	line.restore(h, next)
+}
+
+// UndoTo restores the line and cursor position to the undo state at the
+// given index (starting at 1 for the oldest one), as listed by CompleteUndo,
+// whatever the branch it belongs to. Subsequent undo and redo calls then
+// move from this state.
+func (h *Sources) UndoTo(index int) {
+	h.skip = true
 
-	if line.pos < 1 {
+	line := h.getLineHistory()
+	if line == nil || index < 1 || index > len(line.items) {
 		return
 	}
 
-	undo := line.items[len(line.items)-line.pos]
-	h.line.Set([]rune(undo.line)...)
-	h.cursor.Set(undo.pos)
+	line.restore(h, index-1)
+}
+
+// restore makes the state at index the current one, and
+// restores the line and cursor position to this state.
+func (lh *lineHistory) restore(h *Sources, index int) {
+	lh.current = index
+	lh.moves++
+
+	h.line.Set([]rune(lh.items[index].line)...)
+	h.cursor.Set(lh.items[index].pos)
+}
+
+// CompleteUndo returns the undo states of the current line as completions,
+// from the most recent to the oldest one, with their index, the time at which
+// they were saved, and the text inserted and deleted since the previous state.
+// Selecting one of them replaces the whole line with it.
+func CompleteUndo(h *Sources) completion.Values {
+	line := h.getLineHistory()
+	if line == nil || len(line.items) == 0 {
+		return completion.Values{}
+	}
+
+	h.hint.Set(color.Bold + color.FgCyanBright + h.locale.Get(locale.UndoHistory) + color.Reset)
+
+	compLines := make([]completion.Candidate, 0, len(line.items))
+	width := len(strconv.Itoa(len(line.items)))
+
+	for i := len(line.items) - 1; i >= 0; i-- {
+		compLines = append(compLines, line.candidate(i, width, ""))
+	}
+
+	return undoCompletions(h, compLines)
+}
+
+// CompleteUndoTree is like CompleteUndo, but lists the undo states as a tree:
+// each state is listed below the states saved from it, the states of other
+// branches than the first one saved from a state being indented, and the
+// current state is marked with an asterisk.
+func CompleteUndoTree(h *Sources) completion.Values {
+	line := h.getLineHistory()
+	if line == nil || len(line.items) == 0 {
+		return completion.Values{}
+	}
+
+	h.hint.Set(color.Bold + color.FgCyanBright + h.locale.Get(locale.UndoTree) + color.Reset)
+
+	// Children of each state, in the order they were saved.
+	children := make(map[int][]int)
+	for i, item := range line.items {
+		children[item.parent] = append(children[item.parent], i)
+	}
+
+	compLines := make([]completion.Candidate, 0, len(line.items))
+	width := len(strconv.Itoa(len(line.items)))
+
+	var walk func(index, depth int)
+
+	walk = func(index, depth int) {
+		marker := "o "
+		if index == line.current {
+			marker = "* "
+		}
+
+		compLines = append(compLines, line.candidate(index, width, strings.Repeat("  ", depth)+marker))
+
+		for branch, child := range children[index] {
+			walk(child, depth+min(branch, 1))
+		}
+	}
+
+	for _, root := range children[-1] {
+		walk(root, 0)
+	}
+
+	slices.Reverse(compLines)
+
+	return undoCompletions(h, compLines)
+}
+
+// candidate returns the completion candidate of the undo state at
+// index, with its index padded to width, followed by the graph.
+func (lh *lineHistory) candidate(index, width int, graph string) completion.Candidate {
+	item := lh.items[index]
+
+	var previous string
+	if item.parent != -1 {
+		previous = lh.items[item.parent].line
+	}
+
+	number := strconv.Itoa(index + 1)
+	pad := strings.Repeat(" ", width-len(number))
+	display := strings.ReplaceAll(item.line, "\n", ` `)
+
+	return completion.Candidate{
+		Value:       item.line,
+		Display:     fmt.Sprintf("%s%s %s%s%s", color.Dim, number+pad, graph, color.DimReset, display),
+		Description: item.time.Format(time.TimeOnly) + " " + undoDiff(previous, item.line),
+	}
+}
+
+// undoCompletions returns the undo state candidates as completions,
+// listed in their order, replacing the whole line when selected.
+func undoCompletions(h *Sources, candidates []completion.Candidate) completion.Values {
+	comps := completion.AddRaw(candidates)
+	comps.NoSort["*"] = true
+	comps.ListLong["*"] = true
+	comps.PREFIX = string(*h.line)
+	comps.NoFilter = true
+
+	return comps
+}
+
+// undoDiff returns a short description of the text deleted
+// and inserted to go from the previous line to the next one.
+func undoDiff(previous, next string) string {
+	prev, line := []rune(previous), []rune(next)
+	start, end := diffBounds(prev, line)
+
+	var diff []string
+
+	if deleted := prev[start : len(prev)-end]; len(deleted) > 0 {
+		diff = append(diff, "-"+strconv.Quote(undoDiffText(deleted)))
+	}
+
+	if inserted := line[start : len(line)-end]; len(inserted) > 0 {
+		diff = append(diff, "+"+strconv.Quote(undoDiffText(inserted)))
+	}
+
+	return strings.Join(diff, " ")
+}
+
+// lineDiff returns the original line with the text deleted from it
+// in red, followed by the text inserted in its place in green.
+func lineDiff(original, edited string) string {
+	orig, line := []rune(original), []rune(edited)
+	start, end := diffBounds(orig, line)
+
+	diff := color.Dim + string(orig[:start]) + color.Reset
+	diff += color.FgRed + string(orig[start:len(orig)-end]) + color.Reset
+	diff += color.FgGreen + string(line[start:len(line)-end]) + color.Reset
+	diff += color.Dim + string(orig[len(orig)-end:]) + color.Reset
+
+	return diff
+}
+
+// diffBounds returns the lengths of the longest prefix and
+// of the longest suffix common to both lines, not overlapping.
+func diffBounds(prev, line []rune) (start, end int) {
+	for start < len(prev) && start < len(line) && prev[start] == line[start] {
+		start++
+	}
+
+	for end < len(prev)-start && end < len(line)-start && prev[len(prev)-1-end] == line[len(line)-1-end] {
+		end++
+	}
+
+	return start, end
+}
+
+func undoDiffText(text []rune) string {
+	if len(text) > undoDiffLength {
+		return string(text[:undoDiffLength-1]) + "…"
+	}
+
+	return string(text)
 }
 
 // Last returns the last command ran by the shell.
@@ -161,32 +355,22 @@ func (h *Sources) Last() inputrc.Bind {
 	return h.last
 }
 
-// Pos returns the current position in the undo history, which is
-// equal to its length minus the number of previous undo calls.
+// Pos returns the number of undo and redo moves made in the undo history
+// of the line, which does not change when saving states: callers compare it
+// before and after a command to know whether the latter moved in the history.
 func (h *Sources) Pos() int {
 	lh := h.getLineHistory()
 	if lh == nil {
 		return 0
 	}
 
-	return lh.pos
+	return lh.moves
 }
 
-// Reset will reset the current position in the list
-// of undo items, but will not delete any of them.
+// Reset cancels any pending skip of the next save,
+// but will not delete any of the undo items.
 func (h *Sources) Reset() {
 	h.skip = false
-
-	line := h.getLineHistory()
-	if line == nil {
-		return
-	}
-
-	if !h.undoing {
-		line.pos = 0
-	}
-
-	h.undoing = false
 }
 
 // Always returns a non-nil map, whether or not a history source is found.
@@ -246,7 +430,7 @@ func (h *Sources) restoreLineBuffer() {
 		return
 	}
 
-	undo := lh.items[len(lh.items)-1]
+	undo := lh.items[lh.current]
 
 	// Restore the line to the last known state.
 	h.line.Set([]rune(undo.line)...)
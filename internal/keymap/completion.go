@@ -1,8 +1,15 @@
 package keymap
 
-import "github.com/reeflective/readline/inputrc"
+import (
+	"maps"
+
+	"github.com/reeflective/readline/inputrc"
+)
 
 // menuselectKeys are the default keymaps in menuselect mode.
+// These binds are only defaults: users can rebind any key in
+// the menu-select keymap from their .inputrc configuration.
+// Groups are cycled with menu-complete-next/prev-tag.
 var menuselectKeys = map[string]inputrc.Bind{
 	unescape(`\C-i`):    {Action: "menu-complete"},
 	unescape(`\C-N`):    {Action: "menu-complete"},
@@ -10,12 +17,30 @@ var menuselectKeys = map[string]inputrc.Bind{
 	unescape(`\e[Z`):    {Action: "menu-complete-backward"},
 	unescape(`\C-@`):    {Action: "accept-and-menu-complete"},
 	unescape(`\C-F`):    {Action: "menu-incremental-search"},
+	unescape(`\C-G`):    {Action: "menu-cancel"},
+	unescape(`\M-\C-m`): {Action: "menu-accept"},
+	unescape(`\M-m`):    {Action: "menu-toggle-mark"},
 	unescape(`\e[A`):    {Action: "menu-complete-backward"},
 	unescape(`\e[B`):    {Action: "menu-complete"},
 	unescape(`\e[C`):    {Action: "menu-complete"},
 	unescape(`\e[D`):    {Action: "menu-complete-backward"},
 	unescape(`\e[1;5A`): {Action: "menu-complete-prev-tag"},
 	unescape(`\e[1;5B`): {Action: "menu-complete-next-tag"},
+	unescape(`\e[1;5C`): {Action: "menu-complete-next-alias"},
+	unescape(`\e[3;2~`): {Action: "delete-history-line"},
+	unescape(`\C-L`):    {Action: "clear-screen"},
+	unescape(`\M-\C-L`): {Action: "clear-display"},
+}
+
+// isearchKeys are the default binds specific to the isearch keymap,
+// in addition to the menuselect ones. Users can rebind them with
+// `set keymap isearch` in their .inputrc.
+var isearchKeys = map[string]inputrc.Bind{
+	unescape(`\C-i`): {Action: "isearch-complete-query"},
+	unescape(`\C-T`): {Action: "isearch-toggle-fuzzy"},
+	unescape(`\M-g`): {Action: "isearch-toggle-group"},
+	unescape(`\M-n`): {Action: "isearch-next-match"},
+	unescape(`\M-p`): {Action: "isearch-previous-match"},
 }
 
 // isearchCommands is a subset of commands that are valid in incremental-search mode.
@@ -50,6 +75,9 @@ var isearchCommands = []string{
 	"history-substring-search-backward",
 	"incremental-forward-search-history",
 	"incremental-reverse-search-history",
+	"history-index-search-forward",
+	"history-index-search-backward",
+	"delete-history-line",
 }
 
 // nonIsearchCommands is an even more restricted set of commands
@@ -89,6 +117,11 @@ func (m *Engine) getContextBinds(main bool) (binds map[string]inputrc.Bind) {
 		binds = m.restrictCommands(m.main, isearchCommands)
 	case m.nonIncSearch:
 		binds = m.restrictCommands(m.main, nonIsearchCommands)
+	case m.overlay != nil:
+		if overlay := m.overlay(m.main); len(overlay) > 0 {
+			binds = maps.Clone(binds)
+			maps.Copy(binds, overlay)
+		}
 	}
 
 	return
@@ -2,6 +2,8 @@ package keymap
 
 import (
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/user"
 	"sort"
@@ -13,17 +15,57 @@ import (
 // readline global options specific to this library.
 var readlineOptions = map[string]interface{}{
 	// General edition
-	"autopairs": false,
+	"autopairs":           false,
+	"end-of-file-command": "delete-char",
+	"vi-visual-kill-ring": true,
+	"vi-ignorecase":       false,
+	"vi-smartcase":        false,
+	"vi-wrapscan":         true,
+	"vi-shiftwidth":       8,
+	"vi-iskeyword":        "",
+	"vi-open-line-accept": false,
+	"vi-register-hint":    true,
+	"word-style":          "readline",
+	"word-chars":          "",
+	"paste-confirm":       false,
+	"paste-confirm-size":  1024,
+
+	// External filters
+	"filter-command":         "",
+	"history-filter-command": "",
 
 	// Completion
-	"autocomplete":               false,
-	"completion-list-separator":  "--",
-	"completion-selection-style": "\x1b[1;30m",
+	"autocomplete":                false,
+	"completion-list-separator":   "--",
+	"completion-selection-style":  "\x1b[1;30m",
+	"completion-preview-style":    "\x1b[4m",
+	"completion-alias-preference": "all",
+	"completion-align-groups":     false,
+	"completion-unique-space":     false,
+	"completion-no-match":         "",
+	"completion-menu-footer":      false,
+	"completion-footer-style":     "\x1b[2m",
+	"complete-buffer-words":       false,
+	"history-details":             false,
+	"history-group":               "",
 
 	// Prompt & General UI
-	"transient-prompt":    false,
-	"usage-hint-always":   false,
-	"history-autosuggest": false,
+	"transient-prompt":      false,
+	"partial-line-marker":   "%",
+	"usage-hint-always":     false,
+	"history-autosuggest":   false,
+	"history-diff-hint":     false,
+	"diagnostic-style":      "\x1b[4:3m",
+	"echo-transformed-line": false,
+	"max-redisplay-rate":    60,
+	"screen-reader":         false,
+	"which-key-delay":       0,
+	"cheat-sheet":           false,
+	"max-display-lines":     0,
+	"show-pending-command":  false,
+	"max-helper-rows":       0,
+	"helper-priority":       "hint",
+	"alternate-screen":      false,
 }
 
 // ReloadConfig parses all valid .inputrc configurations and immediately
@@ -33,13 +75,21 @@ func (m *Engine) ReloadConfig(opts ...inputrc.Option) (err error) {
 	m.loadBuiltinOptions()
 	m.loadBuiltinBinds()
 
+	// The user home directory and the environment variables are read
+	// from the shell environment, if it is not the process one.
 	user, _ := user.Current()
+	getenv := os.Getenv
+	env := inputrc.WithEnv(nil)
+
+	if m.getenv != nil {
+		user, getenv, env = nil, m.getenv, inputrc.WithEnv(m.getenv)
+	}
 
 	// Parse library-specific configurations.
 	//
 	// This library implements various additional commands and keymaps.
 	// Parse the configuration with a specific App name, ignoring errors.
-	inputrc.UserDefault(user, m.config, inputrc.WithApp("go"))
+	inputrc.UserDefault(user, m.config, inputrc.WithApp("go"), env)
 
 	// Parse user configurations.
 	//
@@ -47,7 +97,8 @@ func (m *Engine) ReloadConfig(opts ...inputrc.Option) (err error) {
 	// by /etc/inputrc on various Linux distros (for special keys).
 	defaults := []inputrc.Option{
 		inputrc.WithMode("emacs"),
-		inputrc.WithTerm(os.Getenv("TERM")),
+		inputrc.WithTerm(getenv("TERM")),
+		env,
 	}
 
 	opts = append(defaults, opts...)
@@ -106,15 +157,31 @@ func (m *Engine) loadBuiltinBinds() {
 		m.config.Binds[string(ViInsert)][seq] = bind
 	}
 
-	// Vim local keymaps
-	m.config.Binds[string(Visual)] = visualKeys
-	m.config.Binds[string(ViOpp)] = vioppKeys
-	m.config.Binds[string(MenuSelect)] = menuselectKeys
-	m.config.Binds[string(Isearch)] = menuselectKeys
+	// Vim local keymaps, copied so that binding keys in
+	// them does not modify those of all other shells.
+	m.config.Binds[string(Visual)] = maps.Clone(visualKeys)
+	m.config.Binds[string(ViOpp)] = maps.Clone(vioppKeys)
+
+	// Completion local keymaps, which can be configured by
+	// users with `set keymap menu-select` in their .inputrc.
+	for _, local := range []Mode{MenuSelect, Isearch} {
+		if m.config.Binds[string(local)] == nil {
+			m.config.Binds[string(local)] = make(map[string]inputrc.Bind)
+		}
+
+		for seq, bind := range menuselectKeys {
+			m.config.Binds[string(local)][seq] = bind
+		}
+	}
+
+	for seq, bind := range isearchKeys {
+		m.config.Binds[string(Isearch)][seq] = bind
+	}
 
 	// Default TTY binds
 	for _, keymap := range m.config.Binds {
 		keymap[inputrc.Unescape(`\C-C`)] = inputrc.Bind{Action: "abort"}
+		keymap[inputrc.Unescape(`\C-Z`)] = inputrc.Bind{Action: "suspend"}
 	}
 }
 
@@ -133,7 +200,7 @@ func (m *Engine) overrideBindsSpecial() {
 	}
 }
 
-func printBindsReadable(commands []string, all map[string][]string) {
+func printBindsReadable(out io.Writer, commands []string, all map[string][]string) {
 	for _, command := range commands {
 		commandBinds := all[command]
 		sort.Strings(commandBinds)
@@ -148,7 +215,7 @@ func printBindsReadable(commands []string, all map[string][]string) {
 			}
 
 			bindsStr := strings.Join(firstBinds, ", ")
-			fmt.Printf("%s can be found on %s ...\n", command, bindsStr)
+			fmt.Fprintf(out, "%s can be found on %s ...\n", command, bindsStr)
 
 		default:
 			var firstBinds []string
@@ -158,19 +225,19 @@ func printBindsReadable(commands []string, all map[string][]string) {
 			}
 
 			bindsStr := strings.Join(firstBinds, ", ")
-			fmt.Printf("%s can be found on %s\n", command, bindsStr)
+			fmt.Fprintf(out, "%s can be found on %s\n", command, bindsStr)
 		}
 	}
 }
 
-func printBindsInputrc(commands []string, all map[string][]string) {
+func printBindsInputrc(out io.Writer, commands []string, all map[string][]string) {
 	for _, command := range commands {
 		commandBinds := all[command]
 		sort.Strings(commandBinds)
 
 		if len(commandBinds) > 0 {
 			for _, bind := range commandBinds {
-				fmt.Printf("\"%s\": %s\n", bind, command)
+				fmt.Fprintf(out, "\"%s\": %s\n", bind, command)
 			}
 		}
 	}
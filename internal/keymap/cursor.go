@@ -56,19 +56,24 @@ var defaultCursors = map[Mode]CursorStyle{
 func (m *Engine) PrintCursor(keymap Mode) {
 	var cursor CursorStyle
 
+	// Cursor styles are purely decorative.
+	if m.config.GetBool("screen-reader") {
+		return
+	}
+
 	// Check for a configured cursor in .inputrc file.
 	cursorOptname := fmt.Sprintf("cursor-%s", string(keymap))
 	modeSet := strings.TrimSpace(m.config.GetString(cursorOptname))
 
 	if _, valid := cursors[CursorStyle(modeSet)]; valid {
-		fmt.Print(cursors[CursorStyle(modeSet)])
+		fmt.Fprint(m.out, cursors[CursorStyle(modeSet)])
 		return
 	}
 
 	if cursor, valid := defaultCursors[keymap]; valid {
-		fmt.Print(cursors[cursor])
+		fmt.Fprint(m.out, cursors[cursor])
 		return
 	}
 
-	fmt.Print(cursors[cursor])
+	fmt.Fprint(m.out, cursors[cursor])
 }
@@ -43,6 +43,8 @@ func MatchLocal(eng *Engine) (bind inputrc.Bind, command func(), prefix bool) {
 		bind, command, prefix = eng.handleEscape(false)
 	}
 
+	eng.setPrefixKeys(prefix, false, read)
+
 	return bind, command, prefix
 }
 
@@ -94,6 +96,8 @@ func MatchMain(eng *Engine) (bind inputrc.Bind, command func(), prefix bool) {
 		bind, command, prefix = eng.handleEscape(true)
 	}
 
+	eng.setPrefixKeys(prefix, true, read)
+
 	return bind, command, prefix
 }
 
@@ -183,6 +187,47 @@ func (m *Engine) matchBind(keys []byte, binds map[string]inputrc.Bind) (inputrc.
 	return match, prefixed
 }
 
+// PendingBinds returns the binds that can follow the keys matched by prefix
+// during the last dispatch, indexed by their remaining key sequence, along with
+// these prefix keys. When a Vim operator is pending for a movement instead, the
+// binds of both the operator pending and main keymaps are returned, and keys
+// is empty. If nothing is pending, no binds are returned.
+func (m *Engine) PendingBinds() (keys string, binds map[string]inputrc.Bind) {
+	binds = make(map[string]inputrc.Bind)
+
+	switch {
+	case m.prefixKeys != "":
+		m.addPendingBinds(binds, m.prefixKeys, m.getContextBinds(m.prefixMain))
+	case m.local == ViOpp:
+		m.addPendingBinds(binds, "", m.getContextBinds(true))
+		m.addPendingBinds(binds, "", m.getContextBinds(false))
+	}
+
+	return m.prefixKeys, binds
+}
+
+// addPendingBinds adds all binds whose sequence strictly starts with
+// the prefix keys to the pending binds, overwriting existing ones.
+func (m *Engine) addPendingBinds(pending map[string]inputrc.Bind, keys string, binds map[string]inputrc.Bind) {
+	for sequence, bind := range binds {
+		seq := strutil.ConvertMeta([]rune(sequence))
+
+		if len(seq) > len(keys) && strings.HasPrefix(seq, keys) {
+			pending[seq[len(keys):]] = bind
+		}
+	}
+}
+
+func (m *Engine) setPrefixKeys(prefix, main bool, read []byte) {
+	if !prefix {
+		m.prefixKeys = ""
+		return
+	}
+
+	m.prefixKeys = string(read)
+	m.prefixMain = main
+}
+
 func (m *Engine) resolve(bind inputrc.Bind) func() {
 	if bind.Macro {
 		return nil
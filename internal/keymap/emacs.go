@@ -14,6 +14,9 @@ var emacsKeys = map[string]inputrc.Bind{
 	unescape(`\C-x\C-e`): {Action: "edit-command-line"},
 	unescape(`\C-x\C-n`): {Action: "infer-next-history"},
 	unescape(`\C-x\C-o`): {Action: "overwrite-mode"},
+	unescape(`\C-x\C-s`): {Action: "toggle-prompt-secrets"},
+	unescape(`\C-x[`):    {Action: "backward-search-match"},
+	unescape(`\C-x]`):    {Action: "forward-search-match"},
 	unescape(`\C-Xr`):    {Action: "reverse-search-history"},
 	unescape(`\C-Xs`):    {Action: "forward-search-history"},
 	unescape(`\C-Xu`):    {Action: "undo"},
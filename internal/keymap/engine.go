@@ -5,6 +5,7 @@ import (
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/term"
 )
 
 // Engine is used to manage the main and local keymaps for the shell.
@@ -17,18 +18,25 @@ type Engine struct {
 	skip         bool
 	isCaller     bool
 	nonIncSearch bool
+	prefixKeys   string // Keys only matched by prefix in the last dispatch.
+	prefixMain   bool   // The prefix keys were matched in the main keymap.
+	pendingKeys  string // Keys (with iterations) of the pending operators.
 
+	out        *term.Output
 	keys       *core.Keys
 	iterations *core.Iterations
 	config     *inputrc.Config
 	commands   map[string]func()
+	overlay    func(main Mode) map[string]inputrc.Bind // Binds overlaying those of the main keymap.
+	getenv     func(string) string                     // Environment of the shell, or nil for the process one.
 }
 
 // NewEngine is a required constructor for the keymap modes manager.
 // It initializes the keymaps to their defaults or configured values.
-func NewEngine(keys *core.Keys, i *core.Iterations, opts ...inputrc.Option) (*Engine, *inputrc.Config) {
+func NewEngine(out *term.Output, keys *core.Keys, i *core.Iterations, opts ...inputrc.Option) (*Engine, *inputrc.Config) {
 	modes := &Engine{
 		main:       Emacs,
+		out:        out,
 		keys:       keys,
 		iterations: i,
 		config:     inputrc.NewDefaultConfig(),
@@ -41,6 +49,13 @@ func NewEngine(keys *core.Keys, i *core.Iterations, opts ...inputrc.Option) (*En
 	return modes, modes.config
 }
 
+// SetEnv sets the function reading the environment variables used when
+// loading the inputrc configuration (TERM, INPUTRC and the home directory),
+// instead of the process environment. A nil function restores the latter.
+func (m *Engine) SetEnv(getenv func(key string) string) {
+	m.getenv = getenv
+}
+
 // Register adds command functions to the list of available commands.
 // Each key of the map should be a unique name, not yet used by any
 // other builtin/user command, in order not to "overload" the builtins.
@@ -50,6 +65,13 @@ func (m *Engine) Register(commands map[string]func()) {
 	}
 }
 
+// SetOverlay sets a function returning binds overlaying those of the given
+// main keymap when dispatching keys to commands, like those of the binding
+// contexts currently active. These binds are not used by the search modes.
+func (m *Engine) SetOverlay(overlay func(main Mode) map[string]inputrc.Bind) {
+	m.overlay = overlay
+}
+
 // SetMain sets the main keymap of the shell.
 // Valid builtin keymaps are:
 // - emacs, emacs-meta, emacs-ctlx, emacs-standard.
@@ -159,30 +181,17 @@ func (m *Engine) PrintBinds(keymap string, inputrcFormat bool) {
 	}
 
 	if inputrcFormat {
-		printBindsInputrc(commands, allBinds)
+		printBindsInputrc(m.out, commands, allBinds)
 	} else {
-		printBindsReadable(commands, allBinds)
+		printBindsReadable(m.out, commands, allBinds)
 	}
 }
 
-// InputIsTerminator returns true when current input keys are one of
-// the configured or builtin "terminators", which can be configured
-// in .inputrc with the isearch-terminators variable.
+// InputIsTerminator returns true when the keys just dispatched are bound to
+// the abort command (like Ctrl-C or Ctrl-G), rather than abort being run by
+// another command. Keys typed ahead are left in the stack.
 func (m *Engine) InputIsTerminator() bool {
-	terminators := []string{
-		inputrc.Unescape(`\C-G`),
-		inputrc.Unescape(`\C-]`),
-	}
-
-	binds := make(map[string]inputrc.Bind)
-
-	for _, sequence := range terminators {
-		binds[sequence] = inputrc.Bind{Action: "abort", Macro: false}
-	}
-
-	bind, _, _, _ := m.dispatchKeys(binds)
-
-	return bind.Action == "abort"
+	return m.active.Action == "abort"
 }
 
 // Commands returns the map of all command functions available to the shell.
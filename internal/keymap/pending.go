@@ -18,10 +18,31 @@ func (m *Engine) Pending() {
 	m.SetLocal(ViOpp)
 	m.skip = true
 
+	if len(m.pending) == 0 {
+		m.pendingKeys = ""
+	}
+
+	// Record the keys typed for the operator, with its iterations.
+	if m.iterations.IsSet() {
+		m.pendingKeys += m.iterations.Typed()
+	}
+
+	m.pendingKeys += string(m.keys.Caller())
+
 	// Push the widget on the stack of widgets
 	m.pending = append(m.pending, m.active)
 }
 
+// PendingKeys returns the keys typed for the pending operators (eg. 3d), if
+// any, and the keys only matched by prefix during the last dispatch (eg. g).
+func (m *Engine) PendingKeys() (operators, prefix string) {
+	if len(m.pending) > 0 {
+		operators = m.pendingKeys
+	}
+
+	return operators, m.prefixKeys
+}
+
 // CancelPending is used by commands that have been registering themselves
 // as waiting for a pending operator, but have actually been called twice
 // in a row (eg. dd/yy in Vim mode). This removes those commands from queue.
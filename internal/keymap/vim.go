@@ -30,12 +30,14 @@ var viinsKeys = map[string]inputrc.Bind{
 // viinsKeymaps are the default keymaps in Vim Command mode.
 var vicmdKeys = map[string]inputrc.Bind{
 	unescape(`\M-`):     {Action: "vi-movement-mode"},
-	unescape(`\C-A`):    {Action: "switch-keyword"},
+	unescape(`\C-A`):    {Action: "vi-increment"},
 	unescape(`\C-L`):    {Action: "clear-screen"},
 	unescape(`\C-M`):    {Action: "accept-line"},
 	unescape(`\C-N`):    {Action: "next-history"},
 	unescape(`\C-P`):    {Action: "previous-history"},
-	unescape(`\C-X`):    {Action: "switch-keyword"},
+	unescape(`\C-R`):    {Action: "redo"},
+	unescape(`\C-V`):    {Action: "vi-visual-block-mode"},
+	unescape(`\C-X`):    {Action: "vi-decrement"},
 	unescape(`\M-<`):    {Action: "beginning-of-buffer-or-history"},
 	unescape(`\M->`):    {Action: "end-of-buffer-or-history"},
 	unescape(`\M-'`):    {Action: "quote-line"},
@@ -52,6 +54,8 @@ var vicmdKeys = map[string]inputrc.Bind{
 	unescape(`\e[1;5C`): {Action: "forward-word"},
 	unescape(`\e[1;5D`): {Action: "backward-word"},
 	unescape(" "):       {Action: "vi-forward-char"},
+	unescape("#"):       {Action: "vi-pound-insert"},
+	unescape(":"):       {Action: "vi-ex-command"},
 	unescape("$"):       {Action: "vi-end-of-line"},
 	unescape("%"):       {Action: "vi-match"},
 	unescape("\""):      {Action: "vi-set-buffer"},
@@ -59,11 +63,16 @@ var vicmdKeys = map[string]inputrc.Bind{
 	unescape("B"):       {Action: "vi-backward-bigword"},
 	unescape("e"):       {Action: "vi-end-word"},
 	unescape("E"):       {Action: "vi-end-bigword"},
-	unescape("gg"):      {Action: "beginning-of-buffer-or-history"},
-	unescape("ge"):      {Action: "vi-backward-end-word"},
-	unescape("gE"):      {Action: "vi-backward-end-bigword"},
+	unescape("G"):       {Action: "vi-goto-line"},
+	unescape("H"):       {Action: "vi-screen-top"},
+	unescape("L"):       {Action: "vi-screen-bottom"},
+	unescape("M"):       {Action: "vi-screen-middle"},
+	unescape("gg"):      {Action: "vi-goto-line"},
+	unescape("ge"):      {Action: "vi-backward-word-end"},
+	unescape("gE"):      {Action: "vi-backward-blank-word-end"},
 	unescape("gu"):      {Action: "vi-down-case"},
 	unescape("gU"):      {Action: "vi-up-case"},
+	unescape("g~"):      {Action: "vi-oper-swap-case"},
 	unescape("f"):       {Action: "vi-find-next-char"},
 	unescape("t"):       {Action: "vi-find-next-char-skip"},
 	unescape("i"):       {Action: "vi-insertion-mode"},
@@ -93,8 +102,14 @@ var vicmdKeys = map[string]inputrc.Bind{
 	unescape("X"):       {Action: "vi-backward-delete-char"},
 	unescape("y"):       {Action: "vi-yank-to"},
 	unescape("Y"):       {Action: "vi-yank-whole-line"},
+	unescape("zb"):      {Action: "vi-scroll-cursor-bottom"},
+	unescape("zt"):      {Action: "vi-scroll-cursor-top"},
+	unescape("zz"):      {Action: "vi-scroll-cursor-middle"},
 	unescape("|"):       {Action: "vi-column"},
+	unescape("'"):       {Action: "vi-goto-mark-line"},
 	unescape("~"):       {Action: "vi-change-case"},
+	unescape(">"):       {Action: "vi-indent"},
+	unescape("<"):       {Action: "vi-unindent"},
 	unescape("@"):       {Action: "macro-run"},
 }
 
@@ -110,8 +125,8 @@ var vioppKeys = map[string]inputrc.Bind{
 	unescape("ia"):  {Action: "select-in-shell-word"},
 	unescape("iw"):  {Action: "select-in-word"},
 	unescape("s"):   {Action: "vi-select-surround"},
-	unescape("j"):   {Action: "down-line"},
-	unescape("k"):   {Action: "up-line"},
+	unescape("j"):   {Action: "next-screen-line"},
+	unescape("k"):   {Action: "previous-screen-line"},
 }
 
 // viinsKeymaps are the default keymaps in Vim Visual mode.
@@ -135,5 +150,7 @@ var visualKeys = map[string]inputrc.Bind{
 	unescape("v"):   {Action: "vi-edit-command-line"},
 	unescape("x"):   {Action: "vi-delete-to"},
 	unescape("y"):   {Action: "vi-yank-to"},
-	unescape("~"):   {Action: "vi-swap-case"},
+	unescape("~"):   {Action: "vi-oper-swap-case"},
+	unescape(">"):   {Action: "vi-indent"},
+	unescape("<"):   {Action: "vi-unindent"},
 }
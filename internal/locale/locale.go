@@ -0,0 +1,175 @@
+// Package locale contains the catalog of all built-in messages displayed by
+// the shell (search prompts, hints, errors, etc.) and translates them with
+// the translator provided by the application, if any.
+package locale
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Built-in messages, identified by their English text. Some of them are
+// format strings: translations must keep the same verbs in the same order.
+const (
+	// History.
+	NoHistorySource = "No command history source"
+	HistoryError    = "history error: %s"
+	UndoHistory     = "undo history"
+	UndoTree        = "undo tree"
+	LineRestored    = "Unfinished line restored (undo to discard)"
+	Today           = "Today"
+	Yesterday       = "Yesterday"
+	Session         = "Session %s"
+	DeleteHistory   = "delete %q from history?"
+
+	// Incremental search.
+	Isearch            = "%s (isearch): "
+	IncSearch          = "%s (inc-search)"
+	FuzzySearch        = "%s (fuzzy-search)"
+	NonIncSearch       = "%s (non-inc-search): "
+	NoMatches          = " (no matches)"
+	MatchCount         = " (%d matches)"
+	MatchPosition      = " (match %d/%d)"
+	SearchScope        = " (in %s)"
+	QueryWords         = " (words: %s)"
+	IsearchRegexpError = "Failed to compile i-search regexp"
+
+	// Completions.
+	MoreCompletionRows = " %d more completion rows... (scroll down to show)"
+	LoadingCompletions = "loading..."
+	BufferWords        = "buffer words"
+
+	// Accepted lines.
+	ConfirmAccept = " (Enter or y to confirm)"
+
+	// Pastes.
+	PasteConfirm = "paste %d lines, %s — Enter to insert, Esc to cancel, e to edit in $EDITOR"
+
+	// Standalone prompts.
+	Select = "select"
+
+	// External filters.
+	FilterPrompt  = "filter: "
+	CommandPrompt = "command: "
+
+	// Pending keys.
+	MoreBinds = "... %d more binds"
+
+	// Registers and macros.
+	Registers       = "(registers)"
+	RegistersEmpty  = " - empty -"
+	Register        = "(register: %s)"
+	MoreRegisters   = "... %d more registers"
+	RecordingMacro  = "Recording macro: "
+	MacroArgRecord  = "REC (macro arg)"
+	MacroArgRun     = "Run (macro arg)"
+	MacroTooDeep    = "Macro recursion too deep"
+	InputrcReloaded = "Inputrc reloaded"
+
+	// Errors.
+	InputrcReloadError = "Inputrc reload error: %s"
+	SuspendError       = "Failed to suspend: %s"
+	EditorError        = "Editor error: %s"
+	FilterError        = "Filter error: %s"
+	CommandError       = "Command error: %s"
+
+	// Ex commands.
+	ExUnknownCommand     = "Not an editor command: %s"
+	ExInvalidRange       = "Invalid range"
+	ExInvalidPattern     = "Invalid pattern: %s"
+	ExPatternNotFound    = "Pattern not found: %s"
+	ExTrailingCharacters = "Trailing characters: %s"
+	ExUnknownOption      = "Unknown option: %s"
+	ExInvalidArgument    = "Invalid argument: %s"
+
+	// Screen readers.
+	ModeAnnounce = "%s mode"
+
+	// Cheat sheet.
+	CheatComplete = "complete"
+	CheatSearch   = "search"
+	CheatUndo     = "undo"
+	CheatKill     = "kill"
+	CheatYank     = "yank"
+	CheatPut      = "put"
+	CheatNormal   = "normal"
+	CheatInsert   = "insert"
+	CheatAppend   = "append"
+	CheatDelete   = "delete"
+	CheatChange   = "change"
+	CheatVisual   = "visual"
+	CheatCase     = "case"
+	CheatNext     = "next"
+	CheatPrevious = "previous"
+	CheatAccept   = "accept"
+	CheatFilter   = "filter"
+	CheatFuzzy    = "fuzzy"
+	CheatCancel   = "cancel"
+
+	// Completion menu footer.
+	MenuMatcher   = "matcher: %s"
+	MatcherRegexp = "regexp"
+	MatcherFuzzy  = "fuzzy"
+)
+
+// Messages returns all built-in messages, for instance to build catalogs.
+func Messages() []string {
+	return []string{
+		NoHistorySource, HistoryError, UndoHistory, UndoTree, LineRestored, Today, Yesterday, Session,
+		DeleteHistory,
+		Isearch, IncSearch, FuzzySearch, NonIncSearch, NoMatches, MatchCount, MatchPosition, SearchScope,
+		QueryWords, IsearchRegexpError,
+		MoreCompletionRows, LoadingCompletions, BufferWords,
+		MoreBinds, ConfirmAccept, PasteConfirm, Select, FilterPrompt, CommandPrompt,
+		Registers, RegistersEmpty, Register, MoreRegisters, RecordingMacro, MacroArgRecord, MacroArgRun, MacroTooDeep,
+		InputrcReloaded, InputrcReloadError, SuspendError, EditorError, FilterError, CommandError,
+		ExUnknownCommand, ExInvalidRange, ExInvalidPattern, ExPatternNotFound, ExTrailingCharacters,
+		ExUnknownOption, ExInvalidArgument,
+		ModeAnnounce,
+		CheatComplete, CheatSearch, CheatUndo, CheatKill, CheatYank, CheatPut, CheatNormal, CheatInsert,
+		CheatAppend, CheatDelete, CheatChange, CheatVisual, CheatCase, CheatNext, CheatPrevious, CheatAccept,
+		CheatFilter, CheatFuzzy, CheatCancel,
+		MenuMatcher, MatcherRegexp, MatcherFuzzy,
+	}
+}
+
+// Catalog translates the built-in messages displayed by a shell.
+// A nil or empty catalog returns all messages untranslated.
+type Catalog struct {
+	translate func(msg string) string
+	mutex     sync.RWMutex
+}
+
+// SetTranslator sets the function used to translate messages.
+// It should return the message itself when it has no translation.
+func (c *Catalog) SetTranslator(translate func(msg string) string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.translate = translate
+}
+
+// Get returns the translation of a message.
+func (c *Catalog) Get(msg string) string {
+	if c == nil {
+		return msg
+	}
+
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if c.translate == nil {
+		return msg
+	}
+
+	if translated := c.translate(msg); translated != "" {
+		return translated
+	}
+
+	return msg
+}
+
+// Sprintf returns the translation of a message formatted with the given arguments.
+func (c *Catalog) Sprintf(msg string, args ...any) string {
+	return fmt.Sprintf(c.Get(msg), args...)
+}
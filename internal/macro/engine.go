@@ -8,12 +8,19 @@ import (
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/locale"
+	"github.com/reeflective/readline/internal/term"
 	"github.com/reeflective/readline/internal/ui"
 )
 
 // validMacroKeys - All valid macro IDs (keys) for read/write Vim registers.
 var validMacroKeys = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789\""
 
+// maxDepth is the maximum number of macros ran without any key typed by the
+// user in between, like the Vim maxmapdepth option: this stops macros calling
+// themselves, which would otherwise feed keys forever without reading stdin.
+const maxDepth = 1000
+
 // Engine manages all things related to keyboard macros:
 // recording, dumping and feeding (running) them to the shell.
 type Engine struct {
@@ -21,20 +28,27 @@ type Engine struct {
 	current    []rune          // Key sequence of the current macro being recorded.
 	currentKey rune            // The identifier of the macro being recorded.
 	macros     map[rune]string // All previously recorded macros.
+	lastRun    rune            // The identifier of the last macro ran.
 	started    bool
-
-	keys   *core.Keys // The engine feeds macros directly in the key stack.
-	hint   *ui.Hint   // The engine notifies when macro recording starts/stops.
-	status string     // The hint status displaying the currently recorded macro.
+	depth      int // Number of macros ran since the user last typed keys.
+	typed      int // Number of key reads when the last macro was ran.
+
+	out    *term.Output    // Where macros are dumped.
+	keys   *core.Keys      // The engine feeds macros directly in the key stack.
+	hint   *ui.Hint        // The engine notifies when macro recording starts/stops.
+	locale *locale.Catalog // Translates the hint status.
+	status string          // The hint status displaying the currently recorded macro.
 }
 
 // NewEngine is a required constructor to setup a working macro engine.
-func NewEngine(keys *core.Keys, hint *ui.Hint) *Engine {
+func NewEngine(out *term.Output, keys *core.Keys, hint *ui.Hint, l *locale.Catalog) *Engine {
 	return &Engine{
 		current: make([]rune, 0),
 		macros:  make(map[rune]string),
+		out:     out,
 		keys:    keys,
 		hint:    hint,
+		locale:  l,
 	}
 }
 
@@ -76,7 +90,7 @@ func (e *Engine) StartRecord(key rune) {
 
 	e.started = true
 	e.recording = true
-	e.status = color.Dim + "Recording macro: " + color.Bold
+	e.status = color.Dim + e.locale.Get(locale.RecordingMacro) + color.Bold
 	e.hint.Persist(e.status)
 }
 
@@ -121,15 +135,21 @@ func (e *Engine) RunLastMacro() {
 		return
 	}
 
-	e.keys.Feed(false, []rune(macro)...)
+	e.feed(macro, 1)
 }
 
 // RunMacro runs a given macro, injecting its key sequence back into the shell key stack.
 // The key argument should either be one of the valid alphanumeric macro identifiers, or
-// a nil rune (in which case the last recorded macro is ran).
+// a nil rune (in which case the last recorded macro is ran), or '@', in which case the
+// last macro ran with this function is ran again, like `@@` in Vim. The macro is fed
+// as many times as the count argument.
 // Note that this function only feeds the keys of the macro back into the key
 // stack: it does not dispatch them to commands, therefore not running any.
-func (e *Engine) RunMacro(key rune) {
+func (e *Engine) RunMacro(key rune, count int) {
+	if key == '@' {
+		key = e.lastRun
+	}
+
 	if !isValidMacroID(key) && key != 0 {
 		return
 	}
@@ -139,8 +159,29 @@ func (e *Engine) RunMacro(key rune) {
 		return
 	}
 
-	macro = strings.ReplaceAll(macro, `\e`, "\x1b")
-	e.keys.Feed(false, []rune(macro)...)
+	e.lastRun = key
+
+	e.feed(inputrc.Unescape(macro), count)
+}
+
+// feed feeds the macro keys to the key stack, unless too many macros have
+// been ran since the user last typed some keys, which means that a macro is
+// calling itself (directly or not): in this case, the user is notified and
+// the macro is not fed again, so that the shell reads stdin again.
+func (e *Engine) feed(macro string, count int) {
+	if typed := core.Typed(e.keys); typed != e.typed {
+		e.typed = typed
+		e.depth = 0
+	}
+
+	e.depth++
+
+	if e.depth > maxDepth {
+		e.hint.SetTemporary(color.FgRed + e.locale.Get(locale.MacroTooDeep) + color.Reset)
+		return
+	}
+
+	e.keys.Feed(false, []rune(strings.Repeat(macro, count))...)
 }
 
 // PrintLastMacro dumps the last recorded macro sequence to the screen.
@@ -152,7 +193,7 @@ func (e *Engine) PrintLastMacro() {
 	// Print the macro and the prompt.
 	// The shell takes care of clearing itself
 	// before printing, and refreshing after.
-	fmt.Printf("\n%s\n", e.macros[e.currentKey])
+	fmt.Fprintf(e.out, "\n%s\n", e.macros[e.currentKey])
 }
 
 // PrintAllMacros dumps all macros to the screen, which one line
@@ -178,7 +219,7 @@ func (e *Engine) PrintAllMacros() {
 			macro = '"'
 		}
 
-		fmt.Printf("\"%s\": %s\n", string(macro), sequence)
+		fmt.Fprintf(e.out, "\"%s\": %s\n", string(macro), sequence)
 	}
 }
 
@@ -4,7 +4,6 @@ import (
 	"strings"
 
 	"github.com/reeflective/readline/internal/color"
-	"github.com/reeflective/readline/internal/term"
 	"github.com/rivo/uniseg"
 )
 
@@ -24,9 +23,9 @@ func RealLength(s string) int {
 }
 
 // LineSpan computes the number of columns and lines that are needed for a given line,
-// accounting for any ANSI escapes/color codes, and tabulations replaced with 4 spaces.
-func LineSpan(line []rune, idx, indent int) (x, y int) {
-	termWidth := term.GetWidth()
+// accounting for any ANSI escapes/color codes, and tabulations replaced with 4 spaces,
+// when displayed on a terminal of the given width.
+func LineSpan(line []rune, idx, indent, termWidth int) (x, y int) {
 	lineLen := RealLength(string(line))
 	lineLen += indent
 
@@ -0,0 +1,130 @@
+package strutil
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// AddNumber finds the first number under or after the cursor position on its
+// line, like vim's CTRL-A and CTRL-X do, and returns its positions in the line
+// along with the number with delta added to it. Numbers are either decimal
+// (possibly negative), hexadecimal (0x1f), binary (0b101) or octal (017, with
+// a leading zero): the latter three are unsigned, wrap around on 64 bits, and
+// keep their width, padded with zeros. Hexadecimal digits keep the case of the
+// last letter of the number. If there is no number, found is false.
+func AddNumber(line []rune, pos, delta int) (bpos, epos int, number string, found bool) {
+	bol := pos
+	for bol > 0 && line[bol-1] != '\n' {
+		bol--
+	}
+
+	for bpos = bol; bpos < len(line) && line[bpos] != '\n'; bpos = epos {
+		epos = bpos + 1
+
+		if !isDigit(line[bpos]) {
+			continue
+		}
+
+		var base int
+
+		base, epos = scanNumber(line, bpos)
+
+		// Decimal numbers might be negative.
+		if base == 10 && bpos > 0 && line[bpos-1] == '-' {
+			bpos--
+		}
+
+		if epos > pos {
+			return bpos, epos, addNumber(string(line[bpos:epos]), base, delta), true
+		}
+	}
+
+	return 0, 0, "", false
+}
+
+// scanNumber returns the base of the number starting at pos, and its end.
+func scanNumber(line []rune, pos int) (base, end int) {
+	prefixed := func(prefix rune, digit func(r rune) bool) bool {
+		return pos+2 < len(line) && line[pos] == '0' &&
+			unicode.ToLower(line[pos+1]) == prefix && digit(line[pos+2])
+	}
+
+	base, digit := 10, isDigit
+
+	switch {
+	case prefixed('x', isHexDigit):
+		base, digit, pos = 16, isHexDigit, pos+2
+	case prefixed('b', isBinDigit):
+		base, digit, pos = 2, isBinDigit, pos+2
+	}
+
+	end = pos
+	for end < len(line) && digit(line[end]) {
+		end++
+	}
+
+	// Decimal numbers with a leading zero are octal ones,
+	// unless they contain digits not valid in octal.
+	if base == 10 && line[pos] == '0' && end-pos > 1 {
+		base = 8
+
+		for _, char := range line[pos:end] {
+			if char > '7' {
+				base = 10
+			}
+		}
+	}
+
+	return base, end
+}
+
+// addNumber returns the number in the given base, with delta added to it.
+func addNumber(number string, base, delta int) string {
+	if base == 10 {
+		num, _ := new(big.Int).SetString(number, 10)
+		return num.Add(num, big.NewInt(int64(delta))).String()
+	}
+
+	prefix, digits := number[:1], number[1:]
+	if base != 8 {
+		prefix, digits = number[:2], number[2:]
+	}
+
+	num, _ := strconv.ParseUint(digits, base, 64)
+	sum := strconv.FormatUint(num+uint64(delta), base)
+
+	if width := len(digits) - len(sum); width > 0 {
+		sum = strings.Repeat("0", width) + sum
+	}
+
+	if base == 16 && lastLetterUpper(digits) {
+		sum = strings.ToUpper(sum)
+	}
+
+	return prefix + sum
+}
+
+// lastLetterUpper returns true if the last letter of the digits is uppercase.
+func lastLetterUpper(digits string) bool {
+	for i := len(digits) - 1; i >= 0; i-- {
+		if unicode.IsLetter(rune(digits[i])) {
+			return unicode.IsUpper(rune(digits[i]))
+		}
+	}
+
+	return false
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func isBinDigit(r rune) bool {
+	return r == '0' || r == '1'
+}
@@ -158,3 +158,17 @@ double:
 done:
 	return buf.String(), input, nil
 }
+
+// QuoteWord quotes a word containing blanks, so that it is split as a single
+// word again: with double quotes, or with single quotes if it contains some.
+func QuoteWord(word string) string {
+	if !strings.ContainsAny(word, " \t") {
+		return word
+	}
+
+	if strings.Contains(word, "\"") {
+		return "'" + word + "'"
+	}
+
+	return "\"" + word + "\""
+}
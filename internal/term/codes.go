@@ -1,8 +1,11 @@
 package term
 
+import "encoding/base64"
+
 // Terminal control sequences.
 const (
 	NewlineReturn = "\r\n"
+	Bell          = "\a"
 
 	ClearLineAfter   = "\x1b[0K"
 	ClearLineBefore  = "\x1b[1K"
@@ -16,6 +19,15 @@ const (
 	RestoreCursorPos = "\x1b8"
 	HideCursor       = "\x1b[?25l"
 	ShowCursor       = "\x1b[?25h"
+
+	ReverseScreen = "\x1b[?5h" // Displays the screen in reverse video, to flash it.
+	NormalScreen  = "\x1b[?5l"
+
+	AlternateScreen = "\x1b[?1049h" // Saves the cursor, and switches to a cleared alternate screen.
+	PrimaryScreen   = "\x1b[?1049l" // Switches back to the primary screen, and restores the cursor.
+
+	EnableBracketedPaste  = "\x1b[?2004h" // Pastes are enclosed in \e[200~ and \e[201~.
+	DisableBracketedPaste = "\x1b[?2004l"
 )
 
 // Some core keys needed by some stuff.
@@ -25,3 +37,9 @@ var (
 	ArrowRight = string([]byte{27, 91, 67}) // ^[[C
 	ArrowLeft  = string([]byte{27, 91, 68}) // ^[[D
 )
+
+// SetClipboard returns the sequence copying some text to the system
+// clipboard (OSC 52), supported by most terminals, even over SSH.
+func SetClipboard(text string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + Bell
+}
@@ -0,0 +1,85 @@
+package term
+
+import (
+	"runtime"
+	"sync"
+)
+
+// RawGuard puts a terminal into raw mode and guarantees that its original
+// state is restored at most once, whether this restoration is requested by
+// the caller (possibly from another goroutine), or by the garbage collector
+// when the guard is dropped without having been restored.
+type RawGuard struct {
+	term     Terminal
+	restored bool
+	mutex    sync.Mutex
+}
+
+// NewRawGuard puts the terminal into raw mode and returns a guard to restore it.
+func NewRawGuard(term Terminal) (*RawGuard, error) {
+	if err := term.EnterRaw(); err != nil {
+		return nil, err
+	}
+
+	guard := &RawGuard{term: term}
+
+	runtime.SetFinalizer(guard, func(g *RawGuard) {
+		g.Restore()
+	})
+
+	return guard, nil
+}
+
+// Restore restores the terminal to the state it was in before
+// the guard was created. Subsequent calls are no-ops.
+func (g *RawGuard) Restore() error {
+	if g == nil {
+		return nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.restored {
+		return nil
+	}
+
+	g.restored = true
+	runtime.SetFinalizer(g, nil)
+
+	return g.term.ExitRaw()
+}
+
+// Pause restores the terminal to its original state until Resume
+// is called, unless the guard is already restored.
+func (g *RawGuard) Pause() error {
+	if g == nil {
+		return nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.restored {
+		return nil
+	}
+
+	return g.term.ExitRaw()
+}
+
+// Resume puts the terminal back into raw mode, for instance after the
+// process has been stopped and continued, unless the guard is restored.
+func (g *RawGuard) Resume() error {
+	if g == nil {
+		return nil
+	}
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.restored {
+		return nil
+	}
+
+	return g.term.EnterRaw()
+}
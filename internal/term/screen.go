@@ -0,0 +1,339 @@
+package term
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// Screen is a minimal terminal screen emulator, supporting the
+// subset of control sequences used by readline shells: cursor
+// movements, line and screen clearing, switching to and from the
+// alternate screen, and SGR sequences (colors and effects), which
+// are recorded as the style of each cell. The screen scrolls when
+// writing below it.
+type Screen struct {
+	width  int
+	height int
+	lines  [][]Cell
+	x, y   int
+	style  string // SGR sequences applied to the next characters.
+	buf    []byte // Incomplete escape sequences or runes
+
+	// The primary screen lines and cursor, saved
+	// while the alternate screen is displayed.
+	primary            [][]Cell
+	primaryX, primaryY int
+}
+
+// Cell is a character displayed on a screen, with its style.
+type Cell struct {
+	Char  rune   // The character, or a space if none.
+	Style string // SGR sequences (colors, effects) applied to the character, if any.
+}
+
+// NewScreen returns an empty screen with the given dimensions.
+func NewScreen(width, height int) *Screen {
+	screen := &Screen{width: width, height: height}
+	screen.clear()
+
+	return screen
+}
+
+// Write implements io.Writer, and emulates the output on the screen.
+func (s *Screen) Write(buf []byte) (int, error) {
+	data := append(s.buf, buf...)
+	s.buf = nil
+
+	for len(data) > 0 {
+		switch data[0] {
+		case '\x1b':
+			consumed := s.escape(data)
+			if consumed == 0 {
+				s.buf = append(s.buf, data...)
+				return len(buf), nil
+			}
+
+			data = data[consumed:]
+
+		case '\r':
+			s.x = 0
+			data = data[1:]
+
+		case '\n':
+			s.lineFeed()
+			data = data[1:]
+
+		case '\a', '\b':
+			if data[0] == '\b' && s.x > 0 {
+				s.x--
+			}
+
+			data = data[1:]
+
+		default:
+			if !utf8.FullRune(data) {
+				s.buf = append(s.buf, data...)
+				return len(buf), nil
+			}
+
+			char, size := utf8.DecodeRune(data)
+			s.put(char)
+			data = data[size:]
+		}
+	}
+
+	return len(buf), nil
+}
+
+// String returns the text displayed on the screen, without
+// trailing spaces on each line, nor trailing empty lines.
+func (s *Screen) String() string {
+	return RenderCells(s.lines, false)
+}
+
+// Styled is like String, but the text keeps its styles.
+func (s *Screen) Styled() string {
+	return RenderCells(s.lines, true)
+}
+
+// Cells returns a copy of the cells displayed on the screen, row by row.
+func (s *Screen) Cells() [][]Cell {
+	cells := make([][]Cell, len(s.lines))
+
+	for i, line := range s.lines {
+		cells[i] = append([]Cell{}, line...)
+	}
+
+	return cells
+}
+
+// RenderCells returns the text of rows of cells, without trailing
+// spaces on each row, nor trailing empty rows. If styled is true,
+// the text keeps the styles of the cells as SGR sequences, and each
+// row ends with its styles reset.
+func RenderCells(cells [][]Cell, styled bool) string {
+	rows := make([]string, len(cells))
+
+	for i, row := range cells {
+		var text strings.Builder
+
+		end := len(row)
+		for end > 0 && row[end-1].Char == ' ' && (!styled || row[end-1].Style == "") {
+			end--
+		}
+
+		style := ""
+
+		for _, cell := range row[:end] {
+			if styled && cell.Style != style {
+				if style != "" {
+					text.WriteString(color.Reset)
+				}
+
+				text.WriteString(cell.Style)
+				style = cell.Style
+			}
+
+			text.WriteRune(cell.Char)
+		}
+
+		if style != "" {
+			text.WriteString(color.Reset)
+		}
+
+		rows[i] = text.String()
+	}
+
+	return strings.TrimRight(strings.Join(rows, "\n"), "\n")
+}
+
+// Cursor returns the cursor coordinates on the screen (0-based).
+func (s *Screen) Cursor() (x, y int) {
+	return s.x, s.y
+}
+
+// CursorReport returns the answer to a cursor position
+// query, as would be sent by a terminal (1-based).
+func (s *Screen) CursorReport() string {
+	return fmt.Sprintf("\x1b[%d;%dR", s.y+1, s.x+1)
+}
+
+func (s *Screen) clear() {
+	s.lines = make([][]Cell, s.height)
+	for i := range s.lines {
+		s.lines[i] = s.blankLine()
+	}
+}
+
+func (s *Screen) blankLine() []Cell {
+	line := make([]Cell, s.width)
+	for i := range line {
+		line[i] = Cell{Char: ' '}
+	}
+
+	return line
+}
+
+func (s *Screen) put(char rune) {
+	if s.x >= s.width {
+		s.x = 0
+		s.lineFeed()
+	}
+
+	s.lines[s.y][s.x] = Cell{Char: char, Style: s.style}
+	s.x++
+}
+
+func (s *Screen) lineFeed() {
+	if s.y < s.height-1 {
+		s.y++
+		return
+	}
+
+	s.lines = append(s.lines[1:], s.blankLine())
+}
+
+// escape handles an escape sequence at the beginning of data, and
+// returns the number of bytes consumed, or 0 if it is incomplete.
+func (s *Screen) escape(data []byte) int {
+	if len(data) < 2 {
+		return 0
+	}
+
+	// Operating system commands (like clipboard copies) are ignored,
+	// up to their terminator: either a bell or a string terminator.
+	if data[1] == ']' {
+		for end := 2; end < len(data); end++ {
+			switch {
+			case data[end] == '\a':
+				return end + 1
+			case data[end] == '\x1b' && end+1 < len(data) && data[end+1] == '\\':
+				return end + 2
+			}
+		}
+
+		return 0
+	}
+
+	// Save/restore cursor and other two-bytes sequences are ignored.
+	if data[1] != '[' {
+		return 2
+	}
+
+	// Find the final byte of the CSI sequence.
+	end := 2
+	for end < len(data) && (data[end] < 0x40 || data[end] > 0x7e) {
+		end++
+	}
+
+	if end == len(data) {
+		return 0
+	}
+
+	params := string(data[2:end])
+	arg := 1
+
+	if n, err := strconv.Atoi(params); err == nil {
+		arg = n
+	}
+
+	switch data[end] {
+	case 'A':
+		s.y = max(s.y-arg, 0)
+	case 'B':
+		s.y = min(s.y+arg, s.height-1)
+	case 'C':
+		s.x = min(s.x+arg, s.width-1)
+	case 'D':
+		s.x = max(s.x-arg, 0)
+	case 'H':
+		s.x, s.y = 0, 0
+	case 'K':
+		s.clearLine(params)
+	case 'J':
+		s.clearScreen(params)
+	case 'm':
+		s.setStyle(string(data[:end+1]), params)
+	case 'h':
+		if params == "?1049" {
+			s.alternateScreen()
+		}
+	case 'l':
+		if params == "?1049" {
+			s.primaryScreen()
+		}
+	}
+
+	return end + 1
+}
+
+// setStyle adds an SGR sequence to the style of the next
+// characters, or resets this style if the sequence does so.
+func (s *Screen) setStyle(sequence, params string) {
+	switch {
+	case params == "" || params == "0":
+		s.style = ""
+	case strings.HasPrefix(params, "0;"):
+		s.style = color.SGRStart + params[2:] + color.SGREnd
+	default:
+		s.style += sequence
+	}
+}
+
+// alternateScreen saves the primary screen and the
+// cursor, and displays a cleared alternate screen.
+func (s *Screen) alternateScreen() {
+	if s.primary != nil {
+		return
+	}
+
+	s.primary, s.primaryX, s.primaryY = s.lines, s.x, s.y
+	s.clear()
+}
+
+// primaryScreen displays the primary screen
+// again, with the cursor where it was saved.
+func (s *Screen) primaryScreen() {
+	if s.primary == nil {
+		return
+	}
+
+	s.lines, s.x, s.y = s.primary, s.primaryX, s.primaryY
+	s.primary = nil
+}
+
+func (s *Screen) clearLine(mode string) {
+	line := s.lines[s.y]
+
+	switch mode {
+	case "", "0":
+		for i := s.x; i < len(line); i++ {
+			line[i] = Cell{Char: ' '}
+		}
+	case "1":
+		for i := 0; i <= s.x && i < len(line); i++ {
+			line[i] = Cell{Char: ' '}
+		}
+	case "2":
+		for i := range line {
+			line[i] = Cell{Char: ' '}
+		}
+	}
+}
+
+func (s *Screen) clearScreen(mode string) {
+	switch mode {
+	case "", "0":
+		s.clearLine("0")
+
+		for i := s.y + 1; i < len(s.lines); i++ {
+			s.lines[i] = s.blankLine()
+		}
+	case "2", "3":
+		s.clear()
+	}
+}
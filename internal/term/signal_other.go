@@ -0,0 +1,8 @@
+//go:build !unix
+
+package term
+
+// Suspend is not supported on this platform, and does nothing.
+func (g *RawGuard) Suspend() error {
+	return nil
+}
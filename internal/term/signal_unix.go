@@ -0,0 +1,24 @@
+//go:build unix
+
+package term
+
+import "golang.org/x/sys/unix"
+
+// Suspend restores the terminal to its original state and stops the process
+// group, as would the terminal do when Ctrl-Z is pressed in canonical mode.
+// Once the process is continued, the terminal is put back into raw mode.
+func (g *RawGuard) Suspend() error {
+	if g == nil {
+		return nil
+	}
+
+	if err := g.Pause(); err != nil {
+		return err
+	}
+
+	if err := unix.Kill(0, unix.SIGTSTP); err != nil {
+		return err
+	}
+
+	return g.Resume()
+}
@@ -2,34 +2,68 @@ package term
 
 import (
 	"fmt"
+	"io"
 	"os"
-
-	"golang.org/x/term"
 )
 
-// Those variables are very important to realine low-level code: all virtual terminal
-// escape sequences should always be sent and read through the raw terminal file, even
-// if people start using io.MultiWriters and os.Pipes involving basic IO.
-var (
-	stdoutTerm *os.File
-	stdinTerm  *os.File
-	stderrTerm *os.File
-)
+// Terminal is the interface used to control the terminal on which
+// the shell is running: it enters and exits raw mode, and returns
+// the terminal size. The default terminal uses the process stdin
+// and stdout, but hosts may provide their own (eg. an SSH channel).
+type Terminal interface {
+	// EnterRaw puts the terminal into raw mode. It might be
+	// called again while already in raw mode, eg. after the
+	// process has been stopped and continued.
+	EnterRaw() error
+	// ExitRaw restores the terminal to the state it was
+	// in before the first call to EnterRaw.
+	ExitRaw() error
+	// Size returns the width and height of the terminal.
+	Size() (width, height int, err error)
+}
+
+// Resizer is implemented by terminals notifying size changes themselves, on
+// platforms where the shell cannot be notified with signals (eg. a browser).
+type Resizer interface {
+	// Resized returns a channel on which a value is sent after each resize.
+	Resized() <-chan struct{}
+}
+
+// Output is where a shell displays its interface. All virtual terminal escape
+// sequences should always be sent through the raw terminal writer, even if
+// people start using io.MultiWriters and os.Pipes involving basic IO, and
+// the terminal is queried for the size of the screen they are displayed on.
+//
+// Each shell has its own output, so that several shells can run concurrently
+// in the same process, each of them on its own terminal (eg. SSH channels).
+type Output struct {
+	io.Writer
+	Terminal Terminal
+}
+
+// NewOutput returns an output writing all escape sequences and interface
+// elements to the writer, and querying the size of the given terminal.
+func NewOutput(out io.Writer, term Terminal) *Output {
+	return &Output{Writer: out, Terminal: term}
+}
 
-func init() {
-	stdoutTerm = os.Stdout
-	stdoutTerm = os.Stderr
-	stderrTerm = os.Stdin
+// NewProcessOutput returns an output writing to the process stdout,
+// which is also the terminal queried, along with stdin for raw mode.
+func NewProcessOutput() *Output {
+	return NewOutput(os.Stdout, NewFileTerminal(os.Stdin, os.Stdout))
 }
 
 // fallback terminal width when we can't get it through query.
 var defaultTermWidth = 80
 
-// GetWidth returns the width of Stdout or 80 if the width cannot be established.
-func GetWidth() (termWidth int) {
+// Width returns the width of the terminal, or 80 if it cannot be established.
+func (o *Output) Width() (termWidth int) {
+	if o.Terminal == nil {
+		return defaultTermWidth
+	}
+
 	var err error
-	fd := int(stdoutTerm.Fd())
-	termWidth, _, err = GetSize(fd)
+	termWidth, _, err = o.Terminal.Size()
 
 	if err != nil || termWidth == 0 {
 		termWidth = defaultTermWidth
@@ -38,10 +72,14 @@ func GetWidth() (termWidth int) {
 	return
 }
 
-// GetLength returns the length of the terminal
+// Height returns the length of the terminal
 // (Y length), or 80 if it cannot be established.
-func GetLength() int {
-	_, length, err := term.GetSize(0)
+func (o *Output) Height() int {
+	if o.Terminal == nil {
+		return defaultTermWidth
+	}
+
+	_, length, err := o.Terminal.Size()
 
 	if err != nil || length == 0 {
 		return defaultTermWidth
@@ -50,7 +88,55 @@ func GetLength() int {
 	return length
 }
 
-func printf(format string, a ...interface{}) {
-	s := fmt.Sprintf(format, a...)
-	fmt.Print(s)
+// IsFileTerminal returns true if the terminal is connected to files,
+// like the process stdin and stdout, rather than provided by a host.
+func IsFileTerminal(term Terminal) bool {
+	_, isFile := term.(*fileTerminal)
+	return isFile
+}
+
+// fileTerminal is a terminal connected to
+// the file descriptors of the shell process.
+type fileTerminal struct {
+	in    *os.File
+	out   *os.File
+	state *State
+}
+
+// NewFileTerminal returns a terminal connected to the given files,
+// where raw mode is set on the input, and size queried on the output.
+func NewFileTerminal(in, out *os.File) Terminal {
+	return &fileTerminal{in: in, out: out}
+}
+
+func (t *fileTerminal) EnterRaw() error {
+	state, err := MakeRaw(int(t.in.Fd()))
+	if err != nil {
+		return err
+	}
+
+	if t.state == nil {
+		t.state = state
+	}
+
+	return nil
+}
+
+func (t *fileTerminal) ExitRaw() error {
+	if t.state == nil {
+		return nil
+	}
+
+	state := t.state
+	t.state = nil
+
+	return Restore(int(t.in.Fd()), state)
+}
+
+func (t *fileTerminal) Size() (width, height int, err error) {
+	return GetSize(int(t.out.Fd()))
+}
+
+func (o *Output) printf(format string, a ...interface{}) {
+	fmt.Fprintf(o, format, a...)
 }
@@ -0,0 +1,71 @@
+package ui
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/reeflective/readline/internal/color"
+)
+
+// badge is a notification badge displayed in the prompt.
+type badge struct {
+	name    string
+	text    string
+	expires time.Time // The badge never expires if zero.
+}
+
+// Badge attaches a small notification badge (like "2 jobs" or "mail") to the
+// prompt: from the next redisplay on, badges are displayed in the order they
+// were added, in front of the last line of the primary prompt, without the
+// prompt function having to build them. Setting a badge with the name of an
+// existing one replaces its text. If duration is positive, the badge expires
+// after it, if not cleared before. Badges can be set from any goroutine.
+func (p *Prompt) Badge(name, text string, duration time.Duration) {
+	p.badgeMutex.Lock()
+	defer p.badgeMutex.Unlock()
+
+	added := badge{name: name, text: text}
+	if duration > 0 {
+		added.expires = time.Now().Add(duration)
+	}
+
+	index := slices.IndexFunc(p.badges, func(b badge) bool { return b.name == name })
+	if index == -1 {
+		p.badges = append(p.badges, added)
+	} else {
+		p.badges[index] = added
+	}
+}
+
+// ClearBadge removes the named badge from the prompt, or all
+// badges if the name is empty. It can be called from any goroutine.
+func (p *Prompt) ClearBadge(name string) {
+	p.badgeMutex.Lock()
+	defer p.badgeMutex.Unlock()
+
+	p.badges = slices.DeleteFunc(p.badges, func(b badge) bool {
+		return name == "" || b.name == name
+	})
+}
+
+// formatBadges removes the expired badges, and returns the other
+// ones, each one dimmed between brackets and followed by a space.
+func (p *Prompt) formatBadges() string {
+	p.badgeMutex.Lock()
+	defer p.badgeMutex.Unlock()
+
+	now := time.Now()
+
+	p.badges = slices.DeleteFunc(p.badges, func(b badge) bool {
+		return !b.expires.IsZero() && !now.Before(b.expires)
+	})
+
+	var badges strings.Builder
+
+	for _, b := range p.badges {
+		badges.WriteString(color.Dim + "[" + b.text + "]" + color.DimReset + " ")
+	}
+
+	return badges.String()
+}
@@ -0,0 +1,29 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// visibleBellDuration is how long the screen is flashed by the visible bell.
+const visibleBellDuration = 100 * time.Millisecond
+
+// Bell rings the bell as set by the bell-style option: the terminal bell
+// if "audible", a brief flash of the screen (in reverse video) if "visible",
+// and nothing if "none".
+func Bell(out io.Writer, opts *inputrc.Config) {
+	switch strings.Trim(opts.GetString("bell-style"), "\"") {
+	case "none", "off":
+	case "visible":
+		fmt.Fprint(out, term.ReverseScreen)
+		time.Sleep(visibleBellDuration)
+		fmt.Fprint(out, term.NormalScreen)
+	default:
+		fmt.Fprint(out, term.Bell)
+	}
+}
@@ -15,9 +15,13 @@ import (
 type Hint struct {
 	text       []rune
 	persistent []rune
+	provided   []rune
+	diagnosed  []rune
+	guide      []rune
 	cleanup    bool
 	temp       bool
 	set        bool
+	maxRows    int
 }
 
 // Set sets the hint message to the given text.
@@ -42,6 +46,40 @@ func (h *Hint) Persist(hint string) {
 	h.persistent = []rune(hint)
 }
 
+// Provide sets the hint message provided by the application for the current
+// line. It is displayed when no other hint message is set, like completion or
+// search hints, and is neither dropped by hint.Reset(), nor by commands.
+func (h *Hint) Provide(hint string) {
+	h.provided = []rune(hint)
+}
+
+// Diagnose sets the messages of the diagnostics reported on the line, displayed
+// below the other hints but above the guide line, and neither dropped by hint.Reset(),
+// nor by commands.
+func (h *Hint) Diagnose(messages string) {
+	h.diagnosed = []rune(messages)
+}
+
+// Guide sets the guide line, displayed below all other hint sections,
+// and neither dropped by hint.Reset(), nor by commands. An empty guide
+// is not displayed.
+func (h *Hint) Guide(guide string) {
+	h.guide = []rune(guide)
+}
+
+// Snapshot returns a function restoring the hint (persistent
+// and temporary sections) as it is when this function is called.
+func (h *Hint) Snapshot() (restore func()) {
+	saved := *h
+	saved.text = append([]rune{}, h.text...)
+	saved.persistent = append([]rune{}, h.persistent...)
+	saved.provided = append([]rune{}, h.provided...)
+
+	return func() {
+		*h = saved
+	}
+}
+
 // Text returns the current hint text.
 func (h *Hint) Text() string {
 	return string(h.text)
@@ -68,17 +106,32 @@ func (h *Hint) ResetPersist() {
 	h.persistent = make([]rune, 0)
 }
 
-// DisplayHint prints the hint (persistent and/or temporary) sections.
-func DisplayHint(hint *Hint) {
-	if hint.temp && hint.set {
-		hint.set = false
-	} else if hint.temp {
-		hint.Reset()
+// SetMaxRows sets the maximum number of terminal rows used by the hint: longer
+// hints are cropped, their last displayed row ending with an ellipsis. Zero or
+// a negative number of rows displays the entire hint (the default).
+func (h *Hint) SetMaxRows(rows int) {
+	h.maxRows = rows
+}
+
+// Expire drops the temporary hint if it has already been displayed
+// once, or marks it as displayed otherwise. This is called when
+// displaying the hint.
+func (h *Hint) Expire() {
+	if h.temp && h.set {
+		h.set = false
+	} else if h.temp {
+		h.Reset()
 	}
+}
+
+// DisplayHint prints the hint (persistent and/or temporary) sections to the output.
+func DisplayHint(out *term.Output, hint *Hint) {
+	hint.Expire()
 
-	if len(hint.text) == 0 && len(hint.persistent) == 0 {
+	if len(hint.text) == 0 && len(hint.persistent) == 0 && len(hint.provided) == 0 &&
+		len(hint.diagnosed) == 0 && len(hint.guide) == 0 {
 		if hint.cleanup {
-			fmt.Print(term.ClearLineAfter)
+			fmt.Fprint(out, term.ClearLineAfter)
 		}
 
 		hint.cleanup = false
@@ -86,7 +139,7 @@ func DisplayHint(hint *Hint) {
 		return
 	}
 
-	text := hint.renderHint()
+	text := hint.renderHint(out.Width())
 
 	if strutil.RealLength(text) == 0 {
 		return
@@ -95,32 +148,47 @@ func DisplayHint(hint *Hint) {
 	text += term.ClearLineAfter + color.Reset
 
 	if len(text) > 0 {
-		fmt.Print(text)
+		fmt.Fprint(out, text)
 	}
 }
 
-func (h *Hint) renderHint() (text string) {
+func (h *Hint) renderHint(width int) (text string) {
 	if len(h.persistent) > 0 {
 		text += string(h.persistent) + term.NewlineReturn
 	}
 
 	if len(h.text) > 0 {
 		text += string(h.text) + term.NewlineReturn
+	} else if len(h.provided) > 0 {
+		text += string(h.provided) + color.Reset + term.NewlineReturn
+	}
+
+	if len(h.diagnosed) > 0 {
+		text += string(h.diagnosed) + color.Reset + term.NewlineReturn
+	}
+
+	if len(h.guide) > 0 {
+		text += string(h.guide) + color.Reset + term.NewlineReturn
 	}
 
 	if strutil.RealLength(text) == 0 {
 		return
 	}
 
+	if h.maxRows > 0 {
+		text = cropHint(text, width, h.maxRows)
+	}
+
 	// Ensure cross-platform, real display newline.
 	text = strings.ReplaceAll(text, term.NewlineReturn, term.ClearLineAfter+term.NewlineReturn)
 
 	return text
 }
 
-// CoordinatesHint returns the number of terminal rows used by the hint.
-func CoordinatesHint(hint *Hint) int {
-	text := hint.renderHint()
+// CoordinatesHint returns the number of terminal rows used
+// by the hint, when displayed on a terminal of the given width.
+func CoordinatesHint(hint *Hint, width int) int {
+	text := hint.renderHint(width)
 
 	// Nothing to do if no real text
 	text = strings.TrimSuffix(text, term.ClearLineAfter+term.NewlineReturn)
@@ -134,7 +202,7 @@ func CoordinatesHint(hint *Hint) int {
 	lines := strings.Split(text, term.ClearLineAfter)
 
 	for i, line := range lines {
-		x, y := strutil.LineSpan([]rune(line), i, 0)
+		x, y := strutil.LineSpan([]rune(line), i, 0, width)
 		if x != 0 {
 			y++
 		}
@@ -144,3 +212,56 @@ func CoordinatesHint(hint *Hint) int {
 
 	return usedY
 }
+
+// cropHint keeps the lines of a hint fitting in the given number of terminal
+// rows, and trims the last one with an ellipsis if some of the hint is cut.
+func cropHint(text string, width, maxRows int) string {
+	lines := strings.SplitAfter(text, term.NewlineReturn)
+
+	var cropped strings.Builder
+
+	rows := 0
+
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+
+		content := strings.TrimSuffix(line, term.NewlineReturn)
+
+		x, y := strutil.LineSpan([]rune(content), i, 0, width)
+		if x != 0 || y == 0 {
+			y++
+		}
+
+		if rows+y <= maxRows {
+			cropped.WriteString(line)
+			rows += y
+
+			continue
+		}
+
+		// Trim the first line not fitting, or the last one fitting
+		// if there is no room left, to end it with the ellipsis.
+		remain := maxRows - rows
+		if remain == 0 {
+			kept := strings.TrimSuffix(cropped.String(), term.NewlineReturn)
+			start := strings.LastIndex(kept, term.NewlineReturn) + 1
+			if start > 0 {
+				start += len(term.NewlineReturn) - 1
+			}
+
+			cropped.Reset()
+			cropped.WriteString(kept[:start])
+
+			content = kept[start:]
+			remain = 1
+		}
+
+		cropped.WriteString(color.Trim(content, remain*width-1) + color.Reset + "…" + term.NewlineReturn)
+
+		break
+	}
+
+	return cropped.String()
+}
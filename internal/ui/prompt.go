@@ -4,14 +4,22 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
 	"github.com/reeflective/readline/internal/core"
 	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/strutil"
 	"github.com/reeflective/readline/internal/term"
 )
 
+// Bash readline begin/end non-printable delimiters in mode strings.
+var (
+	rxNonPrintBegin = regexp.MustCompile(`\\1`)
+	rxNonPrintEnd   = regexp.MustCompile(`\\2`)
+)
+
 // Prompt stores all prompt rendering/generation functions and is
 // in charge of displaying them, as well as computing their offsets.
 type Prompt struct {
@@ -28,7 +36,17 @@ type Prompt struct {
 	// since last loop. Check refresh prompt funcs.
 	refreshing bool
 
+	// True if secret segments are displayed unmasked.
+	revealed bool
+
+	// Notification badges, set from any goroutine,
+	// and the ones displayed by the last prompt print.
+	badges      []badge
+	badgeMutex  sync.Mutex
+	badgesShown string
+
 	// Shell parameters
+	out     *term.Output
 	line    *core.Line
 	cursor  *core.Cursor
 	keymaps *keymap.Engine
@@ -36,8 +54,9 @@ type Prompt struct {
 }
 
 // NewPrompt is a required constructor to initialize the prompt system.
-func NewPrompt(line *core.Line, cursor *core.Cursor, keymaps *keymap.Engine, opts *inputrc.Config) *Prompt {
+func NewPrompt(out *term.Output, line *core.Line, cursor *core.Cursor, keymaps *keymap.Engine, opts *inputrc.Config) *Prompt {
 	return &Prompt{
+		out:     out,
 		line:    line,
 		cursor:  cursor,
 		keymaps: keymaps,
@@ -47,22 +66,34 @@ func NewPrompt(line *core.Line, cursor *core.Cursor, keymaps *keymap.Engine, opt
 
 // Primary uses a function returning the string to use as the primary prompt.
 func (p *Prompt) Primary(prompt func() string) {
-	p.primaryF = prompt
+	p.primaryF = p.withSecrets(prompt)
+}
+
+// Snapshot returns a function restoring all prompt
+// functions as they are when this function is called.
+func (p *Prompt) Snapshot() (restore func()) {
+	primary, secondary, transient := p.primaryF, p.secondaryF, p.transientF
+	right, tooltip := p.rightF, p.tooltipF
+
+	return func() {
+		p.primaryF, p.secondaryF, p.transientF = primary, secondary, transient
+		p.rightF, p.tooltipF = right, tooltip
+	}
 }
 
 // Right uses a function returning the string to use as the right prompt.
 func (p *Prompt) Right(prompt func() string) {
-	p.rightF = prompt
+	p.rightF = p.withSecrets(prompt)
 }
 
 // Secondary uses a function returning the prompt to use as the secondary prompt.
 func (p *Prompt) Secondary(prompt func() string) {
-	p.secondaryF = prompt
+	p.secondaryF = p.withSecrets(prompt)
 }
 
 // Transient uses a function returning the prompt to use as a transient prompt.
 func (p *Prompt) Transient(prompt func() string) {
-	p.transientF = prompt
+	p.transientF = p.withSecrets(prompt)
 }
 
 // Tooltip uses a function returning the prompt to use as a tooltip prompt.
@@ -81,7 +112,7 @@ func (p *Prompt) Tooltip(prompt func(word string) string) {
 			tooltipWord = shellWords[0]
 		}
 
-		return prompt(tooltipWord)
+		return p.formatSecrets(prompt(tooltipWord))
 	}
 }
 
@@ -95,6 +126,7 @@ func (p *Prompt) PrimaryPrint() {
 	}
 
 	prompt := p.primaryF()
+	p.badgesShown = p.formatBadges()
 
 	prompt, lastPrompt := p.formatPrimaryLines(prompt)
 
@@ -103,10 +135,10 @@ func (p *Prompt) PrimaryPrint() {
 
 	// Print the various lines.
 	if prompt != "" {
-		fmt.Print(prompt)
+		fmt.Fprint(p.out, prompt)
 	}
 
-	fmt.Print(lastPrompt)
+	fmt.Fprint(p.out, lastPrompt)
 
 	// And compute coordinates
 	p.primaryRows = strings.Count(prompt, "\n")
@@ -117,6 +149,39 @@ func (p *Prompt) PrimaryPrint() {
 	}
 }
 
+// PlainPrint prints the entire primary prompt string, stripped of
+// any escape sequence, for use on terminals lacking capabilities.
+func (p *Prompt) PlainPrint() {
+	if p.primaryF == nil {
+		return
+	}
+
+	fmt.Fprint(p.out, color.Strip(p.primaryF()))
+}
+
+// PrimaryText returns the entire primary prompt string, with the badges and
+// mode string last printed with it, but stripped of any escape sequence.
+func (p *Prompt) PrimaryText() string {
+	if p.primaryF == nil {
+		return ""
+	}
+
+	lines := strings.Split(p.primaryF(), "\n")
+	lines[len(lines)-1] = p.formatLastPrompt(lines[len(lines)-1])
+
+	return color.Strip(strings.Join(lines, "\n"))
+}
+
+// TransientText returns the transient prompt string stripped of any
+// escape sequence, or false if there is no transient prompt.
+func (p *Prompt) TransientText() (string, bool) {
+	if p.transientF == nil {
+		return "", false
+	}
+
+	return color.Strip(p.transientF()), true
+}
+
 // PrimaryUsed returns the number of terminal rows on which
 // the primary prompt string spans, excluding the last line
 // if it contains newlines.
@@ -142,9 +207,10 @@ func (p *Prompt) LastPrint() {
 		return
 	}
 
+	p.badgesShown = p.formatBadges()
 	prompt := p.formatLastPrompt(lines[len(lines)-1])
 
-	fmt.Print(prompt)
+	fmt.Fprint(p.out, prompt)
 
 	p.primaryCols = strutil.RealLength(prompt)
 	if p.primaryCols > 0 {
@@ -199,9 +265,19 @@ func (p *Prompt) RightPrint(startColumn int, force bool) {
 	}
 
 	if prompt, canPrint := p.formatRightPrompt(rprompt, startColumn); canPrint {
-		fmt.Print(prompt)
+		fmt.Fprint(p.out, prompt)
+	} else {
+		fmt.Fprint(p.out, term.ClearLineAfter)
+	}
+}
+
+// StatusPrint prints a status string (like a pending command) on
+// the right side of the last line, in place of the right prompt.
+func (p *Prompt) StatusPrint(status string, startColumn int) {
+	if prompt, canPrint := p.formatRightPrompt(status, startColumn); canPrint {
+		fmt.Fprint(p.out, prompt)
 	} else {
-		fmt.Print(term.ClearLineAfter)
+		fmt.Fprint(p.out, term.ClearLineAfter)
 	}
 }
 
@@ -212,12 +288,12 @@ func (p *Prompt) TransientPrint() {
 	}
 
 	// Clean everything below where the prompt will be printed.
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorUp(p.primaryRows)
-	fmt.Print(term.ClearScreenBelow)
+	p.out.MoveCursorBackwards(p.out.Width())
+	p.out.MoveCursorUp(p.primaryRows)
+	fmt.Fprint(p.out, term.ClearScreenBelow)
 
 	// And print the prompt
-	fmt.Print(p.transientF())
+	fmt.Fprint(p.out, p.transientF())
 }
 
 // Refreshing returns true if the prompt is currently redisplaying
@@ -227,6 +303,8 @@ func (p *Prompt) Refreshing() bool {
 }
 
 func (p *Prompt) formatLastPrompt(prompt string) string {
+	prompt = p.badgesShown + prompt
+
 	if !p.opts.GetBool("show-mode-in-prompt") {
 		return prompt
 	}
@@ -246,18 +324,15 @@ func (p *Prompt) formatLastPrompt(prompt string) string {
 	// values, and remove bash readline begin/end non-printable delimiters.
 	status = strings.Trim(status, "\"")
 
-	begin := regexp.MustCompile(`\\1`)
-	end := regexp.MustCompile(`\\2`)
-
-	status = begin.ReplaceAllString(status, "")
-	status = end.ReplaceAllString(status, "")
+	status = rxNonPrintBegin.ReplaceAllString(status, "")
+	status = rxNonPrintEnd.ReplaceAllString(status, "")
 
 	return status + prompt
 }
 
 func (p *Prompt) formatRightPrompt(rprompt string, startColumn int) (prompt string, canPrint bool) {
 	// Dimensions
-	termWidth := term.GetWidth()
+	termWidth := p.out.Width()
 	promptLen := strutil.RealLength(rprompt)
 	padLen := termWidth - startColumn - promptLen
 
@@ -0,0 +1,110 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// Delimiters of secret prompt segments, from the Unicode private use area.
+const (
+	secretBegin = '\uE000'
+	secretEnd   = '\uE001'
+)
+
+// secretMask is the character displayed in place of secret characters.
+const secretMask = '*'
+
+// Secret marks a segment of a prompt string as sensitive: unless revealed,
+// its characters are displayed masked, while its colors are preserved.
+func Secret(segment string) string {
+	return string(secretBegin) + segment + string(secretEnd)
+}
+
+// RevealSecrets sets whether secret prompt segments are
+// displayed as they are (if true), or masked (if false).
+func (p *Prompt) RevealSecrets(reveal bool) {
+	p.revealed = reveal
+}
+
+// SecretsRevealed returns true if secret prompt segments are displayed as they are.
+func (p *Prompt) SecretsRevealed() bool {
+	return p.revealed
+}
+
+// withSecrets wraps a prompt function so that the secret
+// segments of its prompt are masked, unless revealed.
+func (p *Prompt) withSecrets(prompt func() string) func() string {
+	if prompt == nil {
+		return nil
+	}
+
+	return func() string {
+		return p.formatSecrets(prompt())
+	}
+}
+
+// formatSecrets removes the delimiters of all secret segments
+// in a prompt string, and masks these segments unless revealed.
+func (p *Prompt) formatSecrets(prompt string) string {
+	if !strings.ContainsRune(prompt, secretBegin) {
+		return prompt
+	}
+
+	var formatted strings.Builder
+
+	for {
+		start := strings.IndexRune(prompt, secretBegin)
+		if start == -1 {
+			break
+		}
+
+		formatted.WriteString(prompt[:start])
+		prompt = prompt[start+len(string(secretBegin)):]
+
+		segment := prompt
+		prompt = ""
+
+		if end := strings.IndexRune(segment, secretEnd); end != -1 {
+			segment, prompt = segment[:end], segment[end+len(string(secretEnd)):]
+		}
+
+		if p.revealed {
+			formatted.WriteString(segment)
+		} else {
+			formatted.WriteString(maskSecret(segment))
+		}
+	}
+
+	formatted.WriteString(prompt)
+
+	return strings.ReplaceAll(formatted.String(), string(secretEnd), "")
+}
+
+// maskSecret replaces all printable characters of a string with as many mask
+// characters as the columns they use, and leaves escape sequences as they are.
+func maskSecret(segment string) string {
+	var masked strings.Builder
+
+	// 0: printable, 1: after an escape, 2: in a control sequence.
+	escape := 0
+
+	for _, char := range segment {
+		switch {
+		case char == '\x1b':
+			escape = 1
+		case escape == 1 && char == '[':
+			escape = 2
+		case escape == 1, escape == 2 && char >= '@' && char <= '~':
+			escape = 0
+		case escape == 2:
+		default:
+			masked.WriteString(strings.Repeat(string(secretMask), uniseg.StringWidth(string(char))))
+			continue
+		}
+
+		masked.WriteRune(char)
+	}
+
+	return masked.String()
+}
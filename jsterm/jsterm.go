@@ -0,0 +1,235 @@
+//go:build js && wasm
+
+// Package jsterm runs readline shells in a browser, when compiled with
+// GOOS=js and GOARCH=wasm: it provides a terminal exchanging input and
+// output with xterm.js (or any other browser terminal) through callbacks,
+// so that web-based REPLs can reuse all readline editing, completion and
+// history features.
+//
+// Example usage, with an xterm.js terminal stored in a global variable:
+//
+//	xterm := js.Global().Get("term")
+//
+//	term := jsterm.Attach(xterm)
+//	rl := term.Shell()
+//
+//	for {
+//		line, err := rl.Readline()
+//		...
+//	}
+//
+// Other browser terminals can be used by creating a terminal with New, and
+// by feeding it with the user input and the terminal size changes.
+//
+// Note that the input, output and terminal used by readline shells are
+// currently global, so only one terminal can be used at a time.
+package jsterm
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"syscall/js"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// ErrClosed is returned when writing to a closed terminal.
+var ErrClosed = errors.New("terminal closed")
+
+// Terminal is a browser terminal used to run readline shells: it implements
+// the readline.TermState interface, an io.Reader from which the shell reads
+// the input sent by the browser terminal, and an io.Writer passing all the
+// shell output to it.
+type Terminal struct {
+	width   int
+	height  int
+	input   []byte
+	ready   chan struct{}
+	resized chan struct{}
+	closed  bool
+	write   func(data string)
+	release func()
+	mutex   sync.Mutex
+}
+
+// New returns a terminal with the given dimensions, which passes
+// all output written by shells to the write function.
+func New(width, height int, write func(data string)) *Terminal {
+	return &Terminal{
+		width:   width,
+		height:  height,
+		ready:   make(chan struct{}, 1),
+		resized: make(chan struct{}, 1),
+		write:   write,
+	}
+}
+
+// Attach returns a terminal bound to an xterm.js terminal object: the output
+// is written to it, and its data and resize events are forwarded to shells.
+// Other JS objects can be used, as long as they implement the write(data),
+// onData(callback) and onResize(callback) methods and have cols and rows.
+func Attach(xterm js.Value) *Terminal {
+	write := func(data string) {
+		xterm.Call("write", data)
+	}
+
+	t := New(xterm.Get("cols").Int(), xterm.Get("rows").Int(), write)
+
+	onData := js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) > 0 {
+			t.Input(args[0].String())
+		}
+
+		return nil
+	})
+
+	onResize := js.FuncOf(func(this js.Value, args []js.Value) any {
+		if len(args) > 0 {
+			t.Resize(args[0].Get("cols").Int(), args[0].Get("rows").Int())
+		}
+
+		return nil
+	})
+
+	dataListener := xterm.Call("onData", onData)
+	resizeListener := xterm.Call("onResize", onResize)
+
+	t.release = func() {
+		for _, listener := range []js.Value{dataListener, resizeListener} {
+			if listener.Type() == js.TypeObject && listener.Get("dispose").Type() == js.TypeFunction {
+				listener.Call("dispose")
+			}
+		}
+
+		onData.Release()
+		onResize.Release()
+	}
+
+	return t
+}
+
+// Shell returns a new readline shell running on this terminal,
+// initialized with the given inputrc configuration options.
+func (t *Terminal) Shell(opts ...inputrc.Option) *readline.Shell {
+	return readline.NewShellWith(t, t, t, opts...)
+}
+
+// Input sends some data typed in the browser terminal to the shell.
+// It never blocks, and can thus be called from JS event callbacks.
+func (t *Terminal) Input(data string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return
+	}
+
+	t.input = append(t.input, data...)
+
+	select {
+	case t.ready <- struct{}{}:
+	default:
+	}
+}
+
+// Resize updates the dimensions of the terminal, and notifies
+// the shell, which redisplays its interface if currently reading.
+func (t *Terminal) Resize(width, height int) {
+	t.mutex.Lock()
+	t.width, t.height = width, height
+	t.mutex.Unlock()
+
+	select {
+	case t.resized <- struct{}{}:
+	default:
+	}
+}
+
+// Read implements io.Reader, and returns the input sent by the browser terminal.
+func (t *Terminal) Read(buf []byte) (int, error) {
+	for {
+		t.mutex.Lock()
+
+		if len(t.input) > 0 {
+			read := copy(buf, t.input)
+			t.input = t.input[read:]
+			t.mutex.Unlock()
+
+			return read, nil
+		}
+
+		if t.closed {
+			t.mutex.Unlock()
+			return 0, io.EOF
+		}
+
+		t.mutex.Unlock()
+
+		<-t.ready
+	}
+}
+
+// Write implements io.Writer, and passes the shell output to the browser terminal.
+func (t *Terminal) Write(buf []byte) (int, error) {
+	t.mutex.Lock()
+	closed := t.closed
+	t.mutex.Unlock()
+
+	if closed {
+		return 0, ErrClosed
+	}
+
+	t.write(string(buf))
+
+	return len(buf), nil
+}
+
+// Close detaches the terminal from its browser terminal,
+// and makes all subsequent reads return io.EOF.
+func (t *Terminal) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return nil
+	}
+
+	t.closed = true
+
+	if t.release != nil {
+		t.release()
+	}
+
+	select {
+	case t.ready <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// EnterRaw implements readline.TermState: browser terminals
+// have no line discipline, and are thus always in raw mode.
+func (t *Terminal) EnterRaw() error {
+	return nil
+}
+
+// ExitRaw implements readline.TermState.
+func (t *Terminal) ExitRaw() error {
+	return nil
+}
+
+// Size implements readline.TermState.
+func (t *Terminal) Size() (width, height int, err error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.width, t.height, nil
+}
+
+// Resized returns a channel notified each time the terminal is resized.
+func (t *Terminal) Resized() <-chan struct{} {
+	return t.resized
+}
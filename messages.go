@@ -0,0 +1,20 @@
+package readline
+
+import "github.com/reeflective/readline/internal/locale"
+
+// SetTranslator sets the function used to translate all built-in messages
+// displayed by the shell (search prompts, hints, errors, etc.), so that
+// applications using another language don't show mixed-language prompts.
+//
+// The function is passed the English text of a message (see Messages), and
+// should return its translation, or an empty string to keep it untranslated.
+// Some messages are format strings: translations must keep the same verbs.
+func (rl *Shell) SetTranslator(translate func(msg string) string) {
+	rl.locale.SetTranslator(translate)
+}
+
+// Messages returns the English text of all built-in messages displayed
+// by shells, which can be used to build translation catalogs.
+func Messages() []string {
+	return locale.Messages()
+}
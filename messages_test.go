@@ -0,0 +1,34 @@
+package readline_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_SetTranslator(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "> " })
+
+	catalog := map[string]string{
+		"%s (inc-search)": "%s (recherche)",
+		" (no matches)":   " (aucun résultat)",
+	}
+
+	rl.SetTranslator(func(msg string) string { return catalog[msg] })
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	if _, err := term.Run(rl, "hello", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if _, err := term.Run(rl, `\C-r`, "x", `\C-g`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if output := term.Output(); !strings.Contains(output, "(recherche)") || !strings.Contains(output, "(aucun résultat)") {
+		t.Errorf("search hint not translated: %q", output)
+	}
+}
@@ -0,0 +1,177 @@
+package readline
+
+import (
+	"context"
+	"errors"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+)
+
+// ReadNested pushes a nested mini-prompt below the input line, reads a line
+// of input in it, and then pops it, restoring the hints displayed before: the
+// input line, its cursor, completions and keymaps are left untouched.
+//
+// The mini-prompt supports basic emacs-style editing (cursor movements with
+// arrows, Ctrl-A/B/E/F, deletions and kills with Ctrl-D/H/K/U/W). Its line is
+// returned when accepted with Enter, and ErrInterrupt is returned when aborted
+// with Ctrl-C, Ctrl-G or Escape. If the shell is closed, or if its read times
+// out while in the mini-prompt, the corresponding error is returned.
+//
+// This function must be called from commands or widgets run by the shell, for
+// instance to ask "Overwrite? y/n" or an isearch-like query before acting on
+// the line: this way, the shell keeps sole ownership of the terminal.
+func (rl *Shell) ReadNested(prompt string) (string, error) {
+	restore := rl.Hint.Snapshot()
+	defer restore()
+
+	line := new(core.Line)
+	cursor := core.NewCursor(line)
+
+	ctx := rl.readContext()
+
+	for {
+		rl.Hint.Set(nestedHint(prompt, line, cursor))
+		rl.Display.Refresh()
+
+		key, err := rl.readNestedKey(ctx)
+
+		switch {
+		case errors.Is(err, core.ErrIdle):
+			continue
+		case err != nil:
+			return string(*line), rl.readError(ctx, err)
+		}
+
+		switch key {
+		case "\r", "\n":
+			return string(*line), nil
+		case "\x03", "\x07", "\x1b":
+			return string(*line), ErrInterrupt
+		}
+
+		editNested(key, line, cursor)
+	}
+}
+
+// readContext returns the context of the current read, if any.
+func (rl *Shell) readContext() context.Context {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.readCtx == nil {
+		return context.Background()
+	}
+
+	return rl.readCtx
+}
+
+// readNestedKey waits for and returns the next key typed by the user, which
+// is either a single (possibly multibyte) character or an escape sequence.
+// Keys are read from the shell key stack, so that none of them is lost.
+func (rl *Shell) readNestedKey(ctx context.Context) (string, error) {
+	if err := core.WaitAvailableKeys(ctx, rl.Keys, rl.Config); err != nil {
+		return "", err
+	}
+
+	first, empty := core.PopKey(rl.Keys)
+	if empty {
+		return "", nil
+	}
+
+	key := []byte{first}
+
+	switch {
+	case rune(first) == inputrc.Esc:
+		// Escape sequences are read at once, so
+		// their remaining keys are already stacked.
+		if next, empty := core.PeekKey(rl.Keys); empty || (next != '[' && next != 'O') {
+			break
+		}
+
+		for {
+			next, empty := core.PopKey(rl.Keys)
+			if empty {
+				break
+			}
+
+			key = append(key, next)
+
+			if len(key) > 2 && next >= '@' && next <= '~' {
+				break
+			}
+		}
+
+	case first >= utf8.RuneSelf:
+		for !utf8.FullRune(key) {
+			next, empty := core.PopKey(rl.Keys)
+			if empty {
+				break
+			}
+
+			key = append(key, next)
+		}
+	}
+
+	return string(key), nil
+}
+
+// editNested applies an editing key to the nested prompt line.
+func editNested(key string, line *core.Line, cursor *core.Cursor) {
+	switch key {
+	case "\x01", "\x1b[H", "\x1bOH", "\x1b[1~":
+		cursor.Set(0)
+	case "\x05", "\x1b[F", "\x1bOF", "\x1b[4~":
+		cursor.Set(line.Len())
+	case "\x02", "\x1b[D", "\x1bOD":
+		cursor.Dec()
+	case "\x06", "\x1b[C", "\x1bOC":
+		cursor.Inc()
+	case "\x7f", "\x08":
+		if cursor.Pos() > 0 {
+			cursor.Dec()
+			line.CutRune(cursor.Pos())
+		}
+	case "\x04", "\x1b[3~":
+		line.CutRune(cursor.Pos())
+	case "\x0b":
+		line.Cut(cursor.Pos(), line.Len())
+	case "\x15":
+		line.Cut(0, cursor.Pos())
+		cursor.Set(0)
+	case "\x17":
+		backward := line.Backward(line.TokenizeSpace, cursor.Pos())
+		line.Cut(cursor.Pos()+backward, cursor.Pos())
+		cursor.Move(backward)
+	default:
+		char, _ := utf8.DecodeRuneInString(key)
+		if len(key) == 0 || !unicode.IsPrint(char) {
+			return
+		}
+
+		cursor.InsertAt([]rune(key)...)
+	}
+}
+
+// nestedHint returns the nested prompt and its line, with the
+// character under the cursor displayed in reverse video.
+func nestedHint(prompt string, line *core.Line, cursor *core.Cursor) string {
+	pos := cursor.Pos()
+	under := " "
+
+	if pos < line.Len() {
+		under = string((*line)[pos])
+	}
+
+	hint := prompt + color.Reset + string((*line)[:pos])
+	hint += color.Reverse + under + color.ReverseReset
+
+	if pos < line.Len() {
+		hint += string((*line)[pos+1:])
+	}
+
+	return hint
+}
@@ -0,0 +1,50 @@
+package readline_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_ReadNested(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "> " })
+
+	var nestedErr error
+
+	rl.Keymap.Register(map[string]func(){
+		"ask-name": func() {
+			name, err := rl.ReadNested("Name: ")
+			if err == nil {
+				rl.Cursor().InsertAt([]rune(name)...)
+			}
+
+			nestedErr = err
+		},
+	})
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xn`), "ask-name", false)
+
+	line, err := term.Run(rl, "hello ", `\C-xn`, "wrld", `\e[D\e[D\e[D`, "o", `\r`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "hello world" || nestedErr != nil {
+		t.Errorf("Run() line = %q (nested error %v), want %q", line, nestedErr, "hello world")
+	}
+
+	readlinetest.AssertScreen(t, term, "> hello world")
+
+	line, err = term.Run(rl, "hello", `\C-xn`, "abc", `\C-g`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "hello" || !errors.Is(nestedErr, readline.ErrInterrupt) {
+		t.Errorf("Run() line = %q (nested error %v), want %q (nested error %v)", line, nestedErr, "hello", readline.ErrInterrupt)
+	}
+}
@@ -0,0 +1,135 @@
+package readline
+
+import (
+	"io"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+// Option configures a shell created with New.
+type Option func(s *settings)
+
+// settings are the shell settings set by options, applied by New.
+type settings struct {
+	in      io.Reader
+	out     io.Writer
+	state   TermState
+	inputrc []inputrc.Option
+	history []namedHistory
+	apply   []func(rl *Shell) error
+}
+
+// namedHistory is a history source with its name.
+type namedHistory struct {
+	name   string
+	source History
+}
+
+// New returns a readline shell configured with the given options, which are
+// applied in order. Without options, the shell is the same as the one returned
+// by NewShell: it runs on the process terminal, with a default inputrc
+// configuration and binds, and with an in-memory command history.
+//
+// Options are the stable way of configuring shells: the exported fields of the
+// Shell might change along with its internals, while options will keep working.
+// If an option fails to apply (like an invalid editing mode), its error is
+// returned, and the shell is not.
+func New(opts ...Option) (*Shell, error) {
+	var set settings
+
+	for _, opt := range opts {
+		opt(&set)
+	}
+
+	var shell *Shell
+
+	if set.in != nil || set.out != nil || set.state != nil {
+		shell = NewShellWith(set.in, set.out, set.state, set.inputrc...)
+	} else {
+		shell = NewShell(set.inputrc...)
+	}
+
+	if len(set.history) > 0 {
+		shell.History.Delete()
+	}
+
+	for _, history := range set.history {
+		shell.History.Add(history.name, history.source)
+	}
+
+	for _, apply := range set.apply {
+		if err := apply(shell); err != nil {
+			return nil, err
+		}
+	}
+
+	return shell, nil
+}
+
+// WithIO runs the shell on the given terminal instead of the process one:
+// it reads its input from in, writes its output to out, and controls the
+// terminal through state, like a shell returned by NewShellWith.
+func WithIO(in io.Reader, out io.Writer, state TermState) Option {
+	return func(s *settings) {
+		s.in, s.out, s.state = in, out, state
+	}
+}
+
+// WithInputrc sets the options used when parsing and applying
+// inputrc configurations, like the application name.
+func WithInputrc(opts ...inputrc.Option) Option {
+	return func(s *settings) {
+		s.inputrc = append(s.inputrc, opts...)
+	}
+}
+
+// WithHistory adds a named history source. The sources given with this
+// option replace the default in-memory one, and the first one is active.
+func WithHistory(name string, source History) Option {
+	return func(s *settings) {
+		s.history = append(s.history, namedHistory{name, source})
+	}
+}
+
+// WithPrompt sets the function returning the primary prompt.
+func WithPrompt(prompt func() string) Option {
+	return withShell(func(rl *Shell) error {
+		rl.Prompt.Primary(prompt)
+		return nil
+	})
+}
+
+// WithCompleter sets the function producing completions.
+func WithCompleter(completer func(line []rune, cursor int) Completions) Option {
+	return withShell(func(rl *Shell) error {
+		rl.Completer = completer
+		return nil
+	})
+}
+
+// WithHighlighter sets the function highlighting the line.
+func WithHighlighter(highlighter func(line []rune) string) Option {
+	return withShell(func(rl *Shell) error {
+		rl.SyntaxHighlighter = highlighter
+		return nil
+	})
+}
+
+// WithEditingMode sets the editing mode of the shell: "emacs" or "vi".
+func WithEditingMode(mode string) Option {
+	return WithOption("editing-mode", mode)
+}
+
+// WithOption sets the value of an option, like SetOption does.
+func WithOption(name string, value any) Option {
+	return withShell(func(rl *Shell) error {
+		return rl.SetOption(name, value)
+	})
+}
+
+// withShell returns an option applied to the shell once created.
+func withShell(apply func(rl *Shell) error) Option {
+	return func(s *settings) {
+		s.apply = append(s.apply, apply)
+	}
+}
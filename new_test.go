@@ -0,0 +1,64 @@
+package readline_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestNew(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+
+	history := readline.NewInMemoryHistory()
+	history.Write("echo history")
+
+	rl, err := readline.New(
+		readline.WithIO(term, term, term),
+		readline.WithPrompt(func() string { return "new> " }),
+		readline.WithHistory("test", history),
+		readline.WithEditingMode("vi"),
+		readline.WithOption("max-redisplay-rate", 0),
+		readline.WithHighlighter(func(line []rune) string { return strings.ToUpper(string(line)) }),
+		readline.WithCompleter(func(_ []rune, _ int) readline.Completions {
+			return readline.CompleteValues("completed")
+		}),
+	)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if mode, _ := rl.GetOption("editing-mode"); mode != "vi" {
+		t.Errorf("editing-mode = %v, want vi", mode)
+	}
+
+	line, err := term.Run(rl, `\C-p`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "echo history" {
+		t.Errorf("Run() line = %q, want %q", line, "echo history")
+	}
+
+	line, err = term.Run(rl, "comp", `\t`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "completed" {
+		t.Errorf("Run() line = %q, want %q", line, "completed")
+	}
+
+	for _, want := range []string{"new> ", "COMPLETED"} {
+		if !strings.Contains(term.Screen(), want) {
+			t.Errorf("Screen() = %q, want it to contain %q", term.Screen(), want)
+		}
+	}
+
+	if _, err := readline.New(readline.WithIO(term, term, term), readline.WithEditingMode("ed")); !errors.Is(err, readline.ErrOptionValue) {
+		t.Errorf("New() error = %v, want %v", err, readline.ErrOptionValue)
+	}
+}
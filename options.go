@@ -0,0 +1,178 @@
+package readline
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reeflective/readline/inputrc"
+)
+
+// ErrUnknownOption is returned when setting an option that does not exist.
+var ErrUnknownOption = errors.New("unknown option")
+
+// ErrOptionValue is returned when setting an option with an invalid value.
+var ErrOptionValue = errors.New("invalid option value")
+
+// SetOption sets the value of an option and applies it immediately. Options are
+// all the inputrc variables supported by readline (like history-size or editing-mode)
+// and those specific to this library (like autocomplete or max-redisplay-rate).
+// The value must be a bool, an int or a string, depending on the option type, or a
+// string as it would be written in an inputrc file (like "on", "off" or "5000").
+//
+// Options only read when loading the configuration (like disable-completion)
+// take effect once it is reloaded. This function should not be called while
+// the shell is reading input, except from within commands run by the shell.
+//
+// Settings which are not configuration variables are not options, and keep
+// their own setters: callbacks (like Completer or HintProvider), and tunables
+// set with SetTimeout, SetIdleCallback, SetStateFile or SetPanicOutput.
+func (rl *Shell) SetOption(name string, value any) error {
+	current, found := rl.Config.Vars[name]
+	if !found {
+		return fmt.Errorf("%w: %s", ErrUnknownOption, name)
+	}
+
+	value, err := convertOption(name, current, value)
+	if err != nil {
+		return err
+	}
+
+	rl.Config.Set(name, value)
+	rl.updateOption(name)
+
+	return nil
+}
+
+// GetOption returns the current value of an option (a bool, an int or a string
+// depending on its type), or false if there is no option with this name.
+func (rl *Shell) GetOption(name string) (value any, found bool) {
+	value, found = rl.Config.Vars[name]
+	return value, found
+}
+
+// SaveOptions writes the current value of all options to a file in inputrc
+// format, so that they can be loaded again later on with LoadOptions.
+func (rl *Shell) SaveOptions(filename string) error {
+	var buf bytes.Buffer
+
+	rl.writeOptions(&buf)
+
+	return os.WriteFile(filename, buf.Bytes(), 0o600)
+}
+
+// LoadOptions parses an inputrc file (such as one written by SaveOptions) with the
+// shell inputrc options, and immediately applies all the options and binds it sets.
+func (rl *Shell) LoadOptions(filename string) error {
+	mode := rl.Config.GetString("editing-mode")
+
+	if err := inputrc.ParseFile(filename, rl.Config, rl.Opts...); err != nil {
+		return err
+	}
+
+	rl.updateOption("history-size")
+
+	if rl.Config.GetString("editing-mode") != mode {
+		rl.updateOption("editing-mode")
+	}
+
+	return nil
+}
+
+// writeOptions writes all options, alphabetically sorted, in inputrc format.
+func (rl *Shell) writeOptions(w io.Writer) {
+	var names []string
+
+	for name := range rl.Config.Vars {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		var value string
+
+		switch val := rl.Config.Vars[name].(type) {
+		case bool:
+			value = "off"
+			if val {
+				value = "on"
+			}
+		case string:
+			value = strings.ReplaceAll(val, "\x1b", `\e`)
+
+			// Quote values that would otherwise be truncated.
+			if !strings.HasPrefix(value, `"`) && strings.ContainsAny(value, " \t#") {
+				value = `"` + value + `"`
+			}
+		default:
+			value = fmt.Sprint(val)
+		}
+
+		fmt.Fprintf(w, "set %s %s\n", name, value)
+	}
+}
+
+// updateOption applies the new value of an option when
+// it is not simply read from the configuration when used.
+func (rl *Shell) updateOption(name string) {
+	switch name {
+	case "history-size":
+		rl.History.UpdateConfig()
+	case "editing-mode":
+		switch rl.Config.GetString("editing-mode") {
+		case "emacs":
+			rl.emacsEditingMode()
+		case "vi":
+			rl.viInsertMode()
+		}
+	}
+}
+
+// convertOption returns the value converted to the type of the current option
+// value, or an error if the value has another type and cannot be converted.
+func convertOption(name string, current, value any) (any, error) {
+	invalid := fmt.Errorf("%w for %s: %v (%T)", ErrOptionValue, name, value, value)
+	str, isString := value.(string)
+
+	switch current.(type) {
+	case bool:
+		if _, isBool := value.(bool); isBool {
+			return value, nil
+		}
+
+		switch strings.ToLower(str) {
+		case "on", "1":
+			return true, nil
+		case "off", "0":
+			return false, nil
+		}
+
+	case int:
+		if _, isInt := value.(int); isInt {
+			return value, nil
+		}
+
+		if num, err := strconv.Atoi(str); isString && err == nil {
+			return num, nil
+		}
+
+	case string:
+		if !isString {
+			break
+		}
+
+		if name == "editing-mode" && str != "emacs" && str != "vi" {
+			break
+		}
+
+		return value, nil
+	}
+
+	return nil, invalid
+}
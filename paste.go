@@ -0,0 +1,121 @@
+package readline
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/locale"
+)
+
+// This function is bound to the sequence starting a bracketed paste, which
+// terminals send before pasted text when the enable-bracketed-paste option
+// is on: the pasted text is inserted as is, instead of being dispatched as
+// keys, so that its newlines do not accept the line. See paste-confirm.
+func (rl *Shell) bracketedPasteBegin() {
+	rl.insertPaste(rl.Keys.ReadPaste())
+}
+
+// insertPaste inserts pasted text at the cursor, with its newlines normalized.
+// If the paste-confirm option is enabled and the text contains newlines or is
+// at least as long as the paste-confirm-size option, it is only inserted once
+// confirmed, possibly after being edited in the system editor.
+func (rl *Shell) insertPaste(paste []byte) {
+	text := strings.ReplaceAll(string(paste), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	size := rl.Config.GetInt("paste-confirm-size")
+	long := strings.Contains(text, "\n") || (size > 0 && len(text) >= size)
+
+	if rl.Config.GetBool("paste-confirm") && long {
+		confirmed, edit := rl.confirmPaste(text)
+		if edit {
+			text, confirmed = rl.editPaste(text)
+		}
+
+		if !confirmed {
+			rl.History.SkipSave()
+			return
+		}
+	}
+
+	rl.History.Save()
+	rl.cursor.InsertAt([]rune(text)...)
+}
+
+// confirmPaste shows the number of lines and size of the pasted text, and
+// returns true if the user confirms it with Enter, or wants to edit it with
+// `e`. Escape cancels the paste, and other keys too, while being left in the
+// key stack to be dispatched as usual.
+func (rl *Shell) confirmPaste(text string) (confirmed, edit bool) {
+	restore := rl.Hint.Snapshot()
+	defer restore()
+
+	lines := strings.Count(strings.TrimSuffix(text, "\n"), "\n") + 1
+
+	rl.Hint.Set(color.Dim + rl.locale.Sprintf(locale.PasteConfirm, lines, pasteSize(len(text))) + color.Reset)
+	rl.Display.Refresh()
+
+	ctx := rl.readContext()
+
+	for {
+		err := core.WaitAvailableKeys(ctx, rl.Keys, rl.Config)
+		if errors.Is(err, core.ErrIdle) {
+			continue
+		} else if err != nil {
+			return false, false
+		}
+
+		key, empty := core.PeekKey(rl.Keys)
+		if empty {
+			continue
+		}
+
+		switch key {
+		case '\r', '\n':
+			core.PopForce(rl.Keys)
+			return true, false
+		case 'e':
+			core.PopForce(rl.Keys)
+			return false, true
+		case '\x1b':
+			core.PopForce(rl.Keys)
+			return false, false
+		default:
+			return false, false
+		}
+	}
+}
+
+// editPaste returns the pasted text edited in the system editor,
+// or false if the editor failed, with the error shown in the hint.
+func (rl *Shell) editPaste(text string) (string, bool) {
+	var edited []rune
+
+	err := rl.RunInTerminal(func() (err error) {
+		edited, err = rl.Buffers.EditBuffer([]rune(text), "", "", rl.Keymap.IsEmacs())
+		return err
+	})
+	if err != nil {
+		errStr := strings.ReplaceAll(err.Error(), "\n", "")
+		rl.Hint.SetTemporary(color.FgRed + rl.locale.Sprintf(locale.EditorError, errStr))
+
+		return "", false
+	}
+
+	return string(edited), true
+}
+
+// pasteSize returns a size in bytes in a human-readable form (2.3KB).
+func pasteSize(size int) string {
+	switch {
+	case size < 1024:
+		return fmt.Sprintf("%dB", size)
+	case size < 1024*1024:
+		return fmt.Sprintf("%.1fKB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%.1fMB", float64(size)/(1024*1024))
+	}
+}
@@ -0,0 +1,80 @@
+package readline_test
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_BracketedPaste(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("enable-bracketed-paste", true)
+
+	// The end of the paste might be read in other chunks.
+	line, err := term.Run(rl, "a ", "\x1b[200~one\rt", "wo\x1b[20", "1~ b", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if want := "a one\ntwo b"; line != want {
+		t.Errorf("Readline() line = %q, want %q", line, want)
+	}
+}
+
+func TestShell_PasteConfirm(t *testing.T) {
+	shell := func() (*readlinetest.Terminal, *readline.Shell) {
+		term := readlinetest.NewTerminal(80, 10)
+		rl := term.Shell()
+		rl.Config.Set("max-redisplay-rate", 0)
+		rl.Config.Set("paste-confirm", true)
+		rl.Config.Set("paste-confirm-size", 8)
+
+		return term, rl
+	}
+
+	tests := []struct {
+		name  string
+		paste string
+		hint  string
+		keys  []string
+		want  string
+	}{
+		{"confirmed", "one\r\ntwo\r", "paste 2 lines, 8B", []string{`\r`, " three", `\r`}, "a one\ntwo\n three"},
+		{"cancelled", "one\rtwo", "paste 2 lines, 7B", []string{`\e`, "b", `\r`}, "a b"},
+		{"other key", "one two three", "paste 1 lines, 13B", []string{"b", `\r`}, "a b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term, rl := shell()
+			done := make(chan string, 1)
+
+			go func() {
+				line, _ := rl.Readline()
+				done <- line
+			}()
+
+			term.Type("a ")
+			waitForScreen(t, term, "a")
+
+			term.Type(test.paste)
+			waitForScreen(t, term, "a\n"+test.hint+" — Enter to insert, Esc to cancel, e to edit in $EDITOR")
+
+			term.Type(test.keys...)
+
+			if line := <-done; line != test.want {
+				t.Errorf("Readline() line = %q, want %q", line, test.want)
+			}
+		})
+	}
+
+	// Short pastes and single keys are dispatched as usual.
+	term, rl := shell()
+
+	if line, err := term.Run(rl, "ab", "c", `\r`); err != nil || line != "abc" {
+		t.Errorf("Run() = %q, %v, want %q", line, err, "abc")
+	}
+}
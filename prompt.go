@@ -0,0 +1,11 @@
+package readline
+
+import "github.com/reeflective/readline/internal/ui"
+
+// Secret marks a segment of a prompt string as sensitive, like an account
+// identifier, so that it is displayed masked (with its colors kept) in all
+// prompts. The toggle-prompt-secrets command (Ctrl-X Ctrl-S in Emacs mode)
+// temporarily reveals such segments, until the next line is read.
+func Secret(segment string) string {
+	return ui.Secret(segment)
+}
@@ -0,0 +1,81 @@
+package readline_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_PromptSecrets(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Prompt.Primary(func() string { return "aws:" + readline.Secret("123456789012") + " > " })
+
+	if _, err := term.Run(rl, "ls", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if screen := term.Screen(); !strings.Contains(screen, "aws:************ > ls") || strings.Contains(screen, "1234") {
+		t.Errorf("secret prompt segment not masked:\n%s", screen)
+	}
+
+	if _, err := term.Run(rl, "ls", `\C-x\C-s`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if screen := term.Screen(); !strings.Contains(screen, "aws:123456789012 > ls") {
+		t.Errorf("secret prompt segment not revealed:\n%s", screen)
+	}
+
+	if _, err := term.Run(rl, "ls", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if lines := strings.Split(strings.TrimRight(term.Screen(), " \n"), "\n"); !strings.Contains(lines[len(lines)-1], "aws:************ > ls") {
+		t.Errorf("secret prompt segment not masked again on the next line:\n%s", term.Screen())
+	}
+}
+
+func TestShell_PromptBadges(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Prompt.Primary(func() string { return "> " })
+
+	rl.Prompt.Badge("jobs", "2 jobs", 0)
+	rl.Prompt.Badge("mail", "mail", time.Hour)
+	rl.Prompt.Badge("jobs", "1 job", 0)
+
+	if _, err := term.Run(rl, "ls", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if screen := term.Screen(); !strings.Contains(screen, "[1 job] [mail] > ls") {
+		t.Errorf("prompt badges not displayed:\n%s", screen)
+	}
+
+	// Replacing the badge with one expiring right away removes it on next display.
+	rl.Prompt.Badge("mail", "mail", time.Nanosecond)
+
+	if _, err := term.Run(rl, "ls", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if lines := strings.Split(strings.TrimRight(term.Screen(), " \n"), "\n"); !strings.HasSuffix(lines[len(lines)-1], "[1 job] > ls") {
+		t.Errorf("expired prompt badge still displayed:\n%s", term.Screen())
+	}
+
+	rl.Prompt.ClearBadge("")
+
+	if _, err := term.Run(rl, "ls", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if lines := strings.Split(strings.TrimRight(term.Screen(), " \n"), "\n"); lines[len(lines)-1] != "> ls" {
+		t.Errorf("cleared prompt badges still displayed:\n%s", term.Screen())
+	}
+}
@@ -19,9 +19,15 @@
 package readline
 
 import (
+	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/color"
@@ -30,6 +36,7 @@ import (
 	"github.com/reeflective/readline/internal/display"
 	"github.com/reeflective/readline/internal/history"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
 	"github.com/reeflective/readline/internal/macro"
 	"github.com/reeflective/readline/internal/term"
 )
@@ -38,6 +45,19 @@ import (
 // is pressed on the keyboard. The sequence is usually Ctrl-C.
 var ErrInterrupt = errors.New(os.Interrupt.String())
 
+// ErrPanic is returned by Readline when a panic has occurred while reading
+// the line, for instance in a command or a user-provided completer: the panic
+// is recovered, the terminal restored and the panic value and stack printed.
+var ErrPanic = errors.New("readline panic")
+
+// ErrTimeout is returned by Readline when the user has not
+// accepted a line before the timeout set with SetTimeout.
+var ErrTimeout = errors.New("readline timeout")
+
+// ErrClosed is returned by Readline when the shell has been closed,
+// either before or while the shell was reading user input.
+var ErrClosed = errors.New("readline shell closed")
+
 // Readline displays the readline prompt and reads user input.
 // It can return from the call because of different things:
 //
@@ -48,26 +68,72 @@ var ErrInterrupt = errors.New(os.Interrupt.String())
 // In all cases, the current input line is returned along with any error,
 // and it is up to the caller to decide what to do with the line result.
 // When the error is not nil, the returned line is not written to history.
+//
+// If the standard input is not a terminal, or if the terminal is a dumb one
+// ($TERM=dumb), the shell falls back to reading plain lines, without using
+// raw mode nor any escape sequence: the prompt is printed without colors.
 func (rl *Shell) Readline() (string, error) {
-	descriptor := int(os.Stdin.Fd())
+	return rl.ReadlineCtx(context.Background())
+}
 
-	state, err := term.MakeRaw(descriptor)
+// ReadlineCtx is like Readline, but the call is aborted when the context is
+// cancelled or when its deadline has passed: in this case, the terminal is
+// restored, the current input line is left as is on screen and is returned
+// along with the context error. The line is not written to history.
+func (rl *Shell) ReadlineCtx(ctx context.Context) (line string, err error) {
+	defer func() { rl.Hooks.runPostAccept(line, err) }()
+	defer rl.recoverPanic(&err)
+
+	if !rl.isInteractive() {
+		return rl.readlinePlain(ctx)
+	}
+
+	ctx, err = rl.startReading(ctx)
 	if err != nil {
 		return "", err
 	}
-	defer term.Restore(descriptor, state)
+	defer rl.stopReading()
 
 	// Prompts and cursor styles
+	rl.Prompt.RevealSecrets(false)
+	rl.startFreshLine()
 	rl.Display.PrintPrimaryPrompt()
 	defer rl.Display.RefreshTransient()
-	defer fmt.Print(keymap.CursorStyle("default"))
+	defer fmt.Fprint(rl.out, keymap.CursorStyle("default"))
+
+	// Pasted text is enclosed in escape sequences, to be inserted as is.
+	if rl.Config.GetBool("enable-bracketed-paste") {
+		fmt.Fprint(rl.out, term.EnableBracketedPaste)
+		defer fmt.Fprint(rl.out, term.DisableBracketedPaste)
+	}
+
+	// External buffer edits are only allowed
+	// while we are blocked waiting for input.
+	rl.lineMutex.Lock()
+	defer rl.lineMutex.Unlock()
+	defer rl.cancelHint()
+	defer rl.cancelCheck()
+	defer rl.cancelCompletions()
+	defer rl.saveState(false)
 
 	rl.init()
+	rl.loadState()
 
 	// Terminal resize events
 	resize := display.WatchResize(rl.Display)
 	defer close(resize)
 
+	// Process stop/continue events
+	resume := display.WatchResume(rl.Display, rl.resumed)
+	defer close(resume)
+
+	rl.Hooks.runPreRead()
+
+	if start := rl.startRead; start != nil {
+		rl.startRead = nil
+		start()
+	}
+
 	for {
 		// Whether or not the command is resolved, let the macro
 		// engine record the keys if currently recording a macro.
@@ -79,14 +145,50 @@ func (rl *Shell) Readline() (string, error) {
 		// been consumed but did not match any command.
 		core.FlushUsed(rl.Keys)
 
+		// Notify of any change made to the line by the last command.
+		rl.notifyLineChange()
+		rl.updateSearchMatches()
+
 		// Since we always update helpers after being asked to read
 		// for user input again, we do it before actually reading it.
-		rl.Display.Refresh()
+		// When keys arrive faster than we can redisplay, only render
+		// at the maximum rate, and always once all keys are processed.
+		if !rl.Display.Throttle(core.PendingKeys(rl.Keys)) {
+			rl.provideHint()
+			rl.checkLine()
+			rl.updateCheatSheet()
+			rl.Hooks.runPreRender()
+			traceRefresh := rl.traceDuration("refresh")
+			rl.Display.Refresh()
+			traceRefresh()
+		}
 
 		// Block and wait for available user input keys.
 		// These might be read on stdin, or already available because
 		// the macro engine has fed some keys in bulk when running one.
-		core.WaitAvailableKeys(rl.Keys, rl.Config)
+		// If the user has been idle for too long, notify and refresh.
+		err := rl.waitInput(ctx)
+
+		switch {
+		case errors.Is(err, core.ErrIdle):
+			continue
+		case err != nil:
+			err = rl.readError(ctx, err)
+			if errors.Is(err, ErrClosed) {
+				rl.clearHelpers()
+			}
+
+			rl.Display.AcceptLine()
+
+			return string(*rl.line), err
+		}
+
+		// Keys read at once and detected as a paste are
+		// inserted once confirmed, instead of dispatched.
+		if paste := core.TakePaste(rl.Keys); paste != nil {
+			rl.insertPaste(paste)
+			continue
+		}
 
 		// 1 - Local keymap (Completion/Isearch/Vim operator pending).
 		bind, command, prefixed := keymap.MatchLocal(rl.Keymap)
@@ -125,6 +227,242 @@ func (rl *Shell) Readline() (string, error) {
 	}
 }
 
+// startFreshLine moves the prompt below any partial line, but only queries the
+// cursor position on the first read: once lines are accepted, the cursor is
+// left at the beginning of a line. Commands run with RunInTerminal query it
+// again once done, since their output might not end with a newline.
+func (rl *Shell) startFreshLine() {
+	if rl.cursorProbed {
+		return
+	}
+
+	rl.cursorProbed = true
+	rl.Display.StartFreshLine()
+}
+
+// Close stops any running Readline call, clears the hints, diagnostics and
+// completions displayed below its line, restores the terminal to its original
+// state and makes all subsequent Readline calls return ErrClosed.
+// It is safe to call this function from any goroutine and at any time,
+// and to call it more than once.
+func (rl *Shell) Close() error {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.closed = true
+
+	if rl.cancelRead != nil {
+		rl.cancelRead()
+	}
+
+	return rl.rawGuard.Restore()
+}
+
+// SetTimeout sets the maximum duration of each Readline call: if the user has
+// not accepted a line before it expires, the current line is returned along
+// with ErrTimeout. A zero or negative duration disables the timeout (default).
+// The timeout is not enforced when the shell falls back to plain line reading.
+func (rl *Shell) SetTimeout(timeout time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.timeout = timeout
+}
+
+// SetPanicOutput sets the writer to which panics occurring while reading a line
+// are printed along with their stack trace, after the terminal is restored.
+// By default, panics are printed to os.Stderr. A nil writer disables printing.
+func (rl *Shell) SetPanicOutput(w io.Writer) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.panicOutput = w
+}
+
+// recoverPanic recovers from any panic occurring while reading a line,
+// restores the terminal, prints the panic and returns it as an error.
+func (rl *Shell) recoverPanic(err *error) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+
+	rl.mutex.Lock()
+	rl.rawGuard.Restore()
+	output := rl.panicOutput
+	rl.mutex.Unlock()
+
+	fmt.Fprint(rl.out, term.ShowCursor+term.NewlineReturn)
+
+	// Keep the line to restore it on the next read.
+	rl.saveState(true)
+
+	if output != nil {
+		fmt.Fprintf(output, "readline: panic: %v\n\n%s", recovered, debug.Stack())
+	}
+
+	*err = fmt.Errorf("%w: %v", ErrPanic, recovered)
+}
+
+// isInteractive returns true if the shell can use its full line editor.
+// Terminals provided by the host application are always interactive.
+func (rl *Shell) isInteractive() bool {
+	if rl.hosted {
+		return true
+	}
+
+	if rl.getenv("TERM") == "dumb" {
+		return false
+	}
+
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
+
+// readlinePlain reads a line from stdin without any terminal
+// handling, but still prints the prompt and writes to history.
+// The context is only checked before starting to read the line.
+func (rl *Shell) readlinePlain(ctx context.Context) (string, error) {
+	rl.mutex.Lock()
+	closed := rl.closed
+	rl.mutex.Unlock()
+
+	if closed {
+		return "", ErrClosed
+	}
+
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if rl.plainReader == nil {
+		rl.plainReader = bufio.NewReader(os.Stdin)
+	}
+
+	rl.History.Reset()
+	history.Init(rl.History)
+	rl.Prompt.PlainPrint()
+	rl.Hooks.runPreRead()
+
+	// An EOF with some remaining input is returned as a
+	// valid line, and the next call will return the EOF.
+	input, err := rl.plainReader.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || input == "") {
+		return "", err
+	}
+
+	input = strings.TrimSuffix(input, "\n")
+	input = strings.TrimSuffix(input, "\r")
+
+	rl.line.Set([]rune(input)...)
+	rl.cursor.Set(rl.line.Len())
+	rl.History.Accept(false, false, nil)
+
+	_, line, err := rl.History.LineAccepted()
+
+	return line, err
+}
+
+// isReading returns true if the shell is currently running its line editor.
+func (rl *Shell) isReading() bool {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	return rl.cancelRead != nil
+}
+
+// startReading puts the terminal in raw mode and returns a context
+// that is cancelled when the shell is closed, unless it is already.
+func (rl *Shell) startReading(ctx context.Context) (context.Context, error) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.closed {
+		return ctx, ErrClosed
+	}
+
+	guard, err := term.NewRawGuard(rl.out.Terminal)
+	if err != nil {
+		return ctx, err
+	}
+
+	rl.rawGuard = guard
+	ctx, rl.cancelRead = context.WithCancel(ctx)
+
+	// Bound the read to the timeout, if any.
+	if rl.timeout > 0 {
+		cancel := rl.cancelRead
+
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeoutCause(ctx, rl.timeout, ErrTimeout)
+
+		rl.cancelRead = func() {
+			cancelTimeout()
+			cancel()
+		}
+	}
+
+	rl.readCtx = ctx
+
+	return ctx, nil
+}
+
+// stopReading restores the terminal and releases the read context.
+func (rl *Shell) stopReading() {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	rl.cancelRead()
+	rl.cancelRead = nil
+	rl.readCtx = nil
+
+	rl.rawGuard.Restore()
+	rl.rawGuard = nil
+}
+
+// resumed puts the terminal back into raw mode when the process is
+// continued after having been stopped by an external signal, and returns
+// true if the interface must be redisplayed. When the shell has suspended
+// itself, the suspend command is in charge of restoring everything.
+func (rl *Shell) resumed() (redisplay bool) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.suspended {
+		rl.suspended = false
+		return false
+	}
+
+	rl.rawGuard.Resume()
+
+	return rl.rawGuard != nil
+}
+
+// readError returns ErrClosed if the read was aborted because the shell
+// has been closed, ErrTimeout if the read has timed out, or the error as is.
+func (rl *Shell) readError(ctx context.Context, err error) error {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	if rl.closed {
+		return ErrClosed
+	}
+
+	if errors.Is(context.Cause(ctx), ErrTimeout) {
+		return ErrTimeout
+	}
+
+	return err
+}
+
+// clearHelpers drops the hint, diagnostics and completions of a read
+// aborted by Close, which must not be displayed again with the line.
+func (rl *Shell) clearHelpers() {
+	rl.Display.ResetHelpers()
+	rl.Hint.Provide("")
+	rl.Hint.Guide("")
+	rl.showDiagnostics(nil)
+}
+
 // init gathers all steps to perform at the beginning of readline loop.
 func (rl *Shell) init() {
 	// Reset core editor components.
@@ -132,11 +470,21 @@ func (rl *Shell) init() {
 	rl.line.Set()
 	rl.cursor.Set(0)
 	rl.cursor.ResetMark()
+	rl.lastLine = rl.lastLine[:0]
+	rl.lastCursor = 0
+	rl.hintLine = rl.hintLine[:0]
+	rl.hintCursor = -1
+	rl.checkedLine = rl.checkedLine[:0]
+	rl.showDiagnostics(nil)
+	rl.suggestedFor = nil
+	rl.searchMatcher = nil
 	rl.selection.Reset()
 	rl.Buffers.Reset()
 	rl.History.Reset()
 	rl.History.Save()
 	rl.Iterations.Reset()
+	rl.marks = nil
+	rl.blockInsert = nil
 
 	// Some accept-* commands must fetch a specific
 	// line outright, or keep the accepted one.
@@ -145,7 +493,7 @@ func (rl *Shell) init() {
 	// Reset/initialize user interface components.
 	rl.Hint.Reset()
 	rl.completer.ResetForce()
-	display.Init(rl.Display, rl.SyntaxHighlighter)
+	display.Init(rl.Display, rl.highlighter(), rl.suggestLine, rl.pendingCommand)
 }
 
 // run wraps the execution of a target command/sequence with various pre/post actions
@@ -169,11 +517,52 @@ func (rl *Shell) run(main bool, bind inputrc.Bind, command func()) (bool, string
 	// so it knows which line and cursor we should work on.
 	rl.line, rl.cursor, rl.selection = rl.completer.GetBuffer()
 
+	dispatched := rl.Keymap.Local()
+	if main {
+		dispatched = rl.Keymap.Main()
+	}
+
+	// Keys typed in standalone prompts (like form passwords) are not traced.
+	if rl.standalone {
+		rl.trace("dispatch", "keymap", dispatched, "command", bind.Action, "macro", bind.Macro)
+	} else {
+		rl.trace("dispatch", "keys", string(rl.Keys.Caller()), "keymap", dispatched, "command", bind.Action, "macro", bind.Macro)
+	}
+
+	// Notify the command about to run, and any keymap change after it.
+	if command != nil {
+		rl.Hooks.runPreCommand(bind.Action)
+	}
+
+	mainKeymap, localKeymap := rl.Keymap.Main(), rl.Keymap.Local()
+	line, undo := string(*rl.line), rl.History.Pos()
+	recalled, _ := rl.History.Recalled()
+
 	// The command might be nil, because the provided key sequence
 	// did not match any. We regardless execute everything related
 	// to the command, like any pending ones, and cursor checks.
 	rl.execute(command)
 
+	// Record the keys of vi changes, so that they can be repeated,
+	// and keep vi marks on their characters if the line is edited.
+	if main || command != nil {
+		entry, _ := rl.History.Recalled()
+		edited := line != string(*rl.line) && recalled == entry
+		rl.recordViChange(mainKeymap, edited && undo == rl.History.Pos())
+		rl.adjustMarks(line, edited)
+	}
+
+	if mainKeymap != rl.Keymap.Main() || localKeymap != rl.Keymap.Local() {
+		rl.trace("keymap", "main", rl.Keymap.Main(), "local", rl.Keymap.Local())
+		rl.Hooks.runModeChange(string(rl.Keymap.Main()), string(rl.Keymap.Local()))
+
+		if mode := rl.Keymap.Local(); mode != "" {
+			rl.Display.Announce(rl.locale.Sprintf(locale.ModeAnnounce, mode))
+		} else {
+			rl.Display.Announce(rl.locale.Sprintf(locale.ModeAnnounce, rl.Keymap.Main()))
+		}
+	}
+
 	// Either print/clear iterations/active registers hints.
 	rl.updatePosRunHints()
 
@@ -217,6 +606,40 @@ func (rl *Shell) execute(command func()) {
 	}
 }
 
+// runKeys dispatches keys to their commands as if they were typed, and returns
+// once all of them are, without waiting for input: the keys typed ahead are
+// left in the stack, and the ones of an incomplete sequence are dropped.
+// The line is never accepted by these keys, even if they are bound to do so.
+func (rl *Shell) runKeys(keys []rune) {
+	restore := core.Isolate(rl.Keys)
+	defer restore()
+
+	rl.Keys.Feed(false, keys...)
+
+	for core.FlushUsed(rl.Keys); core.PendingKeys(rl.Keys); core.FlushUsed(rl.Keys) {
+		bind, command, prefixed := keymap.MatchLocal(rl.Keymap)
+		if prefixed {
+			continue
+		}
+
+		rl.run(false, bind, command)
+
+		if command != nil {
+			continue
+		}
+
+		completion.UpdateInserted(rl.completer)
+
+		bind, command, prefixed = keymap.MatchMain(rl.Keymap)
+		if prefixed {
+			continue
+		}
+
+		rl.run(true, bind, command)
+		rl.handleUndefined(bind, command)
+	}
+}
+
 // Some commands show their current status as a hint (iterations/macro).
 func (rl *Shell) updatePosRunHints() {
 	hint := core.ResetPostRunIterations(rl.Iterations)
@@ -230,10 +653,35 @@ func (rl *Shell) updatePosRunHints() {
 	if hint != "" {
 		rl.Hint.Persist(hint)
 	} else if selected {
-		rl.Hint.Persist(color.Dim + fmt.Sprintf("(register: %s)", register))
+		rl.Hint.Persist(color.Dim + rl.locale.Sprintf(locale.Register, register))
 	}
 }
 
+// waitInput waits for input keys with the line unlocked, so that it can be
+// edited by other goroutines, and runs the idle callback if no key is read
+// in time. The line is locked again when returning, even on panics, since
+// the main loop always unlocks it when done reading.
+func (rl *Shell) waitInput(ctx context.Context) error {
+	rl.lineMutex.Unlock()
+	defer rl.lineMutex.Lock()
+
+	err := rl.waitPendingKeys(ctx)
+	if errors.Is(err, core.ErrIdle) {
+		rl.runIdle()
+	}
+
+	return err
+}
+
+// runIdle calls the user-provided idle callback, if any.
+func (rl *Shell) runIdle() {
+	if rl.idle == nil {
+		return
+	}
+
+	rl.idle()
+}
+
 // handleUndefined is in charge of all actions to take when the
 // last key/sequence was not dispatched down to a readline command.
 func (rl *Shell) handleUndefined(bind inputrc.Bind, cmd func()) {
@@ -0,0 +1,359 @@
+package readline_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_ScreenReader(t *testing.T) {
+	term, rl := newViTerminal(t, 80, 24)
+	rl.Config.Set("screen-reader", true)
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+	rl.Prompt.Primary(func() string { return "> " })
+
+	line, err := term.Run(rl, "abc", `\C-o`, "ix", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "abxc" {
+		t.Errorf("Run() line = %q, want %q", line, "abxc")
+	}
+
+	// Mode changes are announced below the line, and
+	// the prompt and line are printed again below them.
+	readlinetest.AssertScreen(t, term, "> abc\nvi-command mode\n> abc\nvi-insert mode\n> abxc")
+
+	// Screen readers follow the cursor, which is never hidden.
+	if strings.Contains(term.Output(), "\x1b[?25l") {
+		t.Errorf("Output() = %q, want the cursor never hidden", term.Output())
+	}
+}
+
+func TestShell_BellStyle(t *testing.T) {
+	tests := []struct {
+		style string
+		bell  string
+	}{
+		{style: "audible", bell: "\a"},
+		{style: "visible", bell: "\x1b[?5h"},
+		{style: "none"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+		rl.Config.Set("bell-style", test.style)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+		if _, err := term.Run(rl, "abc", `\C-o`, "0", "fz", `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		rang := strings.Contains(term.Output(), "\a") || strings.Contains(term.Output(), "\x1b[?5h")
+
+		switch {
+		case test.bell == "" && rang:
+			t.Errorf("bell-style %q: Output() = %q, want no bell", test.style, term.Output())
+		case test.bell != "" && !strings.Contains(term.Output(), test.bell):
+			t.Errorf("bell-style %q: Output() = %q, want the %q bell", test.style, term.Output(), test.bell)
+		}
+	}
+}
+
+func TestShell_MaxHelperRows(t *testing.T) {
+	tests := []struct {
+		priority string
+		hintRows int
+	}{
+		{priority: "hint", hintRows: 2},
+		{priority: "completions", hintRows: 1},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+		rl.Config.Set("max-redisplay-rate", 0)
+		rl.Config.Set("max-helper-rows", 4)
+		rl.Config.Set("helper-priority", test.priority)
+
+		rl.HintProvider = func(_ []rune, _ int) string {
+			return strings.Repeat("abcdefghij", 20)
+		}
+
+		rl.Completer = func(_ []rune, _ int) readline.Completions {
+			var values []string
+			for i := 0; i < 20; i++ {
+				values = append(values, fmt.Sprintf("value%02d", i), "description")
+			}
+
+			return readline.CompleteValuesDescribed(values...).NoSort()
+		}
+
+		go rl.Readline()
+
+		term.Type("v", `\e?`, `\t`)
+
+		// Wait for the menu to be fully displayed below the hint.
+		helpers := func() []string {
+			screen := strings.Split(term.Screen(), "\n")
+			return screen[max(len(screen)-4, 0):]
+		}
+
+		displayed := func() bool {
+			rows := helpers()
+			return len(rows) == 4 && strings.HasPrefix(rows[test.hintRows], "value00") &&
+				strings.Contains(rows[3], "more completion rows")
+		}
+
+		ok := waitUntil(displayed)
+
+		rl.Close()
+
+		if !ok {
+			t.Errorf("helper-priority %q: helpers =\n%s\nwant %d hint rows and 4 rows", test.priority, strings.Join(helpers(), "\n"), test.hintRows)
+			continue
+		}
+
+		if row := helpers()[test.hintRows-1]; !strings.HasSuffix(row, "…") {
+			t.Errorf("helper-priority %q: hint row %q, want it cropped with an ellipsis", test.priority, row)
+		}
+	}
+}
+
+func TestShell_StartFreshLine(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "> " })
+
+	// probed returns true if the cursor position was
+	// queried before printing the prompt, once at start.
+	probed := func(start int) bool {
+		output := term.Output()[start:]
+		return strings.Contains(output[:strings.Index(output, "> ")], "\x1b[6n")
+	}
+
+	for i, line := range []string{"one", "two"} {
+		start := len(term.Output())
+
+		if _, err := term.Run(rl, line, `\r`); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if probed(start) != (i == 0) {
+			t.Errorf("Run(%q) queried the cursor = %v, want %v", line, i != 0, i == 0)
+		}
+	}
+
+	// The cursor is queried again after commands run in the terminal.
+	rl.AddBindContext("output", readline.BindContext{
+		Binds: map[string]string{`\C-xo`: "print-output"},
+		Commands: map[string]func(){
+			"print-output": func() {
+				rl.RunInTerminal(func() error {
+					_, err := term.Write([]byte("output"))
+					return err
+				})
+			},
+		},
+	})
+
+	start := len(term.Output())
+
+	if _, err := term.Run(rl, `\C-xo`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if !probed(start + strings.Index(term.Output()[start:], "output")) {
+		t.Error("RunInTerminal() did not query the cursor before redisplaying the prompt")
+	}
+
+	readlinetest.AssertScreen(t, term, "> one\n> two\n>\noutput%\n>")
+}
+
+func TestShell_Close(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Prompt.Primary(func() string { return "> " })
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("alpha", "beta")
+	}
+
+	rl.HintProvider = func(_ []rune, _ int) string { return "a hint" }
+
+	rl.SetChecker(0, func(_ context.Context, _ []rune) []readline.Diagnostic {
+		return []readline.Diagnostic{{Start: 0, End: 2, Message: "a diagnostic"}}
+	})
+
+	done := make(chan error, 1)
+
+	go func() {
+		_, err := rl.Readline()
+		done <- err
+	}()
+
+	term.Type("cmd ", `\e?`)
+	waitForText(t, term, "beta")
+	waitForText(t, term, "a diagnostic")
+
+	// Closing from another goroutine clears the helpers below the line.
+	go rl.Close()
+
+	if err := <-done; !errors.Is(err, readline.ErrClosed) {
+		t.Errorf("Readline() error = %v, want %v", err, readline.ErrClosed)
+	}
+
+	readlinetest.AssertScreen(t, term, "> cmd")
+
+	if frame := rl.Frame().String(); frame != "> cmd" {
+		t.Errorf("Frame() = %q, want the helpers cleared", frame)
+	}
+
+	if term.IsRaw() {
+		t.Error("terminal still in raw mode after Close()")
+	}
+
+	if _, err := rl.Readline(); !errors.Is(err, readline.ErrClosed) {
+		t.Errorf("Readline() error = %v, want %v", err, readline.ErrClosed)
+	}
+}
+
+func TestShell_ReadlinePlain(t *testing.T) {
+	dir := t.TempDir()
+	stdinFile := filepath.Join(dir, "stdin")
+	stdoutFile := filepath.Join(dir, "stdout")
+
+	if err := os.WriteFile(stdinFile, []byte("ls -l\r\necho hi"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdin, err := os.Open(stdinFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+
+	stdout, err := os.Create(stdoutFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+
+	// Shells not provided with a terminal read the process standard input.
+	processStdin, processStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+
+	defer func() { os.Stdin, os.Stdout = processStdin, processStdout }()
+
+	rl := readline.NewShell()
+	rl.Prompt.Primary(func() string { return "\x1b[1;32muser\x1b[0m $ " })
+
+	if err := rl.SetEnv(readline.EnvMap{"TERM": "dumb"}); err != nil {
+		t.Fatalf("SetEnv() error = %v", err)
+	}
+
+	history := readline.NewInMemoryHistory()
+	rl.History.Add("test", history)
+
+	// The last line is returned even without a trailing newline, then EOF.
+	var lines []string
+
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			t.Fatalf("Readline() error = %v", err)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if want := []string{"ls -l", "echo hi"}; !slices.Equal(lines, want) {
+		t.Errorf("Readline() lines = %q, want %q", lines, want)
+	}
+
+	var recorded []string
+
+	for i := 0; i < history.Len(); i++ {
+		line, _ := history.GetLine(i)
+		recorded = append(recorded, line)
+	}
+
+	if want := []string{"ls -l", "echo hi"}; !slices.Equal(recorded, want) {
+		t.Errorf("history = %q, want %q", recorded, want)
+	}
+
+	// Prompts are printed without their escape sequences.
+	output, err := os.ReadFile(stdoutFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := strings.Repeat("user $ ", 3); string(output) != want {
+		t.Errorf("output = %q, want %q", output, want)
+	}
+}
+
+// newViTerminal returns a test terminal of the given size, and
+// its shell in vi editing mode, redisplaying after every key.
+func newViTerminal(t *testing.T, width, height int) (*readlinetest.Terminal, *readline.Shell) {
+	t.Helper()
+
+	term := readlinetest.NewTerminal(width, height)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	if err := rl.SetOption("editing-mode", "vi"); err != nil {
+		t.Fatalf("SetOption() error = %v", err)
+	}
+
+	return term, rl
+}
+
+// waitForScreen waits until the screen, without its surrounding
+// spaces, is the wanted one, and fails the test if it is not in time.
+func waitForScreen(t *testing.T, term *readlinetest.Terminal, want string) {
+	t.Helper()
+
+	waitUntil(func() bool { return strings.TrimSpace(term.Screen()) == want })
+
+	if got := strings.TrimSpace(term.Screen()); got != want {
+		t.Errorf("Screen() = %q, want %q", got, want)
+	}
+}
+
+// waitForText waits until the screen contains the text,
+// and stops the test if it does not in time.
+func waitForText(t *testing.T, term *readlinetest.Terminal, text string) {
+	t.Helper()
+
+	if !waitUntil(func() bool { return strings.Contains(term.Screen(), text) }) {
+		t.Fatalf("Screen() = %q, want it to contain %q", term.Screen(), text)
+	}
+}
+
+// waitUntil checks the condition until it is true or until readlinetest.Timeout,
+// and returns whether it is eventually true.
+func waitUntil(condition func() bool) bool {
+	deadline := time.Now().Add(readlinetest.Timeout)
+	for !condition() && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	return condition()
+}
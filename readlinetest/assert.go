@@ -0,0 +1,69 @@
+package readlinetest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/reeflective/readline"
+)
+
+// UpdateEnv is the environment variable which, when set to a non-empty
+// value, makes AssertGolden write the golden files instead of comparing.
+const UpdateEnv = "READLINETEST_UPDATE"
+
+// AssertBuffer fails the test if the shell input line
+// and cursor position are not the expected ones.
+func AssertBuffer(t testing.TB, rl *readline.Shell, line string, cursor int) {
+	t.Helper()
+
+	gotLine, gotCursor := rl.GetLineAndCursor()
+
+	if string(gotLine) != line {
+		t.Errorf("line = %q, want %q", string(gotLine), line)
+	}
+
+	if gotCursor != cursor {
+		t.Errorf("cursor = %d, want %d", gotCursor, cursor)
+	}
+}
+
+// AssertScreen fails the test if the text displayed
+// on the terminal screen is not the expected one.
+func AssertScreen(t testing.TB, term *Terminal, screen string) {
+	t.Helper()
+
+	if got := term.Screen(); got != screen {
+		t.Errorf("screen =\n%s\nwant:\n%s", got, screen)
+	}
+}
+
+// AssertGolden compares the given text to the contents of the golden file
+// testdata/<name>.golden, and fails the test if they differ. If the UpdateEnv
+// environment variable is set, the golden file is written instead.
+func AssertGolden(t testing.TB, name, got string) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name+".golden")
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file (set %s=1 to create it): %v", UpdateEnv, err)
+	}
+
+	if got != string(want) {
+		t.Errorf("%s mismatch:\n%s\nwant:\n%s", path, got, string(want))
+	}
+}
@@ -0,0 +1,62 @@
+package readlinetest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+)
+
+// longLine is a 10k characters input line, ending with a comment.
+var longLine = []rune(strings.Repeat("word ", 1999) + "# end")
+
+// readLongLine starts reading on a shell whose input line is long, so
+// that each edit made with the shell API is immediately redisplayed.
+func readLongLine(b *testing.B, highlighter func([]rune) string) (*Terminal, *readline.Shell) {
+	b.Helper()
+
+	term := NewTerminal(120, 40)
+	rl := term.Shell()
+	rl.SyntaxHighlighter = highlighter
+	rl.Config.Set("max-redisplay-rate", 0)
+
+	go rl.Readline()
+
+	for !term.IsRaw() {
+		time.Sleep(time.Millisecond)
+	}
+
+	b.Cleanup(func() { rl.Close() })
+	rl.SetLine(longLine)
+
+	return term, rl
+}
+
+func BenchmarkShell_InsertLongLine(b *testing.B) {
+	term, rl := readLongLine(b, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rl.InsertAt(len(longLine)/2, []rune("x"))
+		term.Reset()
+	}
+}
+
+func BenchmarkShell_HighlightLongLine(b *testing.B) {
+	highlighter := func(line []rune) string {
+		return strings.ReplaceAll(string(line), "word", "\x1b[32mword\x1b[0m")
+	}
+
+	term, rl := readLongLine(b, highlighter)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rl.MoveCursor(-1)
+		term.Reset()
+	}
+}
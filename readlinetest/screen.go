@@ -0,0 +1,18 @@
+package readlinetest
+
+import "github.com/reeflective/readline/internal/term"
+
+// Screen is a minimal terminal screen emulator, supporting the
+// subset of control sequences used by readline shells: cursor
+// movements, line and screen clearing, and switching to and from
+// the alternate screen. Colors and other styles
+// are recorded with each character, but only kept by Styled.
+// The screen scrolls when writing below it.
+type Screen struct {
+	*term.Screen
+}
+
+// NewScreen returns an empty screen with the given dimensions.
+func NewScreen(width, height int) *Screen {
+	return &Screen{Screen: term.NewScreen(width, height)}
+}
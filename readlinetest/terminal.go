@@ -0,0 +1,241 @@
+// Package readlinetest provides a fake terminal to test readline shells,
+// their custom commands and completers without a real terminal or PTY:
+// keystrokes are scripted as input, and output is captured as frames
+// and emulated into a screen, on which assertions can be made.
+//
+// Example usage:
+//
+//	term := readlinetest.NewTerminal(80, 24)
+//	rl := term.Shell()
+//
+//	line, err := term.Run(rl, `hello`, `\C-a`, `\r`)
+//	readlinetest.AssertScreen(t, term, "hello")
+//
+// Each terminal has its own input and output, so that tests running
+// shells on distinct terminals can run in parallel.
+package readlinetest
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+)
+
+// queryCursorPos is the sequence used by the shell to query the cursor position.
+const queryCursorPos = "\x1b[6n"
+
+// hideCursor is the sequence used by the shell before redisplaying its interface.
+const hideCursor = "\x1b[?25l"
+
+// Timeout is the maximum duration to wait for a shell to return a line in Run.
+var Timeout = 5 * time.Second
+
+// ErrTimeout is returned by Run when the shell did not return a line in time.
+var ErrTimeout = errors.New("timed out waiting for readline to return")
+
+// Terminal is a fake terminal used to run readline shells: it implements
+// the readline.TermState interface, an io.Reader from which the shell reads
+// scripted keystrokes, and an io.Writer capturing all of the shell output.
+// It also answers cursor position queries sent by the shell, like a real
+// terminal would do.
+type Terminal struct {
+	width  int
+	height int
+	input  chan []byte
+	output bytes.Buffer
+	screen *Screen
+	raw    bool
+	closed bool
+	mutex  sync.Mutex
+}
+
+// NewTerminal returns a fake terminal with the given dimensions.
+func NewTerminal(width, height int) *Terminal {
+	return &Terminal{
+		width:  width,
+		height: height,
+		input:  make(chan []byte, 1024),
+		screen: NewScreen(width, height),
+	}
+}
+
+// Shell returns a new readline shell running on this terminal,
+// initialized with the given inputrc configuration options.
+func (t *Terminal) Shell(opts ...inputrc.Option) *readline.Shell {
+	return readline.NewShellWith(t, t, t, opts...)
+}
+
+// Type sends some keys to the shell, as if they were typed by a user.
+// Keys are unescaped with the inputrc syntax, so that `\C-a` or `\e[A`
+// are sent as the corresponding control and escape sequences.
+func (t *Terminal) Type(keys ...string) {
+	if t.inputClosed() {
+		return
+	}
+
+	for _, key := range keys {
+		t.input <- []byte(inputrc.Unescape(key))
+	}
+}
+
+// Run reads a line with the shell, while typing the given keys in it, and
+// returns the line and error returned by the shell. Keys are queued in order,
+// and each of them is read by the shell as a separate chunk of input. If the
+// shell does not return before Timeout, it is closed and ErrTimeout returned.
+func (t *Terminal) Run(rl *readline.Shell, keys ...string) (line string, err error) {
+	type result struct {
+		line string
+		err  error
+	}
+
+	done := make(chan result, 1)
+
+	go func() {
+		line, err := rl.Readline()
+		done <- result{line, err}
+	}()
+
+	for _, key := range keys {
+		t.Type(key)
+	}
+
+	select {
+	case res := <-done:
+		return res.line, res.err
+	case <-time.After(Timeout):
+		rl.Close()
+		return "", ErrTimeout
+	}
+}
+
+// Read implements io.Reader, and returns the keys typed in the terminal.
+func (t *Terminal) Read(buf []byte) (int, error) {
+	keys, open := <-t.input
+	if !open {
+		return 0, io.EOF
+	}
+
+	return copy(buf, keys), nil
+}
+
+// Write implements io.Writer, captures and emulates the shell output,
+// and answers any cursor position query with the emulated cursor position.
+func (t *Terminal) Write(buf []byte) (int, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.output.Write(buf)
+
+	for _, chunk := range strings.SplitAfter(string(buf), queryCursorPos) {
+		t.screen.Write([]byte(chunk))
+
+		if strings.HasSuffix(chunk, queryCursorPos) && !t.closed {
+			t.input <- []byte(t.screen.CursorReport())
+		}
+	}
+
+	return len(buf), nil
+}
+
+// CloseInput closes the terminal input, like a host closing the channel on
+// which the shell runs: once the keys already typed are read, the shell reads
+// io.EOF. Keys typed afterwards are dropped, and cursor queries not answered.
+func (t *Terminal) CloseInput() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if !t.closed {
+		t.closed = true
+		close(t.input)
+	}
+}
+
+func (t *Terminal) inputClosed() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.closed
+}
+
+// EnterRaw implements readline.TermState.
+func (t *Terminal) EnterRaw() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.raw = true
+
+	return nil
+}
+
+// ExitRaw implements readline.TermState.
+func (t *Terminal) ExitRaw() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.raw = false
+
+	return nil
+}
+
+// Size implements readline.TermState.
+func (t *Terminal) Size() (width, height int, err error) {
+	return t.width, t.height, nil
+}
+
+// IsRaw returns true if the terminal is currently in raw mode.
+func (t *Terminal) IsRaw() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.raw
+}
+
+// Output returns all the raw output written by the shell so far.
+func (t *Terminal) Output() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.output.String()
+}
+
+// Frames returns the output written by the shell, split into frames: each
+// frame starts when the shell hides the cursor to redisplay its interface.
+// With the screen-reader option, the cursor is never hidden: the output is
+// then returned as a single frame.
+func (t *Terminal) Frames() []string {
+	output := t.Output()
+
+	var frames []string
+
+	for _, frame := range strings.SplitAfter(output, hideCursor) {
+		if frame != "" {
+			frames = append(frames, frame)
+		}
+	}
+
+	return frames
+}
+
+// Screen returns the text currently displayed on the emulated
+// screen, without trailing spaces nor trailing empty lines.
+func (t *Terminal) Screen() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return t.screen.String()
+}
+
+// Reset clears the captured output and the emulated screen.
+func (t *Terminal) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.output.Reset()
+	t.screen = NewScreen(t.width, t.height)
+}
@@ -0,0 +1,164 @@
+package readlinetest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+)
+
+func TestScreen_Write(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "Plain text",
+			output: "hello\r\nworld",
+			want:   "hello\nworld",
+		},
+		{
+			name:   "Cursor movements",
+			output: "hello\x1b[3Dy\x1b[1Bz",
+			want:   "heylo\n   z",
+		},
+		{
+			name:   "Clear line after cursor",
+			output: "hello\x1b[3D\x1b[0K",
+			want:   "he",
+		},
+		{
+			name:   "Clear screen below",
+			output: "hello\r\nworld\x1b[1A\x1b[0J",
+			want:   "hello",
+		},
+		{
+			name:   "Colors are ignored",
+			output: "\x1b[31mred\x1b[0m",
+			want:   "red",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			screen := NewScreen(20, 5)
+			screen.Write([]byte(test.output))
+
+			if got := screen.String(); got != test.want {
+				t.Errorf("Screen.String() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTerminal_Run(t *testing.T) {
+	term := NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "> " })
+
+	line, err := term.Run(rl, "world", `\C-a`, "hello ", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "hello world" {
+		t.Errorf("Run() line = %q, want %q", line, "hello world")
+	}
+
+	AssertScreen(t, term, "> hello world")
+
+	if term.IsRaw() {
+		t.Error("terminal still in raw mode after Run()")
+	}
+
+	_, err = term.Run(rl, "abc", `\C-c`)
+	if !errors.Is(err, readline.ErrInterrupt) {
+		t.Errorf("Run() error = %v, want %v", err, readline.ErrInterrupt)
+	}
+}
+
+func TestTerminal_PartialLine(t *testing.T) {
+	term := NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "> " })
+
+	// Output without a trailing newline is kept, and marked.
+	term.Write([]byte("output"))
+
+	if _, err := term.Run(rl, "hello", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	AssertScreen(t, term, "output%\n> hello")
+}
+
+func TestTerminal_GoldenScreen(t *testing.T) {
+	term := NewTerminal(60, 12)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Prompt.Primary(func() string { return "> " })
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteRaw([]readline.Completion{
+			{Value: "commit", Description: "record changes", Tag: "commands"},
+			{Value: "checkout", Description: "switch branches", Tag: "commands"},
+			{Value: "main", Description: "default branch", Tag: "branches"},
+			{Value: "feature", Tag: "branches"},
+		})
+	}
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("git ", `\e?`)
+	for deadline := time.Now().Add(Timeout); !strings.Contains(term.Screen(), "feature"); {
+		if time.Now().After(deadline) {
+			t.Fatalf("Screen() = %q, want it to contain %q", term.Screen(), "feature")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	AssertGolden(t, "completion-groups", term.Screen())
+
+	term.Type(`\C-u`, `\r`)
+	<-done
+}
+
+func TestTerminal_Concurrent(t *testing.T) {
+	terms := []*Terminal{NewTerminal(40, 10), NewTerminal(60, 20)}
+	lines := []string{"first session", "second session"}
+	errs := make(chan error, len(terms))
+
+	for i, term := range terms {
+		go func(i int, term *Terminal) {
+			rl := term.Shell()
+			rl.Config.Set("max-redisplay-rate", 0)
+			rl.Prompt.Primary(func() string { return fmt.Sprintf("%d> ", i) })
+
+			line, err := term.Run(rl, lines[i], `\C-a`, `\C-k`, lines[i], `\r`)
+			if err == nil && line != lines[i] {
+				err = fmt.Errorf("Run() line = %q, want %q", line, lines[i])
+			}
+
+			errs <- err
+		}(i, term)
+	}
+
+	for range terms {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	AssertScreen(t, terms[0], "0> first session")
+	AssertScreen(t, terms[1], "1> second session")
+}
@@ -0,0 +1,84 @@
+package readline
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/locale"
+)
+
+// ErrNoSelection is returned by Select when the line is
+// accepted while none of the items is selected.
+var ErrNoSelection = errors.New("no item selected")
+
+// Select displays the items in the completion menu below a prompt with the
+// title, with incremental search started, and returns the item selected by
+// the user when accepting the line (generally with Enter). Items are grouped
+// and displayed like completions, including their tags, descriptions and
+// styles, and are filtered with the search query as they are typed.
+//
+// If some items have been marked (see SelectMulti), the first one marked is
+// returned. If the line is accepted while no item is selected, ErrNoSelection is
+// returned. As with Readline, if the user presses Ctrl-C (once the search
+// is cancelled), ErrInterrupt is returned. The shell history, completer and
+// prompts are left untouched, and are restored once done. Hooks are not run
+// meanwhile, and the keys typed are not traced.
+func (rl *Shell) Select(title string, items []Completion) (Completion, error) {
+	selected, err := rl.SelectMulti(title, items)
+	if err != nil {
+		return Completion{}, err
+	}
+
+	return selected[0], nil
+}
+
+// SelectMulti is like Select, but lets the user mark several items with the
+// menu-toggle-mark command (M-m by default), which also selects the next item.
+// Marked items are returned in the order they were marked when accepting the
+// line. If no item is marked, the selected one is returned alone, and if none
+// is selected either, ErrNoSelection is returned.
+func (rl *Shell) SelectMulti(title string, items []Completion) ([]Completion, error) {
+	restore := rl.startStandalone()
+	defer restore()
+
+	rl.Prompt.Primary(func() string { return title + " " })
+
+	rl.completer.TakeAccepted()
+
+	rl.startRead = func() {
+		rl.completer.GenerateWith(func() completion.Values {
+			return completion.AddRaw(slices.Clone(items))
+		})
+		rl.completer.IsearchStart(rl.locale.Get(locale.Select), true, true)
+	}
+
+	line, err := rl.Readline()
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []Completion
+
+	for _, marked := range rl.completer.TakeAccepted() {
+		index := slices.IndexFunc(items, func(item Completion) bool {
+			return item.Value == marked.Value && item.Tag == marked.Tag
+		})
+
+		if index >= 0 {
+			selected = append(selected, items[index])
+		}
+	}
+
+	if len(selected) > 0 {
+		return selected, nil
+	}
+
+	for _, item := range items {
+		if item.Value == line {
+			return []Completion{item}, nil
+		}
+	}
+
+	return nil, ErrNoSelection
+}
@@ -0,0 +1,103 @@
+package readline_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_Select(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	items := []readline.Completion{
+		{Value: "red", Description: "warm"},
+		{Value: "green", Description: "natural"},
+		{Value: "blue", Description: "cold"},
+	}
+
+	term.Type("gre", `\r`)
+
+	item, err := rl.Select("Color?", items)
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	if item.Value != "green" || item.Description != "natural" {
+		t.Errorf("Select() = %+v, want the green item", item)
+	}
+
+	if !strings.Contains(term.Output(), "cold") {
+		t.Error("items not displayed before searching")
+	}
+
+	term.Type("zz", `\r`)
+
+	if _, err = rl.Select("Color?", items); !errors.Is(err, readline.ErrNoSelection) {
+		t.Errorf("Select() error = %v, want %v", err, readline.ErrNoSelection)
+	}
+}
+
+func TestShell_SelectMulti(t *testing.T) {
+	items := []readline.Completion{
+		{Value: "red", Description: "warm"},
+		{Value: "green", Description: "natural"},
+		{Value: "blue", Description: "cold"},
+	}
+
+	tests := []struct {
+		name string
+		keys []string
+		want []string
+	}{
+		{name: "Marked", keys: []string{"e", `\em`, `\em`, `\r`}, want: []string{"blue", "green"}},
+		{name: "Marking order", keys: []string{"e", `\C-n`, `\em`, `\C-p`, `\C-p`, `\em`, `\r`}, want: []string{"green", "blue"}},
+		{name: "Unmarked", keys: []string{"e", `\em`, `\C-p`, `\em`, `\r`}, want: []string{"green"}},
+		{name: "Accept menu", keys: []string{"e", `\em`, `\em`, `\e\C-m`, `\r`}, want: []string{"blue", "green"}},
+		{name: "Selected only", keys: []string{"blu", `\r`}, want: []string{"blue"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term := readlinetest.NewTerminal(80, 24)
+			rl := term.Shell()
+
+			term.Type(test.keys...)
+
+			selected, err := rl.SelectMulti("Colors?", items)
+			if err != nil {
+				t.Fatalf("SelectMulti() error = %v", err)
+			}
+
+			var values []string
+			for _, item := range selected {
+				values = append(values, item.Value)
+			}
+
+			if !slices.Equal(values, test.want) {
+				t.Errorf("SelectMulti() = %q, want %q", values, test.want)
+			}
+		})
+	}
+
+	// Select returns the first item marked.
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	term.Type("e", `\C-n`, `\em`, `\em`, `\r`)
+
+	item, err := rl.Select("Color?", items)
+	if err != nil || item.Value != "green" {
+		t.Errorf("Select() = %q, %v, want %q", item.Value, err, "green")
+	}
+
+	term.Type("zz", `\r`)
+
+	if _, err = rl.SelectMulti("Colors?", items); !errors.Is(err, readline.ErrNoSelection) {
+		t.Errorf("SelectMulti() error = %v, want %v", err, readline.ErrNoSelection)
+	}
+}
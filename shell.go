@@ -1,7 +1,16 @@
 package readline
 
 import (
+	"bufio"
+	"context"
 	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/reeflective/readline/inputrc"
 	"github.com/reeflective/readline/internal/completion"
@@ -10,6 +19,7 @@ import (
 	"github.com/reeflective/readline/internal/editor"
 	"github.com/reeflective/readline/internal/history"
 	"github.com/reeflective/readline/internal/keymap"
+	"github.com/reeflective/readline/internal/locale"
 	"github.com/reeflective/readline/internal/macro"
 	"github.com/reeflective/readline/internal/term"
 	"github.com/reeflective/readline/internal/ui"
@@ -23,6 +33,11 @@ import (
 // all the goroutines that need to read user input.
 // Please refer to the README and documentation for more details about the shell
 // and its components, and how to use them.
+//
+// Shells should be created with New and configured with its options, and with
+// the setter methods of the shell and its components: directly setting the
+// exported fields of the shell is deprecated, since they might change along
+// with the shell internals.
 type Shell struct {
 	// Core editor
 	line       *core.Line       // The input line buffer and its management methods.
@@ -42,6 +57,8 @@ type Shell struct {
 	Hint      *ui.Hint           // Usage/hints for completion/isearch below the input line.
 	completer *completion.Engine // Completions generation and display.
 	Display   *display.Engine    // Manages display refresh/update/clearing.
+	Hooks     *Hooks             // Functions called on various events of the shell lifecycle.
+	locale    *locale.Catalog    // Translates the built-in messages.
 
 	// User-provided functions
 
@@ -61,6 +78,116 @@ type Shell struct {
 	// It takes the readline line ([]rune) and cursor pos as parameters,
 	// and returns completions with their associated metadata/settings.
 	Completer func(line []rune, cursor int) Completions
+
+	// CompleterContext is like Completer, but its context is cancelled when
+	// a key is typed while it is generating completions: it should then return
+	// at once with the candidates produced so far, which are displayed without
+	// delaying the key. It is used in place of Completer if both are set.
+	CompleterContext func(ctx context.Context, line []rune, cursor int) Completions
+
+	// Interrupt is called when the interrupt sequence (generally Ctrl-C) is
+	// pressed, with the current input line, and when there is no completion
+	// or search to cancel instead. If nil or if the function returns true, the
+	// line is returned with ErrInterrupt. If false, the line is dropped and the
+	// shell keeps reading on a new, empty line (like bash and zsh do).
+	Interrupt func(line []rune) (abort bool)
+
+	// EndOfFile is called when the end-of-file character (generally Ctrl-D) is
+	// read on an empty line, and returns the error with which the line is then
+	// returned, like io.EOF (the default if nil) or a sentinel of the caller.
+	// If it returns nil, the shell keeps reading (like bash with ignoreeof).
+	// On non-empty lines, the end-of-file-command option is run instead.
+	EndOfFile func(line []rune) error
+
+	// HintProvider returns a hint displayed below the input line, like the usage
+	// of the current command, linter messages or the result of a calculation. It
+	// is called with the line and cursor position whenever one of them changes,
+	// once all keys typed have been processed, and its hint is displayed unless
+	// another one (completions, search) is. An empty hint displays nothing.
+	HintProvider func(line []rune, cursor int) string
+
+	// Suggesters suggest the lines displayed as ghost text after the input line
+	// when the history-autosuggest option is enabled: the first line suggested
+	// by the first suggester and starting with the input line is displayed. If
+	// empty, the most recent history line starting with the input line is.
+	Suggesters []Suggester
+
+	// Executor runs the commands whose output is inserted in the line by
+	// the insert-command-output widget, and returns their standard output.
+	// If nil, commands are run with the system shell (sh -c, or cmd /C).
+	Executor func(command string) (output string, err error)
+
+	// WordClass, if not nil, returns the class of characters used by all word
+	// motion, kill, selection and transpose commands in place of the word-style
+	// and word-chars options, for instance to split words like the host parser:
+	// consecutive characters of the same class form a word, and characters of
+	// class zero separate words. Newlines always separate words.
+	WordClass func(char rune) int
+
+	// Tokenizer, if not nil, splits the line into the tokens of the host grammar,
+	// for instance for a SQL or Lisp REPL. These tokens are the words of all word
+	// and shell-word motion, kill, selection and transpose commands (in place of
+	// WordClass and the word-style option), and the words completed by default.
+	// Characters out of all tokens separate words.
+	Tokenizer func(line []rune) []Token
+
+	// TokenStyles are the styles (sequences of color/text effects) of the
+	// tokens returned by Tokenizer, indexed by token types: when there is no
+	// SyntaxHighlighter, the line is highlighted with them.
+	TokenStyles map[string]string
+
+	// Other user-provided callbacks
+	idle         func()                                                    // Called when no key has been read for some time.
+	asyncHint    func(ctx context.Context, line []rune, cursor int) string // Provides hints asynchronously.
+	hintDebounce time.Duration                                             // Delay before providing hints asynchronously.
+	hintCancel   context.CancelFunc                                        // Cancels the hint being provided asynchronously.
+
+	checker       func(ctx context.Context, line []rune) []Diagnostic // Checks the line asynchronously.
+	checkDebounce time.Duration                                       // Delay before checking the line.
+	checkCancel   context.CancelFunc                                  // Cancels the line check running, if any.
+	checkedLine   []rune                                              // The line last passed to the checker.
+
+	bindContexts    []namedBindContext // Binds and commands overlaid while the line is in a context.
+	asyncCompleters []asyncCompleter   // Completers run concurrently with the shell completer.
+	asyncFetch      *asyncFetch        // Completions being generated by asynchronous completers.
+	recentOutput    []string           // Output lines of the host, whose words are completed.
+
+	// Lifecycle
+	closed          bool                        // The shell has been closed and cannot read input anymore.
+	suspended       bool                        // The shell has stopped itself and will be continued.
+	cancelRead      context.CancelFunc          // Cancels the current Readline call, if any.
+	readCtx         context.Context             // The context of the current Readline call, if any.
+	rawGuard        *term.RawGuard              // Restores the terminal state once done reading.
+	timeout         time.Duration               // Maximum duration of a Readline call.
+	panicOutput     io.Writer                   // Where to print panics recovered while reading.
+	hosted          bool                        // Input, output and terminal are provided by the host.
+	env             Env                         // Where environment variables are read, or nil for the process.
+	out             *term.Output                // Where the interface is displayed, on the shell terminal.
+	plainReader     *bufio.Reader               // Reads lines when stdin is not a terminal.
+	mutex           sync.Mutex                  // Protects the lifecycle state.
+	lineMutex       sync.Mutex                  // Protects the line against concurrent edits.
+	tracer          atomic.Pointer[slog.Logger] // Logs the shell internals when not nil.
+	lastLine        []rune                      // The line last notified to line change hooks.
+	hintLine        []rune                      // The line last passed to the hint provider.
+	hintCursor      int                         // The cursor last passed to the hint provider.
+	suggestedFor    core.Line                   // The line for which a line was last suggested.
+	suggestedCursor int                         // The cursor for which a line was last suggested.
+	suggested       core.Line                   // The line last suggested by the suggesters.
+	stateFile       string                      // Where to save the line and registers, if anywhere.
+	stateLoaded     bool                        // The registers have been restored from the state file.
+	cursorProbed    bool                        // The cursor position has been queried when starting.
+	searchMatcher   *completion.Matcher         // The last search having recalled the line, if any.
+	pendingRegister bool                        // A register is being read, for displaying it as pending.
+	lastCursor      int                         // The cursor position last notified to line change hooks.
+	standalone      bool                        // Lines are read by a standalone prompt (form, selection).
+	startRead       func()                      // Called once when the shell starts reading the next line.
+	yanked          []rune                      // The text last inserted by yank or yank-pop.
+	yankPos         int                         // Where the text last yanked was inserted.
+	change          []rune                      // Keys typed since the vi change being recorded started.
+	changeInsert    bool                        // The vi change being recorded has entered insert mode.
+	lastChange      []rune                      // Keys of the last vi change, repeated by vi-redo.
+	marks           map[rune]int                // Positions of the vi marks set in the line.
+	blockInsert     *viBlockInsert              // Text inserted on a vi visual block, replicated on its lines.
 }
 
 // NewShell returns a readline shell instance initialized with a default
@@ -68,28 +195,45 @@ type Shell struct {
 // The constructor accepts an optional list of inputrc configuration options,
 // which are used when parsing/loading and applying any inputrc configuration.
 func NewShell(opts ...inputrc.Option) *Shell {
+	return newShell(nil, term.NewProcessOutput(), opts...)
+}
+
+// newShell returns a shell reading its input from the reader (the process
+// stdin if nil), and displaying its interface on the given output.
+func newShell(in io.Reader, out *term.Output, opts ...inputrc.Option) *Shell {
 	shell := new(Shell)
+	shell.out = out
 
 	// Core editor
-	keys := new(core.Keys)
+	keys := core.NewKeys(in, out)
 	line := new(core.Line)
 	cursor := core.NewCursor(line)
 	selection := core.NewSelection(line, cursor)
 	iterations := new(core.Iterations)
+	catalog := new(locale.Catalog)
 
 	shell.Keys = keys
 	shell.line = line
 	shell.cursor = cursor
 	shell.selection = selection
-	shell.Buffers = editor.NewBuffers()
+	selection.Tokens = shell.tokenSpans
+	shell.Buffers = editor.NewBuffers(catalog)
+	shell.Buffers.SetClipboard(func(text string) {
+		fmt.Fprint(out, term.SetClipboard(text))
+	})
 	shell.Iterations = iterations
 
 	// Keymaps and commands
-	keymaps, config := keymap.NewEngine(keys, iterations, opts...)
+	keymaps, config := keymap.NewEngine(out, keys, iterations, opts...)
 	keymaps.Register(shell.standardCommands())
 	keymaps.Register(shell.viCommands())
 	keymaps.Register(shell.historyCommands())
 	keymaps.Register(shell.completionCommands())
+	keymaps.Register(shell.formCommands())
+	keymaps.Register(shell.filterCommands())
+	keymaps.Register(shell.exCommands())
+	keymaps.SetOverlay(shell.bindContextsOverlay)
+	keymaps.Register(shell.cheatSheetCommands())
 
 	shell.Keymap = keymaps
 	shell.Config = config
@@ -97,13 +241,13 @@ func NewShell(opts ...inputrc.Option) *Shell {
 
 	// User interface
 	hint := new(ui.Hint)
-	prompt := ui.NewPrompt(line, cursor, keymaps, config)
-	macros := macro.NewEngine(keys, hint)
-	history := history.NewSources(line, cursor, hint, config)
-	completer := completion.NewEngine(hint, keymaps, config)
+	prompt := ui.NewPrompt(out, line, cursor, keymaps, config)
+	macros := macro.NewEngine(out, keys, hint, catalog)
+	history := history.NewSources(line, cursor, hint, config, catalog)
+	completer := completion.NewEngine(out, hint, keymaps, config, catalog)
 	completion.Init(completer, keys, line, cursor, selection, shell.commandCompletion)
 
-	display := display.NewEngine(keys, selection, history, prompt, hint, completer, config)
+	display := display.NewEngine(out, keys, selection, history, prompt, hint, completer, config)
 
 	shell.Config = config
 	shell.Hint = hint
@@ -112,6 +256,32 @@ func NewShell(opts ...inputrc.Option) *Shell {
 	shell.Macros = macros
 	shell.History = history
 	shell.Display = display
+	shell.Hooks = new(Hooks)
+	shell.locale = catalog
+
+	completer.SetHooks(shell.Hooks.runCandidateSelect, shell.Hooks.runCandidateAccept)
+	completer.SetFooter(shell.completionFooter)
+	shell.panicOutput = os.Stderr
+
+	return shell
+}
+
+// TermState is used by the shell to control the terminal on which it runs:
+// it enters and exits raw mode, and queries the terminal size. Hosts running
+// the shell over an SSH channel or a PTY pair must provide an implementation.
+type TermState = term.Terminal
+
+// NewShellWith is like NewShell, but the shell reads its input from the
+// given reader, writes its output to the given writer, and controls the
+// terminal through the given state, instead of using the process stdin
+// and stdout. This allows running the shell over an SSH channel, or a
+// PTY pair owned by the host.
+//
+// Input, output and terminal are owned by the shell: several shells, each
+// with its own terminal, can thus read lines concurrently in the same process.
+func NewShellWith(in io.Reader, out io.Writer, state TermState, opts ...inputrc.Option) *Shell {
+	shell := newShell(in, term.NewOutput(out, state), opts...)
+	shell.hosted = true
 
 	return shell
 }
@@ -137,6 +307,107 @@ func (rl *Shell) Cursor() *core.Cursor { return rl.cursor }
 // selections used to change/select multiple parts of the line at once.
 func (rl *Shell) Selection() *core.Selection { return rl.selection }
 
+// Bell rings the bell as set by the bell-style option: the terminal bell if
+// "audible" (the default), a brief flash of the screen if "visible", or nothing
+// if "none". The shell rings it on errors, like failed character or incremental
+// searches, and hosts can ring it from their commands and hooks for their own.
+func (rl *Shell) Bell() {
+	ui.Bell(rl.out, rl.Config)
+}
+
+// SetLine replaces the current input line with the given one, places the cursor
+// at its end and redisplays the line if the shell is currently reading input.
+// This function is safe to call from other goroutines, but should not be called
+// from within callbacks run by the shell itself (completers, highlighters, etc.)
+// which can safely use the line and cursor returned by Line() and Cursor().
+func (rl *Shell) SetLine(line []rune) {
+	rl.editLine(func() {
+		rl.line.Set(append([]rune{}, line...)...)
+		rl.cursor.Set(rl.line.Len())
+	})
+}
+
+// InsertAt inserts some text at the given position in the current input line,
+// moving the cursor accordingly if it was placed after this position, and then
+// redisplays the line if the shell is currently reading input.
+// This function is safe to call from other goroutines: see SetLine.
+func (rl *Shell) InsertAt(pos int, text []rune) {
+	rl.ReplaceRange(pos, pos, text)
+}
+
+// ReplaceRange replaces the text between the start (included) and end (excluded)
+// positions of the current input line with the given text, and then redisplays
+// the line if the shell is currently reading input. The cursor stays on the same
+// character if it was placed after the range, or goes to the end of the new text
+// if it was placed within it. Invalid ranges are ignored.
+// This function is safe to call from other goroutines: see SetLine. Widgets
+// run by the shell should use Line().InsertBetween() and Cursor() instead.
+func (rl *Shell) ReplaceRange(start, end int, text []rune) {
+	rl.editLine(func() {
+		if start < 0 || start > end || end > rl.line.Len() {
+			return
+		}
+
+		rl.line.InsertBetween(start, end, append([]rune{}, text...)...)
+
+		switch cpos := rl.cursor.Pos(); {
+		case cpos >= end:
+			rl.cursor.Move(len(text) - (end - start))
+		case cpos > start:
+			rl.cursor.Set(start + len(text))
+		}
+	})
+}
+
+// MoveCursor moves the cursor by the given offset (negative to move
+// backward), and redisplays the line if the shell is reading input.
+// This function is safe to call from other goroutines: see SetLine.
+func (rl *Shell) MoveCursor(offset int) {
+	rl.editLine(func() {
+		rl.cursor.Move(offset)
+	})
+}
+
+// GetLineAndCursor returns a copy of the current input line and the
+// cursor position in it. This function is safe to call from other
+// goroutines, but not from within callbacks run by the shell itself.
+func (rl *Shell) GetLineAndCursor() (line []rune, cursor int) {
+	rl.lineMutex.Lock()
+	defer rl.lineMutex.Unlock()
+
+	return append([]rune{}, *rl.line...), rl.cursor.Pos()
+}
+
+// editLine runs an edit function on the line while holding the line
+// lock, saves the edit to the line history and redisplays the line.
+func (rl *Shell) editLine(edit func()) {
+	rl.lineMutex.Lock()
+	defer rl.lineMutex.Unlock()
+
+	rl.History.Save()
+	edit()
+	rl.History.Save()
+	rl.notifyLineChange()
+
+	if rl.isReading() {
+		rl.Display.Refresh()
+	}
+}
+
+// notifyLineChange runs the line change hooks if the input line
+// or the cursor position have changed since the last notification.
+func (rl *Shell) notifyLineChange() {
+	line, cursor := rl.completer.Line()
+	if cursor.Pos() == rl.lastCursor && slices.Equal(*line, rl.lastLine) {
+		return
+	}
+
+	rl.lastLine = append(rl.lastLine[:0], *line...)
+	rl.lastCursor = cursor.Pos()
+	rl.Hooks.runLineChange(rl.lastLine, rl.lastCursor)
+	rl.saveState(true)
+}
+
 // Printf prints a formatted string below the current line and redisplays the prompt
 // and input line (and possibly completions/hints if active) below the logged string.
 // A newline is added to the message so that the prompt is correctly refreshed below.
@@ -144,11 +415,11 @@ func (rl *Shell) Printf(msg string, args ...any) (n int, err error) {
 	// First go back to the last line of the input line,
 	// and clear everything below (hints and completions).
 	rl.Display.CursorBelowLine()
-	term.MoveCursorBackwards(term.GetWidth())
-	fmt.Print(term.ClearScreenBelow)
+	rl.out.MoveCursorBackwards(rl.out.Width())
+	fmt.Fprint(rl.out, term.ClearScreenBelow)
 
 	// Skip a line, and print the formatted message.
-	n, err = fmt.Printf(msg+"\n", args...)
+	n, err = fmt.Fprintf(rl.out, msg+"\n", args...)
 
 	// Redisplay the prompt, input line and active helpers.
 	rl.Prompt.PrimaryPrint()
@@ -163,12 +434,12 @@ func (rl *Shell) PrintTransientf(msg string, args ...any) (n int, err error) {
 	// First go back to the beginning of the line/prompt, and
 	// clear everything below (prompt/line/hints/completions).
 	rl.Display.CursorToLineStart()
-	term.MoveCursorBackwards(term.GetWidth())
-	term.MoveCursorUp(rl.Prompt.PrimaryUsed())
-	fmt.Print(term.ClearScreenBelow)
+	rl.out.MoveCursorBackwards(rl.out.Width())
+	rl.out.MoveCursorUp(rl.Prompt.PrimaryUsed())
+	fmt.Fprint(rl.out, term.ClearScreenBelow)
 
 	// Print the logged message.
-	n, err = fmt.Printf(msg+"\n", args...)
+	n, err = fmt.Fprintf(rl.out, msg+"\n", args...)
 
 	// Redisplay the prompt, input line and active helpers.
 	rl.Prompt.PrimaryPrint()
@@ -176,3 +447,64 @@ func (rl *Shell) PrintTransientf(msg string, args ...any) (n int, err error) {
 
 	return
 }
+
+// AcceptedText returns the prompt and the line last returned by Readline, as
+// plain text and as they were left on screen: with the transient prompt when
+// the transient-prompt option is on, the line as rendered by the syntax
+// highlighter, and the lines of multiline buffers indented like displayed.
+// This way, hosts can write commands to their own logs as users saw them.
+// The text is empty until the line editor has returned a line, and is not
+// updated when reading lines without a terminal.
+func (rl *Shell) AcceptedText() string {
+	return rl.Display.AcceptedText()
+}
+
+// RunInTerminal runs a function needing the terminal in its original state, such
+// as one spawning an editor or a pager: the helpers below the input line are cleared,
+// the terminal exits raw mode and the function is run below the current line. Once
+// done, the terminal is put back into raw mode and the prompt, input line and helpers
+// are redisplayed as they were before.
+// This function should be called from a command bound to some keys, or when the shell
+// is not reading input, since the terminal input would be otherwise read concurrently.
+func (rl *Shell) RunInTerminal(run func() error) error {
+	rl.mutex.Lock()
+	guard := rl.rawGuard
+	rl.mutex.Unlock()
+
+	if guard == nil {
+		return run()
+	}
+
+	rl.Display.CursorBelowLine()
+	fmt.Fprint(rl.out, term.ClearScreenBelow)
+
+	if err := guard.Pause(); err != nil {
+		return err
+	}
+
+	err := run()
+
+	if resumeErr := guard.Resume(); err == nil {
+		err = resumeErr
+	}
+
+	rl.Display.StartFreshLine()
+	rl.Display.PrintPrimaryPrompt()
+	rl.Display.Refresh()
+
+	return err
+}
+
+// SetIdleCallback registers a function to be called each time no key has been
+// received for the given duration while the shell is waiting for user input.
+// This can be used to refresh asynchronous prompt segments, to show inactivity
+// hints or to dismiss temporary messages. The prompt, input line and helpers are
+// refreshed after each call. A zero duration or a nil function disables it.
+func (rl *Shell) SetIdleCallback(timeout time.Duration, callback func()) {
+	if callback == nil {
+		timeout = 0
+	}
+
+	rl.idle = callback
+	rl.Keys.SetIdleTimeout(timeout)
+}
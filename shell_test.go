@@ -0,0 +1,361 @@
+package readline_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_ReplaceRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end int
+		text       string
+		cursor     int
+		wantLine   string
+		wantCursor int
+	}{
+		{name: "Cursor after range", start: 0, end: 5, text: "bye", cursor: 8, wantLine: "bye world", wantCursor: 6},
+		{name: "Cursor within range", start: 0, end: 5, text: "bye", cursor: 2, wantLine: "bye world", wantCursor: 3},
+		{name: "Cursor before range", start: 6, end: 11, text: "you", cursor: 2, wantLine: "hello you", wantCursor: 2},
+		{name: "Invalid range", start: 5, end: 2, text: "bye", cursor: 2, wantLine: "hello world", wantCursor: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rl := readlinetest.NewTerminal(80, 24).Shell()
+			rl.SetLine([]rune("hello world"))
+			rl.MoveCursor(test.cursor - len("hello world"))
+
+			rl.ReplaceRange(test.start, test.end, []rune(test.text))
+
+			line, cursor := rl.GetLineAndCursor()
+			if string(line) != test.wantLine || cursor != test.wantCursor {
+				t.Errorf("ReplaceRange() = %q (cursor %d), want %q (cursor %d)", string(line), cursor, test.wantLine, test.wantCursor)
+			}
+		})
+	}
+}
+
+func TestShell_SetIdleCallback(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	idle := make(chan struct{}, 10)
+
+	rl.SetIdleCallback(20*time.Millisecond, func() {
+		select {
+		case idle <- struct{}{}:
+		default:
+		}
+	})
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("ls")
+
+	select {
+	case <-idle:
+	case <-time.After(readlinetest.Timeout):
+		t.Fatal("idle callback not called")
+	}
+
+	term.Type(`\r`)
+
+	if line := <-done; line != "ls" {
+		t.Errorf("Readline() line = %q, want %q", line, "ls")
+	}
+}
+
+func TestShell_IdleCallbackPanic(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.SetPanicOutput(io.Discard)
+
+	rl.SetIdleCallback(10*time.Millisecond, func() {
+		panic("idle callback crashed")
+	})
+
+	_, err := term.Run(rl, "ls")
+	if !errors.Is(err, readline.ErrPanic) {
+		t.Fatalf("Run() error = %v, want %v", err, readline.ErrPanic)
+	}
+
+	if term.IsRaw() {
+		t.Error("terminal still in raw mode after panic")
+	}
+
+	// The shell is still usable after recovering.
+	rl.SetIdleCallback(0, nil)
+
+	line, err := term.Run(rl, "ls", `\r`)
+	if err != nil || line != "ls" {
+		t.Errorf("Run() = %q, %v, want %q, nil", line, err, "ls")
+	}
+}
+
+func TestShell_EndOfFile(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	_, err := term.Run(rl, `\C-d`)
+	if !errors.Is(err, io.EOF) {
+		t.Errorf("Run() error = %v, want %v", err, io.EOF)
+	}
+
+	errExit := errors.New("exit")
+	confirmed := false
+
+	rl.EndOfFile = func(_ []rune) error {
+		if !confirmed {
+			confirmed = true
+			return nil
+		}
+
+		return errExit
+	}
+
+	_, err = term.Run(rl, `\C-d`, `\C-d`)
+	if !errors.Is(err, errExit) {
+		t.Errorf("Run() error = %v, want %v", err, errExit)
+	}
+
+	rl.Config.Set("end-of-file-command", "backward-delete-char")
+
+	line, err := term.Run(rl, "abc", `\C-d`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "ab" {
+		t.Errorf("Run() line = %q, want %q", line, "ab")
+	}
+}
+
+func TestShell_HostInputClosed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "Empty input", input: ""},
+		{name: "Partial line", input: "abc"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term := readlinetest.NewTerminal(80, 24)
+			rl := readline.NewShellWith(strings.NewReader(test.input), term, term)
+
+			done := make(chan error, 1)
+
+			go func() {
+				_, err := rl.Readline()
+				done <- err
+			}()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, io.EOF) {
+					t.Errorf("Readline() error = %v, want %v", err, io.EOF)
+				}
+			case <-time.After(readlinetest.Timeout):
+				rl.Close()
+				t.Fatal("Readline() did not return when its input was closed")
+			}
+		})
+	}
+
+	// The keys typed before the host closes the input are still read.
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	term.Type("abc")
+	term.CloseInput()
+
+	if _, err := term.Run(rl); !errors.Is(err, io.EOF) {
+		t.Errorf("Run() error = %v, want %v", err, io.EOF)
+	}
+}
+
+func TestShell_RunInTerminal(t *testing.T) {
+	term := readlinetest.NewTerminal(40, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Prompt.Primary(func() string { return "> " })
+
+	var rawInside, rawAfter bool
+
+	rl.AddBindContext("pager", readline.BindContext{
+		Binds: map[string]string{`\C-xp`: "run-pager"},
+		Commands: map[string]func(){
+			"run-pager": func() {
+				err := rl.RunInTerminal(func() error {
+					rawInside = term.IsRaw()
+					_, err := term.Write([]byte("pager output\r\n"))
+
+					return err
+				})
+				if err != nil {
+					t.Errorf("RunInTerminal() error = %v", err)
+				}
+
+				rawAfter = term.IsRaw()
+			},
+		},
+	})
+
+	line, err := term.Run(rl, "ls", `\C-xp`, " -l", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "ls -l" {
+		t.Errorf("Run() line = %q, want %q", line, "ls -l")
+	}
+
+	if rawInside || !rawAfter {
+		t.Errorf("IsRaw() = %v in the function and %v after it, want false and true", rawInside, rawAfter)
+	}
+
+	// The prompt and line are redrawn below the function output.
+	readlinetest.AssertScreen(t, term, "> ls\npager output\n> ls -l")
+}
+
+// lispTokens splits a line into the tokens of a Lisp-like
+// grammar: parentheses, strings (with their spaces) and symbols.
+func lispTokens(line []rune) []readline.Token {
+	var tokens []readline.Token
+
+	for i := 0; i < len(line); i++ {
+		switch char := line[i]; {
+		case char == ' ':
+		case char == '(' || char == ')':
+			tokens = append(tokens, readline.Token{Start: i, End: i + 1, Type: "paren"})
+		case char == '"':
+			end := i + 1
+			for end < len(line) && line[end] != '"' {
+				end++
+			}
+
+			end = min(end+1, len(line))
+			tokens = append(tokens, readline.Token{Start: i, End: end, Type: "string"})
+			i = end - 1
+		default:
+			end := i
+			for end < len(line) && !strings.ContainsRune(` ()"`, line[end]) {
+				end++
+			}
+
+			tokens = append(tokens, readline.Token{Start: i, End: end, Type: "symbol"})
+			i = end - 1
+		}
+	}
+
+	return tokens
+}
+
+func TestShell_Tokenizer(t *testing.T) {
+	tests := []struct {
+		name string
+		keys []string
+		want string
+	}{
+		{name: "Word motions", keys: []string{`(concat "a b" foo)`, `\eb`, `\eb`, `\eb`, "X"}, want: `(concat X"a b" foo)`},
+		{name: "Word kill", keys: []string{`(concat "a b" foo)`, `\eb`, `\eb`, `\eb`, `\ed`}, want: `(concat  foo)`},
+		{name: "Shell words", keys: []string{`(list (foo`, `\C-xd`}, want: `(list (`},
+		{name: "Completion word", keys: []string{"(fo", `\t`}, want: "(foobar"},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+
+		rl.Config.Set("max-redisplay-rate", 0)
+		rl.Config.Bind("emacs", inputrc.Unescape(`\C-xd`), "shell-backward-kill-word", false)
+
+		rl.Tokenizer = lispTokens
+		rl.Completer = func(_ []rune, _ int) readline.Completions {
+			return readline.CompleteValues("foobar", "format")
+		}
+
+		line, err := term.Run(rl, append(test.keys, `\r`)...)
+		if err != nil {
+			t.Fatalf("%s: Run() error = %v", test.name, err)
+		}
+
+		if line != test.want {
+			t.Errorf("%s: Run() = %q, want %q", test.name, line, test.want)
+		}
+	}
+}
+
+func TestShell_TokenStyles(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	rl.Tokenizer = lispTokens
+	rl.TokenStyles = map[string]string{"string": "\x1b[32m"}
+	rl.SetLine([]rune(`(print "hi")`))
+
+	want := `(print ` + "\x1b[32m" + `"hi"`
+	if got := rl.Frame().Styled(); !strings.Contains(got, want) {
+		t.Errorf("Frame().Styled() = %q, want it to contain %q", got, want)
+	}
+}
+
+func TestShell_AcceptedText(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "\x1b[1;32mbox\x1b[0m\n$ " })
+
+	if _, err := term.Run(rl, "echo hi", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if text := rl.AcceptedText(); text != "box\n$ echo hi" {
+		t.Errorf("AcceptedText() = %q, want %q", text, "box\n$ echo hi")
+	}
+
+	// Multiline buffers and highlighted lines are rendered as displayed.
+	rl.SyntaxHighlighter = func(line []rune) string {
+		return "\x1b[31m" + strings.ToUpper(string(line)) + "\x1b[0m"
+	}
+
+	rl.History.Prefill([]rune("a\tb\nc"))
+
+	if _, err := term.Run(rl, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if text := rl.AcceptedText(); text != "box\n$ A     B\n  C" {
+		t.Errorf("AcceptedText() = %q, want %q", text, "box\n$ A     B\n  C")
+	}
+
+	// With a transient prompt, the accepted line is left displayed after it.
+	rl.SyntaxHighlighter = nil
+	rl.Config.Set("transient-prompt", true)
+	rl.Prompt.Transient(func() string { return "\x1b[2m>\x1b[0m " })
+
+	if _, err := term.Run(rl, "ls", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if text := rl.AcceptedText(); text != "> ls" {
+		t.Errorf("AcceptedText() = %q, want %q", text, "> ls")
+	}
+
+	if screen := term.Screen(); !strings.Contains(screen, "> ls") {
+		t.Errorf("Screen() = %q, want it to contain %q", screen, "> ls")
+	}
+}
@@ -0,0 +1,108 @@
+package readline
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/locale"
+)
+
+// lineState is the state of the shell saved to its state file.
+type lineState struct {
+	Line      string            `json:"line,omitempty"`
+	Cursor    int               `json:"cursor,omitempty"`
+	Registers map[string]string `json:"registers,omitempty"`
+}
+
+// SetStateFile makes the shell save its input line and registers (including
+// the kill ring) to a file each time the line changes, when a panic is
+// recovered, and once done reading, so that they survive a crash, or an
+// accidental close of the terminal.
+//
+// Registers are restored from this file when the shell next reads a line. If
+// the shell did not finish reading the line saved, it is restored along with
+// its cursor, and a hint notifies it: undoing the restoration discards it.
+// An empty path disables saving and restoring the state.
+func (rl *Shell) SetStateFile(path string) {
+	rl.stateFile = path
+	rl.stateLoaded = false
+}
+
+// loadState restores the registers and the unfinished line, if any, saved
+// in the state file. Registers are only restored once per state file.
+func (rl *Shell) loadState() {
+	if rl.stateFile == "" || rl.standalone {
+		return
+	}
+
+	data, err := os.ReadFile(rl.stateFile)
+	if err != nil {
+		return
+	}
+
+	var state lineState
+	if err := json.Unmarshal(data, &state); err != nil {
+		rl.trace("state", "file", rl.stateFile, "error", err)
+		return
+	}
+
+	if !rl.stateLoaded {
+		rl.Buffers.Import(state.Registers)
+		rl.stateLoaded = true
+	}
+
+	if state.Line == "" || rl.line.Len() > 0 {
+		return
+	}
+
+	rl.line.Set([]rune(state.Line)...)
+	rl.cursor.Set(state.Cursor)
+	rl.cursor.CheckAppend()
+	rl.History.Save()
+
+	rl.Hint.SetTemporary(color.Dim + rl.locale.Get(locale.LineRestored) + color.Reset)
+}
+
+// saveState writes the input line (if unfinished is true, or an empty one
+// otherwise) and the registers to the state file, if any. The file is
+// replaced at once, so that it is never left half-written. Nothing is
+// saved while reading standalone prompts, like form passwords.
+func (rl *Shell) saveState(unfinished bool) {
+	if rl.stateFile == "" || rl.standalone {
+		return
+	}
+
+	state := lineState{Registers: rl.Buffers.Export()}
+
+	if unfinished {
+		state.Line = string(*rl.line)
+		state.Cursor = rl.cursor.Pos()
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(rl.stateFile), filepath.Base(rl.stateFile)+".*")
+	if err != nil {
+		rl.trace("state", "file", rl.stateFile, "error", err)
+		return
+	}
+
+	_, err = temp.Write(data)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(temp.Name(), rl.stateFile)
+	}
+
+	if err != nil {
+		os.Remove(temp.Name())
+		rl.trace("state", "file", rl.stateFile, "error", err)
+	}
+}
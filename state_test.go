@@ -0,0 +1,57 @@
+package readline_test
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_SetStateFile(t *testing.T) {
+	state := filepath.Join(t.TempDir(), "state.json")
+
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.SetStateFile(state)
+	rl.SetPanicOutput(io.Discard)
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		panic("completer crashed")
+	}
+
+	_, err := term.Run(rl, "echo unfinished", `\t`)
+	if !errors.Is(err, readline.ErrPanic) {
+		t.Fatalf("Run() error = %v, want %v", err, readline.ErrPanic)
+	}
+
+	// A new shell restores the unfinished line,
+	// and the registers saved once done reading.
+	term = readlinetest.NewTerminal(80, 24)
+	rl = term.Shell()
+	rl.SetStateFile(state)
+
+	line, err := term.Run(rl, `\C-w`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "echo " {
+		t.Errorf("Run() line = %q, want %q", line, "echo ")
+	}
+
+	term = readlinetest.NewTerminal(80, 24)
+	rl = term.Shell()
+	rl.SetStateFile(state)
+
+	line, err = term.Run(rl, `\C-y`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "unfinished" {
+		t.Errorf("Run() line = %q, want %q", line, "unfinished")
+	}
+}
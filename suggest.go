@@ -0,0 +1,149 @@
+package readline
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/reeflective/readline/internal/core"
+)
+
+// Suggestion is a line suggested as the continuation of the input line.
+// When the history-autosuggest option is enabled, the first suggestion
+// starting with the input line is displayed after it, as ghost text.
+type Suggestion struct {
+	Line string // The whole suggested line, including the input line.
+}
+
+// Suggester suggests lines for the current input line and cursor position,
+// from the most to the least relevant one. Suggesters are called when the
+// line is redisplayed after having changed, and should therefore be fast,
+// or return when the context is done.
+type Suggester interface {
+	Suggest(ctx context.Context, line []rune, cursor int) []Suggestion
+}
+
+// SuggesterFunc is a function implementing the Suggester interface.
+type SuggesterFunc func(ctx context.Context, line []rune, cursor int) []Suggestion
+
+// Suggest implements the Suggester interface.
+func (f SuggesterFunc) Suggest(ctx context.Context, line []rune, cursor int) []Suggestion {
+	return f(ctx, line, cursor)
+}
+
+// SuggestTimeout returns a suggester giving up on another one if it has not
+// returned its suggestions after the timeout, for instance when querying a
+// remote service: its context is then cancelled and no line is suggested.
+func SuggestTimeout(suggester Suggester, timeout time.Duration) Suggester {
+	return SuggesterFunc(func(ctx context.Context, line []rune, cursor int) []Suggestion {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		done := make(chan []Suggestion, 1)
+
+		go func() {
+			done <- suggester.Suggest(ctx, line, cursor)
+		}()
+
+		select {
+		case suggestions := <-done:
+			return suggestions
+		case <-ctx.Done():
+			return nil
+		}
+	})
+}
+
+// HistorySuggester returns a suggester of the most recent line of the
+// current shell history source starting with the input line. This is
+// the default suggester used when the shell has none.
+func HistorySuggester(rl *Shell) Suggester {
+	return SuggesterFunc(func(_ context.Context, line []rune, _ int) []Suggestion {
+		input := core.Line(line)
+
+		suggested := rl.History.Suggest(&input)
+		if suggested.Len() <= input.Len() {
+			return nil
+		}
+
+		return []Suggestion{{Line: string(suggested)}}
+	})
+}
+
+// CompletionSuggester returns a suggester of the input line with its last
+// word completed with the candidates of the shell completer, in the order
+// they are given. Nothing is suggested when the cursor is not at the end
+// of the line.
+func CompletionSuggester(rl *Shell) Suggester {
+	return SuggesterFunc(func(ctx context.Context, line []rune, cursor int) []Suggestion {
+		if (rl.Completer == nil && rl.CompleterContext == nil) || cursor != len(line) {
+			return nil
+		}
+
+		var comps Completions
+
+		if rl.CompleterContext != nil {
+			comps = rl.CompleterContext(ctx, line, cursor)
+		} else {
+			comps = rl.Completer(line, cursor)
+		}
+
+		text := string(line)
+
+		prefix := comps.PREFIX
+		if prefix == "" {
+			prefix = text[strings.LastIndexAny(text, " \t\n")+1:]
+		}
+
+		if !strings.HasSuffix(text, prefix) {
+			return nil
+		}
+
+		start := strings.TrimSuffix(text, prefix)
+
+		var suggestions []Suggestion
+
+		for _, candidate := range comps.values {
+			if len(candidate.Value) > len(prefix) && strings.HasPrefix(candidate.Value, prefix) {
+				suggestions = append(suggestions, Suggestion{Line: start + candidate.Value})
+			}
+		}
+
+		return suggestions
+	})
+}
+
+// suggestLine returns the line suggested by the first of the shell suggesters
+// (or by the history if there are none) starting with the input line and longer
+// than it, or the input line itself. Suggestions are computed once per line.
+func (rl *Shell) suggestLine(line *core.Line, cursor int) core.Line {
+	if len(rl.Suggesters) == 0 {
+		return rl.History.Suggest(line)
+	}
+
+	if line.Len() == 0 {
+		return *line
+	}
+
+	if rl.suggestedFor != nil && cursor == rl.suggestedCursor && slices.Equal(*line, rl.suggestedFor) {
+		return rl.suggested
+	}
+
+	input := append(core.Line{}, *line...)
+	rl.suggestedFor, rl.suggestedCursor = input, cursor
+	rl.suggested = input
+
+	ctx := rl.readContext()
+
+	for _, suggester := range rl.Suggesters {
+		for _, suggestion := range suggester.Suggest(ctx, slices.Clone(input), cursor) {
+			if len([]rune(suggestion.Line)) > input.Len() && strings.HasPrefix(suggestion.Line, string(input)) {
+				rl.suggested = core.Line([]rune(suggestion.Line))
+				return rl.suggested
+			}
+		}
+	}
+
+	return rl.suggested
+}
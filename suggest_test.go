@@ -0,0 +1,52 @@
+package readline_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_Suggesters(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("history-autosuggest", true)
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xa`), "autosuggest-accept", false)
+
+	slow := readline.SuggesterFunc(func(ctx context.Context, line []rune, _ int) []readline.Suggestion {
+		<-ctx.Done()
+		return []readline.Suggestion{{Line: string(line) + " --slow"}}
+	})
+
+	rl.Completer = func(_ []rune, _ int) readline.Completions {
+		return readline.CompleteValues("status", "stash")
+	}
+
+	rl.Suggesters = []readline.Suggester{
+		readline.SuggestTimeout(slow, 10*time.Millisecond),
+		readline.CompletionSuggester(rl),
+	}
+
+	line, err := term.Run(rl, "git st", `\C-xa`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "git status" {
+		t.Errorf("Run() line = %q, want %q", line, "git status")
+	}
+
+	rl.Suggesters = []readline.Suggester{readline.HistorySuggester(rl)}
+
+	line, err = term.Run(rl, "git", `\C-xa`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "git status" {
+		t.Errorf("Run() line = %q, want %q", line, "git status")
+	}
+}
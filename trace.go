@@ -0,0 +1,52 @@
+package readline
+
+import (
+	"io"
+	"log/slog"
+	"time"
+)
+
+// SetTraceLogger enables the logging of the shell internals to the given
+// logger: keys read and decoded, commands dispatched, redisplay timings and
+// completers durations. All records are logged at the debug level. Passing a
+// nil logger disables tracing. This function can be called at any time, from
+// any goroutine.
+func (rl *Shell) SetTraceLogger(logger *slog.Logger) {
+	rl.tracer.Store(logger)
+}
+
+// SetTraceOutput is like SetTraceLogger, but logs records as text to the
+// given writer, such as a file. Passing a nil writer disables tracing.
+func (rl *Shell) SetTraceOutput(w io.Writer) {
+	if w == nil {
+		rl.SetTraceLogger(nil)
+		return
+	}
+
+	handler := slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug})
+	rl.SetTraceLogger(slog.New(handler))
+}
+
+// trace logs a record if tracing is enabled.
+func (rl *Shell) trace(msg string, args ...any) {
+	logger := rl.tracer.Load()
+	if logger == nil {
+		return
+	}
+
+	logger.Debug(msg, args...)
+}
+
+// traceDuration returns a function logging the time elapsed
+// since the call to traceDuration, if tracing is enabled.
+func (rl *Shell) traceDuration(msg string, args ...any) func() {
+	if rl.tracer.Load() == nil {
+		return func() {}
+	}
+
+	start := time.Now()
+
+	return func() {
+		rl.trace(msg, append(args, "duration", time.Since(start))...)
+	}
+}
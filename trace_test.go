@@ -0,0 +1,116 @@
+package readline_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_SetTraceOutput(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	var trace bytes.Buffer
+
+	rl.SetTraceOutput(&trace)
+
+	if _, err := term.Run(rl, "l", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Dispatch records hold the keys, the keymap and the command run.
+	for _, want := range []string{
+		"level=DEBUG msg=dispatch keys=l keymap=emacs command=self-insert macro=false",
+		`level=DEBUG msg=dispatch keys="\r" keymap=emacs command=accept-line macro=false`,
+		"msg=refresh duration=",
+	} {
+		if !strings.Contains(trace.String(), want) {
+			t.Errorf("trace = %q, want it to contain %q", trace.String(), want)
+		}
+	}
+
+	// Keys typed in masked form fields are not.
+	trace.Reset()
+
+	form := readline.Form{Fields: []readline.Field{{Name: "password", Kind: readline.PasswordField}}}
+	term.Type("secret", `\r`)
+
+	if _, err := rl.RunForm(form); err != nil {
+		t.Fatalf("RunForm() error = %v", err)
+	}
+
+	if want := "msg=dispatch keymap=emacs command=self-insert"; !strings.Contains(trace.String(), want) {
+		t.Errorf("trace = %q, want it to contain %q", trace.String(), want)
+	}
+
+	if strings.Contains(trace.String(), "keys=") {
+		t.Errorf("trace = %q, want no keys", trace.String())
+	}
+
+	// Once disabled, nothing is traced.
+	rl.SetTraceOutput(nil)
+	trace.Reset()
+
+	if _, err := term.Run(rl, "l", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if trace.Len() > 0 {
+		t.Errorf("trace = %q, want nothing once disabled", trace.String())
+	}
+}
+
+func TestShell_SetTraceLogger(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+
+	var records []slog.Record
+
+	rl.SetTraceLogger(slog.New(recordHandler{records: &records}))
+
+	if _, err := term.Run(rl, "l", `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	attrs := make(map[string]string)
+
+	for _, record := range records {
+		if record.Message != "dispatch" {
+			continue
+		}
+
+		record.Attrs(func(attr slog.Attr) bool {
+			attrs[attr.Key] = attr.Value.String()
+			return true
+		})
+
+		break
+	}
+
+	want := map[string]string{"keys": "l", "keymap": "emacs", "command": "self-insert", "macro": "false"}
+	for key, value := range want {
+		if attrs[key] != value {
+			t.Errorf("dispatch record %s = %q, want %q", key, attrs[key], value)
+		}
+	}
+}
+
+// recordHandler is a slog handler recording all records.
+type recordHandler struct {
+	records *[]slog.Record
+}
+
+func (h recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h recordHandler) Handle(_ context.Context, record slog.Record) error {
+	*h.records = append(*h.records, record)
+	return nil
+}
+
+func (h recordHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h recordHandler) WithGroup(string) slog.Handler      { return h }
@@ -0,0 +1,220 @@
+package readline
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrTranscript is returned by Replay when the transcript is not a valid one.
+var ErrTranscript = errors.New("invalid transcript")
+
+// transcriptHeader is the first line of an asciinema v2 transcript.
+type transcriptHeader struct {
+	Version   int   `json:"version"`
+	Width     int   `json:"width"`
+	Height    int   `json:"height"`
+	Timestamp int64 `json:"timestamp"`
+}
+
+// transcriptEvent is an input or output event of a transcript.
+type transcriptEvent struct {
+	time time.Duration
+	kind string
+	data string
+}
+
+// recorder writes the events of a transcript, from any goroutine.
+type recorder struct {
+	out   io.Writer
+	start time.Time
+	err   error
+	mutex sync.Mutex
+}
+
+// Record starts writing a transcript of the shell session to w, in the
+// asciinema v2 format: a header line with the terminal dimensions, followed
+// by one line per chunk of keys read as input ("i" events) and per chunk of
+// interface written as output ("o" events), each timestamped in seconds since
+// the start of the recording. Transcripts can thus be played back with the
+// asciinema player, or replayed in a shell with Replay to reproduce bugs.
+//
+// The returned function stops recording, and returns the first error met
+// while writing the transcript, if any. Both functions must be called either
+// when the shell is not reading input, or from within commands and hooks.
+func (rl *Shell) Record(w io.Writer) (stop func() error) {
+	rec := &recorder{out: w, start: time.Now()}
+
+	header, _ := json.Marshal(transcriptHeader{
+		Version:   2,
+		Width:     rl.out.Width(),
+		Height:    rl.out.Height(),
+		Timestamp: rec.start.Unix(),
+	})
+	rec.write(string(header) + "\n")
+
+	output := rl.out.Writer
+	rl.out.Writer = io.MultiWriter(output, writerFunc(func(buf []byte) {
+		rec.event("o", buf)
+	}))
+
+	rl.Keys.Record(func(keys []byte) {
+		rec.event("i", keys)
+	})
+
+	return func() error {
+		rl.Keys.Record(nil)
+		rl.out.Writer = output
+
+		rec.mutex.Lock()
+		defer rec.mutex.Unlock()
+
+		return rec.err
+	}
+}
+
+// Replay types the input keys of a transcript recorded with Record in the
+// shell, as if they were typed by the user, while the keys actually typed
+// are still read. Keys are typed with their recorded delays divided by speed,
+// or without any delay if speed is zero or negative, and are read by the next
+// Readline calls: the host thus runs this function in its own goroutine, and
+// reads lines as usual. Output events of the transcript are ignored, since
+// the shell displays its interface again as it reads the keys.
+//
+// The function returns once all keys have been typed, or with the context
+// error if it is done before. ErrTranscript is returned, without typing any
+// key, if the transcript is not a valid asciinema v2 one.
+func (rl *Shell) Replay(ctx context.Context, transcript io.Reader, speed float64) error {
+	events, err := readTranscript(transcript)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	for _, event := range events {
+		if event.kind != "i" {
+			continue
+		}
+
+		if speed > 0 {
+			delay := time.Duration(float64(event.time)/speed) - time.Since(start)
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		if err := rl.Keys.Inject(ctx, []byte(event.data)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write writes a line of the transcript, unless a previous write failed.
+func (r *recorder) write(line string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.err != nil {
+		return
+	}
+
+	_, r.err = io.WriteString(r.out, line)
+}
+
+// event writes an input or output event of the transcript.
+func (r *recorder) event(kind string, data []byte) {
+	elapsed := time.Since(r.start).Seconds()
+
+	var event strings.Builder
+
+	encoder := json.NewEncoder(&event)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode([]any{elapsed, kind, string(data)})
+
+	r.write(event.String())
+}
+
+// readTranscript reads all events of an asciinema v2 transcript.
+func readTranscript(transcript io.Reader) ([]transcriptEvent, error) {
+	scanner := bufio.NewScanner(transcript)
+	scanner.Buffer(nil, 1024*1024)
+
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("%w: missing header", ErrTranscript)
+	}
+
+	var header transcriptHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil || header.Version != 2 {
+		return nil, fmt.Errorf("%w: not an asciinema v2 header", ErrTranscript)
+	}
+
+	var events []transcriptEvent
+
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		event, err := readEvent(scanner.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: malformed event", ErrTranscript, line)
+		}
+
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTranscript, err)
+	}
+
+	return events, nil
+}
+
+// readEvent reads an event line of a transcript: [time, kind, data].
+func readEvent(line []byte) (event transcriptEvent, err error) {
+	var (
+		fields  []json.RawMessage
+		seconds float64
+	)
+
+	if err = json.Unmarshal(line, &fields); err != nil {
+		return event, err
+	}
+
+	if len(fields) != 3 {
+		return event, ErrTranscript
+	}
+
+	if err = json.Unmarshal(fields[0], &seconds); err != nil {
+		return event, err
+	}
+
+	if err = json.Unmarshal(fields[1], &event.kind); err != nil {
+		return event, err
+	}
+
+	event.time = time.Duration(seconds * float64(time.Second))
+
+	return event, json.Unmarshal(fields[2], &event.data)
+}
+
+// writerFunc is an io.Writer passing all written bytes to a function.
+type writerFunc func(buf []byte)
+
+// Write implements io.Writer.
+func (w writerFunc) Write(buf []byte) (int, error) {
+	w(buf)
+	return len(buf), nil
+}
@@ -0,0 +1,65 @@
+package readline_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_RecordReplay(t *testing.T) {
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Prompt.Primary(func() string { return "> " })
+
+	var transcript strings.Builder
+
+	stop := rl.Record(&transcript)
+
+	line, err := term.Run(rl, "hello", `\C-a`, "x", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if err := stop(); err != nil {
+		t.Fatalf("stop() error = %v", err)
+	}
+
+	lines := strings.Split(transcript.String(), "\n")
+	if !strings.HasPrefix(lines[0], `{"version":2,"width":80,"height":24,`) {
+		t.Errorf("transcript header = %q, want an asciinema v2 header", lines[0])
+	}
+
+	if !strings.Contains(transcript.String(), `"i","\u0001"]`) {
+		t.Errorf("transcript = %q, want a Ctrl-A input event", transcript.String())
+	}
+
+	if !strings.Contains(transcript.String(), `"o","> `) {
+		t.Errorf("transcript = %q, want the prompt in an output event", transcript.String())
+	}
+
+	replayTerm := readlinetest.NewTerminal(80, 24)
+	replayed := replayTerm.Shell()
+	replayed.Prompt.Primary(func() string { return "> " })
+
+	go replayed.Replay(context.Background(), strings.NewReader(transcript.String()), 0)
+
+	got, err := replayTerm.Run(replayed)
+	if err != nil {
+		t.Fatalf("replayed Run() error = %v", err)
+	}
+
+	if got != line {
+		t.Errorf("replayed Run() line = %q, want %q", got, line)
+	}
+
+	readlinetest.AssertScreen(t, replayTerm, term.Screen())
+
+	err = replayed.Replay(context.Background(), strings.NewReader("not a transcript"), 0)
+	if !errors.Is(err, readline.ErrTranscript) {
+		t.Errorf("Replay() error = %v, want ErrTranscript", err)
+	}
+}
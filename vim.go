@@ -1,9 +1,14 @@
 package readline
 
 import (
+	"slices"
+	"strings"
 	"unicode"
 
 	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/completion"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/history"
 	"github.com/reeflective/readline/internal/keymap"
 	"github.com/reeflective/readline/internal/strutil"
 )
@@ -33,7 +38,8 @@ func (rl *Shell) viCommands() commands {
 		"vi-visual-mode":    rl.viVisualMode,
 		"vi-editing-mode":   rl.viInsertMode,
 
-		"vi-visual-line-mode": rl.viVisualLineMode,
+		"vi-visual-line-mode":  rl.viVisualLineMode,
+		"vi-visual-block-mode": rl.viVisualBlockMode,
 
 		// Movement
 		"vi-backward-char":    rl.viBackwardChar,
@@ -48,13 +54,24 @@ func (rl *Shell) viCommands() commands {
 		"vi-end-bigword":      rl.viForwardBlankWordEnd,
 		"vi-match":            rl.viMatchBracket,
 		"vi-column":           rl.viGotoColumn,
+		"vi-goto-line":        rl.viGotoLine,
+		"vi-screen-top":       rl.viScreenTop,
+		"vi-screen-middle":    rl.viScreenMiddle,
+		"vi-screen-bottom":    rl.viScreenBottom,
 		"vi-end-of-line":      rl.viEndOfLine,
 		"vi-back-to-indent":   rl.viBackToIndent,
 		"vi-first-print":      rl.viFirstPrint,
 		"vi-goto-mark":        rl.viGotoMark,
+		"vi-goto-mark-line":   rl.viGotoMarkLine,
 
-		"vi-backward-end-word":    rl.viBackwardWordEnd,
-		"vi-backward-end-bigword": rl.viBackwardBlankWordEnd,
+		"vi-backward-word-end":       rl.viBackwardWordEnd,
+		"vi-backward-blank-word-end": rl.viBackwardBlankWordEnd,
+		"vi-backward-end-word":       rl.viBackwardWordEnd,
+		"vi-backward-end-bigword":    rl.viBackwardBlankWordEnd,
+
+		"vi-scroll-cursor-top":    rl.viScrollCursorTop,
+		"vi-scroll-cursor-middle": rl.viScrollCursorMiddle,
+		"vi-scroll-cursor-bottom": rl.viScrollCursorBottom,
 
 		// Changing text
 		"vi-change-to":            rl.viChangeTo,
@@ -73,6 +90,11 @@ func (rl *Shell) viCommands() commands {
 		"vi-open-line-below": rl.viOpenLineBelow,
 		"vi-down-case":       rl.viDownCase,
 		"vi-up-case":         rl.viUpCase,
+		"vi-oper-swap-case":  rl.viOperSwapCase,
+		"vi-indent":          rl.viIndent,
+		"vi-unindent":        rl.viUnindent,
+		"vi-increment":       rl.viIncrement,
+		"vi-decrement":       rl.viDecrement,
 
 		// Kill and Yanking
 		"vi-kill-eol":         rl.viKillEol,
@@ -87,6 +109,7 @@ func (rl *Shell) viCommands() commands {
 		"vi-put-after":       rl.viPutAfter,
 		"vi-put-before":      rl.viPutBefore,
 		"vi-set-buffer":      rl.viSetBuffer,
+		"vi-show-registers":  rl.viShowRegisters,
 		"vi-yank-whole-line": rl.viYankWholeLine,
 
 		// Selecting text
@@ -109,6 +132,7 @@ func (rl *Shell) viCommands() commands {
 		"vi-edit-and-execute-command": rl.viEditAndExecuteCommand,
 		"vi-undo":                     rl.undoLast,
 		"vi-redo":                     rl.viRedo,
+		"vi-undo-tree":                rl.viUndoTree,
 
 		"vi-edit-command-line":     rl.viEditCommandLine,
 		"vi-find-next-char":        rl.viFindNextChar,
@@ -119,6 +143,7 @@ func (rl *Shell) viCommands() commands {
 		"vi-search-backward":       rl.viSearchBackward,
 		"vi-search-again-forward":  rl.viSearchAgainForward,
 		"vi-search-again-backward": rl.viSearchAgainBackward,
+		"vi-pound-insert":          rl.viPoundInsert,
 	}
 }
 
@@ -143,6 +168,11 @@ func (rl *Shell) viInsertMode() {
 
 // Enter Vim command mode.
 func (rl *Shell) viCommandMode() {
+	// Replicate any text inserted before/after a visual block.
+	if rl.Keymap.Main() == keymap.ViInsert {
+		rl.viBlockReplicate()
+	}
+
 	// Reset any visual selection and iterations.
 	rl.selection.Reset()
 	rl.Iterations.Reset()
@@ -201,8 +231,30 @@ func (rl *Shell) viVisualLineMode() {
 	rl.Keymap.PrintCursor(keymap.Visual)
 }
 
+// Enter Vim visual mode, selecting a block of text spanning
+// the same columns on all lines between the cursor and its
+// current position (blockwise visual mode).
+func (rl *Shell) viVisualBlockMode() {
+	rl.History.SkipSave()
+	rl.Iterations.Reset()
+	rl.Buffers.Reset()
+
+	rl.Hint.Reset()
+	rl.completer.Reset()
+
+	rl.selection.Mark(rl.cursor.Pos())
+	rl.selection.VisualBlock()
+	rl.Keymap.SetLocal(keymap.Visual)
+}
+
 // Go to the beginning of the current line, and enter Vim insert mode.
+// In visual block mode, insert text before the block on all its lines.
 func (rl *Shell) viInsertBol() {
+	if rl.selection.IsVisualBlock() {
+		rl.viBlockInsert(false)
+		return
+	}
+
 	rl.Iterations.Reset()
 	rl.beginningOfLine()
 	rl.viInsertMode()
@@ -218,7 +270,13 @@ func (rl *Shell) viAddNext() {
 }
 
 // Go to the end of the current line, and enter insert mode.
+// In visual block mode, append text after the block on all its lines.
 func (rl *Shell) viAddEol() {
+	if rl.selection.IsVisualBlock() {
+		rl.viBlockInsert(true)
+		return
+	}
+
 	rl.Iterations.Reset()
 
 	if rl.Keymap.Local() == keymap.Visual {
@@ -285,7 +343,7 @@ func (rl *Shell) viBackwardWord() {
 
 	vii := rl.Iterations.Get()
 	for i := 1; i <= vii; i++ {
-		backward := rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos())
+		backward := rl.line.Backward(rl.wordTokenizer(), rl.cursor.Pos())
 		rl.cursor.Move(backward)
 	}
 }
@@ -300,7 +358,7 @@ func (rl *Shell) viForwardWord() {
 		// of the line, insert the next word from this suggested line.
 		rl.insertAutosuggestPartial(false)
 
-		forward := rl.line.Forward(rl.line.Tokenize, rl.cursor.Pos())
+		forward := rl.line.Forward(rl.wordTokenizer(), rl.cursor.Pos())
 		rl.cursor.Move(forward)
 	}
 }
@@ -327,28 +385,16 @@ func (rl *Shell) viForwardBlankWord() {
 	}
 }
 
-// Move to the end of the previous word, vi-style.
+// Move to the end of the previous word, vi-style: empty lines are words
+// of their own, and the end of the current word is skipped if the cursor
+// is on it. Used after an operator, the cursor character is included.
 func (rl *Shell) viBackwardWordEnd() {
 	rl.History.SkipSave()
 
 	vii := rl.Iterations.Get()
 
-	if rl.line.Len() == 0 {
-		return
-	}
-
 	for i := 1; i <= vii; i++ {
-		rl.cursor.Inc()
-
-		rl.cursor.Move(rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos()))
-		rl.cursor.Move(rl.line.Backward(rl.line.Tokenize, rl.cursor.Pos()))
-
-		// Then move forward, adjusting if we are on a punctuation.
-		if unicode.IsPunct(rl.cursor.Char()) {
-			rl.cursor.Dec()
-		}
-
-		rl.cursor.Move(rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos()))
+		rl.cursor.Move(rl.line.BackwardEnd(rl.wordTokenizer(), rl.cursor.Pos()))
 	}
 }
 
@@ -357,7 +403,7 @@ func (rl *Shell) viForwardWordEnd() {
 	vii := rl.Iterations.Get()
 
 	for i := 1; i <= vii; i++ {
-		forward := rl.line.ForwardEnd(rl.line.Tokenize, rl.cursor.Pos())
+		forward := rl.line.ForwardEnd(rl.wordTokenizer(), rl.cursor.Pos())
 		rl.cursor.Move(forward)
 	}
 }
@@ -369,12 +415,7 @@ func (rl *Shell) viBackwardBlankWordEnd() {
 	vii := rl.Iterations.Get()
 
 	for i := 1; i <= vii; i++ {
-		rl.cursor.Inc()
-
-		rl.cursor.Move(rl.line.Backward(rl.line.TokenizeSpace, rl.cursor.Pos()))
-		rl.cursor.Move(rl.line.Backward(rl.line.TokenizeSpace, rl.cursor.Pos()))
-
-		rl.cursor.Move(rl.line.ForwardEnd(rl.line.TokenizeSpace, rl.cursor.Pos()))
+		rl.cursor.Move(rl.line.BackwardEnd(rl.line.TokenizeSpace, rl.cursor.Pos()))
 	}
 }
 
@@ -412,6 +453,7 @@ func (rl *Shell) viMatchBracket() {
 		}
 
 		if !found {
+			rl.Bell()
 			return
 		}
 
@@ -475,14 +517,150 @@ func (rl *Shell) viFirstPrint() {
 	rl.cursor.ToFirstNonSpace(true)
 }
 
+// Move to the first non-blank character of the buffer line specified by the
+// numeric argument or, without one, of the last line if called with G, and of
+// the first line otherwise (like gg). In operator pending mode, the operator
+// acts on all lines between the cursor one and the target one (eg. dG, ygg).
+// When gg is used on a single-line buffer whose beginning the cursor is at,
+// move to the beginning of the history, like beginning-of-buffer-or-history.
+func (rl *Shell) viGotoLine() {
+	rl.History.SkipSave()
+
+	keys := rl.Keys.Caller()
+	last := len(keys) > 0 && keys[len(keys)-1] == 'G'
+	pending := rl.Keymap.Local() == keymap.ViOpp
+
+	lines := rl.line.Lines() + 1
+	target := 1
+
+	switch {
+	case rl.Iterations.IsSet():
+		target = min(max(rl.Iterations.Get(), 1), lines)
+	case last:
+		target = lines
+	case lines == 1 && !pending && rl.cursor.Pos() == 0:
+		rl.beginningOfBufferOrHistory()
+		return
+	}
+
+	rl.viGotoBufferLine(target - 1)
+}
+
+// Move to the first non-blank character of the line displayed at the top of
+// the buffer viewport, or of the line specified by the numeric argument from it.
+func (rl *Shell) viScreenTop() {
+	rl.History.SkipSave()
+
+	top, _ := rl.Display.Viewport()
+	offset := 0
+
+	if rl.Iterations.IsSet() {
+		offset = max(rl.Iterations.Get()-1, 0)
+	}
+
+	rl.viGotoBufferLine(top + offset)
+}
+
+// Move to the first non-blank character of the line
+// displayed in the middle of the buffer viewport.
+func (rl *Shell) viScreenMiddle() {
+	rl.History.SkipSave()
+
+	top, lines := rl.Display.Viewport()
+	lines = min(lines, rl.line.Lines()+1-top)
+
+	rl.viGotoBufferLine(top + (lines-1)/2)
+}
+
+// Move to the first non-blank character of the line displayed at the bottom of
+// the buffer viewport, or of the line specified by the numeric argument from it.
+func (rl *Shell) viScreenBottom() {
+	rl.History.SkipSave()
+
+	top, lines := rl.Display.Viewport()
+	bottom := min(top+lines, rl.line.Lines()+1) - 1
+
+	if rl.Iterations.IsSet() {
+		bottom -= max(rl.Iterations.Get()-1, 0)
+	}
+
+	rl.viGotoBufferLine(max(bottom, top))
+}
+
+// Scroll the buffer viewport so that the cursor line is displayed at its top,
+// when the buffer has more lines than the max-display-lines option.
+func (rl *Shell) viScrollCursorTop() {
+	rl.History.SkipSave()
+	rl.Display.Scroll(rl.cursor.LinePos())
+}
+
+// Scroll the buffer viewport so that the cursor line is displayed in its
+// middle, when the buffer has more lines than the max-display-lines option.
+func (rl *Shell) viScrollCursorMiddle() {
+	rl.History.SkipSave()
+
+	_, lines := rl.Display.Viewport()
+	rl.Display.Scroll(rl.cursor.LinePos() - lines/2)
+}
+
+// Scroll the buffer viewport so that the cursor line is displayed at its
+// bottom, when the buffer has more lines than the max-display-lines option.
+func (rl *Shell) viScrollCursorBottom() {
+	rl.History.SkipSave()
+
+	_, lines := rl.Display.Viewport()
+	rl.Display.Scroll(rl.cursor.LinePos() - lines + 1)
+}
+
+// viGotoBufferLine moves the cursor to the first non-blank
+// character of a line of the buffer, starting from zero.
+func (rl *Shell) viGotoBufferLine(target int) {
+	pos := 0
+
+	for line := 0; line < target && pos < rl.line.Len(); pos++ {
+		if (*rl.line)[pos] == inputrc.Newline {
+			line++
+		}
+	}
+
+	rl.cursor.Set(pos)
+
+	if !rl.cursor.OnEmptyLine() {
+		rl.cursor.ToFirstNonSpace(true)
+	}
+}
+
 // Move to the first non-blank character in the line.
 func (rl *Shell) viBackToIndent() {
 	rl.cursor.BeginningOfLine()
 	rl.cursor.ToFirstNonSpace(true)
 }
 
-// Move to the specified mark.
+// Read a mark name (a letter) from the keyboard, and move to the position of
+// this mark, as set with vi-set-mark. In operator pending mode, the operator
+// acts on the characters between the cursor and the mark (eg. d`a). If the
+// key is a backquote instead, move to the other end of the selection, or to
+// the position where insert mode was last entered.
 func (rl *Shell) viGotoMark() {
+	rl.History.SkipSave()
+
+	done := rl.Keymap.PendingCursor()
+	key, isAbort := rl.Keys.ReadKey()
+	done()
+
+	if isAbort {
+		return
+	}
+
+	if pos, found := rl.marks[key]; found {
+		rl.cursor.Set(pos)
+		return
+	}
+
+	if key != '`' {
+		return
+	}
+
 	switch {
 	case rl.selection.Active():
 		// We either an active selection, in which case
@@ -506,6 +684,25 @@ func (rl *Shell) viGotoMark() {
 // Changing Text --------------------------------------------------------
 //
 
+// Read a mark name (a letter) from the keyboard, and move to the first non-blank
+// character of the line of this mark, as set with vi-set-mark. In operator pending
+// mode, the operator acts on all lines between the cursor one and the mark one.
+func (rl *Shell) viGotoMarkLine() {
+	rl.History.SkipSave()
+
+	done := rl.Keymap.PendingCursor()
+	key, isAbort := rl.Keys.ReadKey()
+	done()
+
+	pos, found := rl.marks[key]
+	if isAbort || !found {
+		return
+	}
+
+	rl.cursor.Set(pos)
+	rl.viFirstPrint()
+}
+
 // Read a movement command from the keyboard, and kill from the cursor
 // position to the endpoint of the movement. Then enter insert mode.
 // If the command is vi-change, change the current line.
@@ -549,6 +746,19 @@ func (rl *Shell) viChangeTo() {
 		(*rl.line)[bpos] = bchar
 		(*rl.line)[epos] = echar
 
+	case rl.selection.IsVisualBlock():
+		// In visual block mode, insert on all lines of the deleted block.
+		rl.History.Save()
+
+		regions, bcol, _ := rl.selection.Block()
+		cpos := rl.selection.Cursor()
+		cut := rl.selection.Cut()
+		rl.viWriteRegion([]rune(cut))
+		rl.cursor.Set(cpos)
+
+		rl.viInsertMode()
+		rl.blockInsert = &viBlockInsert{pos: cpos, col: bcol, rows: len(regions) - 1}
+
 	case rl.selection.Active():
 		// In visual mode, we have just have a selection to delete.
 		rl.History.Save()
@@ -556,7 +766,7 @@ func (rl *Shell) viChangeTo() {
 		rl.adjustSelectionPending()
 		cpos := rl.selection.Cursor()
 		cut := rl.selection.Cut()
-		rl.Buffers.Write([]rune(cut)...)
+		rl.viWriteRegion([]rune(cut))
 		rl.cursor.Set(cpos)
 
 		rl.viInsertMode()
@@ -601,6 +811,17 @@ func (rl *Shell) viDeleteTo() {
 		rl.Buffers.Write([]rune(text)...)
 		rl.cursor.Set(cpos)
 
+	case rl.selection.IsVisualBlock():
+		// In visual block mode, cut the block on all its lines.
+		rl.History.Save()
+
+		cpos := rl.selection.Cursor()
+		cut := rl.selection.Cut()
+		rl.viWriteRegion([]rune(cut))
+		rl.cursor.Set(cpos)
+
+		rl.viCommandMode()
+
 	case rl.selection.Active():
 		// In visual mode, or with a non-empty selection, just cut it.
 		rl.History.Save()
@@ -608,7 +829,7 @@ func (rl *Shell) viDeleteTo() {
 		rl.adjustSelectionPending()
 		cpos := rl.selection.Cursor()
 		cut := rl.selection.Cut()
-		rl.Buffers.Write([]rune(cut)...)
+		rl.viWriteRegion([]rune(cut))
 		rl.cursor.Set(cpos)
 
 		rl.viCommandMode()
@@ -696,28 +917,43 @@ func (rl *Shell) viReplace() {
 func (rl *Shell) viChangeCase() {
 	switch {
 	case rl.selection.Active() && rl.selection.IsVisual():
-		rl.selection.ReplaceWith(func(char rune) rune {
-			if unicode.IsLower(char) {
-				return unicode.ToUpper(char)
-			}
-
-			return unicode.ToLower(char)
-		})
+		rl.selection.ReplaceWith(swapCase)
 
 	default:
 		if rl.line.Len() == 0 || rl.cursor.Pos() == rl.line.Len() {
 			return
 		}
 
-		char := rl.cursor.Char()
-		if unicode.IsLower(char) {
-			char = unicode.ToUpper(char)
-		} else {
-			char = unicode.ToLower(char)
-		}
+		rl.cursor.ReplaceWith(swapCase(rl.cursor.Char()))
+	}
+}
+
+// Add the numeric argument (1 by default) to the number under or after the
+// cursor on its line, and move the cursor to the last character of the result.
+// Numbers are decimal (possibly negative), hexadecimal (0x1f), binary (0b101)
+// or octal (017): the latter three are unsigned, and keep their width.
+func (rl *Shell) viIncrement() {
+	rl.viAddNumber(rl.Iterations.Get())
+}
+
+// Subtract the numeric argument (1 by default) from the number under or after
+// the cursor on its line, like vi-increment does.
+func (rl *Shell) viDecrement() {
+	rl.viAddNumber(-rl.Iterations.Get())
+}
 
-		rl.cursor.ReplaceWith(char)
+// viAddNumber adds delta to the number under or after the cursor, if any.
+func (rl *Shell) viAddNumber(delta int) {
+	bpos, epos, number, found := strutil.AddNumber(*rl.line, rl.cursor.Pos(), delta)
+	if !found {
+		rl.History.SkipSave()
+		return
 	}
+
+	rl.History.Save()
+
+	rl.line.InsertBetween(bpos, epos, []rune(number)...)
+	rl.cursor.Set(bpos + len(number) - 1)
 }
 
 // Substitute the next character(s).
@@ -808,7 +1044,16 @@ func (rl *Shell) viAddSurround() {
 }
 
 // Create a new line above the current one, and enter insert mode.
+// If vi-open-line-accept is on, accept the line instead, and insert
+// it in the next one read (in insert mode), like accept-and-hold.
 func (rl *Shell) viOpenLineAbove() {
+	if rl.Config.GetBool("vi-open-line-accept") {
+		rl.viInsertMode()
+		rl.acceptAndHold()
+
+		return
+	}
+
 	rl.History.Save()
 	if !rl.cursor.OnEmptyLine() {
 		rl.beginningOfLine()
@@ -819,7 +1064,16 @@ func (rl *Shell) viOpenLineAbove() {
 }
 
 // Create a new line below the current one, and enter insert mode.
+// If vi-open-line-accept is on, accept the line instead, and insert
+// it in the next one read (in insert mode), like accept-and-hold.
 func (rl *Shell) viOpenLineBelow() {
+	if rl.Config.GetBool("vi-open-line-accept") {
+		rl.viInsertMode()
+		rl.acceptAndHold()
+
+		return
+	}
+
 	rl.History.Save()
 	if !rl.cursor.OnEmptyLine() {
 		rl.endOfLine()
@@ -828,60 +1082,171 @@ func (rl *Shell) viOpenLineBelow() {
 	rl.viInsertMode()
 }
 
-// Convert the current word to all lowercase and move past it.
+// Read a movement command from the keyboard, and convert the region from
+// the cursor position to the endpoint of the movement to all lowercase.
+// If the command is vi-down-case (gugu), convert the current line.
 // If in visual mode, operate on the whole selection.
 func (rl *Shell) viDownCase() {
+	rl.viCaseOperator(unicode.ToLower)
+}
+
+// Read a movement command from the keyboard, and convert the region from
+// the cursor position to the endpoint of the movement to all uppercase.
+// If the command is vi-up-case (gUgU), convert the current line.
+// If in visual mode, operate on the whole selection.
+func (rl *Shell) viUpCase() {
+	rl.viCaseOperator(unicode.ToUpper)
+}
+
+// Read a movement command from the keyboard, and swap the case of all
+// characters in the region from the cursor position to the endpoint of
+// the movement. If the command is vi-oper-swap-case (g~g~), swap the
+// case of the current line. If in visual mode, operate on the selection.
+func (rl *Shell) viOperSwapCase() {
+	rl.viCaseOperator(swapCase)
+}
+
+// viCaseOperator is the operator shared by case commands: it either starts
+// waiting for a movement, or converts the characters of the region selected
+// by it (or the current line, or the visual selection) with convert, and puts
+// the cursor at the beginning of the region.
+func (rl *Shell) viCaseOperator(convert func(rune) rune) {
 	switch {
 	case rl.Keymap.IsPending():
 		// In vi operator pending mode, it's that we've been called
-		// twice in a row (eg. `uu`), so modify the entire current line.
+		// twice in a row (eg. `gUgU`), so modify the entire current line.
+		rl.Keymap.CancelPending()
 		rl.History.Save()
 
 		rl.selection.Mark(rl.cursor.Pos())
 		rl.selection.Visual(true)
-		rl.selection.ReplaceWith(unicode.ToLower)
-		rl.viCommandMode()
+		rl.viReplaceSelection(convert)
 
 	case rl.selection.Active():
+		// In visual mode, or with a non-empty selection, convert it.
 		rl.History.Save()
-		rl.selection.ReplaceWith(unicode.ToLower)
-		rl.viCommandMode()
+		rl.adjustSelectionPending()
+		rl.viReplaceSelection(convert)
 
 	default:
-		// Else if we are actually starting a yank action.
+		// Else if we are actually starting the operator.
 		rl.History.SkipSave()
 		rl.Keymap.Pending()
 		rl.selection.Mark(rl.cursor.Pos())
 	}
 }
 
-// Convert the current word to all uppercase and move past it.
-// If in visual mode, operate on the whole selection.
-func (rl *Shell) viUpCase() {
+// viReplaceSelection replaces the characters of the selection with
+// convert, moves the cursor to its beginning and enters command mode.
+func (rl *Shell) viReplaceSelection(convert func(rune) rune) {
+	bpos, _ := rl.selection.Pos()
+	rl.selection.ReplaceWith(convert)
+
+	if bpos != -1 {
+		rl.cursor.Set(bpos)
+	}
+
+	rl.viCommandMode()
+}
+
+// Read a movement command from the keyboard, and indent all lines spanned by
+// the region from the cursor position to the endpoint of the movement with
+// vi-shiftwidth spaces. If the command is vi-indent (>>), indent the current
+// line. If in visual mode, indent the lines of the selection. Blank lines are
+// not indented.
+func (rl *Shell) viIndent() {
+	rl.viShiftOperator(1)
+}
+
+// Read a movement command from the keyboard, and remove vi-shiftwidth columns
+// of indentation (at most) from all lines spanned by the region from the cursor
+// position to the endpoint of the movement. If the command is vi-unindent (<<),
+// unindent the current line. If in visual mode, unindent the selected lines.
+func (rl *Shell) viUnindent() {
+	rl.viShiftOperator(-1)
+}
+
+// viShiftOperator is the operator shared by indentation commands: it either
+// starts waiting for a movement, or shifts the lines spanned by the region it
+// selects (or the current line, or the visual selection) in the direction.
+func (rl *Shell) viShiftOperator(direction int) {
 	switch {
 	case rl.Keymap.IsPending():
 		// In vi operator pending mode, it's that we've been called
-		// twice in a row (eg. `uu`), so modify the entire current line.
+		// twice in a row (eg. `>>`), so shift the current line.
+		rl.Keymap.CancelPending()
 		rl.History.Save()
 
-		rl.selection.Mark(rl.cursor.Pos())
-		rl.selection.Visual(true)
-		rl.selection.ReplaceWith(unicode.ToUpper)
-		rl.viCommandMode()
+		rl.selection.Reset()
+		rl.viShiftLines(rl.cursor.LinePos(), rl.cursor.LinePos(), direction)
 
 	case rl.selection.Active():
+		// In visual mode, or with a non-empty selection, shift its lines:
+		// the operator is always line-wise, whatever the movement was.
 		rl.History.Save()
-		rl.selection.ReplaceWith(unicode.ToUpper)
+		rl.selection.Visual(true)
+
+		bpos, epos := rl.selection.Pos()
+		rl.selection.Reset()
+
+		if bpos != -1 {
+			first := strings.Count(string((*rl.line)[:bpos]), "\n")
+			last := strings.Count(string((*rl.line)[:max(epos-1, bpos)]), "\n")
+			rl.viShiftLines(first, last, direction)
+		}
+
 		rl.viCommandMode()
 
 	default:
-		// Else if we are actually starting a yank action.
+		// Else if we are actually starting the operator.
 		rl.History.SkipSave()
 		rl.Keymap.Pending()
 		rl.selection.Mark(rl.cursor.Pos())
 	}
 }
 
+// viShiftLines indents (or unindents if direction is negative) the buffer lines
+// from first to last by vi-shiftwidth columns, and moves the cursor to the first
+// non-blank character of the first line. Indents are rewritten with spaces, tabs
+// counting as vi-shiftwidth columns.
+func (rl *Shell) viShiftLines(first, last, direction int) {
+	width := max(rl.Config.GetInt("vi-shiftwidth"), 1)
+	lines := rl.bufferLines()
+
+	for index := first; index <= last && index < len(lines); index++ {
+		line := lines[index]
+		text := strings.TrimLeft(line, " \t")
+
+		if direction > 0 && text == "" {
+			continue
+		}
+
+		indent := 0
+
+		for _, char := range line[:len(line)-len(text)] {
+			if char == '\t' {
+				indent += width
+			} else {
+				indent++
+			}
+		}
+
+		indent = max(indent+direction*width, 0)
+		lines[index] = strings.Repeat(" ", indent) + text
+	}
+
+	rl.setBufferLines(lines, first)
+}
+
+// swapCase returns the uppercase of a lowercase character, and conversely.
+func swapCase(char rune) rune {
+	if unicode.IsLower(char) {
+		return unicode.ToUpper(char)
+	}
+
+	return unicode.ToLower(char)
+}
+
 //
 // Killing & Yanking ----------------------------------------------------
 //
@@ -958,7 +1323,7 @@ func (rl *Shell) viYankTo() {
 		rl.adjustSelectionPending()
 		text, _, _, cpos := rl.selection.Pop()
 
-		rl.Buffers.Write([]rune(text)...)
+		rl.viWriteRegion([]rune(text))
 		rl.cursor.Set(cpos)
 
 		rl.viCommandMode()
@@ -1099,6 +1464,22 @@ func (rl *Shell) viSetBuffer() {
 	done := rl.Keymap.PendingCursor()
 	defer done()
 
+	// List the filled registers while reading the name.
+	if rl.Config.GetBool("vi-register-hint") {
+		restore := rl.Hint.Snapshot()
+		defer restore()
+
+		rl.Hint.Set(rl.registersHint())
+	}
+
+	// Display the register as pending while reading it.
+	if rl.Config.GetBool("show-pending-command") {
+		rl.pendingRegister = true
+		defer func() { rl.pendingRegister = false }()
+	}
+
+	rl.Display.Refresh()
+
 	key, isAbort := rl.Keys.ReadKey()
 	if isAbort {
 		return
@@ -1107,6 +1488,14 @@ func (rl *Shell) viSetBuffer() {
 	rl.Buffers.SetActive(key)
 }
 
+// Show the contents of all filled registers in the hint section, one per line, like
+// the Vim :registers command: the unnamed register (the kill buffer), the numbered,
+// lettered, clipboard and read-only ones. The list is cleared at the next key.
+func (rl *Shell) viShowRegisters() {
+	rl.History.SkipSave()
+	rl.Hint.SetTemporary(rl.registersList())
+}
+
 //
 // Selecting Text -------------------------------------------------------
 //
@@ -1124,10 +1513,12 @@ func (rl *Shell) viSelectAShellWord() {
 	rl.History.SkipSave()
 	rl.cursor.CheckCommand()
 
-	// First find the blank word under cursor,
-	// and put or cursor at the beginning of it.
-	bpos, _ := rl.line.SelectBlankWord(rl.cursor.Pos())
-	rl.cursor.Set(bpos)
+	// First find the blank word under cursor, and put or cursor
+	// at the beginning of it, unless words are host tokens.
+	if rl.Tokenizer == nil {
+		bpos, _ := rl.line.SelectBlankWord(rl.cursor.Pos())
+		rl.cursor.Set(bpos)
+	}
 
 	// Then find any enclosing quotes, if valid.
 	rl.selection.SelectAShellWord()
@@ -1136,6 +1527,8 @@ func (rl *Shell) viSelectAShellWord() {
 // Select a word including adjacent blanks, using the normal vi-style word definition.
 func (rl *Shell) viSelectAWord() {
 	rl.History.SkipSave()
+
+	rl.selection.Words = rl.wordClass()
 	rl.selection.SelectAWord()
 }
 
@@ -1154,6 +1547,19 @@ func (rl *Shell) viSelectInBlankWord() {
 func (rl *Shell) viSelectInShellWord() {
 	rl.History.SkipSave()
 
+	// Host tokens include their quotes, if any.
+	if spans := rl.tokenSpans(*rl.line); spans != nil && rl.line.Len() > 0 {
+		bpos, epos := rl.line.SelectSpan(rl.cursor.Pos(), spans)
+		if quote := (*rl.line)[bpos]; epos > bpos+1 && (quote == '\'' || quote == '"') && (*rl.line)[epos] == quote {
+			bpos, epos = bpos+1, epos-1
+		}
+
+		rl.cursor.Set(epos)
+		rl.selection.Mark(bpos)
+
+		return
+	}
+
 	// First find the blank word under cursor,
 	// and put or cursor at the beginning of it.
 	bpos, _ := rl.line.SelectBlankWord(rl.cursor.Pos())
@@ -1182,47 +1588,65 @@ func (rl *Shell) viSelectInShellWord() {
 func (rl *Shell) viSelectInWord() {
 	rl.History.SkipSave()
 
-	bpos, epos := rl.line.SelectWord(rl.cursor.Pos())
+	bpos, epos := rl.selectWord(rl.cursor.Pos())
 	rl.cursor.Set(epos)
 	rl.selection.Mark(bpos)
 }
 
-// Read a key from the keyboard, and attempt to select a region surrounded by those keys.
-// If the key triggering this command is 'i', the selection excludes the surrounding chars.
+// Read a key from the keyboard, and select the text object delimited by this key:
+// the text between the brackets enclosing the cursor for '(' or ')' (also 'b'), '['
+// or ']', '{' or '}' (also 'B'), '<' or '>', the count-th enclosing pair with a
+// numeric argument, or the text between the quotes enclosing the cursor, or the next
+// quoted text on its line, for any other key (like double or single quotes, or backquotes).
+// If the key triggering this command is 'i', the selection excludes the delimiters,
+// otherwise it includes them, and for quotes, the blanks following them (or those
+// preceding them, if none follows). If no such object is found, nothing is selected,
+// and any pending operator is cancelled.
 func (rl *Shell) viSelectInside() {
 	rl.History.SkipSave()
 
-	var inside bool
-
-	// The surround can be either inside or around a surrounding
-	// character, so we look at the input keys: the first one is
-	// the only that triggered this command, so check the second.
-	// Use the first key to know if inside/around is used.
-	keys := rl.Keys.Caller()
-	if keys[0] == 'i' {
-		inside = true
-	}
+	// The first key is the one that triggered this command:
+	// use it to know if inside/around is used.
+	inside := rl.Keys.Caller()[0] == 'i'
 
-	// Then use the next key as the surrounding character.
+	// Then use the next key as the delimiter.
 	char, empty := rl.Keys.Pop()
 	if empty {
 		return
 	}
 
-	bpos, epos, _, _ := rl.line.FindSurround(rune(char), rl.cursor.Pos())
-	if bpos == -1 && epos == -1 {
+	bpos, epos, quotes := rl.viTextObject(rune(char))
+
+	switch {
+	case bpos == -1 || epos == -1:
+		if rl.Keymap.Local() == keymap.ViOpp {
+			rl.Keymap.CancelPending()
+			rl.selection.Reset()
+		}
+
+		return
+
+	case inside && epos == bpos+1:
+		// An empty object selects nothing, so that operators
+		// like c insert at the position of the closing delimiter.
+		rl.selection.MarkRange(epos, epos)
+		rl.cursor.Set(epos)
+
 		return
-	}
 
-	if inside {
+	case inside:
 		bpos++
 		epos--
+
+	case quotes:
+		bpos, epos = rl.viQuotesBlanks(bpos, epos)
 	}
 
 	// Select the range and return: the caller will decide what
 	// to do with the cursor position and the selection itself.
 	rl.selection.Mark(bpos)
 	rl.cursor.Set(epos)
+	rl.selection.Visual(false)
 }
 
 // Read a key from the keyboard, and attempt to create a selection
@@ -1249,6 +1673,49 @@ func (rl *Shell) viSelectSurround() {
 	rl.selection.MarkSurround(bpos, epos)
 }
 
+// viTextObject returns the positions of the delimiters of the text object
+// selected with a key, and true if they are quotes rather than brackets.
+func (rl *Shell) viTextObject(key rune) (bpos, epos int, quotes bool) {
+	switch key {
+	case 'b':
+		key = '('
+	case 'B':
+		key = '{'
+	}
+
+	if !strutil.IsBracket(key) && key != '<' && key != '>' {
+		bpos, epos = rl.line.FindQuotes(key, rl.cursor.Pos())
+		return bpos, epos, true
+	}
+
+	bchar, echar := strutil.MatchSurround(key)
+	bpos, epos = rl.line.FindSurroundPair(bchar, echar, rl.cursor.Pos(), rl.Iterations.Get())
+
+	return bpos, epos, false
+}
+
+// viQuotesBlanks extends the range of a quoted text with the blanks
+// following it, or with those preceding it if none follows.
+func (rl *Shell) viQuotesBlanks(bpos, epos int) (int, int) {
+	isBlank := func(pos int) bool {
+		return pos >= 0 && pos < rl.line.Len() && ((*rl.line)[pos] == ' ' || (*rl.line)[pos] == '\t')
+	}
+
+	if isBlank(epos + 1) {
+		for isBlank(epos + 1) {
+			epos++
+		}
+
+		return bpos, epos
+	}
+
+	for isBlank(bpos - 1) {
+		bpos--
+	}
+
+	return bpos, epos
+}
+
 //
 // Miscellaneous --------------------------------------------------------
 //
@@ -1303,6 +1770,8 @@ func (rl *Shell) viSearchAgain() {
 
 	line, cursor, _ := rl.completer.GetBuffer()
 
+	defer rl.viSearchOptions(*line)()
+
 	rl.History.InsertMatch(line, cursor, true, forward, true)
 	rl.completer.NonIsearchStop()
 }
@@ -1349,10 +1818,70 @@ func (rl *Shell) viCharSearch() {
 	}
 }
 
-// Set the specified mark at the cursor position.
+// Read a mark name (a letter) from the keyboard, and set this mark at
+// the cursor position, to move back to it with vi-goto-mark (`) and
+// vi-goto-mark-line ('). Marks move along with the characters they
+// are set on when the line is edited, and are dropped once it is read.
 func (rl *Shell) viSetMark() {
 	rl.History.SkipSave()
-	rl.selection.Mark(rl.cursor.Pos())
+
+	done := rl.Keymap.PendingCursor()
+	defer done()
+
+	key, isAbort := rl.Keys.ReadKey()
+	if isAbort || !isMarkName(key) {
+		return
+	}
+
+	if rl.marks == nil {
+		rl.marks = make(map[rune]int)
+	}
+
+	rl.marks[key] = rl.cursor.Pos()
+}
+
+// adjustMarks keeps the vi marks on the same characters once the line has
+// been edited, given the line before the edit: marks after the edited text
+// move along with it, and marks in the edited text move to its beginning.
+// If the line has been replaced instead (like a history line), all marks are
+// dropped.
+func (rl *Shell) adjustMarks(before string, edited bool) {
+	after := string(*rl.line)
+	if len(rl.marks) == 0 || before == after {
+		return
+	}
+
+	if !edited {
+		rl.marks = nil
+		return
+	}
+
+	old, line := []rune(before), []rune(after)
+
+	// The edited text is between the common prefix and suffix of both lines.
+	start := 0
+	for start < len(old) && start < len(line) && old[start] == line[start] {
+		start++
+	}
+
+	end := 0
+	for end < len(old)-start && end < len(line)-start && old[len(old)-1-end] == line[len(line)-1-end] {
+		end++
+	}
+
+	for name, pos := range rl.marks {
+		switch {
+		case pos >= len(old)-end:
+			rl.marks[name] = pos + len(line) - len(old)
+		case pos > start:
+			rl.marks[name] = start
+		}
+	}
+}
+
+// isMarkName returns true if the key is a valid vi mark name.
+func isMarkName(key rune) bool {
+	return (key >= 'a' && key <= 'z') || (key >= 'A' && key <= 'Z')
 }
 
 // Invoke an editor on the current command line, and execute the result as shell commands.
@@ -1361,16 +1890,79 @@ func (rl *Shell) viEditAndExecuteCommand() {
 	rl.editAndExecuteCommand()
 }
 
-// Incrementally redo undone text modifications.
-// If at the beginning of the line changes, enter insert mode.
+// Repeat the last change made from command mode (like dw, 3x, cwfoo<Esc>
+// or a text inserted with A), by typing its keys again. If a numeric
+// argument is given, it replaces the one of the change, if any.
 func (rl *Shell) viRedo() {
-	if rl.History.Pos() > 0 {
-		rl.History.Redo()
+	rl.History.SkipSave()
+
+	keys := rl.lastChange
+	if len(keys) == 0 {
 		return
 	}
 
-	// Enter insert mode when no redo possible.
-	rl.viInsertMode()
+	if rl.Iterations.IsSet() {
+		count := rl.Iterations.Typed()
+		rl.Iterations.Reset()
+
+		keys = append([]rune(count), []rune(strings.TrimLeft(string(keys), "0123456789"))...)
+	}
+
+	rl.Keys.Feed(false, keys...)
+}
+
+// Browse the undo tree of the current line in a completion menu: the states
+// are listed below the states they were saved from, with the branches left by
+// editing the line after undoing indented, and the current state marked with
+// an asterisk. Selecting one replaces the line with it. With a numeric argument
+// N, directly restore the line to its Nth undo state, as undo-history does.
+func (rl *Shell) viUndoTree() {
+	if rl.Iterations.IsSet() {
+		rl.History.UndoTo(rl.Iterations.Get())
+		return
+	}
+
+	rl.History.SkipSave()
+	rl.startMenuComplete(func() completion.Values {
+		return history.CompleteUndoTree(rl.History)
+	})
+}
+
+// recordViChange records the keys of the command just run, if it is part of a
+// change made from vi command mode: this includes any numeric argument, register
+// and visual selection typed before the change, the operator and its movement,
+// and all keys typed in insert mode if the change entered it, up to leaving it.
+// The mode is the main keymap before the command, and changed is true if the
+// command has modified the line (undo and history commands excluded).
+func (rl *Shell) recordViChange(mode keymap.Mode, changed bool) {
+	if rl.Keymap.IsEmacs() {
+		rl.change, rl.changeInsert = nil, false
+		return
+	}
+
+	command := mode == keymap.ViCommand || mode == keymap.ViMove || mode == keymap.Vi
+	if !command && !rl.changeInsert {
+		return
+	}
+
+	rl.change = append(rl.change, core.MacroKeys(rl.Keys)...)
+
+	_, register := rl.Buffers.IsSelected()
+	local := rl.Keymap.Local()
+
+	switch {
+	case rl.Keymap.Main() == keymap.ViInsert:
+		// Keys typed in insert mode are part of the change.
+		rl.changeInsert = true
+		return
+	case rl.Iterations.IsPending(), register, local == keymap.ViOpp, local == keymap.Visual:
+		// The change is not complete yet.
+		return
+	case changed || rl.changeInsert:
+		rl.lastChange = rl.change
+	}
+
+	rl.change, rl.changeInsert = nil, false
 }
 
 // Invoke an editor on the current command line.
@@ -1389,6 +1981,55 @@ func (rl *Shell) viEditCommandLine() {
 	}
 }
 
+// Insert the value of the comment-begin variable at the beginning of each
+// line of the buffer, and accept it: the commented line is saved in history
+// without being executed, so that it can be recalled and finished later. If
+// all lines of the buffer are already commented, remove these comments and
+// do not accept the line.
+func (rl *Shell) viPoundInsert() {
+	comment := strings.Trim(rl.Config.GetString("comment-begin"), "\"")
+	if comment == "" {
+		return
+	}
+
+	rl.History.Save()
+
+	lines := strings.Split(string(*rl.line), "\n")
+	commented := true
+
+	for _, line := range lines {
+		if !strings.HasPrefix(line, comment) {
+			commented = false
+			break
+		}
+	}
+
+	// Keep the cursor on the same character, shifted
+	// by the comments on its line and those above it.
+	cpos := rl.cursor.Pos()
+	shift := (strings.Count(string((*rl.line)[:cpos]), "\n") + 1) * len([]rune(comment))
+
+	for i, line := range lines {
+		if commented {
+			lines[i] = strings.TrimPrefix(line, comment)
+		} else {
+			lines[i] = comment + line
+		}
+	}
+
+	rl.line.Set([]rune(strings.Join(lines, "\n"))...)
+
+	if commented {
+		rl.cursor.Set(max(cpos-shift, 0))
+		rl.cursor.CheckCommand()
+
+		return
+	}
+
+	rl.cursor.Set(cpos + shift)
+	rl.acceptLineWith(false, false)
+}
+
 // Read a character from the keyboard, and move to the next occurrence of it in the line.
 func (rl *Shell) viFindNextChar() {
 	vii := rl.Iterations.Get()
@@ -1443,6 +2084,7 @@ func (rl *Shell) viFindChar(forward, skip bool) {
 		pos := rl.line.Find(char, rl.cursor.Pos(), forward)
 
 		if pos == rl.cursor.Pos() || pos == -1 {
+			rl.Bell()
 			break
 		}
 
@@ -1474,6 +2116,8 @@ func (rl *Shell) viSearchAgainForward() {
 
 	line, cursor, _ := rl.completer.GetBuffer()
 
+	defer rl.viSearchOptions(*line)()
+
 	rl.History.InsertMatch(line, cursor, true, true, true)
 	rl.completer.NonIsearchStop()
 }
@@ -1484,6 +2128,8 @@ func (rl *Shell) viSearchAgainBackward() {
 
 	line, cursor, _ := rl.completer.GetBuffer()
 
+	defer rl.viSearchOptions(*line)()
+
 	rl.History.InsertMatch(line, cursor, true, false, true)
 	rl.completer.NonIsearchStop()
 }
@@ -1492,6 +2138,103 @@ func (rl *Shell) viSearchAgainBackward() {
 // Utils ---------------------------------------------------------------
 //
 
+// viSearchOptions applies the vi-ignorecase, vi-smartcase and vi-wrapscan
+// options to the history searches for the pattern, and returns a function
+// restoring the default searches. With vi-smartcase, the case is ignored
+// only if the pattern has no uppercase characters.
+func (rl *Shell) viSearchOptions(pattern []rune) (restore func()) {
+	ignoreCase := rl.Config.GetBool("vi-ignorecase")
+
+	if ignoreCase && rl.Config.GetBool("vi-smartcase") {
+		ignoreCase = !slices.ContainsFunc(pattern, unicode.IsUpper)
+	}
+
+	return rl.History.SearchOptions(ignoreCase, rl.Config.GetBool("vi-wrapscan"))
+}
+
+// viBlockInsert is a text insertion on the first line of a visual
+// block, replicated on its other lines when leaving insert mode.
+type viBlockInsert struct {
+	pos  int  // Where the insertion started on the first line.
+	col  int  // Column at which text is inserted on all lines.
+	rows int  // Number of lines of the block below the first one.
+	pad  bool // Lines too short are padded with spaces, instead of skipped.
+}
+
+// viBlockInsert enters insert mode before the visual block on its first
+// line, or after it if after is true, for the text inserted there to be
+// inserted on all lines of the block when leaving insert mode. When inserting
+// after the block, the lines too short to reach it are padded with spaces.
+func (rl *Shell) viBlockInsert(after bool) {
+	regions, bcol, ecol := rl.selection.Block()
+	if len(regions) == 0 {
+		return
+	}
+
+	block := &viBlockInsert{pos: regions[0][0], col: bcol, rows: len(regions) - 1}
+
+	if after {
+		block.pos, block.col, block.pad = regions[0][1], ecol, true
+
+		rl.cursor.Set(block.pos)
+		rl.cursor.BeginningOfLine()
+
+		if pad := rl.cursor.Pos() + ecol - block.pos; pad > 0 {
+			rl.History.Save()
+			rl.line.Insert(block.pos, []rune(strings.Repeat(" ", pad))...)
+			block.pos += pad
+		}
+	}
+
+	rl.cursor.Set(block.pos)
+	rl.viInsertMode()
+	rl.blockInsert = block
+}
+
+// viBlockReplicate inserts the text inserted on the first line of a
+// visual block at the same column on all its other lines, if any text
+// was inserted without any newline. The block insertion is then done.
+func (rl *Shell) viBlockReplicate() {
+	block := rl.blockInsert
+	rl.blockInsert = nil
+
+	if block == nil || rl.cursor.Pos() <= block.pos || rl.cursor.Pos() > rl.line.Len() {
+		return
+	}
+
+	text := slices.Clone((*rl.line)[block.pos:rl.cursor.Pos()])
+	if slices.Contains(text, inputrc.Newline) {
+		return
+	}
+
+	lineEnd := func(pos int) int {
+		for pos < rl.line.Len() && (*rl.line)[pos] != inputrc.Newline {
+			pos++
+		}
+
+		return pos
+	}
+
+	end := lineEnd(rl.cursor.Pos())
+
+	for row := 0; row < block.rows && end < rl.line.Len(); row++ {
+		start := end + 1
+		end = lineEnd(start)
+
+		insert := text
+
+		switch {
+		case end-start < block.col && !block.pad:
+			continue
+		case end-start < block.col:
+			insert = append([]rune(strings.Repeat(" ", block.col-(end-start))), text...)
+		}
+
+		rl.line.Insert(min(start+block.col, end), insert...)
+		end += len(insert)
+	}
+}
+
 // Some commands accepting a pending operator command (yw/de... etc), must
 // either encompass the character under cursor into the selection, or not.
 // Note that when this command while a yank/delete command has been called
@@ -1505,6 +2248,8 @@ func (rl *Shell) adjustSelectionPending() {
 	switch rl.Keymap.ActiveCommand().Action {
 	// Movements
 	case "vi-end-word", "vi-end-bigword",
+		"vi-backward-word-end", "vi-backward-blank-word-end",
+		"vi-backward-end-word", "vi-backward-end-bigword",
 		"vi-find-next-char", "vi-find-next-char-skip",
 		"vi-find-prev-char", "vi-find-prev-char-skip",
 		"vi-match":
@@ -1513,12 +2258,33 @@ func (rl *Shell) adjustSelectionPending() {
 		// Selectors
 	case "select-in-word", "select-a-word",
 		"select-in-blank-word", "select-a-blank-word",
-		"select-in-shell-word", "select-a-shell-word",
-		"vi-select-inside":
+		"select-in-shell-word", "select-a-shell-word":
 		rl.selection.Visual(false)
 
 		// Modifiers
 	case "vi-change-to":
 		rl.selection.Visual(false)
+
+		// Line-wise movements
+	case "vi-goto-line", "vi-screen-top", "vi-screen-middle", "vi-screen-bottom",
+		"vi-goto-mark-line":
+		rl.selection.Visual(true)
+	}
+}
+
+// viWriteRegion writes text cut or copied from a selection to the active register,
+// or to the kill ring if none is. If it was written to a register, it is also pushed
+// on the kill ring when the vi-visual-kill-ring option is enabled, so that it can be
+// yanked with yank (C-y) and yank-pop (M-y) as well as with the register.
+// Numbered and unnamed registers already are the kill ring, and text written
+// to the black hole one is discarded: neither is pushed again.
+func (rl *Shell) viWriteRegion(text []rune) {
+	name, register := rl.Buffers.IsSelected()
+	killRing := register && !strings.ContainsAny(name, `_"0123456789`)
+
+	rl.Buffers.Write(text...)
+
+	if killRing && rl.Config.GetBool("vi-visual-kill-ring") {
+		rl.Buffers.WriteTo(0, text...)
 	}
 }
@@ -0,0 +1,737 @@
+package readline_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/reeflective/readline"
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_UndoTree(t *testing.T) {
+	edits := []string{"one", `\C-o`, "A two", `\C-o`, "u", "A three", `\C-o`, "u"}
+
+	shell := func() (*readlinetest.Terminal, *readline.Shell) {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.Config.Bind("vi-command", "U", "vi-undo-tree", false)
+
+		return term, rl
+	}
+
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{`\C-r`}, want: "one three"},
+		{keys: []string{"2U"}, want: "one two"},
+		{keys: []string{"2U", "u", `\C-r`}, want: "one two"},
+	}
+
+	for _, test := range tests {
+		term, rl := shell()
+
+		line, err := term.Run(rl, append(append(edits, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("keys %q: line = %q, want %q", test.keys, line, test.want)
+		}
+	}
+
+	term, rl := shell()
+
+	go rl.Readline()
+
+	term.Type(append(edits, "U")...)
+
+	waitForText(t, term, "  o one three")
+
+	if screen := term.Screen(); !strings.Contains(screen, "o one two") || !strings.Contains(screen, "* one ") {
+		t.Errorf("undo tree branches not displayed:\n%s", screen)
+	}
+
+	term.Type(`\C-c`)
+}
+
+func TestShell_ViGotoLine(t *testing.T) {
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{"gg", "x"}, want: "ne\n  two\nthree"},
+		{keys: []string{"gg", "G", "x"}, want: "one\n  two\nhree"},
+		{keys: []string{"2G", "x"}, want: "one\n  wo\nthree"},
+		{keys: []string{"2G", "dG"}, want: "one\n"},
+		{keys: []string{"2G", "ygg", "G", "p"}, want: "one\n  two\nthree\none\n  two"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune("one\n  two\nthree"))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("Run(%q) line = %q, want %q", test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViViewport(t *testing.T) {
+	buffer := "l1\nl2\nl3\nl4\nl5\nl6"
+
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{"H", "x"}, want: "l1\nl2\nl3\n4\nl5\nl6"},
+		{keys: []string{"M", "x"}, want: "l1\nl2\nl3\nl4\n5\nl6"},
+		{keys: []string{"gg", "L", "x"}, want: "l1\nl2\n3\nl4\nl5\nl6"},
+		{keys: []string{"2H", "x"}, want: "l1\nl2\nl3\nl4\n5\nl6"},
+		{keys: []string{"gg", "j", "zt", "L", "x"}, want: "l1\nl2\nl3\n4\nl5\nl6"},
+		{keys: []string{"k", "k", "zz", "H", "x"}, want: "l1\nl2\n3\nl4\nl5\nl6"},
+		{keys: []string{"k", "k", "zb", "H", "x"}, want: "l1\n2\nl3\nl4\nl5\nl6"},
+		{keys: []string{"H", "dL"}, want: "l1\nl2\nl3\n"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+		rl.Config.Set("max-display-lines", 3)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(buffer))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("Run(%q) line = %q, want %q", test.keys, line, test.want)
+		}
+	}
+
+	// Only the lines in the viewport are displayed while reading,
+	// and all of them once the line is accepted.
+	term := readlinetest.NewTerminal(80, 24)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("max-display-lines", 3)
+	rl.History.Prefill([]rune(buffer))
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	want := "l4\nl5\nl6"
+
+	waitForScreen(t, term, want)
+
+	term.Type(`\r`)
+	<-done
+
+	if got := strings.TrimSpace(term.Screen()); got != buffer {
+		t.Errorf("Screen() = %q, want %q", got, buffer)
+	}
+}
+
+func TestShell_ViRegisters(t *testing.T) {
+	shell := func() (*readlinetest.Terminal, *readline.Shell) {
+		term, rl := newViTerminal(t, 40, 10)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+		return term, rl
+	}
+
+	tests := []struct {
+		name string
+		keys []string
+		want string
+	}{
+		{"numbered", []string{"one two", `\C-o`, "0", `"1yw`, "w", `"1P`}, "one one two"},
+		{"zero", []string{"one two", `\C-o`, "0", `"0yw`, "$", `"0p`}, "one twoone "},
+		{"unnamed", []string{"one two", `\C-o`, "0", `""dw`, "$", "p"}, "twoone "},
+		{"clipboard", []string{"one two", `\C-o`, "0", `"+yw`, "$", `"*p`}, "one twoone "},
+		{"black hole", []string{"one two", `\C-o`, "0", "yw", "w", `"_dw`, "p"}, "one one "},
+		{"append", []string{"one two", `\C-o`, "0", `"ayw`, "w", `"Ayw`, `"ap`}, "one tone twowo"},
+		{"invalid", []string{"one two", `\C-o`, "0", `"!dw`, "$", "p"}, "twoone "},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			term, rl := shell()
+
+			line, err := term.Run(rl, append(test.keys, `\r`)...)
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if line != test.want {
+				t.Errorf("Readline() line = %q, want %q", line, test.want)
+			}
+		})
+	}
+
+	// The clipboard registers are also copied to the terminal one.
+	term, rl := shell()
+	var output strings.Builder
+
+	stop := rl.Record(&output)
+
+	if _, err := term.Run(rl, "one two", `\C-o`, "0", `"+yw`, `\r`); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	stop()
+
+	if want := `\u001b]52;c;b25lIA==\u0007`; !strings.Contains(output.String(), want) {
+		t.Errorf("Record() output does not copy %q to the clipboard", "one ")
+	}
+}
+
+func TestShell_ViPoundInsert(t *testing.T) {
+	term, rl := newViTerminal(t, 80, 24)
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	line, err := term.Run(rl, "echo one", `\C-o`, "#")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "" {
+		t.Errorf("Run() line = %q, want %q", line, "")
+	}
+
+	line, err = term.Run(rl, `\C-o`, "k", "#", "A", " two", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "echo one two" {
+		t.Errorf("Run() line = %q, want %q", line, "echo one two")
+	}
+}
+
+func TestShell_ViVisualKillRing(t *testing.T) {
+	term, rl := newViTerminal(t, 80, 24)
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-y`), "yank", false)
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-t`), "yank-pop", false)
+
+	line, err := term.Run(rl, "aaa bbb ccc", `\C-o`, "0", "ved", "w", "ve", `"xd`, "A", `\C-y`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "  cccbbb" {
+		t.Errorf("Run() line = %q, want %q", line, "  cccbbb")
+	}
+
+	line, err = term.Run(rl, "x", `\C-y`, `\C-t`, `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "xaaa" {
+		t.Errorf("Run() line = %q, want %q", line, "xaaa")
+	}
+}
+
+func TestShell_ViSearchOptions(t *testing.T) {
+	tests := []struct {
+		options map[string]any
+		keys    []string
+		want    string
+	}{
+		{keys: []string{"?", "ECHO", `\r`}, want: ""},
+		{options: map[string]any{"vi-ignorecase": true}, keys: []string{"?", "ECHO", `\r`}, want: "echo two"},
+		{options: map[string]any{"vi-ignorecase": true, "vi-smartcase": true}, keys: []string{"?", "Echo", `\r`}, want: "Echo one"},
+		{options: map[string]any{"vi-ignorecase": true, "vi-smartcase": true}, keys: []string{"?", "echo", `\r`}, want: "echo two"},
+		{keys: []string{"?", "o", `\r`, "N"}, want: "Echo one"},
+		{keys: []string{"?", "o", `\r`, "N", "N"}, want: "echo two"},
+		{options: map[string]any{"vi-wrapscan": false}, keys: []string{"?", "o", `\r`, "N", "N"}, want: "Echo one"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		for name, value := range test.options {
+			if err := rl.SetOption(name, value); err != nil {
+				t.Fatalf("SetOption(%q) error = %v", name, err)
+			}
+		}
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+		history := readline.NewInMemoryHistory()
+		history.Write("Echo one")
+		history.Write("ls")
+		history.Write("echo two")
+		rl.History.Delete()
+		rl.History.Add("test", history)
+
+		keys := append([]string{`\C-o`}, test.keys...)
+
+		line, err := term.Run(rl, append(keys, `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("options %v, keys %q: line = %q, want %q", test.options, test.keys, line, test.want)
+		}
+	}
+
+	// Additional word characters in vi modes.
+	term, rl := newViTerminal(t, 80, 24)
+
+	if err := rl.SetOption("vi-iskeyword", "-"); err != nil {
+		t.Fatalf("SetOption() error = %v", err)
+	}
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	line, err := term.Run(rl, "foo-bar baz", `\C-o`, "0", "dw", `\r`)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if line != "baz" {
+		t.Errorf("vi-iskeyword: line = %q, want %q", line, "baz")
+	}
+}
+
+func TestShell_ViMacros(t *testing.T) {
+	record := []string{"x", `\C-o`, "qa", "A!", `\C-o`, "q"}
+
+	tests := []struct {
+		keys []string
+		want string
+	}{
+		{keys: []string{`\r`}, want: "x!"},
+		{keys: []string{"@a", `\r`}, want: "x!!"},
+		{keys: []string{"3@a", `\r`}, want: "x!!!!"},
+		{keys: []string{"@a", "2@@", `\r`}, want: "x!!!!"},
+		{keys: []string{"@b", `\r`}, want: "x!"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+		line, err := term.Run(rl, append(slices.Clone(record), test.keys...)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("keys %q: line = %q, want %q", test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViRecursiveMacro(t *testing.T) {
+	term, rl := newViTerminal(t, 80, 24)
+
+	// Don't redisplay after each of the many keys replayed.
+	rl.Config.Set("max-redisplay-rate", 60)
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	// The macro calls itself: it is stopped after 1000 runs,
+	// and the shell reads the keys typed afterwards again.
+	term.Type("x", `\C-o`, "qa", "A!", `\C-o`, "@a", "q", "@a")
+
+	waitForText(t, term, "Macro recursion too deep")
+
+	term.Type(`\r`)
+
+	if line, want := <-done, "x"+strings.Repeat("!", 1001); line != want {
+		t.Errorf("line = %d chars, want %d", len(line), len(want))
+	}
+}
+
+func TestShell_ViOpenLine(t *testing.T) {
+	tests := []struct {
+		accept bool
+		keys   []string
+		want   []string
+	}{
+		{keys: []string{"abc", `\C-o`, "o", "x", `\r`}, want: []string{"abc\nx"}},
+		{keys: []string{"abc", `\C-o`, "O", "x", `\r`}, want: []string{"x\nabc"}},
+		{accept: true, keys: []string{"abc", `\C-o`, "o", "x", `\r`}, want: []string{"abc", "abcx"}},
+		{accept: true, keys: []string{"abc", `\C-o`, "O", "x", `\r`}, want: []string{"abc", "abcx"}},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		if err := rl.SetOption("vi-open-line-accept", test.accept); err != nil {
+			t.Fatalf("SetOption() error = %v", err)
+		}
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+		term.Type(test.keys...)
+
+		for _, want := range test.want {
+			line, err := rl.Readline()
+			if err != nil {
+				t.Fatalf("Readline() error = %v", err)
+			}
+
+			if line != want {
+				t.Errorf("accept %t, keys %q: line = %q, want %q", test.accept, test.keys, line, want)
+			}
+		}
+	}
+}
+
+func TestShell_ViRepeatChange(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "one two three four", keys: []string{"dw", "."}, want: "three four"},
+		{line: "one two three four", keys: []string{"dw", "2."}, want: "four"},
+		{line: "one two three four five", keys: []string{"d2w", "."}, want: "five"},
+		{line: "abcdefgh", keys: []string{"2x", "."}, want: "efgh"},
+		{line: "abcdefgh", keys: []string{"2x", "3."}, want: "fgh"},
+		{line: "abcdefgh", keys: []string{`"a2x`, "."}, want: "efgh"},
+		{line: "abcdef", keys: []string{"vld", "."}, want: "ef"},
+		{line: "abc", keys: []string{"rx", "l", "."}, want: "xxc"},
+		{line: "a b c", keys: []string{"ix", `\C-o`, "w", "."}, want: "xa xb c"},
+		{line: "a", keys: []string{"A!", `\C-o`, "0", "."}, want: "a!!"},
+		{line: "abc", keys: []string{"l", "."}, want: "abc"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+		keys := append([]string{test.line, `\C-o`, "0"}, test.keys...)
+
+		line, err := term.Run(rl, append(keys, `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q, keys %q: line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViMarks(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "one two three", keys: []string{"0", "wma", "$", "`a", "D"}, want: "one "},
+		{line: "one two three", keys: []string{"0", "wma", "$", "d`a"}, want: "one e"},
+		{line: "one two three", keys: []string{"0", "wma", "0", "d`a"}, want: "two three"},
+		{line: "one two three", keys: []string{"0", "wma", "0", "ix", `\C-o`, "`a", "D"}, want: "xone "},
+		{line: "one two three", keys: []string{"0", "wwma", "0", "dw", "`a", "D"}, want: "two "},
+		{line: "one two three", keys: []string{"0", "wma", "`b", "D"}, want: "one "},
+		{line: "one\n  two\nthree", keys: []string{"kma", "gg", "d'a"}, want: "three"},
+		{line: "one\n  two\nthree", keys: []string{"k$ma", "G", "'a", "D"}, want: "one\n  \nthree"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q, keys %q: line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViTextObjects(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "f(a, g(b), c)", keys: []string{"0", "fa", "di("}, want: "f()"},
+		{line: "f(a, g(b), c)", keys: []string{"0", "fa", "da)"}, want: "f"},
+		{line: "f(a, g(b), c)", keys: []string{"0", "fb", "dib"}, want: "f(a, g(), c)"},
+		{line: "f(a, g(b), c)", keys: []string{"0", "fb", "d2i("}, want: "f()"},
+		{line: "f(a, g(b), c)", keys: []string{"0", "di(", "x"}, want: "(a, g(b), c)"},
+		{line: "f()", keys: []string{"0", "f(", "ci(", "x"}, want: "f(x)"},
+		{line: "a [b] {c}", keys: []string{"0", "fb", "ci]", "x", `\C-o`, "fc", "daB"}, want: "a [x] "},
+		{line: "a <b c> d", keys: []string{"0", "fb", "vi<", "d"}, want: "a <> d"},
+		{line: `echo "one" "two"`, keys: []string{"0", `ci"`, "x"}, want: `echo "x" "two"`},
+		{line: `echo "one" "two"`, keys: []string{"0", "fn", `da"`}, want: `echo "two"`},
+		{line: "echo 'one' `two`", keys: []string{"$", "di`", "0", "fn", "di'"}, want: "echo '' ``"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q, keys %q: line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViBackwardWordEnd(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "one two three", keys: []string{"$", "ge", "x"}, want: "one tw three"},
+		{line: "one two three", keys: []string{"$", "2ge", "x"}, want: "on two three"},
+		{line: "one two  three", keys: []string{"$", "b", "h", "ge", "x"}, want: "one tw  three"},
+		{line: "foo.bar baz", keys: []string{"0", "fb", "ge", "x"}, want: "foobar baz"},
+		{line: "foo.bar baz", keys: []string{"$", "gE", "x"}, want: "foo.ba baz"},
+		{line: "one\n\ntwo", keys: []string{"$", "ge", "x"}, want: "one\ntwo"},
+		{line: "one two three", keys: []string{"$", "b", "dge"}, want: "one twhree"},
+		{line: "one two three", keys: []string{"$", "d2ge"}, want: "on"},
+		{line: "one two.three", keys: []string{"$", "dgE"}, want: "on"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q, keys %q: line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViVisualBlock(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "abc\ndef\nghi", keys: []string{"gg", "0", `\C-v`, "jl", "d"}, want: "c\nf\nghi"},
+		{line: "abc\ndef\nghi", keys: []string{"gg", "l", `\C-v`, "jjy", "$p"}, want: "abcb\ne\nh\ndef\nghi"},
+		{line: "abc\ndef\nghi", keys: []string{"gg", "0", `\C-v`, "jjI", "- ", `\C-o`}, want: "- abc\n- def\n- ghi"},
+		{line: "abc\nd\nghi", keys: []string{"gg", "l", `\C-v`, "jjA", ";", `\C-o`}, want: "ab;c\nd ;\ngh;i"},
+		{line: "abc\ndef\nghi", keys: []string{"gg", "l", `\C-v`, "jjc", "X", `\C-o`}, want: "aXc\ndXf\ngXi"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q, keys %q: line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViShowRegisters(t *testing.T) {
+	term, rl := newViTerminal(t, 30, 10)
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+	rl.Config.Bind("vi-command", inputrc.Unescape(`\C-t`), "vi-show-registers", false)
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("one two three four five six", `\C-o`, "0", `\C-t`)
+	waitForScreen(t, term, "one two three four five six\n(registers) - empty -")
+
+	term.Type(`"ayw`, "w", "y$", `\C-t`)
+	waitForScreen(t, term, "one two three four five six\n(registers)\n"+
+		"\"\" two three four five six\n"+
+		"\"0 two three four five six\n"+
+		"\"1 one\n"+
+		"\"a one")
+
+	term.Type("yy", `\C-t`)
+	waitForScreen(t, term, "one two three four five six\n(registers)\n"+
+		"\"\" one two three four five s…\n"+
+		"\"0 one two three four five s…\n"+
+		"\"1 two three four five six\n"+
+		"\"2 one\n"+
+		"\"a one")
+
+	term.Type("l")
+	waitForScreen(t, term, "one two three four five six")
+
+	term.Type(`\r`)
+	<-done
+}
+
+func TestShell_ViIncrement(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "sleep 9", keys: []string{"0", `\C-a`}, want: "sleep 10"},
+		{line: "sleep 10", keys: []string{"0", "5", `\C-x`}, want: "sleep 5"},
+		{line: "x 1 2", keys: []string{"0", "w", "w", `\C-a`}, want: "x 1 3"},
+		{line: "x 1 2", keys: []string{"0", "w", "w", `\C-x`, `\C-x`, `\C-x`}, want: "x 1 -1"},
+		{line: "x-3", keys: []string{"0", `\C-a`}, want: "x-2"},
+		{line: "x-3", keys: []string{"0", "10", `\C-a`}, want: "x7"},
+		{line: "0x0ff", keys: []string{"0", `\C-a`}, want: "0x100"},
+		{line: "0xFe", keys: []string{"0", "l", `\C-a`, `\C-a`}, want: "0x100"},
+		{line: "0x9F", keys: []string{"$", `\C-a`}, want: "0xA0"},
+		{line: "0x00", keys: []string{"0", `\C-x`}, want: "0xffffffffffffffff"},
+		{line: "0b0111", keys: []string{"0", `\C-a`}, want: "0b1000"},
+		{line: "007", keys: []string{"0", `\C-a`}, want: "010"},
+		{line: "009", keys: []string{"0", `\C-a`}, want: "10"},
+		{line: "v1.9 ok", keys: []string{"$", `\C-a`}, want: "v1.9 ok"},
+		{line: "a 1\nb 2", keys: []string{"0", `\C-a`}, want: "a 1\nb 3"},
+		{line: "n 41", keys: []string{"0", `\C-a`, "x"}, want: "n 4"},
+		{line: "n 41", keys: []string{"0", `\C-a`, "u"}, want: "n 41"},
+		{line: "n 41", keys: []string{"0", `\C-a`, "."}, want: "n 43"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q: Run(%q) line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViCaseOperators(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "foo bar baz", keys: []string{"0", "g", "U", "i", "w"}, want: "FOO bar baz"},
+		{line: "foo bar baz", keys: []string{"0", "w", "g", "U", "e"}, want: "foo BAR baz"},
+		{line: "foo bar baz", keys: []string{"0", "2", "g", "U", "w"}, want: "FOO BAR baz"},
+		{line: "foo bar baz", keys: []string{"0", "w", "g", "U", "$"}, want: "foo BAR BAZ"},
+		{line: "foo bar baz", keys: []string{"0", "g", "U", "g", "U"}, want: "FOO BAR BAZ"},
+		{line: "FOO BAR BAZ", keys: []string{"$", "g", "u", "b"}, want: "FOO BAR baZ"},
+		{line: "FOO BAR BAZ", keys: []string{"0", "g", "u", "i", "w", "w", "."}, want: "foo bar BAZ"},
+		{line: "Foo Bar baz", keys: []string{"0", "g", "~", "$"}, want: "fOO bAR BAZ"},
+		{line: "Foo Bar baz", keys: []string{"0", "g", "~", "g", "~"}, want: "fOO bAR BAZ"},
+		{line: "Foo Bar baz", keys: []string{"0", "g", "~", "i", "w", "x"}, want: "OO Bar baz"},
+		{line: "Foo Bar baz", keys: []string{"0", "v", "e", "~"}, want: "fOO Bar baz"},
+		{line: "foo bar baz", keys: []string{"0", "g", "U", "w", "u"}, want: "foo bar baz"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q: Run(%q) line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
+
+func TestShell_ViIndent(t *testing.T) {
+	tests := []struct {
+		line string
+		keys []string
+		want string
+	}{
+		{line: "a\nb\nc", keys: []string{"g", "g", ">", ">"}, want: "    a\nb\nc"},
+		{line: "a\nb\nc", keys: []string{"g", "g", ">", "j"}, want: "    a\n    b\nc"},
+		{line: "a\n\nc", keys: []string{"g", "g", ">", "G"}, want: "    a\n\n    c"},
+		{line: "a\nb\nc", keys: []string{">", "k"}, want: "a\n    b\n    c"},
+		{line: "a\nb\nc", keys: []string{"g", "g", "V", "j", ">"}, want: "    a\n    b\nc"},
+		{line: "a\nb\nc", keys: []string{"g", "g", ">", ">", "j", "."}, want: "    a\n    b\nc"},
+		{line: "a\nb\nc", keys: []string{"g", "g", ">", ">", ".", "x"}, want: "        \nb\nc"},
+		{line: "a\nb\nc", keys: []string{"g", "g", ">", ">", "u"}, want: "a\nb\nc"},
+		{line: "      a\n\tb", keys: []string{"g", "g", "<", "j"}, want: "  a\nb"},
+		{line: "  a\n    b", keys: []string{"<", "<"}, want: "  a\nb"},
+		{line: "  a\n    b", keys: []string{"g", "g", "<", "<", "x"}, want: "\n    b"},
+	}
+
+	for _, test := range tests {
+		term, rl := newViTerminal(t, 80, 24)
+		rl.Config.Set("vi-shiftwidth", 4)
+
+		rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+		rl.History.Prefill([]rune(test.line))
+
+		line, err := term.Run(rl, append(append([]string{`\C-o`}, test.keys...), `\r`)...)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("%q: Run(%q) line = %q, want %q", test.line, test.keys, line, test.want)
+		}
+	}
+}
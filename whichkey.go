@@ -0,0 +1,227 @@
+package readline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+	"github.com/reeflective/readline/internal/locale"
+	"github.com/reeflective/readline/internal/strutil"
+	"github.com/reeflective/readline/internal/term"
+)
+
+// waitPendingKeys waits for input keys. When some keys only matched binds by
+// prefix (or when a Vim operator is pending) and no other key arrives within
+// the which-key-delay (in milliseconds), all binds that can follow them are
+// listed in the hint section, until the next key is read. The line lock must
+// not be held when calling this function.
+func (rl *Shell) waitPendingKeys(ctx context.Context) error {
+	delay := time.Duration(rl.Config.GetInt("which-key-delay")) * time.Millisecond
+
+	keys, binds := rl.Keymap.PendingBinds()
+	if delay <= 0 || len(binds) == 0 {
+		return core.WaitAvailableKeys(ctx, rl.Keys, rl.Config)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, delay)
+	err := core.WaitAvailableKeys(waitCtx, rl.Keys, rl.Config)
+	cancel()
+
+	if !errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil {
+		return err
+	}
+
+	restore := rl.showPendingBinds(keys, binds)
+
+	err = core.WaitAvailableKeys(ctx, rl.Keys, rl.Config)
+
+	rl.lineMutex.Lock()
+	defer rl.lineMutex.Unlock()
+
+	restore()
+
+	return err
+}
+
+// showPendingBinds displays the binds that can follow the pending keys in
+// the hint section, and returns a function restoring the previous hint.
+func (rl *Shell) showPendingBinds(keys string, binds map[string]inputrc.Bind) (restore func()) {
+	rl.lineMutex.Lock()
+	defer rl.lineMutex.Unlock()
+
+	restore = rl.Hint.Snapshot()
+	rl.Hint.Set(rl.pendingKeysHint(keys, binds))
+	rl.Display.Refresh()
+
+	return restore
+}
+
+// pendingCommand returns the command being typed while some of its keys are
+// pending, like an operator waiting for a movement (3d) or a register ("a),
+// displayed in place of the right prompt if the show-pending-command option
+// is enabled, like the Vim showcmd option. Nothing is returned otherwise.
+func (rl *Shell) pendingCommand() string {
+	var command string
+
+	if rl.pendingRegister {
+		command = `"`
+	} else if register, selected := rl.Buffers.IsSelected(); selected {
+		command = `"` + register
+	}
+
+	operators, prefix := rl.Keymap.PendingKeys()
+
+	return command + inputrc.Escape(operators+rl.Iterations.Typed()+prefix)
+}
+
+// pendingKeysHint returns the pending keys followed by the binds that can
+// follow them, laid out in columns fitting the terminal width, and cropped
+// to the lines available below the input line.
+func (rl *Shell) pendingKeysHint(keys string, binds map[string]inputrc.Bind) string {
+	title := inputrc.Escape(keys)
+	if title == "" {
+		title = string(rl.Keymap.Local())
+	}
+
+	// Uppercase binds only running their lowercase
+	// counterpart would uselessly double the list.
+	sequences := make([]string, 0, len(binds))
+
+	for seq, bind := range binds {
+		if bind.Action == "" || bind.Action == "do-lowercase-version" {
+			continue
+		}
+
+		sequences = append(sequences, seq)
+	}
+
+	sort.Strings(sequences)
+
+	// Escape all sequences and actions, and compute the column width.
+	entries := make([]string, len(sequences))
+	var width int
+
+	for i, seq := range sequences {
+		bind := binds[seq]
+
+		action := bind.Action
+		if bind.Macro {
+			action = `"` + inputrc.EscapeMacro(action) + `"`
+		}
+
+		key := inputrc.Escape(seq)
+		entries[i] = color.Bold + key + color.BoldReset + " " + color.Dim + action + color.DimReset
+		width = max(width, strutil.RealLength(key+" "+action))
+	}
+
+	more := rl.locale.Get(locale.MoreBinds)
+
+	return rl.columnsHint(color.Bold+color.FgCyanBright+title+color.Reset, entries, width, more)
+}
+
+// registersHint returns the names of all filled registers, followed by the
+// first characters of their contents, laid out like the pending keys hint.
+func (rl *Shell) registersHint() string {
+	const previewLength = 16
+
+	names := rl.Buffers.Filled()
+	title := color.Bold + color.FgBlue + rl.locale.Get(locale.Registers) + color.Reset
+
+	if len(names) == 0 {
+		return title + color.Dim + rl.locale.Get(locale.RegistersEmpty) + color.Reset
+	}
+
+	entries := make([]string, len(names))
+	var width int
+
+	for i, name := range names {
+		preview := []rune(strings.ReplaceAll(string(rl.Buffers.Get(name)), "\n", " "))
+		if len(preview) > previewLength {
+			preview = append(preview[:previewLength-1], '…')
+		}
+
+		entries[i] = color.Bold + `"` + string(name) + color.BoldReset + " " + color.Dim + string(preview) + color.DimReset
+		width = max(width, strutil.RealLength(`"`+string(name)+" "+string(preview)))
+	}
+
+	return rl.columnsHint(title, entries, width, rl.locale.Get(locale.MoreRegisters))
+}
+
+// registersList returns the names of the unnamed register and of all filled
+// ones, each followed by its contents on its own line, cropped to the terminal
+// width, and where newlines are displayed as ^J.
+func (rl *Shell) registersList() string {
+	names := rl.Buffers.Filled()
+	if len(rl.Buffers.GetKill()) > 0 {
+		names = append([]rune{'"'}, names...)
+	}
+
+	title := color.Bold + color.FgBlue + rl.locale.Get(locale.Registers) + color.Reset
+
+	if len(names) == 0 {
+		return title + color.Dim + rl.locale.Get(locale.RegistersEmpty) + color.Reset
+	}
+
+	var list strings.Builder
+
+	list.WriteString(title)
+
+	for _, name := range names {
+		contents := []rune(strings.ReplaceAll(string(rl.Buffers.Get(name)), "\n", "^J"))
+		if room := rl.out.Width() - 4; len(contents) > room {
+			contents = append(contents[:max(room-1, 0)], '…')
+		}
+
+		list.WriteString(term.NewlineReturn + color.Bold + `"` + string(name) + color.BoldReset + " " + string(contents))
+	}
+
+	return list.String()
+}
+
+// columnsHint returns a hint title followed by entries laid out in columns of
+// the given width (plus a gap) fitting the terminal width, and cropped to the
+// lines available below the input line: the more format then reports the
+// number of entries left out.
+func (rl *Shell) columnsHint(title string, entries []string, width int, more string) string {
+	width += 2
+	columns := max(1, rl.out.Width()/width)
+	rows := (len(entries) + columns - 1) / columns
+	maxRows := max(1, rl.Display.AvailableHelperLines()-1)
+
+	var hint strings.Builder
+
+	hint.WriteString(title)
+
+	for row := 0; row < min(rows, maxRows); row++ {
+		hint.WriteString(term.NewlineReturn)
+
+		for col := 0; col < columns; col++ {
+			index := row*columns + col
+			if index >= len(entries) {
+				break
+			}
+
+			// Pad entries up to the next column only, since a
+			// line filling the terminal width would wrap.
+			entry := entries[index]
+			if col < columns-1 && index < len(entries)-1 {
+				entry += strings.Repeat(" ", max(width-strutil.RealLength(entry), 0))
+			}
+
+			hint.WriteString(entry)
+		}
+	}
+
+	if rows > maxRows {
+		left := len(entries) - maxRows*columns
+		hint.WriteString(term.NewlineReturn + color.Dim + fmt.Sprintf(more, left) + color.Reset)
+	}
+
+	return hint.String()
+}
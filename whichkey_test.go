@@ -0,0 +1,113 @@
+package readline_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/reeflective/readline/inputrc"
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_ShowPendingCommand(t *testing.T) {
+	term, rl := newViTerminal(t, 20, 10)
+	rl.Config.Set("show-pending-command", true)
+	rl.Config.Set("vi-register-hint", false)
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("one two", `\C-o`, "0", `"`)
+	waitForScreen(t, term, `one two            "`)
+
+	term.Type("a", "3")
+	waitForScreen(t, term, "one two          \"a3\n(arg: 3)")
+
+	term.Type("d")
+	waitForScreen(t, term, "one two         \"a3d\n(register: a)")
+
+	term.Type("l")
+	waitForScreen(t, term, "two")
+
+	term.Type(`\r`)
+
+	if line := <-done; line != " two" {
+		t.Errorf("Readline() line = %q, want %q", line, " two")
+	}
+}
+
+func TestShell_ViRegistersHint(t *testing.T) {
+	term, rl := newViTerminal(t, 50, 10)
+
+	rl.Config.Bind("vi-insert", inputrc.Unescape(`\C-o`), "vi-movement-mode", false)
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("one two three four five", `\C-o`, "0", `"`)
+	waitForScreen(t, term, "one two three four five\n(registers) - empty -")
+
+	term.Type("a", "y", "$", "w", `"`)
+	waitForScreen(t, term, "one two three four five\n(registers)\n"+
+		`"0 one two three f…  "a one two three f…`)
+
+	term.Type("b", "y", "w", `"`)
+	waitForScreen(t, term, "one two three four five\n(registers)\n"+
+		`"0 two               "1 one two three f…`+"\n"+
+		`"a one two three f…  "b two`)
+
+	term.Type(`\e`, `\C-c`)
+	<-done
+}
+
+func TestShell_WhichKeyDelay(t *testing.T) {
+	term := readlinetest.NewTerminal(50, 10)
+	rl := term.Shell()
+	rl.Config.Set("max-redisplay-rate", 0)
+	rl.Config.Set("which-key-delay", 100)
+	rl.Prompt.Primary(func() string { return "> " })
+
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xza`), "beginning-of-line", false)
+	rl.Config.Bind("emacs", inputrc.Unescape(`\C-xzb`), "upcase-word", false)
+
+	done := make(chan string, 1)
+
+	go func() {
+		line, _ := rl.Readline()
+		done <- line
+	}()
+
+	term.Type("ls")
+	waitForScreen(t, term, "> ls")
+
+	// The binds following the pending keys are shown once the delay has passed.
+	typed := time.Now()
+	term.Type(`\C-x`, "z")
+
+	waitForText(t, term, "beginning-of-line")
+
+	if elapsed := time.Since(typed); elapsed < 100*time.Millisecond {
+		t.Errorf("pending keys hint shown after %v, want at least 100ms", elapsed)
+	}
+
+	readlinetest.AssertScreen(t, term, "> ls\n\\C-Xz\na beginning-of-line  b upcase-word")
+
+	// The hint is dropped once the bind is complete.
+	term.Type("a")
+	waitForScreen(t, term, "> ls")
+
+	term.Type("x", `\r`)
+
+	if line := <-done; line != "xls" {
+		t.Errorf("Readline() line = %q, want %q", line, "xls")
+	}
+}
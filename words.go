@@ -0,0 +1,134 @@
+package readline
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/reeflective/readline/internal/color"
+	"github.com/reeflective/readline/internal/core"
+)
+
+// Token is a token of the input line returned by a host Tokenizer:
+// its characters are those of the line from Start (included) to End
+// (excluded), and its Type is used to highlight it with TokenStyles.
+type Token struct {
+	Start int
+	End   int
+	Type  string
+}
+
+// wordClass returns the class of characters making words for all word
+// commands: the shell one if any, or the one of the word-style option,
+// with the characters of the word-chars option (and of the vi-iskeyword
+// one in vi modes) being part of words.
+func (rl *Shell) wordClass() core.WordClass {
+	if rl.WordClass != nil {
+		return rl.WordClass
+	}
+
+	style := strings.Trim(rl.Config.GetString("word-style"), "\"")
+	chars := strings.Trim(rl.Config.GetString("word-chars"), "\"")
+
+	// Vi modes may have their own additional word characters.
+	if !rl.Keymap.IsEmacs() {
+		chars += strings.Trim(rl.Config.GetString("vi-iskeyword"), "\"")
+	}
+
+	if style == "readline" && chars == "" {
+		return nil
+	}
+
+	return core.WordStyle(style, chars)
+}
+
+// wordTokenizer returns the tokenizer splitting the line into words:
+// the tokens of the host tokenizer if any, or those of the word class.
+func (rl *Shell) wordTokenizer() core.Tokenizer {
+	if spans := rl.tokenSpans(*rl.line); spans != nil {
+		return rl.line.TokenizeSpans(spans)
+	}
+
+	return rl.line.TokenizeWords(rl.wordClass())
+}
+
+// shellWordTokenizer returns the tokenizer splitting the line into
+// shell words: the tokens of the host tokenizer if any, or blank words.
+func (rl *Shell) shellWordTokenizer() core.Tokenizer {
+	if spans := rl.tokenSpans(*rl.line); spans != nil {
+		return rl.line.TokenizeSpans(spans)
+	}
+
+	return rl.line.TokenizeSpace
+}
+
+// selectWord returns the begin and end positions of the word around
+// a position: the token of the host tokenizer if any, or the word of
+// the word class.
+func (rl *Shell) selectWord(pos int) (bpos, epos int) {
+	if spans := rl.tokenSpans(*rl.line); spans != nil {
+		return rl.line.SelectSpan(pos, spans)
+	}
+
+	return rl.line.SelectWordClass(pos, rl.wordClass())
+}
+
+// tokenSpans returns the spans of the tokens of a line,
+// or nil if the shell has no tokenizer.
+func (rl *Shell) tokenSpans(line []rune) [][]int {
+	if rl.Tokenizer == nil {
+		return nil
+	}
+
+	tokens := rl.Tokenizer(line)
+	spans := make([][]int, 0, len(tokens))
+
+	for _, token := range tokens {
+		spans = append(spans, []int{token.Start, token.End})
+	}
+
+	return spans
+}
+
+// highlighter returns the syntax highlighter of the shell, or if none,
+// a highlighter styling the tokens of the host tokenizer, if both the
+// tokenizer and token styles are set.
+func (rl *Shell) highlighter() func(line []rune) string {
+	if rl.SyntaxHighlighter != nil || rl.Tokenizer == nil || rl.TokenStyles == nil {
+		return rl.SyntaxHighlighter
+	}
+
+	return rl.highlightTokens
+}
+
+// highlightTokens returns the line with its tokens highlighted with the
+// style of their type, if any. Characters of overlapping tokens are only
+// highlighted with the style of the first one.
+func (rl *Shell) highlightTokens(line []rune) string {
+	tokens := slices.Clone(rl.Tokenizer(line))
+
+	slices.SortStableFunc(tokens, func(a, b Token) int {
+		return a.Start - b.Start
+	})
+
+	var highlighted strings.Builder
+
+	pos := 0
+
+	for _, token := range tokens {
+		start, end := max(token.Start, pos), min(token.End, len(line))
+		style := rl.TokenStyles[token.Type]
+
+		if start >= end || style == "" {
+			continue
+		}
+
+		highlighted.WriteString(string(line[pos:start]))
+		highlighted.WriteString(style + string(line[start:end]) + color.Reset)
+
+		pos = end
+	}
+
+	highlighted.WriteString(string(line[pos:]))
+
+	return highlighted.String()
+}
@@ -0,0 +1,50 @@
+package readline_test
+
+import (
+	"testing"
+
+	"github.com/reeflective/readline/readlinetest"
+)
+
+func TestShell_WordStyle(t *testing.T) {
+	tests := []struct {
+		style, chars string
+		class        func(char rune) int
+		want         string
+	}{
+		{style: "readline", want: "foo->barX_baz"},
+		{style: "vim", want: "fooX->bar_baz"},
+		{style: "vim", chars: "->", want: "Xfoo->bar_baz"},
+		{style: "bash", want: "foo->Xbar_baz"},
+		{style: "space", want: "Xfoo->bar_baz"},
+		{
+			style: "space",
+			class: func(char rune) int {
+				if char == '_' {
+					return 2
+				}
+
+				return 1
+			},
+			want: "foo->barX_baz",
+		},
+	}
+
+	for _, test := range tests {
+		term := readlinetest.NewTerminal(80, 24)
+		rl := term.Shell()
+
+		rl.Config.Set("word-style", test.style)
+		rl.Config.Set("word-chars", test.chars)
+		rl.WordClass = test.class
+
+		line, err := term.Run(rl, "foo->bar_baz", `\eb`, `\eb`, "X", `\r`)
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+
+		if line != test.want {
+			t.Errorf("Run() with style %q and word chars %q = %q, want %q", test.style, test.chars, line, test.want)
+		}
+	}
+}